@@ -0,0 +1,74 @@
+package api
+
+import (
+	"encoding/csv"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"admira-etl/internal/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+const csvContentType = "text/csv"
+
+var csvHeader = []string{
+	"date", "channel", "campaign_id", "clicks", "impressions", "cost",
+	"leads", "opportunities", "closed_won", "revenue",
+	"cpc", "cpa", "cvr_lead_to_opp", "cvr_opp_to_won", "roas", "ctr", "cpm", "match_type",
+}
+
+// wantsCSV reports whether the client asked for CSV, either with ?format=csv
+// or an Accept header listing text/csv.
+func wantsCSV(c *gin.Context) bool {
+	if format := c.Query("format"); format != "" {
+		return strings.EqualFold(format, "csv")
+	}
+	return strings.Contains(c.GetHeader("Accept"), csvContentType)
+}
+
+// writeCSV streams rows to the response as CSV with a header row. Rows are
+// written straight to the ResponseWriter so the encoded output is never held
+// in memory as a whole.
+func writeCSV(c *gin.Context, data []models.TransformedData) error {
+	c.Header("Content-Type", csvContentType+"; charset=utf-8")
+	c.Status(http.StatusOK)
+
+	writer := csv.NewWriter(c.Writer)
+	if err := writer.Write(csvHeader); err != nil {
+		return err
+	}
+
+	for _, item := range data {
+		if err := writer.Write(csvRecord(item)); err != nil {
+			return err
+		}
+	}
+
+	writer.Flush()
+	return writer.Error()
+}
+
+func csvRecord(item models.TransformedData) []string {
+	return []string{
+		item.Date,
+		item.Channel,
+		item.CampaignID,
+		strconv.Itoa(item.Clicks),
+		strconv.Itoa(item.Impressions),
+		strconv.FormatFloat(item.Cost, 'f', 2, 64),
+		strconv.Itoa(item.Leads),
+		strconv.Itoa(item.Opportunities),
+		strconv.Itoa(item.ClosedWon),
+		strconv.FormatFloat(item.Revenue, 'f', 2, 64),
+		strconv.FormatFloat(item.CPC, 'f', 4, 64),
+		strconv.FormatFloat(item.CPA, 'f', 4, 64),
+		strconv.FormatFloat(item.CVRLeadToOpp, 'f', 4, 64),
+		strconv.FormatFloat(item.CVROppToWon, 'f', 4, 64),
+		strconv.FormatFloat(item.ROAS, 'f', 4, 64),
+		strconv.FormatFloat(item.CTR, 'f', 4, 64),
+		strconv.FormatFloat(item.CPM, 'f', 4, 64),
+		item.MatchType,
+	}
+}
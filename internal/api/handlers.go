@@ -1,11 +1,18 @@
 package api
 
 import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
 	"net/http"
+	"runtime"
 	"time"
 
+	"admira-etl/internal/constants"
 	"admira-etl/internal/etl"
 	"admira-etl/internal/models"
+	"admira-etl/internal/version"
 
 	"github.com/gin-gonic/gin"
 	"github.com/sirupsen/logrus"
@@ -34,27 +41,618 @@ func (h *Handlers) RunIngestion(c *gin.Context) {
 		return
 	}
 
-	h.logger.WithField("since", req.Since).Info("Starting ingestion")
+	// Accept any supported date layout, normalised to YYYY-MM-DD
+	for _, date := range []*string{&req.Since, &req.Until} {
+		if *date == "" {
+			continue
+		}
+		normalized, err := etl.NormalizeDate(*date)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, models.ErrorResponse{
+				Error:   "Invalid date format",
+				Message: err.Error(),
+			})
+			return
+		}
+		*date = normalized
+	}
+
+	// YYYY-MM-DD strings order the same as the dates they represent
+	if req.Since != "" && req.Until != "" && req.Since > req.Until {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Error:   "Invalid date range",
+			Message: "since must not be after until",
+		})
+		return
+	}
+
+	opts := etl.IngestOptions{Since: req.Since, Until: req.Until, Full: req.Full}
+	fields := logrus.Fields{"since": req.Since, "until": req.Until, "full": req.Full}
+
+	if req.Async {
+		job := h.service(c).RunIngestionAsync(c.Request.Context(), opts)
+		h.logger.WithFields(fields).WithField("job_id", job.ID).Info("Queued async ingestion")
+		c.JSON(http.StatusAccepted, gin.H{
+			"message": "Ingestion started",
+			"job_id":  job.ID,
+			"since":   req.Since,
+			"until":   req.Until,
+			"full":    req.Full,
+		})
+		return
+	}
+
+	h.logger.WithFields(fields).Info("Starting ingestion")
+
+	if err := h.service(c).RunIngestion(c.Request.Context(), opts); err != nil {
+		h.logger.WithError(err).Error("Ingestion failed")
+
+		var validationErr *etl.ValidationError
+		if errors.As(err, &validationErr) {
+			c.JSON(http.StatusUnprocessableEntity, gin.H{
+				"error":      "Ingestion failed",
+				"message":    err.Error(),
+				"validation": validationErr.Report,
+			})
+			return
+		}
+
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+			Error:   "Ingestion failed",
+			Message: err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":    "Ingestion completed successfully",
+		"since":      req.Since,
+		"until":      req.Until,
+		"full":       req.Full,
+		"validation": h.service(c).LastValidationReport(),
+	})
+}
+
+// ReingestDate reprocesses one day from the upstream APIs, replacing the
+// rows stored for it.
+func (h *Handlers) ReingestDate(c *gin.Context) {
+	var req models.ReingestDateRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		h.logger.WithError(err).Error("Invalid reingestion request")
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Error:   "Invalid request parameters",
+			Message: err.Error(),
+		})
+		return
+	}
+
+	date, err := etl.NormalizeDate(req.Date)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Error:   "Invalid date format",
+			Message: err.Error(),
+		})
+		return
+	}
+
+	h.logger.WithField("date", date).Info("Starting date reingestion")
+
+	deleted, records, err := h.service(c).ReingestDate(c.Request.Context(), date)
+	if err != nil {
+		h.logger.WithError(err).Error("Reingestion failed")
+
+		var validationErr *etl.ValidationError
+		if errors.As(err, &validationErr) {
+			c.JSON(http.StatusUnprocessableEntity, gin.H{
+				"error":      "Reingestion failed",
+				"message":    err.Error(),
+				"validation": validationErr.Report,
+			})
+			return
+		}
+
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+			Error:   "Reingestion failed",
+			Message: err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":    "Reingestion completed successfully",
+		"date":       date,
+		"deleted":    deleted,
+		"records":    records,
+		"validation": h.service(c).LastValidationReport(),
+	})
+}
+
+// IngestData transforms and stores Ads/CRM data pushed in the request body,
+// in the same ExternalResponse shape the upstream APIs return.
+func (h *Handlers) IngestData(c *gin.Context) {
+	var req models.IngestDataRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Error:   "Invalid request parameters",
+			Message: err.Error(),
+		})
+		return
+	}
+
+	if req.Since != "" {
+		normalized, err := etl.NormalizeDate(req.Since)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, models.ErrorResponse{
+				Error:   "Invalid date format",
+				Message: err.Error(),
+			})
+			return
+		}
+		req.Since = normalized
+	}
+
+	var payload models.ExternalResponse
+	if err := c.ShouldBindJSON(&payload); err != nil {
+		if requestTooLarge(c, err) {
+			return
+		}
+		h.logger.WithError(err).Error("Invalid ingestion payload")
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Error:   "Invalid payload",
+			Message: err.Error(),
+		})
+		return
+	}
+
+	records, err := h.service(c).IngestPayload(c.Request.Context(), &payload, req.Since)
+	if errors.Is(err, etl.ErrInvalidPayload) {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Error:   "Invalid payload",
+			Message: err.Error(),
+		})
+		return
+	}
+	if err != nil {
+		h.logger.WithError(err).Error("Payload ingestion failed")
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+			Error:   "Ingestion failed",
+			Message: err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Ingestion completed successfully",
+		"records": records,
+		"since":   req.Since,
+	})
+}
+
+// IngestWebhook stores transformed rows pushed by an upstream, a single
+// TransformedData object or an array of them, once their X-Signature checks
+// out against the sink secret.
+func (h *Handlers) IngestWebhook(c *gin.Context) {
+	body, err := io.ReadAll(c.Request.Body)
+	if err != nil {
+		if requestTooLarge(c, err) {
+			return
+		}
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Error:   "Invalid payload",
+			Message: err.Error(),
+		})
+		return
+	}
+
+	records, err := decodeTransformedData(body)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Error:   "Invalid payload",
+			Message: err.Error(),
+		})
+		return
+	}
+
+	stored, err := h.service(c).IngestSigned(c.Request.Context(), records, c.GetHeader(etl.SignatureHeader))
+	switch {
+	case errors.Is(err, etl.ErrInvalidSignature):
+		h.logger.Warn("Rejected webhook with an invalid signature")
+		c.JSON(http.StatusUnauthorized, models.ErrorResponse{
+			Error:   "Unauthorized",
+			Message: "Invalid signature",
+		})
+		return
+	case errors.Is(err, etl.ErrWebhookNotConfigured):
+		c.JSON(http.StatusServiceUnavailable, models.ErrorResponse{
+			Error:   "Webhook not configured",
+			Message: err.Error(),
+		})
+		return
+	case errors.Is(err, etl.ErrInvalidPayload):
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Error:   "Invalid payload",
+			Message: err.Error(),
+		})
+		return
+	case err != nil:
+		h.logger.WithError(err).Error("Webhook ingestion failed")
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+			Error:   "Ingestion failed",
+			Message: err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Ingestion completed successfully",
+		"records": stored,
+	})
+}
+
+// decodeTransformedData accepts either one JSON object or an array of them.
+func decodeTransformedData(body []byte) ([]models.TransformedData, error) {
+	trimmed := bytes.TrimSpace(body)
+	if len(trimmed) > 0 && trimmed[0] == '[' {
+		var records []models.TransformedData
+		err := json.Unmarshal(trimmed, &records)
+		return records, err
+	}
+
+	var record models.TransformedData
+	if err := json.Unmarshal(trimmed, &record); err != nil {
+		return nil, err
+	}
+	return []models.TransformedData{record}, nil
+}
+
+// GetSnapshot downloads a JSON backup of the stored dataset, suitable for
+// RestoreSnapshot.
+func (h *Handlers) GetSnapshot(c *gin.Context) {
+	c.Header("Content-Type", "application/json; charset=utf-8")
+	c.Header("Content-Disposition", `attachment; filename="admira-etl-snapshot.json"`)
+	c.Status(http.StatusOK)
+
+	if err := h.service(c).WriteSnapshot(c.Writer); err != nil {
+		h.logger.WithError(err).Error("Failed to write snapshot")
+		if !c.Writer.Written() {
+			c.Header("Content-Disposition", "")
+			c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+				Error:   "Failed to write snapshot",
+				Message: err.Error(),
+			})
+		}
+	}
+}
+
+// RestoreSnapshot replaces the stored dataset with an uploaded snapshot.
+func (h *Handlers) RestoreSnapshot(c *gin.Context) {
+	body, err := io.ReadAll(c.Request.Body)
+	if err != nil {
+		if requestTooLarge(c, err) {
+			return
+		}
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Error:   "Invalid payload",
+			Message: err.Error(),
+		})
+		return
+	}
+
+	records, err := h.service(c).RestoreSnapshot(c.Request.Context(), bytes.NewReader(body))
+	if errors.Is(err, etl.ErrInvalidPayload) {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Error:   "Invalid payload",
+			Message: err.Error(),
+		})
+		return
+	}
+	if err != nil {
+		h.logger.WithError(err).Error("Snapshot restore failed")
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+			Error:   "Restore failed",
+			Message: err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Snapshot restored successfully",
+		"records": records,
+	})
+}
+
+// GetIngestionStatus reports when data was last ingested and the extent of
+// what is stored.
+func (h *Handlers) GetIngestionStatus(c *gin.Context) {
+	status, err := h.service(c).IngestionStatus()
+	if err != nil {
+		h.logger.WithError(err).Error("Failed to get ingestion status")
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+			Error:   "Failed to get ingestion status",
+			Message: err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, status)
+}
+
+func (h *Handlers) GetJob(c *gin.Context) {
+	job, exists := h.service(c).GetJob(c.Param("id"))
+	if !exists {
+		c.JSON(http.StatusNotFound, models.ErrorResponse{
+			Error:   "Job not found",
+			Message: "No job with ID " + c.Param("id"),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, job)
+}
+
+func (h *Handlers) GetChannelMetrics(c *gin.Context) {
+	var req models.MetricsChannelRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		h.logger.WithError(err).Error("Invalid channel metrics request")
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Error:   "Invalid request parameters",
+			Message: err.Error(),
+		})
+		return
+	}
+
+	from, to, ok := h.metricsDateRange(c, req.From, req.To)
+	if !ok {
+		return
+	}
+
+	version, ok := negotiateVersion(c)
+	if !ok {
+		return
+	}
+
+	req.Limit = effectiveLimit(req.Limit)
+
+	granularity := etl.Granularity(req.Granularity)
+	if granularity == "" {
+		granularity = etl.GranularityDay
+	}
+	if req.Consolidate && granularity != etl.GranularityDay {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Error:   "Invalid request parameters",
+			Message: "consolidate cannot be combined with granularity",
+		})
+		return
+	}
+
+	sortBy := etl.SortField(req.SortBy)
+	if sortBy == "" {
+		sortBy = etl.SortByDate
+	}
+	order := etl.SortOrder(req.Order)
+	if order == "" {
+		order = etl.SortAsc
+	}
+
+	// Consolidated rows have no date to resume from
+	keyOrder := sortBy == etl.SortByDate && order == etl.SortAsc && !req.Consolidate
+	after, ok := parseCursor(c, req.Cursor, req.Offset, keyOrder)
+	if !ok {
+		return
+	}
+
+	data, nextCursor, err := h.service(c).GetChannelMetrics(etl.ChannelMetricsQuery{
+		From:        from,
+		To:          to,
+		Channel:     req.Channel,
+		Granularity: granularity,
+		SortBy:      sortBy,
+		Order:       order,
+		Limit:       req.Limit,
+		Offset:      req.Offset,
+		After:       after,
+		Consolidate: req.Consolidate,
+		MinCost:     req.MinCost,
+		MinRevenue:  req.MinRevenue,
+	})
+	if err != nil {
+		h.logger.WithError(err).Error("Failed to get channel metrics")
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+			Error:   "Failed to retrieve metrics",
+			Message: err.Error(),
+		})
+		return
+	}
+
+	if wantsCSV(c) {
+		if err := writeCSV(c, data); err != nil {
+			h.logger.WithError(err).Error("Failed to write channel metrics CSV")
+		}
+		return
+	}
+
+	freshness, ok := h.freshness(c)
+	if !ok {
+		return
+	}
+
+	c.JSON(http.StatusOK, withFreshness(gin.H{
+		"data":        shapeRows(version, data),
+		"count":       len(data),
+		"limit":       req.Limit,
+		"offset":      req.Offset,
+		"granularity": granularity,
+		"consolidate": req.Consolidate,
+		"sort_by":     sortBy,
+		"order":       order,
+		"next_cursor": nextCursor,
+	}, freshness))
+}
+
+func (h *Handlers) GetFunnelMetrics(c *gin.Context) {
+	var req models.MetricsFunnelRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		h.logger.WithError(err).Error("Invalid funnel metrics request")
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Error:   "Invalid request parameters",
+			Message: err.Error(),
+		})
+		return
+	}
+
+	from, to, ok := h.metricsDateRange(c, req.From, req.To)
+	if !ok {
+		return
+	}
+
+	version, ok := negotiateVersion(c)
+	if !ok {
+		return
+	}
+
+	req.Limit = effectiveLimit(req.Limit)
+
+	after, ok := parseCursor(c, req.Cursor, req.Offset, true)
+	if !ok {
+		return
+	}
+
+	data, nextCursor, err := h.service(c).GetFunnelMetrics(from, to, req.UTMCampaign, req.Limit, req.Offset, after)
+	if err != nil {
+		h.logger.WithError(err).Error("Failed to get funnel metrics")
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+			Error:   "Failed to retrieve metrics",
+			Message: err.Error(),
+		})
+		return
+	}
+
+	if wantsCSV(c) {
+		if err := writeCSV(c, data); err != nil {
+			h.logger.WithError(err).Error("Failed to write funnel metrics CSV")
+		}
+		return
+	}
+
+	freshness, ok := h.freshness(c)
+	if !ok {
+		return
+	}
+
+	c.JSON(http.StatusOK, withFreshness(gin.H{
+		"data":        shapeRows(version, data),
+		"count":       len(data),
+		"limit":       req.Limit,
+		"offset":      req.Offset,
+		"next_cursor": nextCursor,
+	}, freshness))
+}
+
+// GetSourceMetrics returns totals per UTM source and medium over a date
+// range, optionally restricted to one source and/or medium.
+func (h *Handlers) GetSourceMetrics(c *gin.Context) {
+	var req models.MetricsSourceRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		h.logger.WithError(err).Error("Invalid source metrics request")
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Error:   "Invalid request parameters",
+			Message: err.Error(),
+		})
+		return
+	}
+
+	from, to, ok := h.metricsDateRange(c, req.From, req.To)
+	if !ok {
+		return
+	}
+
+	version, ok := negotiateVersion(c)
+	if !ok {
+		return
+	}
+
+	req.Limit = effectiveLimit(req.Limit)
+
+	data, err := h.service(c).GetSourceMetrics(from, to, req.UTMSource, req.UTMMedium, req.Limit, req.Offset)
+	if err != nil {
+		h.logger.WithError(err).Error("Failed to get source metrics")
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+			Error:   "Failed to retrieve metrics",
+			Message: err.Error(),
+		})
+		return
+	}
+
+	freshness, ok := h.freshness(c)
+	if !ok {
+		return
+	}
+
+	c.JSON(http.StatusOK, withFreshness(gin.H{
+		"data":   shapeRows(version, data),
+		"count":  len(data),
+		"limit":  req.Limit,
+		"offset": req.Offset,
+	}, freshness))
+}
 
-	if err := h.etlService.RunIngestion(c.Request.Context(), req.Since); err != nil {
-		h.logger.WithError(err).Error("Ingestion failed")
+func (h *Handlers) GetCampaignMetrics(c *gin.Context) {
+	var req models.MetricsCampaignRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		h.logger.WithError(err).Error("Invalid campaign metrics request")
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Error:   "Invalid request parameters",
+			Message: err.Error(),
+		})
+		return
+	}
+
+	from, to, ok := h.metricsDateRange(c, req.From, req.To)
+	if !ok {
+		return
+	}
+
+	version, ok := negotiateVersion(c)
+	if !ok {
+		return
+	}
+
+	req.Limit = effectiveLimit(req.Limit)
+
+	data, err := h.service(c).GetCampaignMetrics(from, to, req.CampaignID, req.Consolidate, req.Limit, req.Offset)
+	if err != nil {
+		h.logger.WithError(err).Error("Failed to get campaign metrics")
 		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
-			Error:   "Ingestion failed",
+			Error:   "Failed to retrieve metrics",
 			Message: err.Error(),
 		})
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{
-		"message": "Ingestion completed successfully",
-		"since":   req.Since,
-	})
+	if wantsCSV(c) {
+		if err := writeCSV(c, data); err != nil {
+			h.logger.WithError(err).Error("Failed to write campaign metrics CSV")
+		}
+		return
+	}
+
+	freshness, ok := h.freshness(c)
+	if !ok {
+		return
+	}
+
+	c.JSON(http.StatusOK, withFreshness(gin.H{
+		"data":   shapeRows(version, data),
+		"count":  len(data),
+		"limit":  req.Limit,
+		"offset": req.Offset,
+	}, freshness))
 }
 
-func (h *Handlers) GetChannelMetrics(c *gin.Context) {
-	var req models.MetricsChannelRequest
+func (h *Handlers) GetMetricsSummary(c *gin.Context) {
+	var req models.MetricsSummaryRequest
 	if err := c.ShouldBindQuery(&req); err != nil {
-		h.logger.WithError(err).Error("Invalid channel metrics request")
+		h.logger.WithError(err).Error("Invalid metrics summary request")
 		c.JSON(http.StatusBadRequest, models.ErrorResponse{
 			Error:   "Invalid request parameters",
 			Message: err.Error(),
@@ -62,33 +660,58 @@ func (h *Handlers) GetChannelMetrics(c *gin.Context) {
 		return
 	}
 
-	// Parse dates
-	from, err := time.Parse("2006-01-02", req.From)
+	from, to, ok := h.metricsDateRange(c, req.From, req.To)
+	if !ok {
+		return
+	}
+
+	summary, err := h.service(c).GetMetricsSummary(from, to, req.Channel)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, models.ErrorResponse{
-			Error:   "Invalid from date format",
-			Message: "Expected YYYY-MM-DD format",
+		h.logger.WithError(err).Error("Failed to get metrics summary")
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+			Error:   "Failed to retrieve metrics",
+			Message: err.Error(),
 		})
 		return
 	}
 
-	to, err := time.Parse("2006-01-02", req.To)
-	if err != nil {
+	freshness, ok := h.freshness(c)
+	if !ok {
+		return
+	}
+
+	c.JSON(http.StatusOK, struct {
+		*models.MetricsSummary
+		models.Freshness
+	}{summary, freshness})
+}
+
+func (h *Handlers) GetTimeSeries(c *gin.Context) {
+	var req models.TimeSeriesRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		h.logger.WithError(err).Error("Invalid time series request")
 		c.JSON(http.StatusBadRequest, models.ErrorResponse{
-			Error:   "Invalid to date format",
-			Message: "Expected YYYY-MM-DD format",
+			Error:   "Invalid request parameters",
+			Message: err.Error(),
 		})
 		return
 	}
 
-	// Set default pagination
-	if req.Limit <= 0 {
-		req.Limit = 100
+	from, to, ok := h.metricsDateRange(c, req.From, req.To)
+	if !ok {
+		return
 	}
 
-	data, err := h.etlService.GetChannelMetrics(from, to, req.Channel, req.Limit, req.Offset)
+	series, err := h.service(c).GetTimeSeries(from, to, req.Channel, etl.SortField(req.Metric), etl.GapFill(req.Fill))
+	if errors.Is(err, etl.ErrTimeSeriesTooLong) {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Error:   "Invalid date range",
+			Message: err.Error(),
+		})
+		return
+	}
 	if err != nil {
-		h.logger.WithError(err).Error("Failed to get channel metrics")
+		h.logger.WithError(err).Error("Failed to get time series")
 		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
 			Error:   "Failed to retrieve metrics",
 			Message: err.Error(),
@@ -96,18 +719,21 @@ func (h *Handlers) GetChannelMetrics(c *gin.Context) {
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{
-		"data":   data,
-		"count":  len(data),
-		"limit":  req.Limit,
-		"offset": req.Offset,
-	})
+	freshness, ok := h.freshness(c)
+	if !ok {
+		return
+	}
+
+	c.JSON(http.StatusOK, struct {
+		models.TimeSeries
+		models.Freshness
+	}{series, freshness})
 }
 
-func (h *Handlers) GetFunnelMetrics(c *gin.Context) {
-	var req models.MetricsFunnelRequest
+func (h *Handlers) GetDimensions(c *gin.Context) {
+	var req models.DimensionsRequest
 	if err := c.ShouldBindQuery(&req); err != nil {
-		h.logger.WithError(err).Error("Invalid funnel metrics request")
+		h.logger.WithError(err).Error("Invalid dimensions request")
 		c.JSON(http.StatusBadRequest, models.ErrorResponse{
 			Error:   "Invalid request parameters",
 			Message: err.Error(),
@@ -115,33 +741,97 @@ func (h *Handlers) GetFunnelMetrics(c *gin.Context) {
 		return
 	}
 
-	// Parse dates
-	from, err := time.Parse("2006-01-02", req.From)
+	from, to, ok := h.metricsDateRange(c, req.From, req.To)
+	if !ok {
+		return
+	}
+
+	dimensions, err := h.service(c).GetDimensions(from, to)
 	if err != nil {
+		h.logger.WithError(err).Error("Failed to get dimensions")
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+			Error:   "Failed to retrieve dimensions",
+			Message: err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, dimensions)
+}
+
+func (h *Handlers) CompareMetrics(c *gin.Context) {
+	var req models.MetricsCompareRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		h.logger.WithError(err).Error("Invalid metrics comparison request")
 		c.JSON(http.StatusBadRequest, models.ErrorResponse{
-			Error:   "Invalid from date format",
-			Message: "Expected YYYY-MM-DD format",
+			Error:   "Invalid request parameters",
+			Message: err.Error(),
 		})
 		return
 	}
 
-	to, err := time.Parse("2006-01-02", req.To)
+	from, to, ok := h.metricsDateRange(c, req.From, req.To)
+	if !ok {
+		return
+	}
+
+	comparison, err := h.service(c).CompareMetrics(from, to, req.Channel)
 	if err != nil {
+		h.logger.WithError(err).Error("Failed to compare metrics")
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+			Error:   "Failed to retrieve metrics",
+			Message: err.Error(),
+		})
+		return
+	}
+
+	freshness, ok := h.freshness(c)
+	if !ok {
+		return
+	}
+
+	c.JSON(http.StatusOK, struct {
+		*models.MetricsComparison
+		models.Freshness
+	}{comparison, freshness})
+}
+
+func (h *Handlers) GetTopCampaigns(c *gin.Context) {
+	var req models.TopCampaignsRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		h.logger.WithError(err).Error("Invalid top campaigns request")
 		c.JSON(http.StatusBadRequest, models.ErrorResponse{
-			Error:   "Invalid to date format",
-			Message: "Expected YYYY-MM-DD format",
+			Error:   "Invalid request parameters",
+			Message: err.Error(),
 		})
 		return
 	}
 
-	// Set default pagination
-	if req.Limit <= 0 {
-		req.Limit = 100
+	from, to, ok := h.metricsDateRange(c, req.From, req.To)
+	if !ok {
+		return
+	}
+
+	version, ok := negotiateVersion(c)
+	if !ok {
+		return
+	}
+
+	metric := etl.SortField(req.Metric)
+	if metric == "" {
+		metric = etl.SortByRevenue
+	}
+	n := req.N
+	if n <= 0 {
+		n = constants.DefaultTopN
+	}
+	if n > constants.MaxLimit {
+		n = constants.MaxLimit
 	}
 
-	data, err := h.etlService.GetFunnelMetrics(from, to, req.UTMCampaign, req.Limit, req.Offset)
+	data, err := h.service(c).GetTopCampaigns(from, to, metric, n)
 	if err != nil {
-		h.logger.WithError(err).Error("Failed to get funnel metrics")
+		h.logger.WithError(err).Error("Failed to rank campaigns")
 		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
 			Error:   "Failed to retrieve metrics",
 			Message: err.Error(),
@@ -149,11 +839,52 @@ func (h *Handlers) GetFunnelMetrics(c *gin.Context) {
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{
-		"data":   data,
+	freshness, ok := h.freshness(c)
+	if !ok {
+		return
+	}
+
+	c.JSON(http.StatusOK, withFreshness(gin.H{
+		"data":   shapeRows(version, data),
 		"count":  len(data),
-		"limit":  req.Limit,
-		"offset": req.Offset,
+		"metric": metric,
+		"n":      n,
+		"from":   from.Format("2006-01-02"),
+		"to":     to.Format("2006-01-02"),
+	}, freshness))
+}
+
+func (h *Handlers) DeleteData(c *gin.Context) {
+	var req models.DeleteDataRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		h.logger.WithError(err).Error("Invalid delete data request")
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Error:   "Invalid request parameters",
+			Message: err.Error(),
+		})
+		return
+	}
+
+	from, to, ok := parseDateRange(c, req.From, req.To)
+	if !ok {
+		return
+	}
+
+	deleted, err := h.service(c).DeleteData(from, to, req.Channel)
+	if err != nil {
+		h.logger.WithError(err).Error("Failed to delete data")
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+			Error:   "Failed to delete data",
+			Message: err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"deleted": deleted,
+		"from":    req.From,
+		"to":      req.To,
+		"channel": req.Channel,
 	})
 }
 
@@ -168,10 +899,58 @@ func (h *Handlers) ExportData(c *gin.Context) {
 		return
 	}
 
-	h.logger.WithField("date", req.Date).Info("Starting data export")
+	date, err := etl.NormalizeDate(req.Date)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Error:   "Invalid date format",
+			Message: err.Error(),
+		})
+		return
+	}
+	req.Date = date
+
+	filter := etl.ExportFilter{Channel: req.Channel, CampaignID: req.CampaignID}
+	fields := logrus.Fields{"date": req.Date, "dry_run": req.DryRun}
+	if filter.Channel != "" {
+		fields["channel"] = filter.Channel
+	}
+	if filter.CampaignID != "" {
+		fields["campaign_id"] = filter.CampaignID
+	}
+
+	if req.Async {
+		job := h.service(c).ExportDataAsync(c.Request.Context(), req.Date, req.DryRun, filter)
+		h.logger.WithFields(fields).WithField("job_id", job.ID).Info("Queued async export")
+		c.JSON(http.StatusAccepted, gin.H{
+			"message":     "Export started",
+			"job_id":      job.ID,
+			"date":        req.Date,
+			"channel":     req.Channel,
+			"campaign_id": req.CampaignID,
+			"dry_run":     req.DryRun,
+		})
+		return
+	}
 
-	if err := h.etlService.ExportData(c.Request.Context(), req.Date); err != nil {
+	h.logger.WithFields(fields).Info("Starting data export")
+
+	summary, err := h.service(c).ExportData(c.Request.Context(), req.Date, req.DryRun, filter)
+	if err != nil {
 		h.logger.WithError(err).Error("Export failed")
+
+		var exportErr *etl.ExportError
+		if errors.As(err, &exportErr) {
+			c.JSON(http.StatusBadGateway, gin.H{
+				"error":            "Export failed",
+				"message":          err.Error(),
+				"date":             req.Date,
+				"records_exported": exportErr.Exported,
+				"records_failed":   exportErr.Failed,
+				"summary":          summary,
+			})
+			return
+		}
+
 		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
 			Error:   "Export failed",
 			Message: err.Error(),
@@ -179,27 +958,226 @@ func (h *Handlers) ExportData(c *gin.Context) {
 		return
 	}
 
+	if req.DryRun {
+		c.JSON(http.StatusOK, gin.H{
+			"message": "Export dry run completed, nothing was sent",
+			"date":    req.Date,
+			"dry_run": true,
+			"records": summary.Signed,
+			"count":   len(summary.Signed),
+			"summary": summary,
+		})
+		return
+	}
+
 	c.JSON(http.StatusOK, gin.H{
 		"message": "Export completed successfully",
 		"date":    req.Date,
+		"summary": summary,
+	})
+}
+
+// freshness looks up how current the stored data is for a metrics
+// response, answering with a 500 and reporting false if it can't.
+func (h *Handlers) freshness(c *gin.Context) (models.Freshness, bool) {
+	freshness, err := h.service(c).Freshness()
+	if err != nil {
+		h.logger.WithError(err).Error("Failed to get data freshness")
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+			Error:   "Failed to retrieve metrics",
+			Message: err.Error(),
+		})
+		return models.Freshness{}, false
+	}
+	return freshness, true
+}
+
+// withFreshness adds data_as_of and stale to a metrics response body,
+// leaving data_as_of out before the first ingestion as Freshness does.
+func withFreshness(body gin.H, freshness models.Freshness) gin.H {
+	if freshness.DataAsOf != "" {
+		body["data_as_of"] = freshness.DataAsOf
+	}
+	body["stale"] = freshness.Stale
+	return body
+}
+
+// metricsDateRange parses a metrics query's date range, filling in omitted
+// bounds: to defaults to today and from to the configured number of days
+// before to. Supplied values are validated as usual.
+func (h *Handlers) metricsDateRange(c *gin.Context, fromValue, toValue string) (from, to time.Time, ok bool) {
+	fromValue, toValue = h.service(c).DefaultDateRange(fromValue, toValue)
+	return parseDateRange(c, fromValue, toValue)
+}
+
+// parseDateRange parses the from/to query values, writing a 400 response and
+// returning ok=false when either is malformed.
+func parseDateRange(c *gin.Context, fromValue, toValue string) (from, to time.Time, ok bool) {
+	from, err := time.Parse("2006-01-02", fromValue)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Error:   "Invalid from date format",
+			Message: "Expected YYYY-MM-DD format",
+		})
+		return time.Time{}, time.Time{}, false
+	}
+
+	to, err = time.Parse("2006-01-02", toValue)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Error:   "Invalid to date format",
+			Message: "Expected YYYY-MM-DD format",
+		})
+		return time.Time{}, time.Time{}, false
+	}
+
+	if from.After(to) {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Error:   "Invalid date range",
+			Message: "from must not be after to",
+		})
+		return time.Time{}, time.Time{}, false
+	}
+
+	return from, to, true
+}
+
+// parseCursor decodes the optional cursor query parameter, writing a 400
+// response and returning ok=false when it is malformed or combined with an
+// offset or a non-default sort. An empty value yields a nil cursor.
+func parseCursor(c *gin.Context, value string, offset int, keyOrder bool) (cursor *etl.Cursor, ok bool) {
+	if value == "" {
+		return nil, true
+	}
+
+	if offset > 0 || !keyOrder {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Error:   "Invalid request parameters",
+			Message: etl.ErrCursorNotAllowed.Error(),
+		})
+		return nil, false
+	}
+
+	decoded, err := etl.DecodeCursor(value)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Error:   "Invalid cursor",
+			Message: err.Error(),
+		})
+		return nil, false
+	}
+	return &decoded, true
+}
+
+// effectiveLimit applies the default page size to non-positive limits and caps
+// larger ones at constants.MaxLimit. The returned value is what handlers echo
+// back as "limit" so clients can see when their request was clamped.
+func effectiveLimit(limit int) int {
+	if limit <= 0 {
+		return constants.DefaultLimit
+	}
+	if limit > constants.MaxLimit {
+		return constants.MaxLimit
+	}
+	return limit
+}
+
+func (h *Handlers) GetDeadLetters(c *gin.Context) {
+	items := h.service(c).DeadLetters()
+	c.JSON(http.StatusOK, gin.H{
+		"data":  items,
+		"count": len(items),
 	})
 }
 
+func (h *Handlers) RetryDeadLetters(c *gin.Context) {
+	result, err := h.service(c).RetryDeadLetters(c.Request.Context())
+	if err != nil {
+		h.logger.WithError(err).Error("Dead-letter replay failed")
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+			Error:   "Dead-letter replay failed",
+			Message: err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
+}
+
+// HealthCheck is a plain liveness probe. With ?verbose=true it also reports
+// the state of the stored dataset.
 func (h *Handlers) HealthCheck(c *gin.Context) {
-	c.JSON(http.StatusOK, models.HealthResponse{
+	var req models.HealthRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Error:   "Invalid request parameters",
+			Message: err.Error(),
+		})
+		return
+	}
+
+	response := models.HealthResponse{
 		Status:    "healthy",
 		Timestamp: time.Now().Format(time.RFC3339),
-		Version:   "1.0.0",
-	})
+		Version:   version.Get().Version,
+	}
+
+	if req.Verbose {
+		detail, err := h.etlService.HealthDetail()
+		if err != nil {
+			h.logger.WithError(err).Error("Failed to collect health detail")
+			response.Status = constants.HealthStatusUnhealthy
+			c.JSON(http.StatusServiceUnavailable, response)
+			return
+		}
+		response.Detail = detail
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// Version reports the version, git commit and build time of the running
+// binary.
+func (h *Handlers) Version(c *gin.Context) {
+	c.JSON(http.StatusOK, version.Get())
+}
+
+// DebugStats reports goroutine count, heap usage and GC totals, enough to
+// notice a leak in background jobs or the scheduler.
+func (h *Handlers) DebugStats(c *gin.Context) {
+	var mem runtime.MemStats
+	runtime.ReadMemStats(&mem)
+
+	stats := models.RuntimeStats{
+		Goroutines:   runtime.NumGoroutine(),
+		HeapAlloc:    mem.HeapAlloc,
+		HeapSys:      mem.HeapSys,
+		HeapObjects:  mem.HeapObjects,
+		TotalAlloc:   mem.TotalAlloc,
+		NumGC:        mem.NumGC,
+		PauseTotalNs: mem.PauseTotalNs,
+	}
+	if mem.LastGC > 0 {
+		stats.LastGC = time.Unix(0, int64(mem.LastGC)).UTC().Format(time.RFC3339)
+	}
+
+	c.JSON(http.StatusOK, stats)
 }
 
 func (h *Handlers) ReadinessCheck(c *gin.Context) {
-	// Check if external APIs are accessible
-	// For simplicity, we'll just return ready if the service is running
-	c.JSON(http.StatusOK, models.HealthResponse{
-		Status:    "ready",
-		Timestamp: time.Now().Format(time.RFC3339),
-		Version:   "1.0.0",
+	ready, dependencies := h.etlService.CheckReadiness(c.Request.Context())
+
+	status := constants.HealthStatusReady
+	code := http.StatusOK
+	if !ready {
+		status = constants.HealthStatusUnhealthy
+		code = http.StatusServiceUnavailable
+	}
+
+	c.JSON(code, models.HealthResponse{
+		Status:       status,
+		Timestamp:    time.Now().Format(time.RFC3339),
+		Version:      version.Get().Version,
+		Dependencies: dependencies,
 	})
 }
-
@@ -0,0 +1,1586 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"admira-etl/internal/config"
+	"admira-etl/internal/constants"
+	"admira-etl/internal/etl"
+	"admira-etl/internal/jobs"
+	"admira-etl/internal/models"
+	"admira-etl/internal/storage"
+	"admira-etl/internal/version"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func setupTestRouter(t *testing.T, data []models.TransformedData) *gin.Engine {
+	return setupTestRouterWithConfig(t, &config.Config{}, data)
+}
+
+func setupTestRouterWithConfig(t *testing.T, cfg *config.Config, data []models.TransformedData) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+
+	logger := logrus.New()
+	logger.SetLevel(logrus.ErrorLevel)
+
+	store := storage.NewInMemoryStorage()
+	require.NoError(t, store.StoreTransformedData(data))
+
+	service := etl.NewService(cfg, store, logger)
+	router := gin.New()
+	SetupRoutes(router, NewHandlers(service, logger), cfg)
+	return router
+}
+
+func performRequest(router *gin.Engine, method, path string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w
+}
+
+func TestGetChannelMetrics_Pagination(t *testing.T) {
+	router := setupTestRouter(t, []models.TransformedData{
+		{Date: "2025-01-01", Channel: "google_ads", CampaignID: "C-1001"},
+		{Date: "2025-01-02", Channel: "google_ads", CampaignID: "C-1001"},
+	})
+
+	tests := []struct {
+		name          string
+		query         string
+		expectedCode  int
+		expectedLimit int
+	}{
+		{
+			name:          "default limit",
+			query:         "",
+			expectedCode:  http.StatusOK,
+			expectedLimit: constants.DefaultLimit,
+		},
+		{
+			name:          "limit within bounds",
+			query:         "&limit=50",
+			expectedCode:  http.StatusOK,
+			expectedLimit: 50,
+		},
+		{
+			name:          "over-large limit is capped",
+			query:         "&limit=1000000",
+			expectedCode:  http.StatusOK,
+			expectedLimit: constants.MaxLimit,
+		},
+		{
+			name:         "negative offset is rejected",
+			query:        "&offset=-1",
+			expectedCode: http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := "/api/v1/metrics/channel?from=2025-01-01&to=2025-01-31&channel=google_ads" + tt.query
+			w := performRequest(router, http.MethodGet, path)
+			require.Equal(t, tt.expectedCode, w.Code)
+			if tt.expectedCode != http.StatusOK {
+				return
+			}
+
+			var body struct {
+				Count int `json:"count"`
+				Limit int `json:"limit"`
+			}
+			require.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+			assert.Equal(t, tt.expectedLimit, body.Limit)
+			assert.Equal(t, 2, body.Count)
+		})
+	}
+}
+
+func TestGetFunnelMetrics_LimitIsCapped(t *testing.T) {
+	data := make([]models.TransformedData, 0, constants.MaxLimit+10)
+	for i := 0; i < constants.MaxLimit+10; i++ {
+		data = append(data, models.TransformedData{
+			Date:        "2025-01-01",
+			Channel:     "google_ads",
+			CampaignID:  fmt.Sprintf("C-%d", i),
+			UTMCampaign: "back_to_school",
+		})
+	}
+	router := setupTestRouter(t, data)
+
+	w := performRequest(router, http.MethodGet, "/api/v1/metrics/funnel?from=2025-01-01&to=2025-01-31&utm_campaign=back_to_school&limit=5000")
+	require.Equal(t, http.StatusOK, w.Code)
+
+	var body struct {
+		Data  []models.TransformedData `json:"data"`
+		Count int                      `json:"count"`
+		Limit int                      `json:"limit"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+	assert.Equal(t, constants.MaxLimit, body.Limit)
+	assert.Equal(t, constants.MaxLimit, body.Count)
+	assert.Len(t, body.Data, constants.MaxLimit)
+}
+
+func TestGetMetricsSummary(t *testing.T) {
+	router := setupTestRouter(t, []models.TransformedData{
+		{Date: "2025-01-01", Channel: "google_ads", CampaignID: "C-1001", Clicks: 200, Cost: 100.0, Revenue: 300.0},
+		{Date: "2025-01-02", Channel: "google_ads", CampaignID: "C-1001", Clicks: 300, Cost: 400.0, Revenue: 200.0},
+		{Date: "2025-01-02", Channel: "facebook_ads", CampaignID: "C-2001", Clicks: 50, Cost: 50.0},
+	})
+
+	w := performRequest(router, http.MethodGet, "/api/v1/metrics/summary?from=2025-01-01&to=2025-01-31&channel=google_ads")
+	require.Equal(t, http.StatusOK, w.Code)
+
+	var summary models.MetricsSummary
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &summary))
+	assert.Equal(t, 2, summary.Records)
+	assert.Equal(t, 500, summary.Clicks)
+	assert.Equal(t, 500.0, summary.Cost)
+	assert.Equal(t, 500.0, summary.Revenue)
+	assert.InDelta(t, 1.0, summary.CPC, 0.001)
+	assert.InDelta(t, 1.0, summary.ROAS, 0.001)
+
+	w = performRequest(router, http.MethodGet, "/api/v1/metrics/summary?from=2025-01-01&to=2025-13-01")
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func TestGetMetricsSummary_DefaultRange(t *testing.T) {
+	today := time.Now().UTC()
+	day := func(offset int) string { return today.AddDate(0, 0, offset).Format("2006-01-02") }
+
+	router := setupTestRouterWithConfig(t, &config.Config{DefaultRangeDays: 3}, []models.TransformedData{
+		{Date: day(0), Channel: "google_ads", CampaignID: "C-1001", Clicks: 100},
+		{Date: day(-3), Channel: "google_ads", CampaignID: "C-1001", Clicks: 20},
+		{Date: day(-4), Channel: "google_ads", CampaignID: "C-1001", Clicks: 3},
+	})
+
+	// Both omitted: the last 3 days up to today
+	w := performRequest(router, http.MethodGet, "/api/v1/metrics/summary")
+	require.Equal(t, http.StatusOK, w.Code)
+	var summary models.MetricsSummary
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &summary))
+	assert.Equal(t, day(-3), summary.From)
+	assert.Equal(t, day(0), summary.To)
+	assert.Equal(t, 120, summary.Clicks)
+
+	// Only from omitted: 3 days before the supplied to
+	w = performRequest(router, http.MethodGet, "/api/v1/metrics/summary?to="+day(-1))
+	require.Equal(t, http.StatusOK, w.Code)
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &summary))
+	assert.Equal(t, day(-4), summary.From)
+	assert.Equal(t, 23, summary.Clicks)
+
+	// Only to omitted: up to today
+	w = performRequest(router, http.MethodGet, "/api/v1/metrics/summary?from="+day(-4))
+	require.Equal(t, http.StatusOK, w.Code)
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &summary))
+	assert.Equal(t, 123, summary.Clicks)
+
+	// Supplied values are still validated
+	w = performRequest(router, http.MethodGet, "/api/v1/metrics/summary?to=yesterday")
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	w = performRequest(router, http.MethodGet, "/api/v1/metrics/summary?from="+day(1))
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func TestMetrics_Freshness(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	logger := logrus.New()
+	logger.SetLevel(logrus.ErrorLevel)
+
+	store := storage.NewInMemoryStorage()
+	require.NoError(t, store.StoreTransformedData([]models.TransformedData{
+		{Date: "2025-01-01", Channel: "google_ads", CampaignID: "C-1001", Clicks: 100},
+	}))
+
+	newRouter := func(staleAfter time.Duration) *gin.Engine {
+		cfg := &config.Config{StaleAfter: staleAfter}
+		router := gin.New()
+		SetupRoutes(router, NewHandlers(etl.NewService(cfg, store, logger), logger), cfg)
+		return router
+	}
+	freshness := func(router *gin.Engine, path string) models.Freshness {
+		w := performRequest(router, http.MethodGet, path)
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		var body models.Freshness
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+		return body
+	}
+
+	paths := []string{
+		"/api/v1/metrics/channel?from=2025-01-01&to=2025-01-31&channel=google_ads",
+		"/api/v1/metrics/funnel?from=2025-01-01&to=2025-01-31&utm_campaign=back_to_school",
+		"/api/v1/metrics/source?from=2025-01-01&to=2025-01-31",
+		"/api/v1/metrics/summary?from=2025-01-01&to=2025-01-31",
+		"/api/v1/metrics/compare?from=2025-01-01&to=2025-01-31",
+		"/api/v1/metrics/top?from=2025-01-01&to=2025-01-31",
+	}
+
+	// Never ingested: stale, with no time to report
+	for _, path := range paths {
+		assert.Equal(t, models.Freshness{Stale: true}, freshness(newRouter(time.Hour), path), path)
+	}
+
+	ingested := time.Now().Add(-2 * time.Hour).Truncate(time.Second)
+	require.NoError(t, store.SetLastIngestionTime(ingested))
+	asOf := ingested.Format(time.RFC3339)
+
+	for _, path := range paths {
+		assert.Equal(t, models.Freshness{DataAsOf: asOf, Stale: true}, freshness(newRouter(time.Hour), path), path)
+		assert.Equal(t, models.Freshness{DataAsOf: asOf, Stale: false}, freshness(newRouter(3*time.Hour), path), path)
+	}
+
+	// The summary fields are still at the top level next to the freshness
+	w := performRequest(newRouter(time.Hour), http.MethodGet, "/api/v1/metrics/summary?from=2025-01-01&to=2025-01-31")
+	var summary models.MetricsSummary
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &summary))
+	assert.Equal(t, 100, summary.Clicks)
+}
+
+func TestGetSourceMetrics(t *testing.T) {
+	router := setupTestRouter(t, []models.TransformedData{
+		{Date: "2025-01-01", Channel: "google_ads", CampaignID: "C-1001", UTMSource: "google", UTMMedium: "cpc", Clicks: 200, Cost: 100.0},
+		{Date: "2025-01-02", Channel: "google_ads", CampaignID: "C-1002", UTMSource: "google", UTMMedium: "cpc", Clicks: 300, Cost: 400.0},
+		{Date: "2025-01-02", Channel: "facebook_ads", CampaignID: "C-2001", UTMSource: "facebook", UTMMedium: "paid_social", Clicks: 50, Cost: 50.0},
+	})
+
+	w := performRequest(router, http.MethodGet, "/api/v1/metrics/source?from=2025-01-01&to=2025-01-31&utm_medium=cpc")
+	require.Equal(t, http.StatusOK, w.Code)
+
+	var response struct {
+		Data  []models.TransformedData `json:"data"`
+		Count int                      `json:"count"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+	require.Equal(t, 1, response.Count)
+	assert.Equal(t, "google", response.Data[0].UTMSource)
+	assert.Equal(t, "cpc", response.Data[0].UTMMedium)
+	assert.Equal(t, 500, response.Data[0].Clicks)
+	assert.Equal(t, 500.0, response.Data[0].Cost)
+
+	w = performRequest(router, http.MethodGet, "/api/v1/metrics/source?from=2025-01-01&to=2025-01-31")
+	require.Equal(t, http.StatusOK, w.Code)
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+	assert.Equal(t, 2, response.Count)
+
+	w = performRequest(router, http.MethodGet, "/api/v1/metrics/source?from=2025-01-01&to=2025-01")
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func TestGetCampaignMetrics(t *testing.T) {
+	router := setupTestRouter(t, []models.TransformedData{
+		{Date: "2025-01-02", Channel: "google_ads", CampaignID: "C-1001", Clicks: 200, Cost: 100.0},
+		{Date: "2025-01-01", Channel: "facebook_ads", CampaignID: "C-1001", Clicks: 300, Cost: 200.0},
+		{Date: "2025-01-01", Channel: "google_ads", CampaignID: "C-1002", Clicks: 50, Cost: 50.0},
+	})
+
+	var response struct {
+		Data  []models.TransformedData `json:"data"`
+		Count int                      `json:"count"`
+	}
+
+	w := performRequest(router, http.MethodGet, "/api/v1/metrics/campaign?from=2025-01-01&to=2025-01-31&campaign_id=C-1001")
+	require.Equal(t, http.StatusOK, w.Code)
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+	require.Equal(t, 2, response.Count)
+	assert.Equal(t, "facebook_ads", response.Data[0].Channel)
+	assert.Equal(t, "google_ads", response.Data[1].Channel)
+
+	w = performRequest(router, http.MethodGet, "/api/v1/metrics/campaign?from=2025-01-01&to=2025-01-31&campaign_id=C-1001&consolidate=true")
+	require.Equal(t, http.StatusOK, w.Code)
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+	require.Equal(t, 1, response.Count)
+	assert.Equal(t, 500, response.Data[0].Clicks)
+	assert.Equal(t, 300.0, response.Data[0].Cost)
+	assert.Empty(t, response.Data[0].Channel)
+
+	w = performRequest(router, http.MethodGet, "/api/v1/metrics/campaign?from=2025-01-01&to=2025-01-31")
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func TestGetTimeSeries(t *testing.T) {
+	router := setupTestRouter(t, []models.TransformedData{
+		{Date: "2025-01-01", Channel: "google_ads", CampaignID: "C-1001", Clicks: 200, Cost: 100.0},
+		{Date: "2025-01-03", Channel: "google_ads", CampaignID: "C-1001", Clicks: 300, Cost: 200.0},
+	})
+
+	w := performRequest(router, http.MethodGet, "/api/v1/metrics/timeseries?from=2025-01-01&to=2025-01-03&metric=clicks&fill=null")
+	require.Equal(t, http.StatusOK, w.Code)
+
+	var series models.TimeSeries
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &series))
+	assert.Equal(t, []string{"2025-01-01", "2025-01-02", "2025-01-03"}, series.Dates)
+	require.Len(t, series.Values, 3)
+	assert.Equal(t, 200.0, *series.Values[0])
+	assert.Nil(t, series.Values[1])
+	assert.Equal(t, 300.0, *series.Values[2])
+	assert.Contains(t, w.Body.String(), `"values":[200,null,300]`)
+
+	w = performRequest(router, http.MethodGet, "/api/v1/metrics/timeseries?from=2025-01-01&to=2025-01-03&metric=cost")
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `"values":[100,0,200]`)
+
+	for _, query := range []string{
+		"from=2025-01-01&to=2025-01-03",
+		"from=2025-01-01&to=2025-01-03&metric=ctr",
+		"from=2025-01-01&to=2025-01-03&metric=clicks&fill=linear",
+		"from=2000-01-01&to=2025-01-03&metric=clicks",
+	} {
+		w = performRequest(router, http.MethodGet, "/api/v1/metrics/timeseries?"+query)
+		assert.Equal(t, http.StatusBadRequest, w.Code, query)
+	}
+}
+
+func TestGetChannelMetrics_CSV(t *testing.T) {
+	router := setupTestRouter(t, []models.TransformedData{
+		{
+			Date: "2025-01-01", Channel: "google_ads", CampaignID: "C-1001",
+			Clicks: 1000, Impressions: 50000, Cost: 250.0, Leads: 100,
+			Opportunities: 3, ClosedWon: 2, Revenue: 8000.0,
+			CPC: 0.25, CPA: 2.5, CVRLeadToOpp: 0.03, CVROppToWon: 2.0 / 3.0, ROAS: 32.0,
+			MatchType: "exact",
+		},
+	})
+
+	tests := []struct {
+		name   string
+		path   string
+		accept string
+	}{
+		{
+			name:   "accept header",
+			path:   "/api/v1/metrics/channel?from=2025-01-01&to=2025-01-31&channel=google_ads",
+			accept: "text/csv",
+		},
+		{
+			name: "format query parameter",
+			path: "/api/v1/metrics/channel?from=2025-01-01&to=2025-01-31&channel=google_ads&format=csv",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, tt.path, nil)
+			if tt.accept != "" {
+				req.Header.Set("Accept", tt.accept)
+			}
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			require.Equal(t, http.StatusOK, w.Code)
+			assert.Contains(t, w.Header().Get("Content-Type"), "text/csv")
+
+			lines := strings.Split(strings.TrimSpace(w.Body.String()), "\n")
+			require.Len(t, lines, 2)
+			assert.Equal(t, "date,channel,campaign_id,clicks,impressions,cost,leads,opportunities,closed_won,revenue,cpc,cpa,cvr_lead_to_opp,cvr_opp_to_won,roas,ctr,cpm,match_type", lines[0])
+			assert.Equal(t, "2025-01-01,google_ads,C-1001,1000,50000,250.00,100,3,2,8000.00,0.2500,2.5000,0.0300,0.6667,32.0000,0.0000,0.0000,exact", lines[1])
+		})
+	}
+}
+
+func TestMetrics_ResponseVersion(t *testing.T) {
+	router := setupTestRouter(t, []models.TransformedData{
+		{
+			Date: "2025-01-01", Channel: "google_ads", CampaignID: "C-1001", UTMCampaign: "back_to_school",
+			Clicks: 1000, Impressions: 50000, Cost: 250.0, Leads: 100,
+			Opportunities: 3, ClosedWon: 2, Revenue: 8000.0, WeightedRevenue: 8000.0,
+			CPC: 0.25, CPA: 2.5, CVRLeadToOpp: 0.03, CVROppToWon: 2.0 / 3.0, ROAS: 32.0,
+		},
+	})
+	ratioFields := []string{"cpa", "cpc", "cpm", "ctr", "cvr_lead_to_opp", "cvr_opp_to_won", "roas"}
+
+	firstRow := func(t *testing.T, path, header string) map[string]interface{} {
+		t.Helper()
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		if header != "" {
+			req.Header.Set("Accept-Version", header)
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+		var response struct {
+			Data []map[string]interface{} `json:"data"`
+		}
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+		require.Len(t, response.Data, 1)
+		return response.Data[0]
+	}
+
+	paths := []string{
+		"/api/v1/metrics/channel?from=2025-01-01&to=2025-01-31&channel=google_ads",
+		"/api/v1/metrics/funnel?from=2025-01-01&to=2025-01-31&utm_campaign=back_to_school",
+		"/api/v1/metrics/source?from=2025-01-01&to=2025-01-31",
+		"/api/v1/metrics/top?from=2025-01-01&to=2025-01-31",
+	}
+	for _, path := range paths {
+		t.Run("v1 is flat "+path, func(t *testing.T) {
+			for _, header := range []string{"", "1", "v1"} {
+				row := firstRow(t, path, header)
+				assert.NotContains(t, row, "ratios")
+				for _, field := range ratioFields {
+					assert.Contains(t, row, field)
+				}
+			}
+		})
+
+		t.Run("v2 nests ratios "+path, func(t *testing.T) {
+			for _, row := range []map[string]interface{}{firstRow(t, path, "2"), firstRow(t, path+"&v=v2", "1")} {
+				assert.Equal(t, 8000.0, row["revenue"])
+				for _, field := range ratioFields {
+					assert.NotContains(t, row, field)
+				}
+				ratios, ok := row["ratios"].(map[string]interface{})
+				require.True(t, ok, "ratios is %T", row["ratios"])
+				assert.Len(t, ratios, len(ratioFields))
+				assert.Equal(t, 0.6667, ratios["cvr_opp_to_won"])
+				assert.Equal(t, 32.0, ratios["roas"])
+			}
+		})
+	}
+
+	t.Run("unknown version", func(t *testing.T) {
+		w := performRequest(router, http.MethodGet, paths[0]+"&v=3")
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		assert.Contains(t, w.Body.String(), "unsupported response version")
+	})
+}
+
+func TestGetChannelMetrics_Granularity(t *testing.T) {
+	router := setupTestRouter(t, []models.TransformedData{
+		{Date: "2025-01-06", Channel: "google_ads", CampaignID: "C-1001", Clicks: 100},
+		{Date: "2025-01-08", Channel: "google_ads", CampaignID: "C-1001", Clicks: 300},
+		{Date: "2025-01-14", Channel: "google_ads", CampaignID: "C-1001", Clicks: 200},
+	})
+
+	w := performRequest(router, http.MethodGet, "/api/v1/metrics/channel?from=2025-01-01&to=2025-01-31&channel=google_ads&granularity=week")
+	require.Equal(t, http.StatusOK, w.Code)
+
+	var body struct {
+		Data        []models.TransformedData `json:"data"`
+		Granularity string                   `json:"granularity"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+	assert.Equal(t, "week", body.Granularity)
+	require.Len(t, body.Data, 2)
+	assert.Equal(t, "2025-W02", body.Data[0].Date)
+	assert.Equal(t, 400, body.Data[0].Clicks)
+	assert.Equal(t, "2025-W03", body.Data[1].Date)
+
+	w = performRequest(router, http.MethodGet, "/api/v1/metrics/channel?from=2025-01-01&to=2025-01-31&channel=google_ads&granularity=hour")
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func TestGetChannelMetrics_Consolidate(t *testing.T) {
+	router := setupTestRouter(t, []models.TransformedData{
+		{Date: "2025-01-06", Channel: "google_ads", CampaignID: "C-1001", Clicks: 100, Cost: 50.0},
+		{Date: "2025-01-08", Channel: "google_ads", CampaignID: "C-1001", Clicks: 300, Cost: 150.0},
+		{Date: "2025-01-14", Channel: "google_ads", CampaignID: "C-1002", Clicks: 200, Cost: 20.0},
+	})
+
+	w := performRequest(router, http.MethodGet, "/api/v1/metrics/channel?from=2025-01-01&to=2025-01-31&channel=google_ads&consolidate=true")
+	require.Equal(t, http.StatusOK, w.Code)
+
+	var body struct {
+		Data        []models.TransformedData `json:"data"`
+		Consolidate bool                     `json:"consolidate"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+	assert.True(t, body.Consolidate)
+	require.Len(t, body.Data, 2)
+	assert.Equal(t, "", body.Data[0].Date)
+	assert.Equal(t, "C-1001", body.Data[0].CampaignID)
+	assert.Equal(t, 400, body.Data[0].Clicks)
+	assert.InDelta(t, 0.5, body.Data[0].CPC, 0.001)
+	assert.Equal(t, "C-1002", body.Data[1].CampaignID)
+
+	w = performRequest(router, http.MethodGet, "/api/v1/metrics/channel?from=2025-01-01&to=2025-01-31&channel=google_ads&consolidate=true&granularity=week")
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	cursor := etl.Cursor{Date: "2025-01-06", Channel: "google_ads", CampaignID: "C-1001"}.Encode()
+	w = performRequest(router, http.MethodGet, "/api/v1/metrics/channel?from=2025-01-01&to=2025-01-31&channel=google_ads&consolidate=true&cursor="+cursor)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func TestGetChannelMetrics_Thresholds(t *testing.T) {
+	router := setupTestRouter(t, []models.TransformedData{
+		{Date: "2025-01-01", Channel: "google_ads", CampaignID: "C-1001", Cost: 5.0},
+		{Date: "2025-01-02", Channel: "google_ads", CampaignID: "C-1001", Cost: 10.0},
+	})
+
+	w := performRequest(router, http.MethodGet, "/api/v1/metrics/channel?from=2025-01-01&to=2025-01-31&channel=google_ads&min_cost=10")
+	require.Equal(t, http.StatusOK, w.Code)
+
+	var body struct {
+		Data []models.TransformedData `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+	require.Len(t, body.Data, 1)
+	assert.Equal(t, "2025-01-02", body.Data[0].Date)
+
+	for _, param := range []string{"min_cost=-1", "min_revenue=-0.5"} {
+		w = performRequest(router, http.MethodGet, "/api/v1/metrics/channel?from=2025-01-01&to=2025-01-31&channel=google_ads&"+param)
+		assert.Equal(t, http.StatusBadRequest, w.Code, param)
+	}
+}
+
+func TestGetChannelMetrics_Sort(t *testing.T) {
+	router := setupTestRouter(t, []models.TransformedData{
+		{Date: "2025-01-01", Channel: "google_ads", CampaignID: "C-1001", Cost: 10.0},
+		{Date: "2025-01-02", Channel: "google_ads", CampaignID: "C-1001", Cost: 300.0},
+		{Date: "2025-01-03", Channel: "google_ads", CampaignID: "C-1001", Cost: 150.0},
+	})
+
+	w := performRequest(router, http.MethodGet, "/api/v1/metrics/channel?from=2025-01-01&to=2025-01-31&channel=google_ads&sort_by=cost&order=desc")
+	require.Equal(t, http.StatusOK, w.Code)
+
+	var body struct {
+		Data   []models.TransformedData `json:"data"`
+		SortBy string                   `json:"sort_by"`
+		Order  string                   `json:"order"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+	assert.Equal(t, "cost", body.SortBy)
+	assert.Equal(t, "desc", body.Order)
+	require.Len(t, body.Data, 3)
+	assert.Equal(t, 300.0, body.Data[0].Cost)
+	assert.Equal(t, 150.0, body.Data[1].Cost)
+	assert.Equal(t, 10.0, body.Data[2].Cost)
+
+	w = performRequest(router, http.MethodGet, "/api/v1/metrics/channel?from=2025-01-01&to=2025-01-31&channel=google_ads")
+	require.Equal(t, http.StatusOK, w.Code)
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+	assert.Equal(t, "date", body.SortBy)
+	assert.Equal(t, "asc", body.Order)
+
+	w = performRequest(router, http.MethodGet, "/api/v1/metrics/channel?from=2025-01-01&to=2025-01-31&channel=google_ads&sort_by=campaign_name")
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	w = performRequest(router, http.MethodGet, "/api/v1/metrics/channel?from=2025-01-01&to=2025-01-31&channel=google_ads&order=up")
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func TestGetChannelMetrics_Cursor(t *testing.T) {
+	router := setupTestRouter(t, []models.TransformedData{
+		{Date: "2025-01-01", Channel: "google_ads", CampaignID: "C-1001"},
+		{Date: "2025-01-02", Channel: "google_ads", CampaignID: "C-1001"},
+		{Date: "2025-01-03", Channel: "google_ads", CampaignID: "C-1001"},
+	})
+
+	base := "/api/v1/metrics/channel?from=2025-01-01&to=2025-01-31&channel=google_ads&limit=2"
+
+	type page struct {
+		Data       []models.TransformedData `json:"data"`
+		NextCursor string                   `json:"next_cursor"`
+	}
+
+	w := performRequest(router, http.MethodGet, base)
+	require.Equal(t, http.StatusOK, w.Code)
+	var first page
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &first))
+	require.Len(t, first.Data, 2)
+	require.NotEmpty(t, first.NextCursor)
+
+	w = performRequest(router, http.MethodGet, base+"&cursor="+first.NextCursor)
+	require.Equal(t, http.StatusOK, w.Code)
+	var second page
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &second))
+	require.Len(t, second.Data, 1)
+	assert.Equal(t, "2025-01-03", second.Data[0].Date)
+	assert.Empty(t, second.NextCursor)
+
+	for _, query := range []string{
+		"&cursor=" + first.NextCursor + "&offset=2",
+		"&cursor=" + first.NextCursor + "&sort_by=cost",
+		"&cursor=not-a-cursor",
+	} {
+		w = performRequest(router, http.MethodGet, base+query)
+		assert.Equal(t, http.StatusBadRequest, w.Code, query)
+	}
+}
+
+func TestGetTopCampaigns(t *testing.T) {
+	router := setupTestRouter(t, []models.TransformedData{
+		{Date: "2025-01-01", Channel: "google_ads", CampaignID: "C-1001", Cost: 100.0, Revenue: 500.0},
+		{Date: "2025-01-01", Channel: "facebook_ads", CampaignID: "C-2001", Cost: 100.0, Revenue: 900.0},
+		{Date: "2025-01-02", Channel: "google_ads", CampaignID: "C-3001", Cost: 100.0, Revenue: 100.0},
+	})
+
+	w := performRequest(router, http.MethodGet, "/api/v1/metrics/top?from=2025-01-01&to=2025-01-31&n=2")
+	require.Equal(t, http.StatusOK, w.Code)
+
+	var body struct {
+		Data   []models.TransformedData `json:"data"`
+		Count  int                      `json:"count"`
+		Metric string                   `json:"metric"`
+		N      int                      `json:"n"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+	assert.Equal(t, "revenue", body.Metric)
+	assert.Equal(t, 2, body.N)
+	require.Equal(t, 2, body.Count)
+	assert.Equal(t, "C-2001", body.Data[0].CampaignID)
+	assert.Equal(t, "C-1001", body.Data[1].CampaignID)
+
+	w = performRequest(router, http.MethodGet, "/api/v1/metrics/top?from=2025-01-01&to=2025-01-31&metric=clicks")
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func TestGetDimensions(t *testing.T) {
+	router := setupTestRouter(t, []models.TransformedData{
+		{Date: "2025-01-01", Channel: "google_ads", CampaignID: "C-1001"},
+		{Date: "2025-01-02", Channel: "google_ads", CampaignID: "C-1001"},
+		{Date: "2025-01-02", Channel: "facebook_ads", CampaignID: "C-1001"},
+		{Date: "2025-01-03", Channel: "facebook_ads", CampaignID: "C-2001"},
+		{Date: "2025-02-01", Channel: "linkedin_ads", CampaignID: "C-3001"},
+	})
+
+	w := performRequest(router, http.MethodGet, "/api/v1/dimensions?from=2025-01-01&to=2025-01-31")
+	require.Equal(t, http.StatusOK, w.Code)
+
+	var dimensions models.Dimensions
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &dimensions))
+	assert.Equal(t, "2025-01-01", dimensions.From)
+	assert.Equal(t, "2025-01-31", dimensions.To)
+	assert.Equal(t, []string{"facebook_ads", "google_ads"}, dimensions.Channels)
+	assert.Equal(t, []string{"C-1001", "C-2001"}, dimensions.CampaignIDs)
+
+	// A range with nothing stored lists empty sets rather than nulls
+	w = performRequest(router, http.MethodGet, "/api/v1/dimensions?from=2024-01-01&to=2024-01-31")
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `{"from":"2024-01-01","to":"2024-01-31","channels":[],"campaign_ids":[]}`, w.Body.String())
+
+	w = performRequest(router, http.MethodGet, "/api/v1/dimensions?from=2025-02-01&to=2025-01-01")
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func TestCompareMetrics(t *testing.T) {
+	router := setupTestRouter(t, []models.TransformedData{
+		{Date: "2025-01-06", Channel: "google_ads", CampaignID: "C-1001", Clicks: 100},
+		{Date: "2025-01-13", Channel: "google_ads", CampaignID: "C-1001", Clicks: 150},
+	})
+
+	w := performRequest(router, http.MethodGet, "/api/v1/metrics/compare?from=2025-01-13&to=2025-01-19&channel=google_ads")
+	require.Equal(t, http.StatusOK, w.Code)
+
+	var body struct {
+		Current  models.MetricsSummary `json:"current"`
+		Previous models.MetricsSummary `json:"previous"`
+		Deltas   map[string]*float64   `json:"deltas"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+	assert.Equal(t, "2025-01-06", body.Previous.From)
+	assert.Equal(t, 100, body.Previous.Clicks)
+	assert.Equal(t, 150, body.Current.Clicks)
+	require.NotNil(t, body.Deltas["clicks"])
+	assert.InDelta(t, 50.0, *body.Deltas["clicks"], 0.001)
+	assert.Contains(t, body.Deltas, "revenue")
+	assert.Nil(t, body.Deltas["revenue"])
+
+	w = performRequest(router, http.MethodGet, "/api/v1/metrics/compare?from=2025-01-19&to=2025-01-13")
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func TestHealthCheck_Verbose(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	logger := logrus.New()
+	logger.SetLevel(logrus.ErrorLevel)
+
+	store := storage.NewInMemoryStorage()
+	require.NoError(t, store.StoreTransformedData([]models.TransformedData{
+		{Date: "2025-01-01", Channel: "google_ads", CampaignID: "C-1001"},
+		{Date: "2025-01-02", Channel: "google_ads", CampaignID: "C-1001"},
+		{Date: "2025-01-02", Channel: "facebook_ads", CampaignID: "C-2001"},
+	}))
+	lastIngestion := time.Date(2025, 1, 3, 6, 0, 0, 0, time.UTC)
+	require.NoError(t, store.SetLastIngestionTime(lastIngestion))
+
+	cfg := &config.Config{StorageBackend: constants.StorageBackendMemory}
+	router := gin.New()
+	SetupRoutes(router, NewHandlers(etl.NewService(cfg, store, logger), logger), cfg)
+
+	w := performRequest(router, http.MethodGet, "/healthz?verbose=true")
+	require.Equal(t, http.StatusOK, w.Code)
+
+	var body models.HealthResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+	assert.Equal(t, "healthy", body.Status)
+	require.NotNil(t, body.Detail)
+	assert.Equal(t, 3, body.Detail.Records)
+	assert.Equal(t, "2025-01-03T06:00:00Z", body.Detail.LastIngestion)
+	assert.Equal(t, constants.StorageBackendMemory, body.Detail.StorageBackend)
+
+	// Plain liveness probes keep the simple form
+	w = performRequest(router, http.MethodGet, "/healthz")
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.NotContains(t, w.Body.String(), "detail")
+}
+
+func TestVersion(t *testing.T) {
+	router := setupTestRouter(t, nil)
+
+	// Unset build variables read "dev"
+	w := performRequest(router, http.MethodGet, "/version")
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `{"version": "dev", "commit": "dev", "build_time": "dev"}`, w.Body.String())
+
+	defer func(v, commit, buildTime string) {
+		version.Version, version.Commit, version.BuildTime = v, commit, buildTime
+	}(version.Version, version.Commit, version.BuildTime)
+	version.Version, version.Commit, version.BuildTime = "1.2.0", "abc1234", "2025-01-01T00:00:00Z"
+
+	w = performRequest(router, http.MethodGet, "/version")
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `{"version": "1.2.0", "commit": "abc1234", "build_time": "2025-01-01T00:00:00Z"}`, w.Body.String())
+
+	// Health responses report the same version
+	var health models.HealthResponse
+	w = performRequest(router, http.MethodGet, "/healthz")
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &health))
+	assert.Equal(t, "1.2.0", health.Version)
+}
+
+func TestGetIngestionStatus(t *testing.T) {
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/ads" {
+			w.Write([]byte(`{"external":{"ads":{"performance":[
+				{"date":"2025-01-03","campaign_id":"C-1001","channel":"google_ads","clicks":100},
+				{"date":"2025-01-01","campaign_id":"C-1001","channel":"google_ads","clicks":80},
+				{"date":"2025-01-02","campaign_id":"C-2001","channel":"facebook_ads","clicks":50}]}}}`))
+			return
+		}
+		w.Write([]byte(`{"external":{"crm":{"opportunities":[]}}}`))
+	}))
+	defer upstream.Close()
+
+	router := setupTestRouterWithConfig(t, &config.Config{
+		AdsAPIURL: upstream.URL + "/ads",
+		CRMAPIURL: upstream.URL + "/crm",
+	}, nil)
+
+	// Nothing has been ingested yet
+	w := performRequest(router, http.MethodGet, "/api/v1/ingest/status")
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `{"records": 0}`, w.Body.String())
+
+	before := time.Now().Add(-time.Second)
+	w = performRequest(router, http.MethodPost, "/api/v1/ingest/run")
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+	w = performRequest(router, http.MethodGet, "/api/v1/ingest/status")
+	require.Equal(t, http.StatusOK, w.Code)
+
+	var status models.IngestionStatus
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &status))
+	assert.Equal(t, 3, status.Records)
+	assert.Equal(t, "2025-01-01", status.FirstDate)
+	assert.Equal(t, "2025-01-03", status.LastDate)
+
+	lastIngestion, err := time.Parse(time.RFC3339, status.LastIngestion)
+	require.NoError(t, err)
+	assert.False(t, lastIngestion.Before(before.Truncate(time.Second)))
+}
+
+func TestIngestData(t *testing.T) {
+	router := setupTestRouter(t, nil)
+
+	payload := `{
+		"external": {
+			"ads": {"performance": [
+				{"date": "2025-01-01", "campaign_id": "C-1001", "channel": "google_ads", "clicks": 100, "impressions": 5000, "cost": 50.0,
+				 "utm_campaign": "back_to_school", "utm_source": "google", "utm_medium": "cpc"},
+				{"date": "2025-01-02", "campaign_id": "C-1001", "channel": "google_ads", "clicks": 200, "impressions": 8000, "cost": 80.0,
+				 "utm_campaign": "back_to_school", "utm_source": "google", "utm_medium": "cpc"}
+			]},
+			"crm": {"opportunities": [
+				{"opportunity_id": "O-1", "stage": "closed_won", "amount": 1000, "created_at": "2025-01-01T10:00:00Z",
+				 "utm_campaign": "back_to_school", "utm_source": "google", "utm_medium": "cpc"}
+			]}
+		}
+	}`
+
+	post := func(path, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, path, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	w := post("/api/v1/ingest/data?since=2025/01/02", payload)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+	var result struct {
+		Records int    `json:"records"`
+		Since   string `json:"since"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &result))
+	assert.Equal(t, 1, result.Records)
+	assert.Equal(t, "2025-01-02", result.Since)
+
+	w = post("/api/v1/ingest/data", payload)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &result))
+	assert.Equal(t, 2, result.Records)
+
+	w = performRequest(router, http.MethodGet, "/api/v1/metrics/channel?from=2025-01-01&to=2025-01-01&channel=google_ads")
+	require.Equal(t, http.StatusOK, w.Code)
+	var metrics struct {
+		Data []models.TransformedData `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &metrics))
+	require.Len(t, metrics.Data, 1)
+	assert.Equal(t, 100, metrics.Data[0].Clicks)
+	assert.Equal(t, 1, metrics.Data[0].ClosedWon)
+	assert.Equal(t, 1000.0, metrics.Data[0].Revenue)
+
+	for name, body := range map[string]string{
+		"malformed JSON":  `{"external":`,
+		"missing ads":     `{"external": {"crm": {"opportunities": []}}}`,
+		"bad ads date":    `{"external": {"ads": {"performance": [{"date": "01/02/2025", "campaign_id": "C-1", "channel": "google_ads"}]}}}`,
+		"missing channel": `{"external": {"ads": {"performance": [{"date": "2025-01-02", "campaign_id": "C-1"}]}}}`,
+	} {
+		t.Run(name, func(t *testing.T) {
+			w := post("/api/v1/ingest/data", body)
+			assert.Equal(t, http.StatusBadRequest, w.Code)
+		})
+	}
+}
+
+func TestIngestWebhook(t *testing.T) {
+	cfg := &config.Config{SinkSecret: "secret"}
+	router := setupTestRouterWithConfig(t, cfg, nil)
+	signer := etl.NewService(cfg, storage.NewInMemoryStorage(), logrus.New())
+
+	records := []models.TransformedData{
+		{Date: "2025-01-01", Channel: "google_ads", CampaignID: "C-1001", Clicks: 100, Cost: 25.0, Revenue: 500.0},
+		{Date: "2025-01-02", Channel: "google_ads", CampaignID: "C-1001", Clicks: 40, Cost: 10.0},
+	}
+
+	post := func(body interface{}, signature string) *httptest.ResponseRecorder {
+		raw, err := json.Marshal(body)
+		require.NoError(t, err)
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/ingest/webhook", strings.NewReader(string(raw)))
+		req.Header.Set("Content-Type", "application/json")
+		if signature != "" {
+			req.Header.Set(etl.SignatureHeader, signature)
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	// A tampered batch is refused and nothing is stored
+	tampered := append([]models.TransformedData(nil), records...)
+	tampered[1].Revenue = 999.0
+	w := post(tampered, signer.BatchSignature(records))
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+	w = post(records, "")
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+
+	w = post(records, signer.BatchSignature(records))
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	var result struct {
+		Records int `json:"records"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &result))
+	assert.Equal(t, 2, result.Records)
+
+	// A single object is signed like an exported record
+	single := models.TransformedData{Date: "2025-01-03", Channel: "google_ads", CampaignID: "C-1001", Clicks: 7}
+	w = post(single, signer.BatchSignature([]models.TransformedData{single}))
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+	w = performRequest(router, http.MethodGet, "/api/v1/metrics/channel?from=2025-01-01&to=2025-01-31&channel=google_ads")
+	require.Equal(t, http.StatusOK, w.Code)
+	var metrics struct {
+		Data []models.TransformedData `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &metrics))
+	require.Len(t, metrics.Data, 3)
+	assert.Equal(t, 500.0, metrics.Data[0].Revenue)
+	assert.Equal(t, 0.0, metrics.Data[1].Revenue)
+
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/ingest/webhook", strings.NewReader(`{"date":`))
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func TestReadinessCheck(t *testing.T) {
+	up := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer up.Close()
+
+	down := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer down.Close()
+
+	tests := []struct {
+		name           string
+		cfg            *config.Config
+		expectedCode   int
+		expectedStatus string
+		expectedDeps   map[string]string
+	}{
+		{
+			name:           "all dependencies reachable",
+			cfg:            &config.Config{AdsAPIURL: up.URL, CRMAPIURL: up.URL},
+			expectedCode:   http.StatusOK,
+			expectedStatus: constants.HealthStatusReady,
+			expectedDeps:   map[string]string{"ads_api": "healthy", "crm_api": "healthy"},
+		},
+		{
+			name:           "one dependency failing",
+			cfg:            &config.Config{AdsAPIURL: up.URL, CRMAPIURL: down.URL},
+			expectedCode:   http.StatusServiceUnavailable,
+			expectedStatus: constants.HealthStatusUnhealthy,
+			expectedDeps:   map[string]string{"ads_api": "healthy", "crm_api": "unhealthy"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			router := setupTestRouterWithConfig(t, tt.cfg, nil)
+
+			w := performRequest(router, http.MethodGet, "/readyz")
+			require.Equal(t, tt.expectedCode, w.Code)
+
+			var body models.HealthResponse
+			require.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+			assert.Equal(t, tt.expectedStatus, body.Status)
+			assert.Equal(t, tt.expectedDeps, body.Dependencies)
+		})
+	}
+}
+
+func TestMetricsEndpoint(t *testing.T) {
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if r.URL.Path == "/ads" {
+			w.Write([]byte(`{"external":{"ads":{"performance":[
+				{"date":"2025-01-01","campaign_id":"C-1001","channel":"google_ads","clicks":100,"impressions":1000,"cost":50.0,
+				 "utm_campaign":"back_to_school","utm_source":"google","utm_medium":"cpc"}]}}}`))
+			return
+		}
+		w.Write([]byte(`{"external":{"crm":{"opportunities":[]}}}`))
+	}))
+	defer upstream.Close()
+
+	router := setupTestRouterWithConfig(t, &config.Config{
+		AdsAPIURL: upstream.URL + "/ads",
+		CRMAPIURL: upstream.URL + "/crm",
+	}, nil)
+
+	w := performRequest(router, http.MethodPost, "/api/v1/ingest/run")
+	require.Equal(t, http.StatusOK, w.Code)
+
+	w = performRequest(router, http.MethodGet, "/metrics")
+	require.Equal(t, http.StatusOK, w.Code)
+
+	body := w.Body.String()
+	for _, name := range []string{
+		"admira_etl_ingestion_duration_seconds",
+		"admira_etl_ingestion_records_processed_total",
+		"admira_etl_http_client_requests_total",
+		"admira_etl_http_request_duration_seconds",
+	} {
+		assert.Contains(t, body, name)
+	}
+	assert.Contains(t, body, `admira_etl_ingestion_duration_seconds_count{status="success"}`)
+	assert.Contains(t, body, `route="/api/v1/ingest/run"`)
+}
+
+func TestRunIngestion_Validation(t *testing.T) {
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/ads" {
+			w.Write([]byte(`{"external":{"ads":{"performance":[
+				{"date":"2025-01-01","campaign_id":"C-1001","channel":"google_ads","clicks":100},
+				{"date":"2025-01-01","campaign_id":"C-1002","channel":"google_ads","clicks":"many"}]}}}`))
+			return
+		}
+		w.Write([]byte(`{"external":{"crm":{"opportunities":[]}}}`))
+	}))
+	defer upstream.Close()
+
+	type response struct {
+		Error      string `json:"error"`
+		Validation struct {
+			Mode     string `json:"mode"`
+			Checked  int    `json:"checked"`
+			Rejected []struct {
+				Source   string          `json:"source"`
+				Record   json.RawMessage `json:"record"`
+				Problems []string        `json:"problems"`
+			} `json:"rejected"`
+		} `json:"validation"`
+	}
+
+	// By default the invalid row is skipped and reported
+	router := setupTestRouterWithConfig(t, &config.Config{
+		AdsAPIURL: upstream.URL + "/ads",
+		CRMAPIURL: upstream.URL + "/crm",
+	}, nil)
+
+	w := performRequest(router, http.MethodPost, "/api/v1/ingest/run")
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	var result response
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &result))
+	assert.Equal(t, "skip_invalid", result.Validation.Mode)
+	assert.Equal(t, 2, result.Validation.Checked)
+	require.Len(t, result.Validation.Rejected, 1)
+	assert.Equal(t, "ads", result.Validation.Rejected[0].Source)
+	assert.Contains(t, string(result.Validation.Rejected[0].Record), "C-1002")
+
+	// In reject-all mode the run fails with the same report
+	router = setupTestRouterWithConfig(t, &config.Config{
+		AdsAPIURL:      upstream.URL + "/ads",
+		CRMAPIURL:      upstream.URL + "/crm",
+		ValidationMode: "reject_all",
+	}, nil)
+
+	w = performRequest(router, http.MethodPost, "/api/v1/ingest/run")
+	require.Equal(t, http.StatusUnprocessableEntity, w.Code, w.Body.String())
+	result = response{}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &result))
+	assert.Equal(t, "Ingestion failed", result.Error)
+	assert.Equal(t, "reject_all", result.Validation.Mode)
+	assert.Len(t, result.Validation.Rejected, 1)
+}
+
+func waitForJob(t *testing.T, router *gin.Engine, id string, status jobs.Status) jobs.Job {
+	t.Helper()
+
+	var job jobs.Job
+	require.Eventually(t, func() bool {
+		w := performRequest(router, http.MethodGet, "/api/v1/jobs/"+id)
+		require.Equal(t, http.StatusOK, w.Code)
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &job))
+		return job.Status == status
+	}, 2*time.Second, 10*time.Millisecond)
+	return job
+}
+
+func TestRunIngestion_Async(t *testing.T) {
+	release := make(chan struct{})
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+		w.Header().Set("Content-Type", "application/json")
+		if r.URL.Path == "/ads" {
+			w.Write([]byte(`{"external":{"ads":{"performance":[{"date":"2025-01-01","campaign_id":"C-1001","channel":"google_ads","clicks":100,"cost":50.0}]}}}`))
+			return
+		}
+		w.Write([]byte(`{"external":{"crm":{"opportunities":[]}}}`))
+	}))
+	defer upstream.Close()
+
+	router := setupTestRouterWithConfig(t, &config.Config{
+		AdsAPIURL: upstream.URL + "/ads",
+		CRMAPIURL: upstream.URL + "/crm",
+	}, nil)
+
+	w := performRequest(router, http.MethodPost, "/api/v1/ingest/run?async=true")
+	require.Equal(t, http.StatusAccepted, w.Code)
+
+	var accepted struct {
+		JobID string `json:"job_id"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &accepted))
+	require.NotEmpty(t, accepted.JobID)
+
+	// The job is blocked on the upstream, so it stays running
+	job := waitForJob(t, router, accepted.JobID, jobs.StatusRunning)
+	assert.Equal(t, "ingestion", job.Type)
+
+	close(release)
+	job = waitForJob(t, router, accepted.JobID, jobs.StatusSucceeded)
+	assert.Empty(t, job.Error)
+	assert.NotNil(t, job.FinishedAt)
+
+	w = performRequest(router, http.MethodGet, "/api/v1/metrics/channel?from=2025-01-01&to=2025-01-31&channel=google_ads")
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `"count":1`)
+}
+
+func TestReingestDate(t *testing.T) {
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if r.URL.Path == "/ads" {
+			w.Write([]byte(`{"external":{"ads":{"performance":[{"date":"2025-01-01","campaign_id":"C-1001","channel":"google_ads","clicks":100,"cost":50.0}]}}}`))
+			return
+		}
+		w.Write([]byte(`{"external":{"crm":{"opportunities":[]}}}`))
+	}))
+	defer upstream.Close()
+
+	router := setupTestRouterWithConfig(t, &config.Config{
+		AdsAPIURL: upstream.URL + "/ads",
+		CRMAPIURL: upstream.URL + "/crm",
+	}, []models.TransformedData{
+		{Date: "2025-01-01", Channel: "google_ads", CampaignID: "C-1001", Clicks: 1},
+		{Date: "2025-01-01", Channel: "google_ads", CampaignID: "C-1001", Clicks: 2},
+	})
+
+	w := performRequest(router, http.MethodPost, "/api/v1/ingest/date?date=2025-01-01")
+	require.Equal(t, http.StatusOK, w.Code)
+
+	var body struct {
+		Date    string `json:"date"`
+		Deleted int    `json:"deleted"`
+		Records int    `json:"records"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+	assert.Equal(t, "2025-01-01", body.Date)
+	assert.Equal(t, 2, body.Deleted)
+	assert.Equal(t, 1, body.Records)
+
+	w = performRequest(router, http.MethodGet, "/api/v1/metrics/channel?from=2025-01-01&to=2025-01-01&channel=google_ads")
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `"count":1`)
+	assert.Contains(t, w.Body.String(), `"clicks":100`)
+
+	for _, query := range []string{"", "?date=yesterday"} {
+		w = performRequest(router, http.MethodPost, "/api/v1/ingest/date"+query)
+		assert.Equal(t, http.StatusBadRequest, w.Code, "query %q", query)
+	}
+}
+
+func TestRunIngestion_AsyncFailure(t *testing.T) {
+	router := setupTestRouter(t, nil)
+
+	w := performRequest(router, http.MethodPost, "/api/v1/ingest/run?async=true")
+	require.Equal(t, http.StatusAccepted, w.Code)
+
+	var accepted struct {
+		JobID string `json:"job_id"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &accepted))
+
+	job := waitForJob(t, router, accepted.JobID, jobs.StatusFailed)
+	assert.Contains(t, job.Error, "ads API URL not configured")
+}
+
+func TestInvalidDateRanges(t *testing.T) {
+	router := setupTestRouter(t, nil)
+
+	tests := []struct {
+		name   string
+		method string
+		path   string
+	}{
+		{"ingestion since after until", http.MethodPost, "/api/v1/ingest/run?since=2025-01-05&until=2025-01-01"},
+		{"metrics from after to", http.MethodGet, "/api/v1/metrics/channel?from=2025-01-31&to=2025-01-01&channel=google_ads"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := performRequest(router, tt.method, tt.path)
+			assert.Equal(t, http.StatusBadRequest, w.Code)
+			assert.Contains(t, w.Body.String(), `"error":"Invalid date range"`)
+		})
+	}
+}
+
+func TestRunIngestion_InvalidDateFormat(t *testing.T) {
+	router := setupTestRouter(t, nil)
+
+	w := performRequest(router, http.MethodPost, "/api/v1/ingest/run?since=01-02-2025")
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Body.String(), `"error":"Invalid date format"`)
+}
+
+func TestDeleteData(t *testing.T) {
+	router := setupTestRouter(t, []models.TransformedData{
+		{Date: "2025-01-01", Channel: "google_ads", CampaignID: "C-1001"},
+		{Date: "2025-01-01", Channel: "facebook_ads", CampaignID: "C-2001"},
+		{Date: "2025-01-05", Channel: "google_ads", CampaignID: "C-1001"},
+	})
+
+	w := performRequest(router, http.MethodDelete, "/api/v1/data?from=2025-01-01&to=2025-01-02&channel=google_ads")
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `"deleted":1`)
+
+	w = performRequest(router, http.MethodGet, "/api/v1/metrics/channel?from=2025-01-01&to=2025-01-31&channel=google_ads")
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `"count":1`)
+
+	w = performRequest(router, http.MethodDelete, "/api/v1/data?from=2025-01-01")
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func TestSnapshotEndpoints(t *testing.T) {
+	router := setupTestRouter(t, []models.TransformedData{
+		{Date: "2025-01-01", Channel: "google_ads", CampaignID: "C-1001", Clicks: 100, Revenue: 500.0},
+		{Date: "2025-01-05", Channel: "google_ads", CampaignID: "C-1002", Clicks: 50},
+	})
+	channelPath := "/api/v1/metrics/channel?from=2025-01-01&to=2025-01-31&channel=google_ads"
+
+	w := performRequest(router, http.MethodGet, "/api/v1/admin/snapshot")
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Header().Get("Content-Disposition"), "attachment")
+	snapshot := w.Body.Bytes()
+	before := performRequest(router, http.MethodGet, channelPath).Body.String()
+
+	w = performRequest(router, http.MethodDelete, "/api/v1/data?from=2025-01-01&to=2025-01-31")
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, performRequest(router, http.MethodGet, channelPath).Body.String(), `"count":0`)
+
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/admin/snapshot", bytes.NewReader(snapshot))
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.Contains(t, w.Body.String(), `"records":2`)
+
+	// Everything is back, down to the last field
+	assert.JSONEq(t, before, performRequest(router, http.MethodGet, channelPath).Body.String())
+
+	req = httptest.NewRequest(http.MethodPost, "/api/v1/admin/snapshot", strings.NewReader(`{"data": [{"date": "yesterday"}]}`))
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, performRequest(router, http.MethodGet, channelPath).Body.String(), `"count":2`)
+}
+
+func TestNamespaces(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	logger := logrus.New()
+	logger.SetLevel(logrus.ErrorLevel)
+
+	store := storage.NewInMemoryStorage()
+	require.NoError(t, store.StoreTransformedData([]models.TransformedData{
+		{Date: "2025-01-01", Channel: "google_ads", CampaignID: "C-1001", Clicks: 100},
+	}))
+	service := etl.NewService(&config.Config{Namespaces: []string{"staging", "preview"}}, store, logger)
+	service.SetNamespaces(storage.NewNamespaces(store, func(string) (storage.Storage, error) {
+		return storage.NewInMemoryStorage(), nil
+	}))
+	router := gin.New()
+	SetupRoutes(router, NewHandlers(service, logger), &config.Config{})
+
+	request := func(method, path, namespace, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		if namespace != "" {
+			req.Header.Set(namespaceHeader, namespace)
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	payload := `{
+		"external": {
+			"ads": {"performance": [
+				{"date": "2025-01-02", "campaign_id": "C-9001", "channel": "facebook_ads", "clicks": 7, "impressions": 100, "cost": 5.0,
+				 "utm_campaign": "preview", "utm_source": "facebook", "utm_medium": "cpc"}
+			]},
+			"crm": {"opportunities": []}
+		}
+	}`
+	w := request(http.MethodPost, "/api/v1/ingest/data", "staging", payload)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+	// Namespaces that aren't configured are rejected
+	w = request(http.MethodPost, "/api/v1/ingest/data", "unlisted", payload)
+	assert.Equal(t, http.StatusBadRequest, w.Code, w.Body.String())
+
+	campaigns := func(namespace string) []string {
+		w := request(http.MethodGet, "/api/v1/dimensions?from=2025-01-01&to=2025-01-31", namespace, "")
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		var dimensions models.Dimensions
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &dimensions))
+		return dimensions.CampaignIDs
+	}
+
+	// Each namespace only sees its own rows; no header means the default
+	assert.Equal(t, []string{"C-9001"}, campaigns("staging"))
+	assert.Equal(t, []string{"C-1001"}, campaigns(""))
+	assert.Equal(t, []string{"C-1001"}, campaigns("default"))
+	assert.Equal(t, []string{}, campaigns("preview"))
+
+	w = request(http.MethodGet, "/api/v1/metrics/summary?from=2025-01-01&to=2025-01-31", "", "")
+	require.Equal(t, http.StatusOK, w.Code)
+	var summary models.MetricsSummary
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &summary))
+	assert.Equal(t, 100, summary.Clicks)
+
+	w = request(http.MethodGet, "/api/v1/ingest/status", "../prod", "")
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func TestNamespaces_Disabled(t *testing.T) {
+	router := setupTestRouter(t, nil)
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/ingest/status", nil)
+	req.Header.Set(namespaceHeader, "staging")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	// The default namespace needs no registry
+	req.Header.Set(namespaceHeader, "default")
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+}
+
+func TestGetJob_NotFound(t *testing.T) {
+	router := setupTestRouter(t, nil)
+
+	w := performRequest(router, http.MethodGet, "/api/v1/jobs/does-not-exist")
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	assert.Contains(t, w.Body.String(), `"error":"Job not found"`)
+}
+
+func TestExportData_PartialFailure(t *testing.T) {
+	sink := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var record models.TransformedData
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&record))
+		if record.Channel == "facebook_ads" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer sink.Close()
+
+	router := setupTestRouterWithConfig(t, &config.Config{SinkURL: sink.URL, SinkSecret: "secret"}, []models.TransformedData{
+		{Date: "2025-01-01", Channel: "google_ads", CampaignID: "C-1001"},
+		{Date: "2025-01-01", Channel: "facebook_ads", CampaignID: "C-2001"},
+	})
+
+	w := performRequest(router, http.MethodPost, "/api/v1/export/run?date=2025-01-01")
+	require.Equal(t, http.StatusBadGateway, w.Code)
+
+	var body struct {
+		RecordsExported int `json:"records_exported"`
+		RecordsFailed   []struct {
+			Channel    string `json:"channel"`
+			CampaignID string `json:"campaign_id"`
+			Error      string `json:"error"`
+		} `json:"records_failed"`
+		Summary struct {
+			Records         int `json:"records"`
+			RecordsExported int `json:"records_exported"`
+		} `json:"summary"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+	assert.Equal(t, 1, body.RecordsExported)
+	assert.Equal(t, 2, body.Summary.Records)
+	assert.Equal(t, 1, body.Summary.RecordsExported)
+	require.Len(t, body.RecordsFailed, 1)
+	assert.Equal(t, "facebook_ads", body.RecordsFailed[0].Channel)
+	assert.Equal(t, "C-2001", body.RecordsFailed[0].CampaignID)
+	assert.Contains(t, body.RecordsFailed[0].Error, "HTTP 400")
+}
+
+func TestExportData_Async(t *testing.T) {
+	release := make(chan struct{})
+	sink := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+		var record models.TransformedData
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&record))
+		if record.Channel == "facebook_ads" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer sink.Close()
+
+	router := setupTestRouterWithConfig(t, &config.Config{SinkURL: sink.URL, SinkSecret: "secret"}, []models.TransformedData{
+		{Date: "2025-01-01", Channel: "google_ads", CampaignID: "C-1001"},
+		{Date: "2025-01-01", Channel: "facebook_ads", CampaignID: "C-2001"},
+	})
+
+	w := performRequest(router, http.MethodPost, "/api/v1/export/run?date=2025-01-01&async=true")
+	require.Equal(t, http.StatusAccepted, w.Code)
+
+	var accepted struct {
+		JobID string `json:"job_id"`
+		Date  string `json:"date"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &accepted))
+	require.NotEmpty(t, accepted.JobID)
+	assert.Equal(t, "2025-01-01", accepted.Date)
+
+	// The job is blocked on the sink, so it stays running
+	job := waitForJob(t, router, accepted.JobID, jobs.StatusRunning)
+	assert.Equal(t, "export", job.Type)
+	assert.Nil(t, job.Result)
+
+	// One of the two deliveries fails, which fails the job
+	close(release)
+	job = waitForJob(t, router, accepted.JobID, jobs.StatusFailed)
+	assert.Contains(t, job.Error, "failed to export 1 of 2 records")
+
+	w = performRequest(router, http.MethodGet, "/api/v1/jobs/"+accepted.JobID)
+	var body struct {
+		Result struct {
+			Exported int `json:"exported"`
+			Failed   int `json:"failed"`
+			Summary  struct {
+				Records       int `json:"records"`
+				RecordsFailed []struct {
+					CampaignID string `json:"campaign_id"`
+				} `json:"records_failed"`
+			} `json:"summary"`
+		} `json:"result"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+	assert.Equal(t, 1, body.Result.Exported)
+	assert.Equal(t, 1, body.Result.Failed)
+	assert.Equal(t, 2, body.Result.Summary.Records)
+	require.Len(t, body.Result.Summary.RecordsFailed, 1)
+	assert.Equal(t, "C-2001", body.Result.Summary.RecordsFailed[0].CampaignID)
+}
+
+func TestExportData_DryRun(t *testing.T) {
+	var calls atomic.Int32
+	sink := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer sink.Close()
+
+	router := setupTestRouterWithConfig(t, &config.Config{SinkURL: sink.URL, SinkSecret: "secret"}, []models.TransformedData{
+		{Date: "2025-01-01", Channel: "google_ads", CampaignID: "C-1001", Clicks: 100},
+		{Date: "2025-01-01", Channel: "google_ads", CampaignID: "C-1001", Clicks: 50},
+		{Date: "2025-01-01", Channel: "facebook_ads", CampaignID: "C-2001", Clicks: 80},
+	})
+
+	w := performRequest(router, http.MethodPost, "/api/v1/export/run?date=2025-01-01&dry_run=true")
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Zero(t, calls.Load(), "dry run must not contact the sink")
+
+	var body struct {
+		DryRun  bool `json:"dry_run"`
+		Count   int  `json:"count"`
+		Summary struct {
+			Records  int `json:"records"`
+			Channels []struct {
+				Channel string `json:"channel"`
+				Records int    `json:"records"`
+			} `json:"channels"`
+		} `json:"summary"`
+		Records []struct {
+			Record    models.TransformedData `json:"record"`
+			Signature string                 `json:"signature"`
+		} `json:"records"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+	assert.True(t, body.DryRun)
+	assert.Equal(t, 2, body.Count)
+	require.Len(t, body.Records, 2)
+	// Consolidated per channel and campaign, ordered by channel
+	assert.Equal(t, "C-2001", body.Records[0].Record.CampaignID)
+	assert.Equal(t, "C-1001", body.Records[1].Record.CampaignID)
+	assert.Equal(t, 150, body.Records[1].Record.Clicks)
+	for _, record := range body.Records {
+		assert.Len(t, record.Signature, 64)
+	}
+	assert.Equal(t, 2, body.Summary.Records)
+	assert.Len(t, body.Summary.Channels, 2)
+
+	// channel narrows what gets consolidated
+	w = performRequest(router, http.MethodPost, "/api/v1/export/run?date=2025-01-01&dry_run=true&channel=facebook_ads")
+	require.Equal(t, http.StatusOK, w.Code)
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+	assert.Equal(t, 1, body.Count)
+	require.Len(t, body.Records, 1)
+	assert.Equal(t, "C-2001", body.Records[0].Record.CampaignID)
+
+	// Nothing failed, so nothing is queued for replay either
+	w = performRequest(router, http.MethodGet, "/api/v1/export/deadletter")
+	assert.Contains(t, w.Body.String(), `"count":0`)
+}
+
+func TestDeadLetterEndpoints(t *testing.T) {
+	var sinkUp atomic.Bool
+	sink := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !sinkUp.Load() {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer sink.Close()
+
+	router := setupTestRouterWithConfig(t, &config.Config{SinkURL: sink.URL, SinkSecret: "secret"}, []models.TransformedData{
+		{Date: "2025-01-01", Channel: "google_ads", CampaignID: "C-1001"},
+	})
+
+	w := performRequest(router, http.MethodPost, "/api/v1/export/run?date=2025-01-01")
+	require.Equal(t, http.StatusBadGateway, w.Code)
+
+	w = performRequest(router, http.MethodGet, "/api/v1/export/deadletter")
+	require.Equal(t, http.StatusOK, w.Code)
+
+	var pending struct {
+		Data []struct {
+			Record    models.TransformedData `json:"record"`
+			LastError string                 `json:"last_error"`
+			Attempts  int                    `json:"attempts"`
+		} `json:"data"`
+		Count int `json:"count"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &pending))
+	require.Equal(t, 1, pending.Count)
+	assert.Equal(t, "C-1001", pending.Data[0].Record.CampaignID)
+	assert.Equal(t, 1, pending.Data[0].Attempts)
+	assert.Contains(t, pending.Data[0].LastError, "HTTP 400")
+
+	sinkUp.Store(true)
+	w = performRequest(router, http.MethodPost, "/api/v1/export/retry")
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `{"retried":1,"succeeded":1,"remaining":0}`, w.Body.String())
+
+	w = performRequest(router, http.MethodGet, "/api/v1/export/deadletter")
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `"count":0`)
+}
+
+func TestUnknownRoutes(t *testing.T) {
+	router := setupTestRouter(t, nil)
+
+	tests := []struct {
+		name         string
+		method       string
+		path         string
+		expectedCode int
+		expectedErr  string
+	}{
+		{"unknown path", http.MethodGet, "/api/v1/nope", http.StatusNotFound, "Not found"},
+		{"unknown top-level path", http.MethodGet, "/nope", http.StatusNotFound, "Not found"},
+		{"wrong method", http.MethodDelete, "/api/v1/metrics/channel", http.StatusMethodNotAllowed, "Method not allowed"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := performRequest(router, tt.method, tt.path)
+			assert.Equal(t, tt.expectedCode, w.Code)
+			assert.Contains(t, w.Header().Get("Content-Type"), "application/json")
+
+			var body models.ErrorResponse
+			require.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+			assert.Equal(t, tt.expectedErr, body.Error)
+			assert.Contains(t, body.Message, tt.path)
+		})
+	}
+}
@@ -0,0 +1,552 @@
+package api
+
+import (
+	"compress/gzip"
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"net"
+	"net/http"
+	"runtime/debug"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	httpclient "admira-etl/internal/http"
+	"admira-etl/internal/models"
+	"admira-etl/internal/telemetry"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+	"golang.org/x/time/rate"
+)
+
+// RequestID echoes the incoming X-Request-ID on the response and stores it on
+// the request context, so calls the service makes to upstream APIs and sinks
+// while handling the request carry the same ID.
+func RequestID() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if id := c.GetHeader(httpclient.RequestIDHeader); id != "" {
+			c.Header(httpclient.RequestIDHeader, id)
+			c.Request = c.Request.WithContext(httpclient.WithRequestID(c.Request.Context(), id))
+		}
+		c.Next()
+	}
+}
+
+// Recovery turns a panic in a later handler into a 500 with an
+// ErrorResponse body, logging the panic, its stack trace and the request ID
+// so the failure can be matched to the client's request.
+func Recovery(logger *logrus.Logger) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		defer func() {
+			recovered := recover()
+			if recovered == nil {
+				return
+			}
+
+			logger.WithFields(logrus.Fields{
+				"panic":      fmt.Sprint(recovered),
+				"stack":      string(debug.Stack()),
+				"request_id": c.GetHeader(httpclient.RequestIDHeader),
+				"method":     c.Request.Method,
+				"path":       c.Request.URL.Path,
+			}).Error("Recovered from panic")
+
+			// Nothing more can be sent once the handler has written a body
+			if c.Writer.Written() {
+				c.Abort()
+				return
+			}
+			c.AbortWithStatusJSON(http.StatusInternalServerError, models.ErrorResponse{
+				Error:   "Internal server error",
+				Message: "The request could not be completed",
+			})
+		}()
+
+		c.Next()
+	}
+}
+
+// RequestMetrics records the latency of every request, labelled by the
+// matched route template rather than the raw path to keep cardinality low.
+func RequestMetrics() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+		c.Next()
+
+		route := c.FullPath()
+		if route == "" {
+			route = "unmatched"
+		}
+
+		telemetry.API.RequestDuration.
+			WithLabelValues(c.Request.Method, route, strconv.Itoa(c.Writer.Status())).
+			Observe(time.Since(start).Seconds())
+	}
+}
+
+// APIKeyAuth rejects requests that don't present apiKey either as
+// "Authorization: Bearer <key>" or in the X-API-Key header. An empty apiKey
+// disables the check so existing deployments keep working unchanged.
+func APIKeyAuth(apiKey string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if apiKey == "" {
+			c.Next()
+			return
+		}
+
+		provided := requestAPIKey(c)
+		if provided == "" {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, models.ErrorResponse{
+				Error:   "Unauthorized",
+				Message: "Missing API key",
+			})
+			return
+		}
+
+		if subtle.ConstantTimeCompare([]byte(provided), []byte(apiKey)) != 1 {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, models.ErrorResponse{
+				Error:   "Unauthorized",
+				Message: "Invalid API key",
+			})
+			return
+		}
+
+		c.Next()
+	}
+}
+
+// requestAPIKey returns the key sent in X-API-Key or as a bearer token.
+func requestAPIKey(c *gin.Context) string {
+	if key := c.GetHeader("X-API-Key"); key != "" {
+		return key
+	}
+	if auth := c.GetHeader("Authorization"); strings.HasPrefix(auth, "Bearer ") {
+		return strings.TrimPrefix(auth, "Bearer ")
+	}
+	return ""
+}
+
+// MaxRequestBytes rejects request bodies larger than limit with a 413. A
+// declared Content-Length over the limit is refused before the handler runs;
+// otherwise the body is wrapped so reading past the limit fails, which
+// handlers report with requestTooLarge. A non-positive limit disables the
+// check.
+func MaxRequestBytes(limit int64) gin.HandlerFunc {
+	if limit <= 0 {
+		return func(c *gin.Context) { c.Next() }
+	}
+
+	return func(c *gin.Context) {
+		if c.Request.ContentLength > limit {
+			c.AbortWithStatusJSON(http.StatusRequestEntityTooLarge, requestTooLargeResponse(limit))
+			return
+		}
+		if c.Request.Body != nil {
+			c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, limit)
+		}
+		c.Next()
+	}
+}
+
+// requestTooLarge responds with a 413 and reports true if err came from
+// reading past the MaxRequestBytes limit.
+func requestTooLarge(c *gin.Context, err error) bool {
+	var tooLarge *http.MaxBytesError
+	if !errors.As(err, &tooLarge) {
+		return false
+	}
+	c.JSON(http.StatusRequestEntityTooLarge, requestTooLargeResponse(tooLarge.Limit))
+	return true
+}
+
+func requestTooLargeResponse(limit int64) models.ErrorResponse {
+	return models.ErrorResponse{
+		Error:   "Request body too large",
+		Message: fmt.Sprintf("Request bodies are limited to %d bytes", limit),
+	}
+}
+
+// Compress gzips responses for clients that send Accept-Encoding: gzip once
+// the body reaches minBytes; smaller bodies are sent as is. At most minBytes
+// are held back before deciding, so streamed responses such as CSV are
+// compressed as they are written rather than buffered whole.
+func Compress(minBytes int) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if c.Request.Method == http.MethodHead || !acceptsGzip(c.GetHeader("Accept-Encoding")) {
+			c.Next()
+			return
+		}
+
+		writer := &gzipResponseWriter{ResponseWriter: c.Writer, minBytes: minBytes}
+		writer.Header().Add("Vary", "Accept-Encoding")
+		c.Writer = writer
+		// After a panic the buffered body is dropped and Recovery writes
+		// straight to the underlying writer
+		defer func() { c.Writer = writer.ResponseWriter }()
+
+		c.Next()
+		writer.finish()
+	}
+}
+
+// acceptsGzip reports whether an Accept-Encoding header allows gzip, either
+// by name or through "*", without a zero q-value.
+func acceptsGzip(header string) bool {
+	for _, part := range strings.Split(header, ",") {
+		coding, params, _ := strings.Cut(part, ";")
+		coding = strings.TrimSpace(coding)
+		if !strings.EqualFold(coding, "gzip") && coding != "*" {
+			continue
+		}
+		if q, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			if weight, err := strconv.ParseFloat(q, 64); err == nil && weight == 0 {
+				continue
+			}
+		}
+		return true
+	}
+	return false
+}
+
+// gzipResponseWriter buffers the start of a response until it reaches
+// minBytes, then switches to gzip for the rest of it.
+type gzipResponseWriter struct {
+	gin.ResponseWriter
+	minBytes int
+	buf      []byte
+	gz       *gzip.Writer
+	// plain is set once the body is known to go out uncompressed
+	plain bool
+}
+
+func (w *gzipResponseWriter) Write(data []byte) (int, error) {
+	switch {
+	case w.gz != nil:
+		return w.gz.Write(data)
+	case w.plain:
+		return w.ResponseWriter.Write(data)
+	}
+
+	w.buf = append(w.buf, data...)
+	if len(w.buf) >= w.minBytes {
+		if err := w.start(); err != nil {
+			return 0, err
+		}
+	}
+	return len(data), nil
+}
+
+func (w *gzipResponseWriter) WriteString(s string) (int, error) {
+	return w.Write([]byte(s))
+}
+
+// Flush sends what has been written so far, compressing it if it hasn't
+// been decided yet.
+func (w *gzipResponseWriter) Flush() {
+	if w.gz == nil && !w.plain {
+		if err := w.start(); err != nil {
+			return
+		}
+	}
+	if w.gz != nil {
+		w.gz.Flush()
+	}
+	w.ResponseWriter.Flush()
+}
+
+// start begins the compressed body, unless the handler already encoded it,
+// and writes out the buffered bytes.
+func (w *gzipResponseWriter) start() error {
+	if w.Header().Get("Content-Encoding") != "" {
+		w.plain = true
+	} else {
+		w.Header().Set("Content-Encoding", "gzip")
+		w.Header().Del("Content-Length")
+		w.gz = gzip.NewWriter(w.ResponseWriter)
+	}
+
+	buffered := w.buf
+	w.buf = nil
+	_, err := w.Write(buffered)
+	return err
+}
+
+// finish sends a body that stayed under minBytes as is, or completes the
+// gzip stream.
+func (w *gzipResponseWriter) finish() {
+	if w.gz == nil && !w.plain {
+		w.plain = true
+		if len(w.buf) > 0 {
+			w.ResponseWriter.Write(w.buf)
+			w.buf = nil
+		}
+		return
+	}
+	if w.gz != nil {
+		w.gz.Close()
+	}
+}
+
+// rateLimiterIdleTTL is how long an unused client bucket is kept before it is
+// swept, bounding memory when many distinct clients come and go.
+const rateLimiterIdleTTL = 10 * time.Minute
+
+type clientLimiter struct {
+	limiter  *rate.Limiter
+	lastSeen time.Time
+}
+
+type rateLimiter struct {
+	mu        sync.Mutex
+	clients   map[string]*clientLimiter
+	rps       rate.Limit
+	burst     int
+	lastSweep time.Time
+}
+
+func (rl *rateLimiter) get(key string, now time.Time) *rate.Limiter {
+	rl.mu.Lock()
+	defer rl.mu.Unlock()
+
+	if now.Sub(rl.lastSweep) > rateLimiterIdleTTL {
+		for k, client := range rl.clients {
+			if now.Sub(client.lastSeen) > rateLimiterIdleTTL {
+				delete(rl.clients, k)
+			}
+		}
+		rl.lastSweep = now
+	}
+
+	client, exists := rl.clients[key]
+	if !exists {
+		client = &clientLimiter{limiter: rate.NewLimiter(rl.rps, rl.burst)}
+		rl.clients[key] = client
+	}
+	client.lastSeen = now
+	return client.limiter
+}
+
+// RateLimit applies a token bucket of rps tokens per second and the given
+// burst to each client, keyed by the IP the connection comes from. It runs
+// before authentication, so it can't trust the API key a request sends, nor
+// X-Forwarded-For, which any client can set: keying by either would let a
+// client get a fresh bucket per made-up value. Exhausted clients get a 429
+// with a Retry-After header. A non-positive rps disables limiting.
+func RateLimit(rps float64, burst int) gin.HandlerFunc {
+	if rps <= 0 {
+		return func(c *gin.Context) { c.Next() }
+	}
+	if burst <= 0 {
+		burst = 1
+	}
+
+	rl := &rateLimiter{
+		clients:   make(map[string]*clientLimiter),
+		rps:       rate.Limit(rps),
+		burst:     burst,
+		lastSweep: time.Now(),
+	}
+
+	return func(c *gin.Context) {
+		now := time.Now()
+		reservation := rl.get(remoteHost(c.Request), now).ReserveN(now, 1)
+		if delay := reservation.DelayFrom(now); delay > 0 {
+			reservation.CancelAt(now)
+			c.Header("Retry-After", strconv.Itoa(int(math.Ceil(delay.Seconds()))))
+			c.AbortWithStatusJSON(http.StatusTooManyRequests, models.ErrorResponse{
+				Error:   "Too many requests",
+				Message: "Rate limit exceeded, retry later",
+			})
+			return
+		}
+
+		c.Next()
+	}
+}
+
+// remoteHost returns the host part of the connection's remote address, or
+// the whole address when it has no port.
+func remoteHost(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+// RequestTimeout puts a deadline of timeout on each request's context, so
+// upstream calls and other work honouring it are cancelled. A request still
+// running at the deadline gets a 503 with an ErrorResponse body straight
+// away, unless its response had already started, and anything the handler
+// writes after that is dropped. The handler is still waited for before the
+// middleware returns. A non-positive timeout disables the deadline.
+func RequestTimeout(timeout time.Duration, logger *logrus.Logger) gin.HandlerFunc {
+	if timeout <= 0 {
+		return func(c *gin.Context) { c.Next() }
+	}
+
+	return func(c *gin.Context) {
+		ctx, cancel := context.WithTimeout(c.Request.Context(), timeout)
+		defer cancel()
+		c.Request = c.Request.WithContext(ctx)
+
+		writer := &timeoutResponseWriter{ResponseWriter: c.Writer, header: c.Writer.Header().Clone()}
+		c.Writer = writer
+		defer func() { c.Writer = writer.ResponseWriter }()
+
+		// The handler runs on its own goroutine so the timeout response
+		// doesn't wait for it; a panic is handed back to Recovery here
+		done := make(chan struct{})
+		var panicked interface{}
+		go func() {
+			defer close(done)
+			defer func() { panicked = recover() }()
+			c.Next()
+		}()
+
+		select {
+		case <-done:
+		case <-ctx.Done():
+			if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+				logger.WithFields(logrus.Fields{
+					"request_id": c.GetHeader(httpclient.RequestIDHeader),
+					"method":     c.Request.Method,
+					"path":       c.Request.URL.Path,
+					"timeout":    timeout.String(),
+				}).Warn("Request timed out")
+				writer.timeout()
+			}
+			<-done
+		}
+
+		if panicked != nil {
+			panic(panicked)
+		}
+		writer.WriteHeaderNow()
+	}
+}
+
+// timeoutResponseWriter keeps the handler's headers and status to itself
+// until its first write, so a timeout response can still be sent in their
+// place, and drops every write once the request has timed out.
+type timeoutResponseWriter struct {
+	gin.ResponseWriter
+
+	mu          sync.Mutex
+	header      http.Header
+	status      int
+	wroteHeader bool
+	timedOut    bool
+}
+
+func (w *timeoutResponseWriter) Header() http.Header {
+	return w.header
+}
+
+func (w *timeoutResponseWriter) WriteHeader(code int) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if !w.wroteHeader {
+		w.status = code
+	}
+}
+
+func (w *timeoutResponseWriter) WriteHeaderNow() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.timedOut {
+		return
+	}
+	w.writeHeader()
+	w.ResponseWriter.WriteHeaderNow()
+}
+
+func (w *timeoutResponseWriter) Write(data []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.timedOut {
+		return 0, http.ErrHandlerTimeout
+	}
+	w.writeHeader()
+	return w.ResponseWriter.Write(data)
+}
+
+func (w *timeoutResponseWriter) WriteString(s string) (int, error) {
+	return w.Write([]byte(s))
+}
+
+func (w *timeoutResponseWriter) Flush() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.timedOut {
+		return
+	}
+	w.writeHeader()
+	w.ResponseWriter.Flush()
+}
+
+func (w *timeoutResponseWriter) Status() int {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.status != 0 && !w.timedOut {
+		return w.status
+	}
+	return w.ResponseWriter.Status()
+}
+
+func (w *timeoutResponseWriter) Written() bool {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.wroteHeader
+}
+
+// writeHeader hands the handler's headers and status to the underlying
+// writer, once. Callers hold mu.
+func (w *timeoutResponseWriter) writeHeader() {
+	if w.wroteHeader {
+		return
+	}
+	w.wroteHeader = true
+
+	header := w.ResponseWriter.Header()
+	for key := range header {
+		if _, ok := w.header[key]; !ok {
+			header.Del(key)
+		}
+	}
+	for key, values := range w.header {
+		header[key] = values
+	}
+	if w.status != 0 {
+		w.ResponseWriter.WriteHeader(w.status)
+	}
+}
+
+// timeout sends the 503 unless the handler's response has already started.
+// The body's length is declared so clients have all of it before the
+// handler finishes.
+func (w *timeoutResponseWriter) timeout() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.timedOut = true
+	if w.wroteHeader {
+		return
+	}
+
+	body, _ := json.Marshal(models.ErrorResponse{
+		Error:   "Request timeout",
+		Message: "The request did not complete in time",
+	})
+	header := w.ResponseWriter.Header()
+	header.Set("Content-Type", "application/json; charset=utf-8")
+	header.Set("Content-Length", strconv.Itoa(len(body)))
+	w.ResponseWriter.WriteHeader(http.StatusServiceUnavailable)
+	w.ResponseWriter.Write(body)
+	w.ResponseWriter.Flush()
+}
@@ -0,0 +1,394 @@
+package api
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"admira-etl/internal/config"
+	"admira-etl/internal/constants"
+	"admira-etl/internal/etl"
+	"admira-etl/internal/models"
+	"admira-etl/internal/storage"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAPIKeyAuth(t *testing.T) {
+	router := setupTestRouterWithConfig(t, &config.Config{APIKey: "s3cret"}, nil)
+	path := "/api/v1/metrics/channel?from=2025-01-01&to=2025-01-31&channel=google_ads"
+
+	tests := []struct {
+		name         string
+		headers      map[string]string
+		expectedCode int
+	}{
+		{"missing key", nil, http.StatusUnauthorized},
+		{"wrong bearer token", map[string]string{"Authorization": "Bearer nope"}, http.StatusUnauthorized},
+		{"wrong X-API-Key", map[string]string{"X-API-Key": "nope"}, http.StatusUnauthorized},
+		{"non-bearer authorization", map[string]string{"Authorization": "Basic s3cret"}, http.StatusUnauthorized},
+		{"correct bearer token", map[string]string{"Authorization": "Bearer s3cret"}, http.StatusOK},
+		{"correct X-API-Key", map[string]string{"X-API-Key": "s3cret"}, http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, path, nil)
+			for key, value := range tt.headers {
+				req.Header.Set(key, value)
+			}
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.expectedCode, w.Code)
+			if tt.expectedCode == http.StatusUnauthorized {
+				assert.Contains(t, w.Body.String(), `"error":"Unauthorized"`)
+			}
+		})
+	}
+
+	t.Run("health endpoints stay public", func(t *testing.T) {
+		w := performRequest(router, http.MethodGet, "/healthz")
+		assert.Equal(t, http.StatusOK, w.Code)
+	})
+}
+
+func TestAPIKeyAuth_DisabledWhenUnset(t *testing.T) {
+	router := setupTestRouterWithConfig(t, &config.Config{}, nil)
+
+	w := performRequest(router, http.MethodGet, "/api/v1/metrics/channel?from=2025-01-01&to=2025-01-31&channel=google_ads")
+	assert.Equal(t, http.StatusOK, w.Code)
+}
+
+func TestRateLimit(t *testing.T) {
+	router := setupTestRouterWithConfig(t, &config.Config{RateLimitRPS: 1, RateLimitBurst: 3}, nil)
+	path := "/api/v1/metrics/channel?from=2025-01-01&to=2025-01-31&channel=google_ads"
+
+	send := func(remoteAddr, apiKey string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		req.RemoteAddr = remoteAddr
+		if apiKey != "" {
+			req.Header.Set("X-API-Key", apiKey)
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	// The burst is served, the next request is throttled
+	for i := 0; i < 3; i++ {
+		assert.Equal(t, http.StatusOK, send("10.0.0.1:1234", "").Code)
+	}
+	w := send("10.0.0.1:1234", "")
+	assert.Equal(t, http.StatusTooManyRequests, w.Code)
+	assert.Equal(t, "1", w.Header().Get("Retry-After"))
+	assert.Contains(t, w.Body.String(), `"error":"Too many requests"`)
+
+	// Other IPs have their own bucket, but an unchecked API key doesn't
+	// buy a fresh one
+	assert.Equal(t, http.StatusOK, send("10.0.0.2:1234", "").Code)
+	assert.Equal(t, http.StatusTooManyRequests, send("10.0.0.1:1234", "made-up-key").Code)
+
+	// Neither does a spoofed X-Forwarded-For, nor another source port
+	for i := 0; i < 3; i++ {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		req.RemoteAddr = fmt.Sprintf("10.0.0.1:%d", 2000+i)
+		req.Header.Set("X-Forwarded-For", fmt.Sprintf("203.0.113.%d", i))
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusTooManyRequests, w.Code)
+	}
+
+	// Health checks are never limited
+	for i := 0; i < 5; i++ {
+		req := httptest.NewRequest(http.MethodGet, "/healthz", nil)
+		req.RemoteAddr = "10.0.0.1:1234"
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusOK, w.Code)
+	}
+}
+
+func TestRateLimit_DisabledWhenUnset(t *testing.T) {
+	router := setupTestRouterWithConfig(t, &config.Config{}, nil)
+
+	for i := 0; i < 50; i++ {
+		w := performRequest(router, http.MethodGet, "/api/v1/metrics/channel?from=2025-01-01&to=2025-01-31&channel=google_ads")
+		assert.Equal(t, http.StatusOK, w.Code)
+	}
+}
+
+func TestMaxRequestBytes(t *testing.T) {
+	router := setupTestRouterWithConfig(t, &config.Config{MaxRequestBytes: 64}, nil)
+	payload := `{"external": {"ads": {"performance": [{"date": "2025-01-01", "channel": "google_ads", "campaign_id": "C-1001"}]}}}`
+	require.Greater(t, len(payload), 64)
+
+	send := func(declareLength bool) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/ingest/data", strings.NewReader(payload))
+		if !declareLength {
+			// Chunked bodies are only caught while being read
+			req.ContentLength = -1
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	for _, declareLength := range []bool{true, false} {
+		w := send(declareLength)
+		assert.Equal(t, http.StatusRequestEntityTooLarge, w.Code, w.Body.String())
+		var body models.ErrorResponse
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+		assert.Equal(t, "Request body too large", body.Error)
+		assert.Contains(t, body.Message, "64 bytes")
+	}
+
+	// The same body fits under the default limit
+	router = setupTestRouterWithConfig(t, &config.Config{MaxRequestBytes: constants.DefaultMaxRequestBytes}, nil)
+	w := send(true)
+	assert.Equal(t, http.StatusOK, w.Code, w.Body.String())
+}
+
+func TestCompress(t *testing.T) {
+	rows := make([]models.TransformedData, 0, 50)
+	for day := 1; day <= 25; day++ {
+		date := fmt.Sprintf("2025-01-%02d", day)
+		rows = append(rows,
+			models.TransformedData{Date: date, Channel: "google_ads", CampaignID: "C-1001", Clicks: day},
+			models.TransformedData{Date: date, Channel: "google_ads", CampaignID: "C-1002", Clicks: day},
+		)
+	}
+	router := setupTestRouterWithConfig(t, &config.Config{CompressMinBytes: constants.DefaultCompressMinBytes}, rows)
+	path := "/api/v1/metrics/channel?from=2025-01-01&to=2025-01-31&channel=google_ads"
+
+	send := func(path, acceptEncoding string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		if acceptEncoding != "" {
+			req.Header.Set("Accept-Encoding", acceptEncoding)
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	gunzip := func(t *testing.T, w *httptest.ResponseRecorder) []byte {
+		reader, err := gzip.NewReader(w.Body)
+		require.NoError(t, err)
+		body, err := io.ReadAll(reader)
+		require.NoError(t, err)
+		return body
+	}
+
+	plain := send(path, "")
+	require.Equal(t, http.StatusOK, plain.Code)
+	assert.Empty(t, plain.Header().Get("Content-Encoding"))
+	require.Greater(t, plain.Body.Len(), constants.DefaultCompressMinBytes)
+
+	t.Run("large JSON is gzipped", func(t *testing.T) {
+		w := send(path, "gzip, deflate")
+		require.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, "gzip", w.Header().Get("Content-Encoding"))
+		assert.Contains(t, w.Header().Get("Vary"), "Accept-Encoding")
+		assert.Less(t, w.Body.Len(), plain.Body.Len())
+		assert.JSONEq(t, plain.Body.String(), string(gunzip(t, w)))
+	})
+
+	t.Run("CSV is gzipped", func(t *testing.T) {
+		expected := send(path+"&format=csv", "")
+		w := send(path+"&format=csv", "gzip")
+		require.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, "gzip", w.Header().Get("Content-Encoding"))
+		assert.Contains(t, w.Header().Get("Content-Type"), "text/csv")
+		assert.Equal(t, expected.Body.String(), string(gunzip(t, w)))
+	})
+
+	t.Run("small responses are left alone", func(t *testing.T) {
+		w := send(path+"&limit=1", "gzip")
+		require.Equal(t, http.StatusOK, w.Code)
+		assert.Empty(t, w.Header().Get("Content-Encoding"))
+		assert.True(t, json.Valid(w.Body.Bytes()))
+	})
+
+	t.Run("gzip refused by the client", func(t *testing.T) {
+		for _, header := range []string{"identity", "gzip;q=0", "br"} {
+			w := send(path, header)
+			assert.Empty(t, w.Header().Get("Content-Encoding"), header)
+			assert.Equal(t, plain.Body.String(), w.Body.String(), header)
+		}
+	})
+}
+
+func TestRequestID_PropagatesDownstream(t *testing.T) {
+	received := make(chan string, 4)
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received <- r.Header.Get("X-Request-ID")
+		w.Write([]byte(`{"external": {}}`))
+	}))
+	defer upstream.Close()
+
+	gin.SetMode(gin.TestMode)
+	logger := logrus.New()
+	logger.SetLevel(logrus.ErrorLevel)
+
+	cfg := &config.Config{AdsAPIURL: upstream.URL, CRMAPIURL: upstream.URL, HTTPTimeout: 5 * time.Second}
+	service := etl.NewService(cfg, storage.NewInMemoryStorage(), logger)
+
+	// Middleware only applies to routes registered after it, as in main
+	router := gin.New()
+	router.Use(RequestID())
+	SetupRoutes(router, NewHandlers(service, logger), cfg)
+
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/ingest/run?full=true", nil)
+	req.Header.Set("X-Request-ID", "req-abc")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.Equal(t, "req-abc", w.Header().Get("X-Request-ID"))
+	assert.Equal(t, "req-abc", <-received)
+	assert.Equal(t, "req-abc", <-received)
+}
+
+func TestRecovery(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	var logs bytes.Buffer
+	logger := logrus.New()
+	logger.SetOutput(&logs)
+	logger.SetFormatter(&logrus.JSONFormatter{})
+
+	router := gin.New()
+	router.Use(Recovery(logger))
+	router.GET("/boom", func(c *gin.Context) {
+		panic("something broke")
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/boom", nil)
+	req.Header.Set("X-Request-ID", "req-123")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	require.Equal(t, http.StatusInternalServerError, w.Code)
+	assert.Contains(t, w.Header().Get("Content-Type"), "application/json")
+
+	var body models.ErrorResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+	assert.Equal(t, "Internal server error", body.Error)
+	assert.NotEmpty(t, body.Message)
+
+	var entry map[string]interface{}
+	require.NoError(t, json.Unmarshal(logs.Bytes(), &entry))
+	assert.Equal(t, "Recovered from panic", entry["msg"])
+	assert.Equal(t, "something broke", entry["panic"])
+	assert.Equal(t, "req-123", entry["request_id"])
+	assert.Contains(t, entry["stack"], "TestRecovery")
+}
+
+func TestRequestTimeout(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	logger := logrus.New()
+	logger.SetLevel(logrus.FatalLevel)
+
+	cancelled := make(chan struct{})
+	router := gin.New()
+	router.Use(Recovery(logger))
+	router.Use(RequestTimeout(50*time.Millisecond, logger))
+	router.GET("/slow", func(c *gin.Context) {
+		// A handler honouring its context stops at the deadline; its own
+		// response is dropped in favour of the timeout
+		select {
+		case <-c.Request.Context().Done():
+			close(cancelled)
+		case <-time.After(5 * time.Second):
+		}
+		c.Header("X-Slow", "yes")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "too late"})
+	})
+	router.GET("/stubborn", func(c *gin.Context) {
+		time.Sleep(150 * time.Millisecond)
+		c.JSON(http.StatusOK, gin.H{"status": "done"})
+	})
+	router.GET("/fast", func(c *gin.Context) {
+		c.Header("X-Fast", "yes")
+		c.JSON(http.StatusCreated, gin.H{"status": "ok"})
+	})
+	router.GET("/empty", func(c *gin.Context) {
+		c.Status(http.StatusNoContent)
+	})
+	router.GET("/boom", func(c *gin.Context) {
+		panic("something broke")
+	})
+
+	send := func(path string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+		return w
+	}
+
+	for _, path := range []string{"/slow", "/stubborn"} {
+		t.Run(path, func(t *testing.T) {
+			w := send(path)
+			require.Equal(t, http.StatusServiceUnavailable, w.Code)
+			assert.Contains(t, w.Header().Get("Content-Type"), "application/json")
+			assert.Empty(t, w.Header().Get("X-Slow"))
+
+			var body models.ErrorResponse
+			require.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+			assert.Equal(t, "Request timeout", body.Error)
+		})
+	}
+
+	select {
+	case <-cancelled:
+	default:
+		t.Fatal("handler context was not cancelled")
+	}
+
+	t.Run("fast requests are untouched", func(t *testing.T) {
+		w := send("/fast")
+		require.Equal(t, http.StatusCreated, w.Code)
+		assert.Equal(t, "yes", w.Header().Get("X-Fast"))
+		assert.JSONEq(t, `{"status":"ok"}`, w.Body.String())
+
+		w = send("/empty")
+		assert.Equal(t, http.StatusNoContent, w.Code)
+		assert.Empty(t, w.Body.String())
+	})
+
+	t.Run("panics still reach Recovery", func(t *testing.T) {
+		w := send("/boom")
+		require.Equal(t, http.StatusInternalServerError, w.Code)
+		assert.Contains(t, w.Body.String(), "Internal server error")
+	})
+}
+
+func TestDebugStats(t *testing.T) {
+	router := setupTestRouterWithConfig(t, &config.Config{APIKey: "s3cret"}, nil)
+
+	w := performRequest(router, http.MethodGet, "/debug/stats")
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+
+	req := httptest.NewRequest(http.MethodGet, "/debug/stats", nil)
+	req.Header.Set("X-API-Key", "s3cret")
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	require.Equal(t, http.StatusOK, w.Code)
+
+	var stats models.RuntimeStats
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &stats))
+	assert.Positive(t, stats.Goroutines)
+	assert.Positive(t, stats.HeapAlloc)
+	assert.GreaterOrEqual(t, stats.HeapSys, stats.HeapAlloc)
+	assert.Positive(t, stats.HeapObjects)
+	assert.GreaterOrEqual(t, stats.TotalAlloc, stats.HeapAlloc)
+}
@@ -0,0 +1,58 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+
+	"admira-etl/internal/etl"
+	"admira-etl/internal/models"
+	"admira-etl/internal/storage"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	namespaceHeader = "X-Namespace"
+
+	// namespaceServiceKey holds the service selected by SelectNamespace
+	// in the gin context.
+	namespaceServiceKey = "etl_service"
+)
+
+// SelectNamespace routes the request to the storage namespace named by the
+// X-Namespace header, so a test ingestion and the queries checking it can
+// run against their own data. Without the header the configured namespace
+// is used. Invalid or unavailable namespaces are rejected with a 400.
+func (h *Handlers) SelectNamespace(c *gin.Context) {
+	name := strings.TrimSpace(c.GetHeader(namespaceHeader))
+	if name == "" {
+		c.Next()
+		return
+	}
+
+	service, err := h.etlService.Namespace(name)
+	if err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, storage.ErrInvalidNamespace) || errors.Is(err, etl.ErrNamespacesDisabled) || errors.Is(err, etl.ErrNamespaceNotAllowed) {
+			status = http.StatusBadRequest
+		}
+		h.logger.WithError(err).WithField("namespace", name).Error("Failed to select namespace")
+		c.AbortWithStatusJSON(status, models.ErrorResponse{
+			Error:   "Invalid namespace",
+			Message: err.Error(),
+		})
+		return
+	}
+
+	c.Set(namespaceServiceKey, service)
+	c.Next()
+}
+
+// service returns the ETL service for the request's namespace.
+func (h *Handlers) service(c *gin.Context) *etl.Service {
+	if service, ok := c.Get(namespaceServiceKey); ok {
+		return service.(*etl.Service)
+	}
+	return h.etlService
+}
@@ -0,0 +1,20 @@
+package api
+
+import (
+	_ "embed"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// openAPISpec is the OpenAPI 3 description of the public endpoints. It is
+// maintained by hand alongside the handlers and the structs in
+// internal/models, so changes to either should be mirrored here.
+//
+//go:embed openapi.json
+var openAPISpec []byte
+
+// OpenAPISpec serves the embedded OpenAPI document.
+func OpenAPISpec(c *gin.Context) {
+	c.Data(http.StatusOK, "application/json; charset=utf-8", openAPISpec)
+}
@@ -0,0 +1,166 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+	"testing"
+
+	"admira-etl/internal/etl"
+	"admira-etl/internal/jobs"
+	"admira-etl/internal/models"
+	"admira-etl/internal/storage"
+	"admira-etl/internal/version"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type openAPIDocument struct {
+	OpenAPI string `json:"openapi"`
+	Info    struct {
+		Title   string `json:"title"`
+		Version string `json:"version"`
+	} `json:"info"`
+	Paths      map[string]map[string]json.RawMessage `json:"paths"`
+	Components struct {
+		Schemas map[string]struct {
+			Properties map[string]json.RawMessage `json:"properties"`
+		} `json:"schemas"`
+		Responses map[string]json.RawMessage `json:"responses"`
+	} `json:"components"`
+}
+
+var pathParam = regexp.MustCompile(`\{(\w+)\}`)
+
+func TestOpenAPISpec(t *testing.T) {
+	router := setupTestRouter(t, nil)
+
+	w := performRequest(router, "GET", "/openapi.json")
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Header().Get("Content-Type"), "application/json")
+
+	var doc openAPIDocument
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &doc))
+	assert.True(t, strings.HasPrefix(doc.OpenAPI, "3."), "unexpected openapi version %q", doc.OpenAPI)
+	assert.NotEmpty(t, doc.Info.Title)
+	assert.NotEmpty(t, doc.Info.Version)
+
+	// Every documented operation is served by the router and has responses
+	registered := make(map[string]bool)
+	for _, route := range router.Routes() {
+		registered[route.Method+" "+route.Path] = true
+	}
+	for path, operations := range doc.Paths {
+		// OpenAPI writes path parameters as {id}, gin as :id
+		routed := pathParam.ReplaceAllString(path, ":$1")
+		for method, raw := range operations {
+			assert.True(t, registered[strings.ToUpper(method)+" "+routed], "%s %s is documented but not routed", method, path)
+
+			var operation struct {
+				Responses map[string]json.RawMessage `json:"responses"`
+			}
+			require.NoError(t, json.Unmarshal(raw, &operation))
+			assert.NotEmpty(t, operation.Responses, "%s %s has no responses", method, path)
+		}
+	}
+	for _, route := range []string{
+		"POST /api/v1/ingest/run", "POST /api/v1/ingest/data",
+		"GET /api/v1/metrics/channel", "GET /api/v1/metrics/funnel",
+		"POST /api/v1/export/run", "GET /healthz",
+	} {
+		parts := strings.SplitN(route, " ", 2)
+		assert.Contains(t, doc.Paths[parts[1]], strings.ToLower(parts[0]), "%s is not documented", route)
+	}
+
+	// Every reference points at a defined component
+	for _, ref := range collectRefs(w.Body.Bytes()) {
+		switch {
+		case strings.HasPrefix(ref, "#/components/schemas/"):
+			assert.Contains(t, doc.Components.Schemas, strings.TrimPrefix(ref, "#/components/schemas/"))
+		case strings.HasPrefix(ref, "#/components/responses/"):
+			assert.Contains(t, doc.Components.Responses, strings.TrimPrefix(ref, "#/components/responses/"))
+		default:
+			t.Errorf("unexpected reference %q", ref)
+		}
+	}
+
+	// Schemas list exactly the JSON fields of the models they describe
+	for name, model := range map[string]interface{}{
+		"ErrorResponse":     models.ErrorResponse{},
+		"HealthResponse":    models.HealthResponse{},
+		"HealthDetail":      models.HealthDetail{},
+		"AdsPerformance":    models.AdsPerformance{},
+		"Opportunity":       models.Opportunity{},
+		"TransformedData":   models.TransformedData{},
+		"TransformedDataV2": models.TransformedDataV2{},
+		"Ratios":            models.Ratios{},
+		"Dimensions":        models.Dimensions{},
+		"TimeSeries":        models.TimeSeries{},
+		"MetricsSummary":    models.MetricsSummary{},
+		"MetricsComparison": models.MetricsComparison{},
+		"MetricsDeltas":     models.MetricsDeltas{},
+		"IngestionStatus":   models.IngestionStatus{},
+		"Freshness":         models.Freshness{},
+		"ValidationReport":  etl.ValidationReport{},
+		"ExportJobResult":   etl.ExportJobResult{},
+		"Job":               jobs.Job{},
+		"VersionInfo":       version.Info{},
+		"RuntimeStats":      models.RuntimeStats{},
+		"RejectedRecord":    etl.RejectedRecord{},
+		"Snapshot":          storage.Snapshot{},
+	} {
+		var documented []string
+		for property := range doc.Components.Schemas[name].Properties {
+			documented = append(documented, property)
+		}
+		sort.Strings(documented)
+		assert.Equal(t, jsonFields(model), documented, "schema %s", name)
+	}
+}
+
+// collectRefs returns every $ref value in a JSON document.
+func collectRefs(raw []byte) []string {
+	var root interface{}
+	if err := json.Unmarshal(raw, &root); err != nil {
+		return nil
+	}
+
+	var refs []string
+	var walk func(v interface{})
+	walk = func(v interface{}) {
+		switch node := v.(type) {
+		case map[string]interface{}:
+			for key, child := range node {
+				if ref, ok := child.(string); ok && key == "$ref" {
+					refs = append(refs, ref)
+					continue
+				}
+				walk(child)
+			}
+		case []interface{}:
+			for _, child := range node {
+				walk(child)
+			}
+		}
+	}
+	walk(root)
+	return refs
+}
+
+// jsonFields returns the sorted JSON names of a struct's fields.
+func jsonFields(model interface{}) []string {
+	typ := reflect.TypeOf(model)
+	var fields []string
+	for i := 0; i < typ.NumField(); i++ {
+		name := strings.Split(typ.Field(i).Tag.Get("json"), ",")[0]
+		if name != "" && name != "-" {
+			fields = append(fields, name)
+		}
+	}
+	sort.Strings(fields)
+	return fields
+}
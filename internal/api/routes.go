@@ -1,26 +1,98 @@
 package api
 
 import (
+	"fmt"
+	"net/http"
+
+	"admira-etl/internal/config"
+	"admira-etl/internal/models"
+	"admira-etl/internal/telemetry"
+
 	"github.com/gin-gonic/gin"
 )
 
-func SetupRoutes(router *gin.Engine, handlers *Handlers) {
+func SetupRoutes(router *gin.Engine, handlers *Handlers, cfg *config.Config) {
+	router.Use(RequestMetrics())
+
+	// Prometheus metrics
+	router.GET("/metrics", gin.WrapH(telemetry.Handler()))
+
 	// Health check endpoints
 	router.GET("/healthz", handlers.HealthCheck)
 	router.GET("/readyz", handlers.ReadinessCheck)
+	router.GET("/version", handlers.Version)
+
+	// Runtime stats share the API key with v1
+	debug := router.Group("/debug")
+	debug.Use(APIKeyAuth(cfg.APIKey))
+	debug.GET("/stats", handlers.DebugStats)
+
+	// API description
+	router.GET("/openapi.json", OpenAPISpec)
+
+	// Unknown paths and methods get the same JSON errors as everything else
+	router.HandleMethodNotAllowed = true
+	router.NoRoute(NotFound)
+	router.NoMethod(MethodNotAllowed)
 
 	// API v1 routes
 	v1 := router.Group("/api/v1")
+	v1.Use(RateLimit(cfg.RateLimitRPS, cfg.RateLimitBurst))
+	v1.Use(APIKeyAuth(cfg.APIKey))
+	v1.Use(MaxRequestBytes(int64(cfg.MaxRequestBytes)))
+	v1.Use(Compress(cfg.CompressMinBytes))
+	v1.Use(handlers.SelectNamespace)
 	{
 		// Ingestion endpoints
 		v1.POST("/ingest/run", handlers.RunIngestion)
+		v1.POST("/ingest/date", handlers.ReingestDate)
+		v1.POST("/ingest/data", handlers.IngestData)
+		v1.POST("/ingest/webhook", handlers.IngestWebhook)
+		v1.GET("/ingest/status", handlers.GetIngestionStatus)
+
+		// Background job status
+		v1.GET("/jobs/:id", handlers.GetJob)
 
 		// Metrics endpoints
 		v1.GET("/metrics/channel", handlers.GetChannelMetrics)
 		v1.GET("/metrics/funnel", handlers.GetFunnelMetrics)
+		v1.GET("/metrics/source", handlers.GetSourceMetrics)
+		v1.GET("/metrics/campaign", handlers.GetCampaignMetrics)
+		v1.GET("/metrics/timeseries", handlers.GetTimeSeries)
+		v1.GET("/metrics/summary", handlers.GetMetricsSummary)
+		v1.GET("/metrics/top", handlers.GetTopCampaigns)
+		v1.GET("/metrics/compare", handlers.CompareMetrics)
+
+		// Distinct channels and campaigns, for filter dropdowns
+		v1.GET("/dimensions", handlers.GetDimensions)
+
+		// Stored data management
+		v1.DELETE("/data", handlers.DeleteData)
+
+		// Backup and restore
+		v1.GET("/admin/snapshot", handlers.GetSnapshot)
+		v1.POST("/admin/snapshot", handlers.RestoreSnapshot)
 
 		// Export endpoints
 		v1.POST("/export/run", handlers.ExportData)
+		v1.POST("/export/retry", handlers.RetryDeadLetters)
+		v1.GET("/export/deadletter", handlers.GetDeadLetters)
 	}
 }
 
+// NotFound answers requests for unregistered paths.
+func NotFound(c *gin.Context) {
+	c.JSON(http.StatusNotFound, models.ErrorResponse{
+		Error:   "Not found",
+		Message: fmt.Sprintf("No route for %s %s", c.Request.Method, c.Request.URL.Path),
+	})
+}
+
+// MethodNotAllowed answers requests for a registered path with a method it
+// doesn't support.
+func MethodNotAllowed(c *gin.Context) {
+	c.JSON(http.StatusMethodNotAllowed, models.ErrorResponse{
+		Error:   "Method not allowed",
+		Message: fmt.Sprintf("%s is not supported for %s", c.Request.Method, c.Request.URL.Path),
+	})
+}
@@ -0,0 +1,60 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"admira-etl/internal/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// responseVersion selects the shape of the rows returned by the metrics
+// endpoints, so clients can pin a shape while the models evolve.
+type responseVersion int
+
+const (
+	// responseV1 is the original flat row, ratios alongside the counters.
+	responseV1 responseVersion = 1
+	// responseV2 nests the derived ratios under "ratios".
+	responseV2 responseVersion = 2
+
+	acceptVersionHeader = "Accept-Version"
+)
+
+// negotiateVersion reads the requested response version from ?v= or, failing
+// that, the Accept-Version header. Both accept "1"/"v1" and "2"/"v2"; no
+// version means v1. On an unknown version it writes a 400 and returns false.
+func negotiateVersion(c *gin.Context) (responseVersion, bool) {
+	value := c.Query("v")
+	if value == "" {
+		value = c.GetHeader(acceptVersionHeader)
+	}
+
+	switch strings.TrimPrefix(strings.ToLower(strings.TrimSpace(value)), "v") {
+	case "", "1":
+		return responseV1, true
+	case "2":
+		return responseV2, true
+	}
+
+	c.JSON(http.StatusBadRequest, models.ErrorResponse{
+		Error:   "Invalid request parameters",
+		Message: fmt.Sprintf("unsupported response version %q, expected 1 or 2", value),
+	})
+	return 0, false
+}
+
+// shapeRows returns rows in the shape of the given response version.
+func shapeRows(version responseVersion, data []models.TransformedData) interface{} {
+	if version != responseV2 {
+		return data
+	}
+
+	shaped := make([]models.TransformedDataV2, len(data))
+	for i, item := range data {
+		shaped[i] = item.V2()
+	}
+	return shaped
+}
@@ -1,36 +1,391 @@
 package config
 
 import (
+	"errors"
+	"fmt"
+	"io"
 	"os"
+	"strconv"
+	"strings"
 	"time"
 
 	"admira-etl/internal/constants"
+
+	"gopkg.in/yaml.v3"
 )
 
 type Config struct {
-	AdsAPIURL   string
-	CRMAPIURL   string
-	SinkURL     string
-	SinkSecret  string
-	Port        string
-	LogLevel    string
-	HTTPTimeout time.Duration
-	MaxRetries  int
-	RetryDelay  time.Duration
-}
-
-func Load() *Config {
-	return &Config{
-		AdsAPIURL:   getEnv("ADS_API_URL", ""),
-		CRMAPIURL:   getEnv("CRM_API_URL", ""),
-		SinkURL:     getEnv("SINK_URL", ""),
-		SinkSecret:  getEnv("SINK_SECRET", ""),
-		Port:        getEnv("PORT", constants.DefaultPort),
-		LogLevel:    getEnv("LOG_LEVEL", constants.DefaultLogLevel),
+	AdsAPIURL   string        `yaml:"ads_api_url"`
+	CRMAPIURL   string        `yaml:"crm_api_url"`
+	SinkURL     string        `yaml:"sink_url"`
+	SinkSecret  string        `yaml:"sink_secret"`
+	Port        string        `yaml:"port"`
+	LogLevel    string        `yaml:"log_level"`
+	APIKey      string        `yaml:"api_key"`
+	HTTPTimeout time.Duration `yaml:"http_timeout"`
+	MaxRetries  int           `yaml:"max_retries"`
+	RetryDelay  time.Duration `yaml:"retry_delay"`
+
+	// AdsAPIHeaders and CRMAPIHeaders are sent with every request to that
+	// upstream, e.g. an Authorization or X-API-Key header. Their values
+	// are masked wherever they are logged.
+	AdsAPIHeaders map[string]string `yaml:"ads_api_headers"`
+	CRMAPIHeaders map[string]string `yaml:"crm_api_headers"`
+
+	// SinkTimeout bounds each export request to a sink, separately from
+	// HTTPTimeout, which applies to the Ads and CRM fetches.
+	SinkTimeout time.Duration `yaml:"sink_timeout"`
+
+	// RetryBackoff selects how the delay between retries grows from
+	// RetryDelay: "linear" (1x, 2x, 3x...) or "exponential" (1x, 2x, 4x...).
+	// MaxRetryDelay caps a single delay; 0 leaves it uncapped.
+	RetryBackoff  string        `yaml:"retry_backoff"`
+	MaxRetryDelay time.Duration `yaml:"max_retry_delay"`
+
+	// MaxRetryDuration bounds the time one outbound call spends on attempts
+	// and backoff; 0 leaves it bounded by MaxRetries and HTTPTimeout only.
+	MaxRetryDuration time.Duration `yaml:"max_retry_duration"`
+
+	// LogSampleRate caps how many times one noisy warning (an unparseable
+	// ads date, a retried request) is logged per ingestion or export; the
+	// rest are summed up in a single summary line. 0 logs every one.
+	LogSampleRate int `yaml:"log_sample_rate"`
+
+	// LogRedact masks LogRedactFields (by default revenue figures and
+	// signatures) wherever they appear in log fields, including inside
+	// logged records.
+	LogRedact       bool     `yaml:"log_redact"`
+	LogRedactFields []string `yaml:"log_redact_fields"`
+
+	// RetryableNetworkErrors lists the transport failures worth retrying
+	// (timeout, connection_refused, connection_reset, dns_temporary,
+	// dns_not_found, other); empty keeps the client's transient defaults.
+	RetryableNetworkErrors []string `yaml:"retryable_network_errors"`
+
+	// ProxyURL sends calls to the Ads/CRM APIs and sinks through this proxy,
+	// overriding HTTP_PROXY, HTTPS_PROXY and NO_PROXY, which apply otherwise.
+	ProxyURL string `yaml:"proxy_url"`
+
+	// ReadinessTimeout bounds each dependency probe made by /readyz.
+	ReadinessTimeout time.Duration `yaml:"readiness_timeout"`
+
+	// ReadinessFailureThreshold is how many probes in a row must fail before
+	// /readyz reports unready, and ReadinessSuccessThreshold how many must
+	// pass before it reports ready again, so a blip doesn't flap the pod.
+	ReadinessFailureThreshold int `yaml:"readiness_failure_threshold"`
+	ReadinessSuccessThreshold int `yaml:"readiness_success_threshold"`
+
+	// ShutdownTimeout bounds how long a SIGINT/SIGTERM waits for in-flight
+	// ETL work and open requests before the process exits.
+	ShutdownTimeout time.Duration `yaml:"shutdown_timeout"`
+
+	// RequestTimeout bounds how long one API request may run before it
+	// is cancelled and answered with a 503; 0 lets requests run unbounded.
+	RequestTimeout time.Duration `yaml:"request_timeout"`
+
+	// RateLimitRPS and RateLimitBurst size the per-IP token bucket on
+	// /api/v1; an RPS of 0 disables rate limiting.
+	RateLimitRPS   float64 `yaml:"rate_limit_rps"`
+	RateLimitBurst int     `yaml:"rate_limit_burst"`
+
+	// MaxRequestBytes caps request bodies on /api/v1; larger ones get a 413.
+	// 0 disables the cap.
+	MaxRequestBytes int `yaml:"max_request_bytes"`
+
+	// CompressMinBytes is the smallest /api/v1 response body gzipped for
+	// clients that accept it; 0 compresses every response.
+	CompressMinBytes int `yaml:"compress_min_bytes"`
+
+	// MatchStrategy selects how far UTM matching falls back when there is no
+	// exact match: "exact", "campaign_fallback" or "full".
+	MatchStrategy string `yaml:"match_strategy"`
+
+	// FuzzyUTMMatch also treats "-", "_" and whitespace as one separator and
+	// ignores surrounding punctuation when matching UTMs, so
+	// "back-to-school" matches "back_to_school".
+	FuzzyUTMMatch bool `yaml:"fuzzy_utm_match"`
+
+	// AttributionModel selects how an opportunity matched by several ad
+	// rows is credited: "full", "first_touch", "last_touch" or "linear".
+	AttributionModel string `yaml:"attribution_model"`
+
+	// LeadSource selects where lead counts come from: "estimate" (10% of
+	// clicks) or "crm" (matched opportunities in the lead stage).
+	LeadSource string `yaml:"lead_source"`
+
+	// LeadRates overrides the share of clicks estimated to become leads
+	// (10% by default) per channel, e.g. google_ads: 0.12.
+	LeadRates map[string]float64 `yaml:"lead_rates"`
+
+	// MetricPrecision is how many decimals derived metrics (CPC, CPA, the
+	// conversion rates, ROAS, CTR and CPM) are rounded to when rows are
+	// transformed, at most the 4 JSON responses carry. 0, the default, keeps
+	// full precision.
+	MetricPrecision int `yaml:"metric_precision"`
+
+	// BaseCurrency is the currency revenue and ROAS are reported in;
+	// opportunities without a currency are assumed to be in it.
+	BaseCurrency string `yaml:"base_currency"`
+
+	// CurrencyRates converts other currencies to BaseCurrency, as base units
+	// per unit of each currency (e.g. EUR: 1.08 with a USD base).
+	CurrencyRates map[string]float64 `yaml:"currency_rates"`
+
+	// UnknownCurrency selects what happens to opportunities in a currency
+	// missing from CurrencyRates: "pass_through" counts the amount as is,
+	// "skip" leaves the opportunity out.
+	UnknownCurrency string `yaml:"unknown_currency"`
+
+	// StageWeights maps opportunity stages to the share of their amount
+	// counted as weighted pipeline revenue (e.g. proposal: 0.5). closed_won
+	// counts fully unless overridden; unlisted stages count nothing.
+	StageWeights map[string]float64 `yaml:"stage_weights"`
+
+	// AnomalyBounds caps plausible values of derived metrics (cpc, cpa,
+	// cvr_lead_to_opp, cvr_opp_to_won, roas, ctr, cpm); transformed rows
+	// exceeding one are flagged. Empty disables the check.
+	AnomalyBounds map[string]float64 `yaml:"anomaly_bounds"`
+
+	// ValidationMode selects what happens to fetched records that fail
+	// validation: "skip_invalid" drops them, "reject_all" fails the run.
+	ValidationMode string `yaml:"validation_mode"`
+
+	// NegativeValues selects what happens to negative clicks, impressions,
+	// cost and amounts: "reject" treats them as invalid, "clamp" zeroes them.
+	NegativeValues string `yaml:"negative_values"`
+
+	// PartialIngest lets an ingestion carry on without CRM data when the CRM
+	// API can't be fetched, storing the ads rows with zeroed funnel
+	// metrics. By default a CRM failure fails the run.
+	PartialIngest bool `yaml:"partial_ingest"`
+
+	// DefaultRangeDays is how far back a metrics query without a from date
+	// reaches; to defaults to today.
+	DefaultRangeDays int `yaml:"default_range_days"`
+
+	// StaleAfter is how old the last ingestion can get before metrics
+	// responses flag their data as stale.
+	StaleAfter time.Duration `yaml:"stale_after"`
+
+	// TransformConcurrency caps the workers used to transform ads rows; 0
+	// uses one per CPU and 1 keeps the transform sequential.
+	TransformConcurrency int `yaml:"transform_concurrency"`
+
+	StorageBackend  string `yaml:"storage_backend"`
+	StorageFilePath string `yaml:"storage_file_path"`
+
+	// RedisURL is the server the redis backend connects to, e.g.
+	// redis://localhost:6379/0.
+	RedisURL string `yaml:"redis_url"`
+
+	// DataRetentionDays evicts stored rows dated more than this many days
+	// ago whenever new data is stored; 0 keeps everything.
+	DataRetentionDays int `yaml:"data_retention_days"`
+
+	// Namespace is the storage namespace used by requests without an
+	// X-Namespace header and by scheduled ingestion; empty means the
+	// default namespace.
+	Namespace string `yaml:"namespace"`
+
+	// Namespaces lists the other namespaces requests may select with
+	// X-Namespace. Anything else is rejected, so clients can't make the
+	// service open a store for every name they send.
+	Namespaces []string `yaml:"namespaces"`
+
+	// IngestSchedule is a cron expression for automatic incremental
+	// ingestion; empty disables the scheduler.
+	IngestSchedule string `yaml:"ingest_schedule"`
+
+	// SinkURLs lists every export destination. When empty, SinkURL is used as
+	// the single sink.
+	SinkURLs []string `yaml:"sink_urls"`
+
+	// SinkType selects where exports go: "http" POSTs to the sink URLs,
+	// "kafka" produces to KafkaTopic on KafkaBrokers and "file" appends
+	// NDJSON to SinkFilePath.
+	SinkType     string   `yaml:"sink_type"`
+	KafkaBrokers []string `yaml:"kafka_brokers"`
+	KafkaTopic   string   `yaml:"kafka_topic"`
+
+	// SinkFilePath is the file sink's output. It is rotated once it would
+	// grow past SinkFileMaxBytes; 0 never rotates.
+	SinkFilePath     string `yaml:"sink_file_path"`
+	SinkFileMaxBytes int    `yaml:"sink_file_max_bytes"`
+
+	// SinkBulk sends each sink every consolidated record of an export in
+	// one POST, as a JSON array signed as a batch, instead of one POST per
+	// record.
+	SinkBulk bool `yaml:"sink_bulk"`
+
+	// envErrors holds the environment variables Load couldn't parse, which
+	// Validate reports.
+	envErrors []error
+}
+
+// Sinks returns the export destinations: SinkURLs when set, otherwise
+// SinkURL on its own, or nil when no sink is configured.
+func (c *Config) Sinks() []string {
+	if len(c.SinkURLs) > 0 {
+		return c.SinkURLs
+	}
+	if c.SinkURL != "" {
+		return []string{c.SinkURL}
+	}
+	return nil
+}
+
+// Load builds the configuration from defaults, then the YAML file named by
+// CONFIG_FILE (if any), then environment variables, each layer overriding
+// the one before.
+func Load() (*Config, error) {
+	cfg := &Config{
+		Port:        constants.DefaultPort,
+		LogLevel:    constants.DefaultLogLevel,
 		HTTPTimeout: constants.DefaultHTTPTimeout * time.Second,
+		SinkTimeout: constants.DefaultSinkTimeout * time.Second,
+		SinkType:    constants.DefaultSinkType,
 		MaxRetries:  constants.DefaultMaxRetries,
 		RetryDelay:  constants.DefaultRetryDelay * time.Second,
+
+		RetryBackoff:  constants.DefaultRetryBackoff,
+		MaxRetryDelay: constants.DefaultMaxRetryDelay * time.Second,
+
+		SinkFileMaxBytes: constants.DefaultSinkFileMaxBytes,
+
+		LogSampleRate: constants.DefaultLogSampleRate,
+
+		ReadinessTimeout: constants.DefaultReadinessTimeout * time.Second,
+		ShutdownTimeout:  constants.DefaultShutdownTimeout * time.Second,
+
+		ReadinessFailureThreshold: constants.DefaultReadinessFailureThreshold,
+		ReadinessSuccessThreshold: constants.DefaultReadinessSuccessThreshold,
+
+		MetricPrecision: constants.DefaultMetricPrecision,
+
+		DefaultRangeDays: constants.DefaultRangeDays,
+		StaleAfter:       constants.DefaultStaleAfter * time.Hour,
+
+		RateLimitRPS:   constants.DefaultRateLimitRPS,
+		RateLimitBurst: constants.DefaultRateLimitBurst,
+
+		MaxRequestBytes:  constants.DefaultMaxRequestBytes,
+		CompressMinBytes: constants.DefaultCompressMinBytes,
+
+		MatchStrategy: constants.DefaultMatchStrategy,
+
+		AttributionModel: constants.DefaultAttributionModel,
+
+		LeadSource: constants.DefaultLeadSource,
+
+		ValidationMode: constants.DefaultValidationMode,
+		NegativeValues: constants.DefaultNegativeValues,
+
+		BaseCurrency:    constants.DefaultBaseCurrency,
+		UnknownCurrency: constants.UnknownCurrencyPassThrough,
+
+		StorageBackend:  constants.StorageBackendMemory,
+		StorageFilePath: constants.DefaultStorageFilePath,
 	}
+
+	if path := os.Getenv("CONFIG_FILE"); path != "" {
+		if err := cfg.loadFile(path); err != nil {
+			return nil, err
+		}
+	}
+
+	cfg.applyEnv()
+	return cfg, nil
+}
+
+// loadFile overlays the values set in a YAML file. Unknown keys are
+// rejected so typos don't silently fall back to defaults.
+func (c *Config) loadFile(path string) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("failed to open config file: %w", err)
+	}
+	defer file.Close()
+
+	decoder := yaml.NewDecoder(file)
+	decoder.KnownFields(true)
+	if err := decoder.Decode(c); err != nil && !errors.Is(err, io.EOF) {
+		return fmt.Errorf("failed to parse config file %s: %w", path, err)
+	}
+	return nil
+}
+
+// applyEnv overrides fields with any environment variables that are set.
+func (c *Config) applyEnv() {
+	c.AdsAPIURL = getEnv("ADS_API_URL", c.AdsAPIURL)
+	c.CRMAPIURL = getEnv("CRM_API_URL", c.CRMAPIURL)
+	c.AdsAPIHeaders = getEnvHeaders("ADS_API_HEADERS", c.AdsAPIHeaders)
+	c.CRMAPIHeaders = getEnvHeaders("CRM_API_HEADERS", c.CRMAPIHeaders)
+	c.SinkURL = getEnv("SINK_URL", c.SinkURL)
+	c.SinkSecret = getEnv("SINK_SECRET", c.SinkSecret)
+	c.SinkTimeout = c.getEnvDuration("SINK_TIMEOUT", c.SinkTimeout)
+	c.Port = getEnv("PORT", c.Port)
+	c.LogLevel = getEnv("LOG_LEVEL", c.LogLevel)
+	c.LogSampleRate = c.getEnvInt("LOG_SAMPLE_RATE", c.LogSampleRate)
+	c.LogRedact = c.getEnvBool("LOG_REDACT", c.LogRedact)
+	if fields := getEnvList("LOG_REDACT_FIELDS"); fields != nil {
+		c.LogRedactFields = fields
+	}
+	c.APIKey = getEnv("API_KEY", c.APIKey)
+	c.ShutdownTimeout = c.getEnvDuration("SHUTDOWN_TIMEOUT", c.ShutdownTimeout)
+	c.RequestTimeout = c.getEnvDuration("REQUEST_TIMEOUT", c.RequestTimeout)
+	c.ReadinessFailureThreshold = c.getEnvInt("READINESS_FAILURE_THRESHOLD", c.ReadinessFailureThreshold)
+	c.ReadinessSuccessThreshold = c.getEnvInt("READINESS_SUCCESS_THRESHOLD", c.ReadinessSuccessThreshold)
+	c.ProxyURL = getEnv("PROXY_URL", c.ProxyURL)
+
+	c.RateLimitRPS = c.getEnvFloat("RATE_LIMIT_RPS", c.RateLimitRPS)
+	c.RateLimitBurst = c.getEnvInt("RATE_LIMIT_BURST", c.RateLimitBurst)
+	c.MaxRequestBytes = c.getEnvInt("MAX_REQUEST_BYTES", c.MaxRequestBytes)
+	c.CompressMinBytes = c.getEnvInt("COMPRESS_MIN_BYTES", c.CompressMinBytes)
+
+	c.MatchStrategy = getEnv("MATCH_STRATEGY", c.MatchStrategy)
+	c.FuzzyUTMMatch = c.getEnvBool("FUZZY_UTM_MATCH", c.FuzzyUTMMatch)
+	c.AttributionModel = getEnv("ATTRIBUTION_MODEL", c.AttributionModel)
+	c.LeadSource = getEnv("LEAD_SOURCE", c.LeadSource)
+	c.LeadRates = getEnvRates("LEAD_RATES", ":", c.LeadRates)
+	c.MetricPrecision = c.getEnvInt("METRIC_PRECISION", c.MetricPrecision)
+	c.ValidationMode = getEnv("VALIDATION_MODE", c.ValidationMode)
+	c.NegativeValues = getEnv("NEGATIVE_VALUES", c.NegativeValues)
+	c.PartialIngest = c.getEnvBool("PARTIAL_INGEST", c.PartialIngest)
+	c.BaseCurrency = getEnv("BASE_CURRENCY", c.BaseCurrency)
+	c.CurrencyRates = getEnvRates("CURRENCY_RATES", "=", c.CurrencyRates)
+	c.StageWeights = getEnvRates("STAGE_WEIGHTS", "=", c.StageWeights)
+	c.AnomalyBounds = getEnvRates("ANOMALY_BOUNDS", "=", c.AnomalyBounds)
+	c.UnknownCurrency = getEnv("UNKNOWN_CURRENCY", c.UnknownCurrency)
+	c.DefaultRangeDays = c.getEnvInt("DEFAULT_RANGE_DAYS", c.DefaultRangeDays)
+	c.StaleAfter = c.getEnvDuration("STALE_AFTER", c.StaleAfter)
+	c.TransformConcurrency = c.getEnvInt("TRANSFORM_CONCURRENCY", c.TransformConcurrency)
+
+	c.StorageBackend = getEnv("STORAGE_BACKEND", c.StorageBackend)
+	c.StorageFilePath = getEnv("STORAGE_FILE_PATH", c.StorageFilePath)
+	c.RedisURL = getEnv("REDIS_URL", c.RedisURL)
+	c.DataRetentionDays = c.getEnvInt("DATA_RETENTION_DAYS", c.DataRetentionDays)
+	c.Namespace = getEnv("NAMESPACE", c.Namespace)
+	if namespaces := getEnvList("NAMESPACES"); namespaces != nil {
+		c.Namespaces = namespaces
+	}
+
+	if kinds := getEnvList("RETRYABLE_NETWORK_ERRORS"); kinds != nil {
+		c.RetryableNetworkErrors = kinds
+	}
+
+	if sinks := getEnvList("SINK_URLS"); sinks != nil {
+		c.SinkURLs = sinks
+	}
+	c.SinkBulk = c.getEnvBool("SINK_BULK", c.SinkBulk)
+	c.SinkType = getEnv("SINK_TYPE", c.SinkType)
+	if brokers := getEnvList("KAFKA_BROKERS"); brokers != nil {
+		c.KafkaBrokers = brokers
+	}
+	c.KafkaTopic = getEnv("KAFKA_TOPIC", c.KafkaTopic)
+	c.SinkFilePath = getEnv("SINK_FILE_PATH", c.SinkFilePath)
+	c.SinkFileMaxBytes = c.getEnvInt("SINK_FILE_MAX_BYTES", c.SinkFileMaxBytes)
+
+	c.IngestSchedule = getEnv("INGEST_SCHEDULE", c.IngestSchedule)
 }
 
 func getEnv(key, defaultValue string) string {
@@ -40,3 +395,115 @@ func getEnv(key, defaultValue string) string {
 	return defaultValue
 }
 
+// getEnvInt, getEnvBool, getEnvFloat and getEnvDuration parse a set
+// variable, or return defaultValue when it is unset. A value that doesn't
+// parse also keeps defaultValue, and is recorded for Validate to report.
+func (c *Config) getEnvInt(key string, defaultValue int) int {
+	raw := os.Getenv(key)
+	if raw == "" {
+		return defaultValue
+	}
+	value, err := strconv.Atoi(raw)
+	if err != nil {
+		c.envErrors = append(c.envErrors, fmt.Errorf("%s must be an integer, got %q", key, raw))
+		return defaultValue
+	}
+	return value
+}
+
+func (c *Config) getEnvBool(key string, defaultValue bool) bool {
+	raw := os.Getenv(key)
+	if raw == "" {
+		return defaultValue
+	}
+	value, err := strconv.ParseBool(raw)
+	if err != nil {
+		c.envErrors = append(c.envErrors, fmt.Errorf("%s must be true or false, got %q", key, raw))
+		return defaultValue
+	}
+	return value
+}
+
+func (c *Config) getEnvFloat(key string, defaultValue float64) float64 {
+	raw := os.Getenv(key)
+	if raw == "" {
+		return defaultValue
+	}
+	value, err := strconv.ParseFloat(raw, 64)
+	if err != nil {
+		c.envErrors = append(c.envErrors, fmt.Errorf("%s must be a number, got %q", key, raw))
+		return defaultValue
+	}
+	return value
+}
+
+// getEnvDuration parses a Go duration such as "45s" or "2m".
+func (c *Config) getEnvDuration(key string, defaultValue time.Duration) time.Duration {
+	raw := os.Getenv(key)
+	if raw == "" {
+		return defaultValue
+	}
+	value, err := time.ParseDuration(raw)
+	if err != nil {
+		c.envErrors = append(c.envErrors, fmt.Errorf("%s must be a duration such as 30s or 2m, got %q", key, raw))
+		return defaultValue
+	}
+	return value
+}
+
+// getEnvRates parses comma-separated CODE=rate pairs such as
+// "EUR=1.08,GBP=1.27" (or STAGE=weight pairs such as "proposal=0.5"), with
+// separator between each name and its value. A malformed pair keeps the
+// previous layer's table.
+func getEnvRates(key, separator string, defaultValue map[string]float64) map[string]float64 {
+	pairs := getEnvList(key)
+	if pairs == nil {
+		return defaultValue
+	}
+
+	rates := make(map[string]float64, len(pairs))
+	for _, pair := range pairs {
+		code, value, ok := strings.Cut(pair, separator)
+		rate, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+		if !ok || err != nil || strings.TrimSpace(code) == "" {
+			return defaultValue
+		}
+		rates[strings.TrimSpace(code)] = rate
+	}
+	return rates
+}
+
+// getEnvHeaders parses semicolon-separated Name:Value pairs such as
+// "Authorization:Bearer abc;X-Tenant:acme". Values may contain colons; a
+// malformed pair keeps the previous layer's headers.
+func getEnvHeaders(key string, defaultValue map[string]string) map[string]string {
+	value := os.Getenv(key)
+	if strings.TrimSpace(value) == "" {
+		return defaultValue
+	}
+
+	headers := make(map[string]string)
+	for _, pair := range strings.Split(value, ";") {
+		if strings.TrimSpace(pair) == "" {
+			continue
+		}
+		name, headerValue, ok := strings.Cut(pair, ":")
+		if !ok || strings.TrimSpace(name) == "" {
+			return defaultValue
+		}
+		headers[strings.TrimSpace(name)] = strings.TrimSpace(headerValue)
+	}
+	return headers
+}
+
+// getEnvList splits a comma-separated variable, trimming whitespace and
+// dropping empty entries.
+func getEnvList(key string) []string {
+	var values []string
+	for _, value := range strings.Split(os.Getenv(key), ",") {
+		if value = strings.TrimSpace(value); value != "" {
+			values = append(values, value)
+		}
+	}
+	return values
+}
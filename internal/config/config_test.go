@@ -0,0 +1,254 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"admira-etl/internal/constants"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const sampleYAML = `
+ads_api_url: https://ads.example.com/v1
+crm_api_url: https://crm.example.com/v1
+sink_secret: file-secret
+sink_urls:
+  - https://sink-a.example.com
+  - https://sink-b.example.com
+port: "9090"
+http_timeout: 45s
+max_retries: 5
+shutdown_timeout: 2m
+match_strategy: exact
+storage_backend: file
+storage_file_path: /var/lib/admira/data.json
+`
+
+func writeConfigFile(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o644))
+	return path
+}
+
+// clearEnv blanks the variables Load reads so the host environment can't
+// leak into the test; empty values count as unset.
+func clearEnv(t *testing.T) {
+	for _, key := range []string{
+		"ADS_API_URL", "CRM_API_URL", "ADS_API_HEADERS", "CRM_API_HEADERS", "SINK_URL", "SINK_URLS", "SINK_SECRET", "SINK_BULK", "SINK_TIMEOUT", "SINK_TYPE", "KAFKA_BROKERS", "KAFKA_TOPIC", "SINK_FILE_PATH", "SINK_FILE_MAX_BYTES", "PORT",
+		"LOG_LEVEL", "LOG_SAMPLE_RATE", "LOG_REDACT", "LOG_REDACT_FIELDS", "API_KEY", "PROXY_URL", "RATE_LIMIT_RPS", "RATE_LIMIT_BURST", "MAX_REQUEST_BYTES", "COMPRESS_MIN_BYTES", "MATCH_STRATEGY", "FUZZY_UTM_MATCH",
+		"ATTRIBUTION_MODEL", "LEAD_SOURCE", "LEAD_RATES", "METRIC_PRECISION", "STORAGE_BACKEND", "STORAGE_FILE_PATH", "REDIS_URL", "INGEST_SCHEDULE",
+		"RETRYABLE_NETWORK_ERRORS", "VALIDATION_MODE", "NEGATIVE_VALUES", "PARTIAL_INGEST",
+		"BASE_CURRENCY", "CURRENCY_RATES", "UNKNOWN_CURRENCY", "STAGE_WEIGHTS", "ANOMALY_BOUNDS", "DEFAULT_RANGE_DAYS",
+		"SHUTDOWN_TIMEOUT", "REQUEST_TIMEOUT", "READINESS_FAILURE_THRESHOLD", "READINESS_SUCCESS_THRESHOLD", "STALE_AFTER", "TRANSFORM_CONCURRENCY", "DATA_RETENTION_DAYS", "NAMESPACE", "NAMESPACES", "CONFIG_FILE",
+	} {
+		t.Setenv(key, "")
+	}
+}
+
+func TestLoad_YAMLFile(t *testing.T) {
+	clearEnv(t)
+	t.Setenv("CONFIG_FILE", writeConfigFile(t, sampleYAML))
+
+	cfg, err := Load()
+	require.NoError(t, err)
+
+	assert.Equal(t, "https://ads.example.com/v1", cfg.AdsAPIURL)
+	assert.Equal(t, "https://crm.example.com/v1", cfg.CRMAPIURL)
+	assert.Equal(t, []string{"https://sink-a.example.com", "https://sink-b.example.com"}, cfg.SinkURLs)
+	assert.Equal(t, "9090", cfg.Port)
+	assert.Equal(t, 45*time.Second, cfg.HTTPTimeout)
+	assert.Equal(t, 5, cfg.MaxRetries)
+	assert.Equal(t, 2*time.Minute, cfg.ShutdownTimeout)
+	assert.Equal(t, "exact", cfg.MatchStrategy)
+	assert.Equal(t, constants.StorageBackendFile, cfg.StorageBackend)
+
+	// Fields the file leaves out keep their defaults
+	assert.Equal(t, constants.DefaultRetryDelay*time.Second, cfg.RetryDelay)
+	assert.Equal(t, constants.DefaultAttributionModel, cfg.AttributionModel)
+	assert.Equal(t, constants.DefaultLeadSource, cfg.LeadSource)
+	assert.Equal(t, constants.DefaultValidationMode, cfg.ValidationMode)
+	assert.Equal(t, constants.DefaultNegativeValues, cfg.NegativeValues)
+	assert.Equal(t, constants.DefaultRangeDays, cfg.DefaultRangeDays)
+	assert.Equal(t, constants.DefaultStaleAfter*time.Hour, cfg.StaleAfter)
+	assert.Equal(t, constants.DefaultMaxRequestBytes, cfg.MaxRequestBytes)
+	assert.Equal(t, constants.DefaultLogSampleRate, cfg.LogSampleRate)
+}
+
+func TestLoad_EnvOverridesFile(t *testing.T) {
+	clearEnv(t)
+	t.Setenv("CONFIG_FILE", writeConfigFile(t, sampleYAML))
+	t.Setenv("PORT", "7070")
+	t.Setenv("SINK_URLS", "https://sink-c.example.com")
+
+	cfg, err := Load()
+	require.NoError(t, err)
+
+	assert.Equal(t, "7070", cfg.Port)
+	assert.Equal(t, []string{"https://sink-c.example.com"}, cfg.SinkURLs)
+	assert.Equal(t, "file-secret", cfg.SinkSecret)
+}
+
+func TestLoad_EnvOnly(t *testing.T) {
+	clearEnv(t)
+	t.Setenv("ADS_API_URL", "https://ads.example.com")
+
+	cfg, err := Load()
+	require.NoError(t, err)
+
+	assert.Equal(t, "https://ads.example.com", cfg.AdsAPIURL)
+	assert.Equal(t, constants.DefaultPort, cfg.Port)
+	assert.Equal(t, constants.DefaultHTTPTimeout*time.Second, cfg.HTTPTimeout)
+	assert.Equal(t, constants.DefaultShutdownTimeout*time.Second, cfg.ShutdownTimeout)
+}
+
+func TestLoad_ShutdownTimeout(t *testing.T) {
+	clearEnv(t)
+	t.Setenv("CONFIG_FILE", writeConfigFile(t, sampleYAML))
+
+	t.Setenv("SHUTDOWN_TIMEOUT", "45s")
+	cfg, err := Load()
+	require.NoError(t, err)
+	assert.Equal(t, 45*time.Second, cfg.ShutdownTimeout)
+
+	// Unparseable values keep the previous layer's setting, and Validate
+	// reports them; a bare number has no unit, so it isn't a duration
+	for _, value := range []string{"soon", "30"} {
+		t.Setenv("SHUTDOWN_TIMEOUT", value)
+		cfg, err = Load()
+		require.NoError(t, err)
+		assert.Equal(t, 2*time.Minute, cfg.ShutdownTimeout)
+
+		err = cfg.Validate()
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), fmt.Sprintf("SHUTDOWN_TIMEOUT must be a duration such as 30s or 2m, got %q", value))
+	}
+}
+
+func TestLoad_MalformedNumbers(t *testing.T) {
+	clearEnv(t)
+	t.Setenv("MAX_REQUEST_BYTES", "10MB")
+	t.Setenv("RATE_LIMIT_RPS", "fast")
+	t.Setenv("LOG_REDACT", "maybe")
+
+	cfg, err := Load()
+	require.NoError(t, err)
+	assert.Equal(t, constants.DefaultMaxRequestBytes, cfg.MaxRequestBytes)
+
+	err = cfg.Validate()
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), `MAX_REQUEST_BYTES must be an integer, got "10MB"`)
+	assert.Contains(t, err.Error(), `RATE_LIMIT_RPS must be a number, got "fast"`)
+	assert.Contains(t, err.Error(), `LOG_REDACT must be true or false, got "maybe"`)
+}
+
+func TestLoad_InvalidFile(t *testing.T) {
+	clearEnv(t)
+
+	t.Setenv("CONFIG_FILE", filepath.Join(t.TempDir(), "missing.yaml"))
+	_, err := Load()
+	assert.ErrorContains(t, err, "failed to open config file")
+
+	// Misspelt keys are rejected rather than ignored
+	t.Setenv("CONFIG_FILE", writeConfigFile(t, "ads_api_uri: https://ads.example.com\n"))
+	_, err = Load()
+	assert.ErrorContains(t, err, "failed to parse config file")
+}
+
+func TestLoad_RetryableNetworkErrors(t *testing.T) {
+	clearEnv(t)
+	t.Setenv("CONFIG_FILE", writeConfigFile(t, "retryable_network_errors: [timeout]\n"))
+
+	cfg, err := Load()
+	require.NoError(t, err)
+	assert.Equal(t, []string{"timeout"}, cfg.RetryableNetworkErrors)
+
+	t.Setenv("RETRYABLE_NETWORK_ERRORS", "timeout, dns_not_found")
+	cfg, err = Load()
+	require.NoError(t, err)
+	assert.Equal(t, []string{"timeout", "dns_not_found"}, cfg.RetryableNetworkErrors)
+}
+
+func TestLoad_UpstreamHeaders(t *testing.T) {
+	clearEnv(t)
+	t.Setenv("CONFIG_FILE", writeConfigFile(t, "ads_api_headers:\n  X-API-Key: from-file\n"))
+
+	cfg, err := Load()
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"X-API-Key": "from-file"}, cfg.AdsAPIHeaders)
+	assert.Nil(t, cfg.CRMAPIHeaders)
+
+	t.Setenv("ADS_API_HEADERS", "X-API-Key: abc; X-Tenant:acme")
+	t.Setenv("CRM_API_HEADERS", "Authorization:Bearer a:b")
+	cfg, err = Load()
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"X-API-Key": "abc", "X-Tenant": "acme"}, cfg.AdsAPIHeaders)
+	assert.Equal(t, map[string]string{"Authorization": "Bearer a:b"}, cfg.CRMAPIHeaders)
+
+	// A malformed pair keeps the file's headers
+	t.Setenv("ADS_API_HEADERS", "X-API-Key:abc;X-Tenant")
+	cfg, err = Load()
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"X-API-Key": "from-file"}, cfg.AdsAPIHeaders)
+}
+
+func TestLoad_CurrencyRates(t *testing.T) {
+	clearEnv(t)
+	t.Setenv("CONFIG_FILE", writeConfigFile(t, "base_currency: EUR\ncurrency_rates:\n  USD: 0.92\n"))
+
+	cfg, err := Load()
+	require.NoError(t, err)
+	assert.Equal(t, "EUR", cfg.BaseCurrency)
+	assert.Equal(t, map[string]float64{"USD": 0.92}, cfg.CurrencyRates)
+	assert.Equal(t, constants.UnknownCurrencyPassThrough, cfg.UnknownCurrency)
+
+	t.Setenv("CURRENCY_RATES", "USD=0.9, GBP=1.17")
+	cfg, err = Load()
+	require.NoError(t, err)
+	assert.Equal(t, map[string]float64{"USD": 0.9, "GBP": 1.17}, cfg.CurrencyRates)
+
+	// A malformed pair keeps the file's table
+	t.Setenv("CURRENCY_RATES", "USD=0.9,GBP")
+	cfg, err = Load()
+	require.NoError(t, err)
+	assert.Equal(t, map[string]float64{"USD": 0.92}, cfg.CurrencyRates)
+}
+
+func TestLoad_LeadRates(t *testing.T) {
+	clearEnv(t)
+	t.Setenv("CONFIG_FILE", writeConfigFile(t, "lead_rates:\n  google_ads: 0.12\n"))
+
+	cfg, err := Load()
+	require.NoError(t, err)
+	assert.Equal(t, map[string]float64{"google_ads": 0.12}, cfg.LeadRates)
+
+	t.Setenv("LEAD_RATES", "google_ads:0.15, facebook_ads:0.08")
+	cfg, err = Load()
+	require.NoError(t, err)
+	assert.Equal(t, map[string]float64{"google_ads": 0.15, "facebook_ads": 0.08}, cfg.LeadRates)
+
+	// A malformed pair keeps the file's table
+	t.Setenv("LEAD_RATES", "google_ads=0.15")
+	cfg, err = Load()
+	require.NoError(t, err)
+	assert.Equal(t, map[string]float64{"google_ads": 0.12}, cfg.LeadRates)
+}
+
+func TestLoad_StageWeights(t *testing.T) {
+	clearEnv(t)
+	t.Setenv("CONFIG_FILE", writeConfigFile(t, "stage_weights:\n  proposal: 0.5\n"))
+
+	cfg, err := Load()
+	require.NoError(t, err)
+	assert.Equal(t, map[string]float64{"proposal": 0.5}, cfg.StageWeights)
+
+	t.Setenv("STAGE_WEIGHTS", "qualified=0.2, proposal=0.6")
+	cfg, err = Load()
+	require.NoError(t, err)
+	assert.Equal(t, map[string]float64{"qualified": 0.2, "proposal": 0.6}, cfg.StageWeights)
+}
@@ -0,0 +1,236 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"admira-etl/internal/constants"
+	"admira-etl/internal/storage"
+)
+
+// Validate checks the configuration is usable before the server starts, so
+// mistakes fail fast instead of surfacing mid-request. Every problem found
+// is reported, not just the first.
+func (c *Config) Validate() error {
+	errs := append([]error(nil), c.envErrors...)
+
+	// The Ads and CRM APIs are always needed for ingestion
+	errs = append(errs, validateURL("ADS_API_URL", c.AdsAPIURL))
+	errs = append(errs, validateURL("CRM_API_URL", c.CRMAPIURL))
+
+	// Sinks are optional, but once one is configured export needs the secret
+	switch c.SinkType {
+	case "", constants.SinkTypeHTTP:
+		sinks := c.Sinks()
+		for _, sink := range sinks {
+			errs = append(errs, validateURL("sink URL", sink))
+		}
+		if len(sinks) > 0 && c.SinkSecret == "" {
+			errs = append(errs, errors.New("SINK_SECRET is required when a sink is configured"))
+		}
+	case constants.SinkTypeKafka:
+		if len(c.KafkaBrokers) == 0 || c.KafkaTopic == "" {
+			errs = append(errs, errors.New("KAFKA_BROKERS and KAFKA_TOPIC are required for the kafka sink"))
+		}
+		if c.SinkSecret == "" {
+			errs = append(errs, errors.New("SINK_SECRET is required when a sink is configured"))
+		}
+	case constants.SinkTypeFile:
+		if c.SinkFilePath == "" {
+			errs = append(errs, errors.New("SINK_FILE_PATH is required for the file sink"))
+		}
+		if c.SinkFileMaxBytes < 0 {
+			errs = append(errs, fmt.Errorf("SINK_FILE_MAX_BYTES must not be negative, got %d", c.SinkFileMaxBytes))
+		}
+		if c.SinkSecret == "" {
+			errs = append(errs, errors.New("SINK_SECRET is required when a sink is configured"))
+		}
+	default:
+		errs = append(errs, fmt.Errorf("unknown SINK_TYPE %q", c.SinkType))
+	}
+
+	if port, err := strconv.Atoi(c.Port); err != nil || port < 1 || port > 65535 {
+		errs = append(errs, fmt.Errorf("PORT must be a number between 1 and 65535, got %q", c.Port))
+	}
+
+	if c.HTTPTimeout <= 0 {
+		errs = append(errs, fmt.Errorf("HTTP timeout must be positive, got %s", c.HTTPTimeout))
+	}
+	if c.SinkTimeout <= 0 {
+		errs = append(errs, fmt.Errorf("SINK_TIMEOUT must be positive, got %s", c.SinkTimeout))
+	}
+	if c.MaxRetries < 0 {
+		errs = append(errs, fmt.Errorf("max retries must not be negative, got %d", c.MaxRetries))
+	}
+	if c.RetryDelay <= 0 {
+		errs = append(errs, fmt.Errorf("retry delay must be positive, got %s", c.RetryDelay))
+	}
+	if c.MaxRetryDelay < 0 {
+		errs = append(errs, fmt.Errorf("max retry delay must not be negative, got %s", c.MaxRetryDelay))
+	}
+	if c.MaxRetryDuration < 0 {
+		errs = append(errs, fmt.Errorf("max retry duration must not be negative, got %s", c.MaxRetryDuration))
+	}
+	if c.ProxyURL != "" {
+		errs = append(errs, validateProxyURL(c.ProxyURL))
+	}
+	if c.LogSampleRate < 0 {
+		errs = append(errs, fmt.Errorf("LOG_SAMPLE_RATE must not be negative, got %d", c.LogSampleRate))
+	}
+	if c.ReadinessTimeout <= 0 {
+		errs = append(errs, fmt.Errorf("readiness timeout must be positive, got %s", c.ReadinessTimeout))
+	}
+	if c.RequestTimeout < 0 {
+		errs = append(errs, fmt.Errorf("REQUEST_TIMEOUT must not be negative, got %s", c.RequestTimeout))
+	}
+	if c.ReadinessFailureThreshold < 1 {
+		errs = append(errs, fmt.Errorf("READINESS_FAILURE_THRESHOLD must be at least 1, got %d", c.ReadinessFailureThreshold))
+	}
+	if c.ReadinessSuccessThreshold < 1 {
+		errs = append(errs, fmt.Errorf("READINESS_SUCCESS_THRESHOLD must be at least 1, got %d", c.ReadinessSuccessThreshold))
+	}
+	if c.TransformConcurrency < 0 {
+		errs = append(errs, fmt.Errorf("TRANSFORM_CONCURRENCY must not be negative, got %d", c.TransformConcurrency))
+	}
+	if c.DefaultRangeDays <= 0 {
+		errs = append(errs, fmt.Errorf("DEFAULT_RANGE_DAYS must be positive, got %d", c.DefaultRangeDays))
+	}
+	if c.StaleAfter <= 0 {
+		errs = append(errs, fmt.Errorf("STALE_AFTER must be positive, got %s", c.StaleAfter))
+	}
+	if c.ShutdownTimeout <= 0 {
+		errs = append(errs, fmt.Errorf("SHUTDOWN_TIMEOUT must be positive, got %s", c.ShutdownTimeout))
+	}
+
+	if c.RateLimitRPS < 0 {
+		errs = append(errs, fmt.Errorf("RATE_LIMIT_RPS must not be negative, got %g", c.RateLimitRPS))
+	}
+	if c.RateLimitRPS > 0 && c.RateLimitBurst <= 0 {
+		errs = append(errs, fmt.Errorf("RATE_LIMIT_BURST must be positive when rate limiting is enabled, got %d", c.RateLimitBurst))
+	}
+	if c.MaxRequestBytes < 0 {
+		errs = append(errs, fmt.Errorf("MAX_REQUEST_BYTES must not be negative, got %d", c.MaxRequestBytes))
+	}
+	if c.CompressMinBytes < 0 {
+		errs = append(errs, fmt.Errorf("COMPRESS_MIN_BYTES must not be negative, got %d", c.CompressMinBytes))
+	}
+
+	errs = append(errs, validateHeaders("ADS_API_HEADERS", c.AdsAPIHeaders))
+	errs = append(errs, validateHeaders("CRM_API_HEADERS", c.CRMAPIHeaders))
+
+	for code, rate := range c.CurrencyRates {
+		if rate <= 0 {
+			errs = append(errs, fmt.Errorf("currency rate for %s must be positive, got %g", code, rate))
+		}
+	}
+
+	for channel, rate := range c.LeadRates {
+		if rate < 0 || rate > 1 {
+			errs = append(errs, fmt.Errorf("lead rate for %s must be between 0 and 1, got %g", channel, rate))
+		}
+	}
+
+	if c.MetricPrecision < 0 || c.MetricPrecision > constants.MaxMetricPrecision {
+		errs = append(errs, fmt.Errorf("METRIC_PRECISION must be between 0 and %d, got %d", constants.MaxMetricPrecision, c.MetricPrecision))
+	}
+
+	for stage, weight := range c.StageWeights {
+		if weight < 0 || weight > 1 {
+			errs = append(errs, fmt.Errorf("stage weight for %s must be between 0 and 1, got %g", stage, weight))
+		}
+	}
+
+	for metric, bound := range c.AnomalyBounds {
+		if bound <= 0 {
+			errs = append(errs, fmt.Errorf("anomaly bound for %s must be positive, got %g", metric, bound))
+		}
+	}
+
+	switch c.StorageBackend {
+	case constants.StorageBackendMemory:
+	case constants.StorageBackendFile:
+		if c.StorageFilePath == "" {
+			errs = append(errs, errors.New("STORAGE_FILE_PATH is required for the file storage backend"))
+		}
+	case constants.StorageBackendRedis:
+		if c.RedisURL == "" {
+			errs = append(errs, errors.New("REDIS_URL is required for the redis storage backend"))
+		}
+	default:
+		errs = append(errs, fmt.Errorf("unknown STORAGE_BACKEND %q", c.StorageBackend))
+	}
+	if c.Namespace != "" {
+		if err := storage.ValidateNamespace(c.Namespace); err != nil {
+			errs = append(errs, fmt.Errorf("NAMESPACE: %w", err))
+		}
+	}
+	for _, namespace := range c.Namespaces {
+		if err := storage.ValidateNamespace(namespace); err != nil {
+			errs = append(errs, fmt.Errorf("NAMESPACES: %w", err))
+		}
+	}
+	if c.DataRetentionDays < 0 {
+		errs = append(errs, fmt.Errorf("DATA_RETENTION_DAYS must not be negative, got %d", c.DataRetentionDays))
+	}
+
+	return errors.Join(errs...)
+}
+
+// validateURL checks value is a non-empty, absolute http(s) URL.
+func validateURL(name, value string) error {
+	if value == "" {
+		return fmt.Errorf("%s is required", name)
+	}
+
+	parsed, err := url.Parse(value)
+	if err != nil {
+		return fmt.Errorf("%s is not a valid URL: %w", name, err)
+	}
+	if (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+		return fmt.Errorf("%s must be an absolute http(s) URL, got %q", name, value)
+	}
+	return nil
+}
+
+// validateProxyURL checks value is an absolute http(s) or socks5 URL, the
+// proxy schemes the stdlib transport supports.
+func validateProxyURL(value string) error {
+	parsed, err := url.Parse(value)
+	if err != nil {
+		return fmt.Errorf("PROXY_URL is not a valid URL: %w", err)
+	}
+	switch parsed.Scheme {
+	case "http", "https", "socks5":
+		if parsed.Host != "" {
+			return nil
+		}
+	}
+	return fmt.Errorf("PROXY_URL must be an absolute http(s) or socks5 URL, got %q", value)
+}
+
+// validateHeaders checks every header name is a valid HTTP token and no
+// value contains a line break, which net/http would refuse to send.
+func validateHeaders(setting string, headers map[string]string) error {
+	var errs []error
+	for name, value := range headers {
+		if name == "" || strings.IndexFunc(name, func(r rune) bool { return !isTokenChar(r) }) >= 0 {
+			errs = append(errs, fmt.Errorf("%s has an invalid header name %q", setting, name))
+		}
+		if strings.ContainsAny(value, "\r\n") {
+			errs = append(errs, fmt.Errorf("%s header %s has a line break in its value", setting, name))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// isTokenChar reports whether r may appear in an HTTP header name.
+func isTokenChar(r rune) bool {
+	switch {
+	case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9':
+		return true
+	}
+	return strings.ContainsRune("!#$%&'*+-.^_`|~", r)
+}
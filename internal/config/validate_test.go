@@ -0,0 +1,134 @@
+package config
+
+import (
+	"testing"
+	"time"
+
+	"admira-etl/internal/constants"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func validConfig() *Config {
+	return &Config{
+		AdsAPIURL:        "https://ads.example.com/v1",
+		CRMAPIURL:        "https://crm.example.com/v1",
+		Port:             "8080",
+		HTTPTimeout:      30 * time.Second,
+		SinkTimeout:      30 * time.Second,
+		MaxRetries:       3,
+		RetryDelay:       time.Second,
+		ReadinessTimeout: 2 * time.Second,
+		ShutdownTimeout:  30 * time.Second,
+		RateLimitRPS:     10,
+		RateLimitBurst:   20,
+		DefaultRangeDays: 7,
+		StaleAfter:       24 * time.Hour,
+		StorageBackend:   constants.StorageBackendMemory,
+
+		ReadinessFailureThreshold: 1,
+		ReadinessSuccessThreshold: 1,
+	}
+}
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name   string
+		modify func(c *Config)
+		errMsg string
+	}{
+		{name: "valid", modify: func(c *Config) {}},
+		{name: "valid with sink", modify: func(c *Config) {
+			c.SinkURL = "https://sink.example.com"
+			c.SinkSecret = "secret"
+		}},
+		{name: "valid with upstream headers", modify: func(c *Config) { c.AdsAPIHeaders = map[string]string{"X-API-Key": "abc"} }},
+		{name: "valid with proxy", modify: func(c *Config) { c.ProxyURL = "socks5://proxy.internal:1080" }},
+		{name: "missing ads URL", modify: func(c *Config) { c.AdsAPIURL = "" }, errMsg: "ADS_API_URL is required"},
+		{name: "missing CRM URL", modify: func(c *Config) { c.CRMAPIURL = "" }, errMsg: "CRM_API_URL is required"},
+		{name: "relative URL", modify: func(c *Config) { c.AdsAPIURL = "ads.example.com/v1" }, errMsg: "ADS_API_URL must be an absolute http(s) URL"},
+		{name: "unsupported scheme", modify: func(c *Config) { c.CRMAPIURL = "ftp://crm.example.com" }, errMsg: "CRM_API_URL must be an absolute http(s) URL"},
+		{name: "unparseable URL", modify: func(c *Config) { c.AdsAPIURL = "http://[::1" }, errMsg: "ADS_API_URL is not a valid URL"},
+		{name: "sink without secret", modify: func(c *Config) { c.SinkURLs = []string{"https://sink.example.com"} }, errMsg: "SINK_SECRET is required"},
+		{name: "malformed sink", modify: func(c *Config) {
+			c.SinkURLs = []string{"https://sink.example.com", "not a url"}
+			c.SinkSecret = "secret"
+		}, errMsg: "sink URL must be an absolute http(s) URL"},
+		{name: "valid kafka sink", modify: func(c *Config) {
+			c.SinkType = constants.SinkTypeKafka
+			c.KafkaBrokers = []string{"kafka-1:9092"}
+			c.KafkaTopic = "admira-metrics"
+			c.SinkSecret = "secret"
+		}},
+		{name: "kafka sink without topic", modify: func(c *Config) {
+			c.SinkType = constants.SinkTypeKafka
+			c.KafkaBrokers = []string{"kafka-1:9092"}
+			c.SinkSecret = "secret"
+		}, errMsg: "KAFKA_BROKERS and KAFKA_TOPIC are required"},
+		{name: "file sink without path", modify: func(c *Config) {
+			c.SinkType = constants.SinkTypeFile
+			c.SinkSecret = "secret"
+		}, errMsg: "SINK_FILE_PATH is required for the file sink"},
+		{name: "unknown sink type", modify: func(c *Config) { c.SinkType = "ftp" }, errMsg: `unknown SINK_TYPE "ftp"`},
+		{name: "non-numeric port", modify: func(c *Config) { c.Port = "http" }, errMsg: "PORT must be a number"},
+		{name: "port out of range", modify: func(c *Config) { c.Port = "70000" }, errMsg: "PORT must be a number"},
+		{name: "invalid header name", modify: func(c *Config) { c.AdsAPIHeaders = map[string]string{"X API Key": "abc"} }, errMsg: `ADS_API_HEADERS has an invalid header name "X API Key"`},
+		{name: "header value with line break", modify: func(c *Config) { c.CRMAPIHeaders = map[string]string{"Authorization": "a\r\nX-Evil: 1"} }, errMsg: "CRM_API_HEADERS header Authorization has a line break in its value"},
+		{name: "proxy without scheme", modify: func(c *Config) { c.ProxyURL = "proxy.internal:3128" }, errMsg: "PROXY_URL must be an absolute http(s) or socks5 URL"},
+		{name: "zero timeout", modify: func(c *Config) { c.HTTPTimeout = 0 }, errMsg: "HTTP timeout must be positive"},
+		{name: "zero sink timeout", modify: func(c *Config) { c.SinkTimeout = 0 }, errMsg: "SINK_TIMEOUT must be positive"},
+		{name: "negative retries", modify: func(c *Config) { c.MaxRetries = -1 }, errMsg: "max retries must not be negative"},
+		{name: "zero retry delay", modify: func(c *Config) { c.RetryDelay = 0 }, errMsg: "retry delay must be positive"},
+		{name: "negative max retry duration", modify: func(c *Config) { c.MaxRetryDuration = -time.Second }, errMsg: "max retry duration must not be negative"},
+		{name: "negative transform concurrency", modify: func(c *Config) { c.TransformConcurrency = -1 }, errMsg: "TRANSFORM_CONCURRENCY must not be negative"},
+		{name: "zero default range", modify: func(c *Config) { c.DefaultRangeDays = 0 }, errMsg: "DEFAULT_RANGE_DAYS must be positive"},
+		{name: "zero stale after", modify: func(c *Config) { c.StaleAfter = 0 }, errMsg: "STALE_AFTER must be positive"},
+		{name: "negative max retry delay", modify: func(c *Config) { c.MaxRetryDelay = -time.Second }, errMsg: "max retry delay must not be negative"},
+		{name: "negative request timeout", modify: func(c *Config) { c.RequestTimeout = -time.Second }, errMsg: "REQUEST_TIMEOUT must not be negative"},
+		{name: "zero readiness failure threshold", modify: func(c *Config) { c.ReadinessFailureThreshold = 0 }, errMsg: "READINESS_FAILURE_THRESHOLD must be at least 1"},
+		{name: "zero readiness success threshold", modify: func(c *Config) { c.ReadinessSuccessThreshold = 0 }, errMsg: "READINESS_SUCCESS_THRESHOLD must be at least 1"},
+		{name: "zero shutdown timeout", modify: func(c *Config) { c.ShutdownTimeout = 0 }, errMsg: "SHUTDOWN_TIMEOUT must be positive"},
+		{name: "negative rate limit", modify: func(c *Config) { c.RateLimitRPS = -1 }, errMsg: "RATE_LIMIT_RPS must not be negative"},
+		{name: "negative log sample rate", modify: func(c *Config) { c.LogSampleRate = -1 }, errMsg: "LOG_SAMPLE_RATE must not be negative"},
+		{name: "negative max request bytes", modify: func(c *Config) { c.MaxRequestBytes = -1 }, errMsg: "MAX_REQUEST_BYTES must not be negative"},
+		{name: "negative compress min bytes", modify: func(c *Config) { c.CompressMinBytes = -1 }, errMsg: "COMPRESS_MIN_BYTES must not be negative"},
+		{name: "zero burst", modify: func(c *Config) { c.RateLimitBurst = 0 }, errMsg: "RATE_LIMIT_BURST must be positive"},
+		{name: "unknown storage backend", modify: func(c *Config) { c.StorageBackend = "postgres" }, errMsg: `unknown STORAGE_BACKEND "postgres"`},
+		{name: "non-positive currency rate", modify: func(c *Config) { c.CurrencyRates = map[string]float64{"EUR": 1.1, "GBP": 0} }, errMsg: "currency rate for GBP must be positive"},
+		{name: "metric precision too high", modify: func(c *Config) { c.MetricPrecision = 5 }, errMsg: "METRIC_PRECISION must be between 0 and 4"},
+		{name: "lead rate out of range", modify: func(c *Config) { c.LeadRates = map[string]float64{"google_ads": 12} }, errMsg: "lead rate for google_ads must be between 0 and 1"},
+		{name: "stage weight out of range", modify: func(c *Config) { c.StageWeights = map[string]float64{"proposal": 1.5} }, errMsg: "stage weight for proposal must be between 0 and 1"},
+		{name: "non-positive anomaly bound", modify: func(c *Config) { c.AnomalyBounds = map[string]float64{"roas": 0} }, errMsg: "anomaly bound for roas must be positive"},
+		{name: "negative retention", modify: func(c *Config) { c.DataRetentionDays = -7 }, errMsg: "DATA_RETENTION_DAYS must not be negative"},
+		{name: "valid namespace", modify: func(c *Config) { c.Namespace = "staging" }},
+		{name: "invalid namespace", modify: func(c *Config) { c.Namespace = "../prod" }, errMsg: "NAMESPACE: invalid namespace"},
+		{name: "valid namespaces", modify: func(c *Config) { c.Namespaces = []string{"staging", "preview"} }},
+		{name: "invalid namespaces", modify: func(c *Config) { c.Namespaces = []string{"staging", "Preview"} }, errMsg: "NAMESPACES: invalid namespace"},
+		{name: "file backend without path", modify: func(c *Config) { c.StorageBackend = constants.StorageBackendFile }, errMsg: "STORAGE_FILE_PATH is required"},
+		{name: "redis backend without URL", modify: func(c *Config) { c.StorageBackend = constants.StorageBackendRedis }, errMsg: "REDIS_URL is required"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := validConfig()
+			tt.modify(cfg)
+
+			err := cfg.Validate()
+			if tt.errMsg == "" {
+				assert.NoError(t, err)
+				return
+			}
+			assert.ErrorContains(t, err, tt.errMsg)
+		})
+	}
+}
+
+func TestValidate_ReportsEveryProblem(t *testing.T) {
+	cfg := validConfig()
+	cfg.AdsAPIURL = ""
+	cfg.CRMAPIURL = ""
+
+	err := cfg.Validate()
+	assert.ErrorContains(t, err, "ADS_API_URL is required")
+	assert.ErrorContains(t, err, "CRM_API_URL is required")
+}
@@ -5,15 +5,61 @@ const (
 	DefaultPort     = "8080"
 	DefaultLogLevel = "info"
 	
+	// Repeated warnings logged per run before the rest are only counted
+	DefaultLogSampleRate = 10
+	
 	// HTTP timeouts
 	DefaultHTTPTimeout = 30
+	DefaultSinkTimeout = 30
 	DefaultMaxRetries  = 3
 	DefaultRetryDelay  = 1
+
+	// Retry backoff growth and the cap on one delay, in seconds
+	DefaultRetryBackoff  = "exponential"
+	DefaultMaxRetryDelay = 30
+	
+	// Storage backends
+	StorageBackendMemory   = "memory"
+	StorageBackendFile     = "file"
+	StorageBackendRedis    = "redis"
+	DefaultStorageFilePath = "data/admira-etl.json"
+	
+	// Export sink types
+	SinkTypeHTTP    = "http"
+	SinkTypeKafka   = "kafka"
+	SinkTypeFile    = "file"
+	DefaultSinkType = SinkTypeHTTP
+	
+	// Size at which the file sink rotates its output (100 MiB)
+	DefaultSinkFileMaxBytes = 100 << 20
+	
+	// Rate limiting, off unless RATE_LIMIT_RPS is set
+	DefaultRateLimitRPS   = 0
+	DefaultRateLimitBurst = 20
+	
+	// Request body cap on /api/v1 (10 MiB)
+	DefaultMaxRequestBytes = 10 << 20
+	
+	// Smallest /api/v1 response body worth gzipping
+	DefaultCompressMinBytes = 1024
 	
 	// Pagination
 	DefaultLimit  = 100
 	MaxLimit      = 1000
 	DefaultOffset = 0
+	DefaultTopN   = 10
+
+	// Longest range, in days, a time series query may span
+	MaxTimeSeriesDays = 3660
+	
+	// Days covered by a metrics query that omits from
+	DefaultRangeDays = 7
+	
+	// Age, in hours, past which metrics are flagged as stale
+	DefaultStaleAfter = 24
+	
+	// Upper bound on pages followed when fetching from a paginated upstream
+	MaxExternalPages = 100
 	
 	// Date format
 	DateFormat = "2006-01-02"
@@ -25,13 +71,57 @@ const (
 	APIVersion = "v1"
 	
 	// Health check
+	DefaultReadinessTimeout = 2
+
+	// Consecutive /readyz probes needed to turn unready or ready again
+	DefaultReadinessFailureThreshold = 1
+	DefaultReadinessSuccessThreshold = 1
+
+	// Decimals derived metrics are stored with, 0 for full precision; JSON
+	// responses carry at most 4, so rounding finer than that is pointless
+	DefaultMetricPrecision = 0
+	MaxMetricPrecision     = 4
+
+	// Graceful shutdown, in seconds
+	DefaultShutdownTimeout = 30
 	HealthStatusHealthy = "healthy"
 	HealthStatusReady   = "ready"
 	HealthStatusUnhealthy = "unhealthy"
 	
+	// UTM matching
+	DefaultMatchStrategy = "full"
+	MatchTypeExact       = "exact"
+	MatchTypeCampaign    = "campaign"
+	MatchTypeSource      = "source"
+	MatchTypeNone        = "none"
+
+	// Attribution
+	DefaultAttributionModel = "full"
+
+	// Lead counting
+	LeadSourceEstimate = "estimate"
+	LeadSourceCRM      = "crm"
+	DefaultLeadSource  = LeadSourceEstimate
+
+	// Currency normalization
+	DefaultBaseCurrency        = "USD"
+	UnknownCurrencyPassThrough = "pass_through"
+	UnknownCurrencySkip        = "skip"
+
+	// Validation of fetched records
+	ValidationModeSkipInvalid = "skip_invalid"
+	ValidationModeRejectAll   = "reject_all"
+	DefaultValidationMode     = ValidationModeSkipInvalid
+	
+	// Negative clicks, impressions, cost and amounts
+	NegativeValuesReject  = "reject"
+	NegativeValuesClamp   = "clamp"
+	DefaultNegativeValues = NegativeValuesReject
+	
 	// Opportunity stages
 	StageClosedWon = "closed_won"
 	StageProposal  = "proposal"
 	StageQualified = "qualified"
 	StageLead      = "lead"
+	StageClosedLost = "closed_lost"
 )
@@ -0,0 +1,216 @@
+package etl
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"admira-etl/internal/constants"
+	"admira-etl/internal/models"
+)
+
+// dailyAggregates caches the stored rows of each date merged per channel
+// and campaign, so summaries and consolidated metrics add up a row per
+// campaign and day instead of rescanning every stored row. Dates are loaded
+// on first use and recomputed after every ingestion; each write drops the
+// dates it touches. gen counts the drops so a load racing a write never
+// caches rows read before it.
+type dailyAggregates struct {
+	mu   sync.Mutex
+	days map[string][]aggregateRow
+	gen  uint64
+}
+
+// aggregateRow is the (date, channel, campaign) total of records stored
+// rows. Like any merged row its ratios are recomputed from the totals.
+type aggregateRow struct {
+	row     models.TransformedData
+	records int
+}
+
+func newDailyAggregates() *dailyAggregates {
+	return &dailyAggregates{days: make(map[string][]aggregateRow)}
+}
+
+// lookup returns the cached aggregates of dates, the dates that aren't
+// cached, and the generation to pass to store once those are loaded.
+func (a *dailyAggregates) lookup(dates []string) (map[string][]aggregateRow, []string, uint64) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	cached := make(map[string][]aggregateRow, len(dates))
+	var missing []string
+	for _, date := range dates {
+		rows, ok := a.days[date]
+		if !ok {
+			missing = append(missing, date)
+			continue
+		}
+		cached[date] = rows
+	}
+	return cached, missing, a.gen
+}
+
+// store caches loaded dates unless something was dropped since gen.
+func (a *dailyAggregates) store(gen uint64, days map[string][]aggregateRow) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if gen != a.gen {
+		return
+	}
+	for date, rows := range days {
+		a.days[date] = rows
+	}
+}
+
+// drop forgets the cached aggregates of the dates for which keep is false.
+func (a *dailyAggregates) drop(keep func(date string) bool) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	a.gen++
+	for date := range a.days {
+		if !keep(date) {
+			delete(a.days, date)
+		}
+	}
+}
+
+// aggregatesEnabled reports whether the daily aggregates can be trusted. A
+// Redis store may be shared with other instances, whose writes would never
+// invalidate this instance's cache.
+func (s *Service) aggregatesEnabled() bool {
+	return s.config.StorageBackend != constants.StorageBackendRedis
+}
+
+// aggregatedRows returns the daily aggregates dated within [from, to] in
+// date order, reading every date not cached yet in a single storage query.
+func (s *Service) aggregatedRows(from, to time.Time) ([]aggregateRow, error) {
+	first, last, err := s.storage.DateRange()
+	if err != nil || first == "" {
+		return nil, err
+	}
+
+	// Only stored dates can have rows, which also bounds open-ended ranges
+	from, _ = time.Parse(dateLayout, from.Format(dateLayout))
+	to, _ = time.Parse(dateLayout, to.Format(dateLayout))
+	if firstDate, err := time.Parse(dateLayout, first); err == nil && from.Before(firstDate) {
+		from = firstDate
+	}
+	if lastDate, err := time.Parse(dateLayout, last); err == nil && to.After(lastDate) {
+		to = lastDate
+	}
+
+	var dates []string
+	for date := from; !date.After(to); date = date.AddDate(0, 0, 1) {
+		dates = append(dates, date.Format(dateLayout))
+	}
+
+	cached, missing, gen := s.aggregates.lookup(dates)
+	if len(missing) > 0 {
+		loadFrom, _ := time.Parse(dateLayout, missing[0])
+		loadTo, _ := time.Parse(dateLayout, missing[len(missing)-1])
+		data, err := s.storage.GetTransformedData(loadFrom, loadTo, nil, 0, 0)
+		if err != nil {
+			return nil, err
+		}
+
+		byDate := aggregateByDate(data)
+		loaded := make(map[string][]aggregateRow, len(missing))
+		for _, date := range missing {
+			loaded[date] = byDate[date]
+			cached[date] = byDate[date]
+		}
+		s.aggregates.store(gen, loaded)
+	}
+
+	var rows []aggregateRow
+	for _, date := range dates {
+		rows = append(rows, cached[date]...)
+	}
+	return rows, nil
+}
+
+// aggregateByDate merges rows sharing a date, channel and campaign, keeping
+// each date's aggregates in order of first appearance as mergeRows does.
+func aggregateByDate(data []models.TransformedData) map[string][]aggregateRow {
+	days := make(map[string][]aggregateRow)
+	index := make(map[string]int)
+
+	for _, item := range data {
+		key := item.Date + "|" + item.Channel + "|" + item.CampaignID
+		if i, ok := index[key]; ok {
+			aggregate := &days[item.Date][i]
+			addTotals(&aggregate.row, item)
+			recomputeDerivedMetrics(&aggregate.row)
+			aggregate.records++
+			continue
+		}
+		index[key] = len(days[item.Date])
+		days[item.Date] = append(days[item.Date], aggregateRow{row: item, records: 1})
+	}
+	return days
+}
+
+// refreshAggregates drops the aggregates of the dates rows were just stored
+// under, and of any dates the store's retention may have evicted, then
+// recomputes the stored dates so the next summary finds them cached. A
+// failed recompute is only logged: the dates load on first use instead.
+func (s *Service) refreshAggregates(rows []models.TransformedData) {
+	touched := make(map[string]bool, len(rows))
+	for _, row := range rows {
+		touched[row.Date] = true
+	}
+
+	cutoff := ""
+	if s.config.DataRetentionDays > 0 {
+		cutoff = s.now().UTC().AddDate(0, 0, -s.config.DataRetentionDays).Format(dateLayout)
+	}
+	s.aggregates.drop(func(date string) bool {
+		return !touched[date] && date >= cutoff
+	})
+
+	if !s.aggregatesEnabled() || len(touched) == 0 {
+		return
+	}
+
+	dates := make([]string, 0, len(touched))
+	for date := range touched {
+		dates = append(dates, date)
+	}
+	sort.Strings(dates)
+
+	from, errFrom := time.Parse(dateLayout, dates[0])
+	to, errTo := time.Parse(dateLayout, dates[len(dates)-1])
+	if errFrom != nil || errTo != nil {
+		return
+	}
+	if _, err := s.aggregatedRows(from, to); err != nil {
+		s.logger.WithError(err).Warn("Failed to recompute daily aggregates")
+	}
+}
+
+// consolidatedRows returns the channel's rows dated within [from, to]
+// merged into one per campaign, from the daily aggregates when enabled.
+func (s *Service) consolidatedRows(from, to time.Time, channel string) ([]models.TransformedData, error) {
+	if !s.aggregatesEnabled() {
+		data, err := s.storage.GetTransformedData(from, to, map[string]string{"channel": channel}, 0, 0)
+		if err != nil {
+			return nil, err
+		}
+		return s.ConsolidateDataByChannelAndCampaign(data), nil
+	}
+
+	aggregates, err := s.aggregatedRows(from, to)
+	if err != nil {
+		return nil, err
+	}
+	var data []models.TransformedData
+	for _, aggregate := range aggregates {
+		if aggregate.row.Channel == channel {
+			data = append(data, aggregate.row)
+		}
+	}
+	return s.ConsolidateDataByChannelAndCampaign(data), nil
+}
@@ -0,0 +1,213 @@
+package etl
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"admira-etl/internal/config"
+	"admira-etl/internal/constants"
+	"admira-etl/internal/models"
+	"admira-etl/internal/storage"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDailyAggregates_MatchRows(t *testing.T) {
+	logger := logrus.New()
+	logger.SetLevel(logrus.ErrorLevel)
+
+	// C-1001 has two rows on the 1st, so its aggregate merges them
+	store := storage.NewInMemoryStorage()
+	require.NoError(t, store.StoreTransformedData([]models.TransformedData{
+		{Date: "2025-01-01", Channel: "google_ads", CampaignID: "C-1001", Clicks: 100, Impressions: 1000, Cost: 50.0, Leads: 10, Opportunities: 2, ClosedWon: 1, Revenue: 200.0, Anomalies: []string{"roas"}},
+		{Date: "2025-01-01", Channel: "google_ads", CampaignID: "C-1001", Clicks: 60, Impressions: 800, Cost: 30.0, Leads: 6, Revenue: 40.0},
+		{Date: "2025-01-01", Channel: "facebook_ads", CampaignID: "C-2001", Clicks: 80, Cost: 20.0, Leads: 8, Opportunities: 4, ClosedWon: 2, Revenue: 500.0},
+		{Date: "2025-01-02", Channel: "google_ads", CampaignID: "C-1001", Clicks: 40, Cost: 12.5, Leads: 4, Opportunities: 1, Revenue: 0.5, ROAS: 0.04},
+		{Date: "2025-01-02", Channel: "google_ads", CampaignID: "C-1002", Clicks: 20, Cost: 4.0, Leads: 2},
+		{Date: "2025-01-04", Channel: "google_ads", CampaignID: "C-1002", Clicks: 10, Cost: 2.0, Leads: 1, Revenue: 8.0},
+	}))
+
+	// Both services share the store; only the first reads aggregates
+	cached := NewService(&config.Config{}, store, logger)
+	direct := NewService(&config.Config{StorageBackend: constants.StorageBackendRedis}, store, logger)
+
+	date := func(value string) time.Time {
+		parsed, err := time.Parse(dateLayout, value)
+		require.NoError(t, err)
+		return parsed
+	}
+	ranges := [][2]time.Time{
+		{date("2025-01-01"), date("2025-01-04")},
+		{date("2025-01-02"), date("2025-01-03")},
+		{date("2024-01-01"), date("2026-01-01")},
+		{date("2025-01-05"), date("2025-01-09")},
+	}
+
+	for _, r := range ranges {
+		for _, channel := range []string{"", "google_ads", "facebook_ads"} {
+			expected, err := direct.GetMetricsSummary(r[0], r[1], channel)
+			require.NoError(t, err)
+			actual, err := cached.GetMetricsSummary(r[0], r[1], channel)
+			require.NoError(t, err)
+			assert.Equal(t, expected, actual, "summary of %s to %s for %q", r[0], r[1], channel)
+
+			query := ChannelMetricsQuery{From: r[0], To: r[1], Channel: channel, Consolidate: true}
+			expectedRows, _, err := direct.GetChannelMetrics(query)
+			require.NoError(t, err)
+			actualRows, _, err := cached.GetChannelMetrics(query)
+			require.NoError(t, err)
+			assert.Equal(t, expectedRows, actualRows, "consolidated %s to %s for %q", r[0], r[1], channel)
+		}
+	}
+
+	// Raw rows are counted, not aggregates
+	summary, err := cached.GetMetricsSummary(date("2025-01-01"), date("2025-01-01"), "google_ads")
+	require.NoError(t, err)
+	assert.Equal(t, 2, summary.Records)
+}
+
+func TestDailyAggregates_Invalidation(t *testing.T) {
+	logger := logrus.New()
+	logger.SetLevel(logrus.ErrorLevel)
+
+	store := storage.NewInMemoryStorage()
+	service := NewService(&config.Config{}, store, logger)
+
+	day1, _ := time.Parse(dateLayout, "2025-01-01")
+	day2, _ := time.Parse(dateLayout, "2025-01-02")
+
+	ingest := func(date string, clicks int) {
+		_, err := service.IngestPayload(context.Background(), &models.ExternalResponse{External: models.ExternalData{
+			Ads: &models.AdsData{Performance: []models.AdsPerformance{
+				{Date: date, CampaignID: "C-1001", Channel: "google_ads", Clicks: clicks, Cost: 10.0},
+			}},
+		}}, "")
+		require.NoError(t, err)
+	}
+	clicks := func(from, to time.Time) int {
+		summary, err := service.GetMetricsSummary(from, to, "")
+		require.NoError(t, err)
+		return summary.Clicks
+	}
+
+	ingest("2025-01-01", 100)
+	ingest("2025-01-02", 50)
+	assert.Equal(t, 150, clicks(day1, day2))
+
+	// Rows stored behind the service's back stay invisible while their
+	// date is cached, which shows the summary reads the aggregates
+	require.NoError(t, store.StoreTransformedData([]models.TransformedData{
+		{Date: "2025-01-01", Channel: "google_ads", CampaignID: "C-1001", Clicks: 1000},
+		{Date: "2025-01-02", Channel: "google_ads", CampaignID: "C-1001", Clicks: 1000},
+	}))
+	assert.Equal(t, 150, clicks(day1, day2))
+
+	// Re-ingesting the 1st recomputes that date only
+	ingest("2025-01-01", 7)
+	assert.Equal(t, 1107, clicks(day1, day1))
+	assert.Equal(t, 50, clicks(day2, day2))
+
+	// Deleting invalidates the deleted range
+	_, err := service.DeleteData(day2, day2, "")
+	require.NoError(t, err)
+	assert.Equal(t, 0, clicks(day2, day2))
+	assert.Equal(t, 1107, clicks(day1, day2))
+
+	// Restoring a backup invalidates everything
+	var backup bytes.Buffer
+	require.NoError(t, store.WriteSnapshot(&backup))
+	ingest("2025-01-01", 3)
+	assert.Equal(t, 1110, clicks(day1, day2))
+	_, err = service.RestoreSnapshot(context.Background(), &backup)
+	require.NoError(t, err)
+	assert.Equal(t, 1107, clicks(day1, day2))
+}
+
+// unpersistedStorage keeps stored rows in memory but reports the write as
+// failed, as a FileStorage does when persisting fails.
+type unpersistedStorage struct {
+	*storage.InMemoryStorage
+}
+
+func (u unpersistedStorage) StoreTransformedData(data []models.TransformedData) error {
+	if err := u.InMemoryStorage.StoreTransformedData(data); err != nil {
+		return err
+	}
+	return errors.New("failed to persist")
+}
+
+func TestDailyAggregates_FailedStore(t *testing.T) {
+	logger := logrus.New()
+	logger.SetLevel(logrus.FatalLevel)
+
+	inner := storage.NewInMemoryStorage()
+	require.NoError(t, inner.StoreTransformedData([]models.TransformedData{
+		{Date: "2025-01-01", Channel: "google_ads", CampaignID: "C-1001", Clicks: 100},
+	}))
+	service := NewService(&config.Config{}, unpersistedStorage{inner}, logger)
+
+	day, _ := time.Parse(dateLayout, "2025-01-01")
+	clicks := func() int {
+		summary, err := service.GetMetricsSummary(day, day, "")
+		require.NoError(t, err)
+		return summary.Clicks
+	}
+	assert.Equal(t, 100, clicks())
+
+	// The rows a failed store left behind are counted, not hidden behind
+	// the cached aggregate
+	_, err := service.IngestPayload(context.Background(), &models.ExternalResponse{External: models.ExternalData{
+		Ads: &models.AdsData{Performance: []models.AdsPerformance{
+			{Date: "2025-01-01", CampaignID: "C-1001", Channel: "google_ads", Clicks: 5},
+		}},
+	}}, "")
+	require.Error(t, err)
+	assert.Equal(t, 105, clicks())
+
+	service.config.SinkSecret = "secret"
+	records := []models.TransformedData{{Date: "2025-01-01", Channel: "google_ads", CampaignID: "C-1001", Clicks: 7}}
+	_, err = service.IngestSigned(context.Background(), records, service.BatchSignature(records))
+	require.Error(t, err)
+	assert.Equal(t, 112, clicks())
+}
+
+func TestDailyAggregates_Retention(t *testing.T) {
+	logger := logrus.New()
+	logger.SetLevel(logrus.ErrorLevel)
+
+	now := time.Date(2025, 1, 10, 12, 0, 0, 0, time.UTC)
+	store := storage.NewInMemoryStorage()
+	service := NewService(&config.Config{DataRetentionDays: 5}, store, logger)
+	service.now = func() time.Time { return now }
+
+	require.NoError(t, store.StoreTransformedData([]models.TransformedData{
+		{Date: "2025-01-01", Channel: "google_ads", CampaignID: "C-1001", Clicks: 100},
+		{Date: "2025-01-08", Channel: "google_ads", CampaignID: "C-1001", Clicks: 10},
+	}))
+	from, _ := time.Parse(dateLayout, "2025-01-01")
+	to, _ := time.Parse(dateLayout, "2025-01-10")
+
+	summary, err := service.GetMetricsSummary(from, to, "")
+	require.NoError(t, err)
+	assert.Equal(t, 110, summary.Clicks)
+
+	// Evicting the 1st behind the cache, as a retention-enabled store does
+	// on its next write, must not leave it cached
+	_, err = store.DeleteTransformedData(from, from, nil)
+	require.NoError(t, err)
+	_, err = service.IngestPayload(context.Background(), &models.ExternalResponse{External: models.ExternalData{
+		Ads: &models.AdsData{Performance: []models.AdsPerformance{
+			{Date: "2025-01-09", CampaignID: "C-1001", Channel: "google_ads", Clicks: 1},
+		}},
+	}}, "")
+	require.NoError(t, err)
+
+	summary, err = service.GetMetricsSummary(from, to, "")
+	require.NoError(t, err)
+	assert.Equal(t, 11, summary.Clicks)
+}
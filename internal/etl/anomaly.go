@@ -0,0 +1,97 @@
+package etl
+
+import (
+	"sort"
+	"strings"
+
+	"admira-etl/internal/models"
+
+	"github.com/sirupsen/logrus"
+)
+
+// anomalyMetrics are the derived metrics that can be given a sanity bound,
+// in the order anomalies are reported.
+var anomalyMetrics = []struct {
+	name  string
+	value func(models.TransformedData) float64
+}{
+	{"cpc", func(d models.TransformedData) float64 { return d.CPC }},
+	{"cpa", func(d models.TransformedData) float64 { return d.CPA }},
+	{"cvr_lead_to_opp", func(d models.TransformedData) float64 { return d.CVRLeadToOpp }},
+	{"cvr_opp_to_won", func(d models.TransformedData) float64 { return d.CVROppToWon }},
+	{"roas", func(d models.TransformedData) float64 { return d.ROAS }},
+	{"ctr", func(d models.TransformedData) float64 { return d.CTR }},
+	{"cpm", func(d models.TransformedData) float64 { return d.CPM }},
+}
+
+// newAnomalyBounds normalizes the configured metric bounds, warning about
+// and dropping metrics that can't be checked.
+func newAnomalyBounds(configured map[string]float64, logger *logrus.Logger) map[string]float64 {
+	known := make(map[string]bool, len(anomalyMetrics))
+	for _, metric := range anomalyMetrics {
+		known[metric.name] = true
+	}
+
+	bounds := make(map[string]float64, len(configured))
+	var unknown []string
+	for name, bound := range configured {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if !known[name] {
+			unknown = append(unknown, name)
+			continue
+		}
+		bounds[name] = bound
+	}
+	if len(unknown) > 0 {
+		sort.Strings(unknown)
+		logger.WithField("metrics", unknown).Warn("Ignoring anomaly bounds for unknown metrics")
+	}
+	return bounds
+}
+
+// flagAnomalies records on each row the derived metrics that exceed their
+// configured bound and logs a warning per flagged row. The rows are kept:
+// a glitch is surfaced rather than silently dropped or reported as is.
+func (s *Service) flagAnomalies(data []models.TransformedData) {
+	if len(s.anomalyBounds) == 0 {
+		return
+	}
+
+	for i := range data {
+		row := &data[i]
+		row.Anomalies = nil
+		for _, metric := range anomalyMetrics {
+			bound, ok := s.anomalyBounds[metric.name]
+			if ok && metric.value(*row) > bound {
+				row.Anomalies = append(row.Anomalies, metric.name)
+			}
+		}
+		if len(row.Anomalies) > 0 {
+			s.logger.WithFields(logrus.Fields{
+				"date":        row.Date,
+				"channel":     row.Channel,
+				"campaign_id": row.CampaignID,
+				"anomalies":   row.Anomalies,
+			}).Warn("Implausible metric values")
+		}
+	}
+}
+
+// mergeAnomalies returns the union of two rows' anomalies, keeping dst's
+// order. It never appends into dst's backing array, which may be shared
+// with a stored row.
+func mergeAnomalies(dst, src []string) []string {
+	for _, anomaly := range src {
+		found := false
+		for _, existing := range dst {
+			if existing == anomaly {
+				found = true
+				break
+			}
+		}
+		if !found {
+			dst = append(dst[:len(dst):len(dst)], anomaly)
+		}
+	}
+	return dst
+}
@@ -0,0 +1,75 @@
+package etl
+
+import (
+	"testing"
+	"time"
+
+	"admira-etl/internal/config"
+	"admira-etl/internal/models"
+	"admira-etl/internal/storage"
+
+	"github.com/sirupsen/logrus"
+	"github.com/sirupsen/logrus/hooks/test"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTransformData_FlagsAnomalies(t *testing.T) {
+	adsData := &models.AdsData{Performance: []models.AdsPerformance{
+		{Date: "2025-01-01", CampaignID: "C-1001", Channel: "google_ads", Clicks: 100, Cost: 50.0, UTMCampaign: "normal"},
+		// 1.00 of spend returning 10,000 is a ROAS of 10,000
+		{Date: "2025-01-01", CampaignID: "C-1002", Channel: "google_ads", Clicks: 100, Cost: 1.0, UTMCampaign: "glitch"},
+	}}
+	crmData := &models.CRMData{Opportunities: []models.Opportunity{
+		{OpportunityID: "O-1", Stage: "closed_won", Amount: 200.0, UTMCampaign: "normal"},
+		{OpportunityID: "O-2", Stage: "closed_won", Amount: 10000.0, UTMCampaign: "glitch"},
+	}}
+
+	t.Run("flagged over the bound", func(t *testing.T) {
+		logger, hook := test.NewNullLogger()
+		service := NewService(&config.Config{
+			AnomalyBounds: map[string]float64{"ROAS": 100, "cpc": 5, "unknown": 1},
+		}, storage.NewInMemoryStorage(), logger)
+
+		result, err := service.transformData(adsData, crmData, time.Time{}, time.Time{})
+		require.NoError(t, err)
+		require.Len(t, result, 2)
+
+		assert.Empty(t, result[0].Anomalies)
+		assert.InDelta(t, 10000.0, result[1].ROAS, 0.001)
+		assert.Equal(t, []string{"roas"}, result[1].Anomalies)
+
+		var warnings []*logrus.Entry
+		for _, entry := range hook.AllEntries() {
+			if entry.Level == logrus.WarnLevel && entry.Message == "Implausible metric values" {
+				warnings = append(warnings, entry)
+			}
+		}
+		require.Len(t, warnings, 1)
+		assert.Equal(t, "C-1002", warnings[0].Data["campaign_id"])
+		assert.Equal(t, []string{"roas"}, warnings[0].Data["anomalies"])
+	})
+
+	t.Run("no bounds configured", func(t *testing.T) {
+		logger, _ := test.NewNullLogger()
+		service := NewService(&config.Config{}, storage.NewInMemoryStorage(), logger)
+
+		result, err := service.transformData(adsData, crmData, time.Time{}, time.Time{})
+		require.NoError(t, err)
+		for _, row := range result {
+			assert.Empty(t, row.Anomalies)
+		}
+	})
+}
+
+func TestMergeAnomalies(t *testing.T) {
+	stored := make([]string, 1, 4)
+	stored[0] = "roas"
+
+	merged := mergeAnomalies(stored, []string{"cpc", "roas"})
+	assert.Equal(t, []string{"roas", "cpc"}, merged)
+	// The stored row's backing array is left alone
+	assert.Equal(t, "", stored[:2][1])
+
+	assert.Nil(t, mergeAnomalies(nil, nil))
+}
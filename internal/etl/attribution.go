@@ -0,0 +1,119 @@
+package etl
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"admira-etl/internal/models"
+)
+
+// AttributionModel controls how an opportunity matched by several ad rows is
+// credited across them.
+type AttributionModel string
+
+const (
+	// AttributionFull credits every matching ad row with the whole
+	// opportunity. Totals across rows over-count shared opportunities.
+	AttributionFull AttributionModel = "full"
+	// AttributionFirstTouch credits only the earliest matching ad row.
+	AttributionFirstTouch AttributionModel = "first_touch"
+	// AttributionLastTouch credits only the latest matching ad row.
+	AttributionLastTouch AttributionModel = "last_touch"
+	// AttributionLinear splits revenue evenly across the matching ad rows.
+	AttributionLinear AttributionModel = "linear"
+)
+
+// ParseAttributionModel converts a configuration value into an
+// AttributionModel.
+func ParseAttributionModel(value string) (AttributionModel, error) {
+	switch model := AttributionModel(strings.ToLower(strings.TrimSpace(value))); model {
+	case AttributionFull, AttributionFirstTouch, AttributionLastTouch, AttributionLinear:
+		return model, nil
+	case "":
+		return AttributionFull, nil
+	default:
+		return "", fmt.Errorf("unknown attribution model %q", value)
+	}
+}
+
+// Credit is the share of an opportunity attributed to one ad row.
+type Credit struct {
+	Opportunity models.Opportunity
+	// Share is the fraction of the opportunity's revenue credited.
+	Share float64
+	// Counted reports whether the row counts the opportunity (and its
+	// closed-won outcome) towards its totals. Counts are whole numbers, so
+	// under linear attribution only the first touch counts them.
+	Counted bool
+}
+
+// fullCredit credits every opportunity in full to a single row.
+func fullCredit(opportunities []models.Opportunity) []Credit {
+	credits := make([]Credit, 0, len(opportunities))
+	for _, opp := range opportunities {
+		credits = append(credits, Credit{Opportunity: opp, Share: 1, Counted: true})
+	}
+	return credits
+}
+
+// attribute distributes each row's matched opportunities according to the
+// configured AttributionModel. matches[i] holds the opportunities matched by
+// ads[i]; the result holds the credits for each row in the same order.
+// Touches are ordered by ad date, ties broken by input order. Opportunities
+// without an ID can't be told apart across rows and always get full credit.
+func (s *Service) attribute(ads []models.AdsPerformance, matches [][]models.Opportunity) [][]Credit {
+	credits := make([][]Credit, len(ads))
+	if s.attribution == AttributionFull {
+		// Rows are independent under full credit, so spread them across
+		// workers like the rest of the transform
+		forEachIndex(len(matches), s.concurrency, func(i int) {
+			credits[i] = fullCredit(matches[i])
+		})
+		return credits
+	}
+
+	// Collect the rows touching each opportunity, in first-seen order so
+	// the output doesn't depend on map iteration
+	var (
+		ids             []string
+		touches         = make(map[string][]int)
+		opportunityByID = make(map[string]models.Opportunity)
+	)
+	for i, opportunities := range matches {
+		for _, opp := range opportunities {
+			if opp.OpportunityID == "" {
+				credits[i] = append(credits[i], Credit{Opportunity: opp, Share: 1, Counted: true})
+				continue
+			}
+			if _, seen := touches[opp.OpportunityID]; !seen {
+				ids = append(ids, opp.OpportunityID)
+				opportunityByID[opp.OpportunityID] = opp
+			}
+			touches[opp.OpportunityID] = append(touches[opp.OpportunityID], i)
+		}
+	}
+
+	for _, id := range ids {
+		rows := touches[id]
+		sort.SliceStable(rows, func(a, b int) bool {
+			return ads[rows[a]].Date < ads[rows[b]].Date
+		})
+
+		opp := opportunityByID[id]
+		switch s.attribution {
+		case AttributionFirstTouch:
+			credits[rows[0]] = append(credits[rows[0]], Credit{Opportunity: opp, Share: 1, Counted: true})
+		case AttributionLastTouch:
+			last := rows[len(rows)-1]
+			credits[last] = append(credits[last], Credit{Opportunity: opp, Share: 1, Counted: true})
+		case AttributionLinear:
+			share := 1 / float64(len(rows))
+			for n, row := range rows {
+				credits[row] = append(credits[row], Credit{Opportunity: opp, Share: share, Counted: n == 0})
+			}
+		}
+	}
+
+	return credits
+}
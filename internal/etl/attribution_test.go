@@ -0,0 +1,78 @@
+package etl
+
+import (
+	"testing"
+	"time"
+
+	"admira-etl/internal/config"
+	"admira-etl/internal/models"
+	"admira-etl/internal/storage"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTransformData_Attribution(t *testing.T) {
+	logger := logrus.New()
+	logger.SetLevel(logrus.ErrorLevel)
+
+	// Two days of the same campaign share UTMs, so both rows match the deal
+	adsData := &models.AdsData{Performance: []models.AdsPerformance{
+		{
+			Date: "2025-01-02", CampaignID: "C-1001", Channel: "google_ads", Clicks: 100, Cost: 100.0,
+			UTMCampaign: "back_to_school", UTMSource: "google", UTMMedium: "cpc",
+		},
+		{
+			Date: "2025-01-01", CampaignID: "C-1001", Channel: "google_ads", Clicks: 100, Cost: 100.0,
+			UTMCampaign: "back_to_school", UTMSource: "google", UTMMedium: "cpc",
+		},
+	}}
+	crmData := &models.CRMData{Opportunities: []models.Opportunity{
+		{
+			OpportunityID: "O-9001", Stage: "closed_won", Amount: 5000.0,
+			UTMCampaign: "back_to_school", UTMSource: "google", UTMMedium: "cpc",
+		},
+	}}
+
+	// Expectations follow the output order, 2025-01-01 first
+	tests := []struct {
+		model     string
+		revenue   []float64
+		closedWon []int
+	}{
+		{"full", []float64{5000, 5000}, []int{1, 1}},
+		{"first_touch", []float64{5000, 0}, []int{1, 0}},
+		{"last_touch", []float64{0, 5000}, []int{0, 1}},
+		{"linear", []float64{2500, 2500}, []int{1, 0}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.model, func(t *testing.T) {
+			service := NewService(&config.Config{AttributionModel: tt.model}, storage.NewInMemoryStorage(), logger)
+
+			result, err := service.transformData(adsData, crmData, time.Time{}, time.Time{})
+			require.NoError(t, err)
+			require.Len(t, result, 2)
+
+			for i := range result {
+				assert.Equal(t, tt.revenue[i], result[i].Revenue, "revenue of %s", result[i].Date)
+				assert.Equal(t, tt.closedWon[i], result[i].ClosedWon, "closed won of %s", result[i].Date)
+				assert.InDelta(t, tt.revenue[i]/100.0, result[i].ROAS, 0.001)
+			}
+		})
+	}
+}
+
+func TestParseAttributionModel(t *testing.T) {
+	model, err := ParseAttributionModel(" Linear ")
+	require.NoError(t, err)
+	assert.Equal(t, AttributionLinear, model)
+
+	model, err = ParseAttributionModel("")
+	require.NoError(t, err)
+	assert.Equal(t, AttributionFull, model)
+
+	_, err = ParseAttributionModel("time_decay")
+	assert.Error(t, err)
+}
@@ -0,0 +1,121 @@
+package etl
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"admira-etl/internal/constants"
+	"admira-etl/internal/models"
+
+	"github.com/sirupsen/logrus"
+)
+
+// UnknownCurrencyPolicy controls what happens to opportunities in a
+// currency the conversion table doesn't cover.
+type UnknownCurrencyPolicy string
+
+const (
+	// UnknownCurrencyPassThrough counts the amount as if it were already in
+	// the base currency.
+	UnknownCurrencyPassThrough UnknownCurrencyPolicy = constants.UnknownCurrencyPassThrough
+	// UnknownCurrencySkip leaves the opportunity out of the transform.
+	UnknownCurrencySkip UnknownCurrencyPolicy = constants.UnknownCurrencySkip
+)
+
+// ParseUnknownCurrencyPolicy converts a configuration value into an
+// UnknownCurrencyPolicy.
+func ParseUnknownCurrencyPolicy(value string) (UnknownCurrencyPolicy, error) {
+	switch policy := UnknownCurrencyPolicy(strings.ToLower(strings.TrimSpace(value))); policy {
+	case UnknownCurrencyPassThrough, UnknownCurrencySkip:
+		return policy, nil
+	case "":
+		return UnknownCurrencyPassThrough, nil
+	default:
+		return "", fmt.Errorf("unknown currency policy %q", value)
+	}
+}
+
+// currencyConverter converts opportunity amounts into the base currency.
+// Rates are base-currency units per unit of each currency.
+type currencyConverter struct {
+	base    string
+	rates   map[string]float64
+	unknown UnknownCurrencyPolicy
+}
+
+func newCurrencyConverter(base string, rates map[string]float64, unknown UnknownCurrencyPolicy) *currencyConverter {
+	if base = normalizeCurrency(base); base == "" {
+		base = constants.DefaultBaseCurrency
+	}
+
+	normalized := make(map[string]float64, len(rates))
+	for code, rate := range rates {
+		normalized[normalizeCurrency(code)] = rate
+	}
+	return &currencyConverter{base: base, rates: normalized, unknown: unknown}
+}
+
+// normalizeCurrency uppercases and trims an ISO 4217 code.
+func normalizeCurrency(code string) string {
+	return strings.ToUpper(strings.TrimSpace(code))
+}
+
+// rate returns the conversion rate for code and whether it is known. An
+// empty code means the base currency.
+func (c *currencyConverter) rate(code string) (float64, bool) {
+	code = normalizeCurrency(code)
+	if code == "" || code == c.base {
+		return 1, true
+	}
+	rate, ok := c.rates[code]
+	return rate, ok
+}
+
+// toBase returns an opportunity's amount in the base currency. Amounts in
+// unknown currencies pass through unchanged.
+func (c *currencyConverter) toBase(opp models.Opportunity) float64 {
+	if rate, ok := c.rate(opp.Currency); ok {
+		return opp.Amount * rate
+	}
+	return opp.Amount
+}
+
+// checkCurrencies logs one warning per currency missing from the conversion
+// table and, when the policy is to skip them, drops those opportunities.
+func (s *Service) checkCurrencies(opportunities []models.Opportunity) []models.Opportunity {
+	kept := opportunities[:0:0]
+	unknown := make(map[string][]string)
+	for _, opp := range opportunities {
+		if _, ok := s.currency.rate(opp.Currency); !ok {
+			code := normalizeCurrency(opp.Currency)
+			unknown[code] = append(unknown[code], opp.OpportunityID)
+			if s.currency.unknown == UnknownCurrencySkip {
+				continue
+			}
+		}
+		kept = append(kept, opp)
+	}
+
+	message := "Unknown opportunity currency, counting amounts unconverted"
+	if s.currency.unknown == UnknownCurrencySkip {
+		message = "Unknown opportunity currency, skipping opportunities"
+	}
+
+	codes := make([]string, 0, len(unknown))
+	for code := range unknown {
+		codes = append(codes, code)
+	}
+	sort.Strings(codes)
+	for _, code := range codes {
+		ids := unknown[code]
+		s.logger.WithFields(logrus.Fields{
+			"currency":       code,
+			"base_currency":  s.currency.base,
+			"count":          len(ids),
+			"opportunity_id": ids[0],
+		}).Warn(message)
+	}
+
+	return kept
+}
@@ -0,0 +1,88 @@
+package etl
+
+import (
+	"testing"
+	"time"
+
+	"admira-etl/internal/config"
+	"admira-etl/internal/models"
+	"admira-etl/internal/storage"
+
+	"github.com/sirupsen/logrus"
+	"github.com/sirupsen/logrus/hooks/test"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func mixedCurrencyData() (*models.AdsData, *models.CRMData) {
+	adsData := &models.AdsData{Performance: []models.AdsPerformance{{
+		Date: "2025-01-01", CampaignID: "C-1001", Channel: "google_ads", Clicks: 100, Cost: 100.0,
+		UTMCampaign: "spring", UTMSource: "google", UTMMedium: "cpc",
+	}}}
+	crmData := &models.CRMData{Opportunities: []models.Opportunity{
+		{OpportunityID: "O-1", Stage: "closed_won", Amount: 1000.0, UTMCampaign: "spring", UTMSource: "google", UTMMedium: "cpc"},
+		{OpportunityID: "O-2", Stage: "closed_won", Amount: 1000.0, Currency: "eur", UTMCampaign: "spring", UTMSource: "google", UTMMedium: "cpc"},
+		{OpportunityID: "O-3", Stage: "closed_won", Amount: 1000.0, Currency: "GBP", UTMCampaign: "spring", UTMSource: "google", UTMMedium: "cpc"},
+		{OpportunityID: "O-4", Stage: "closed_won", Amount: 1000.0, Currency: "JPY", UTMCampaign: "spring", UTMSource: "google", UTMMedium: "cpc"},
+		{OpportunityID: "O-5", Stage: "closed_won", Amount: 1000.0, Currency: "USD", UTMCampaign: "spring", UTMSource: "google", UTMMedium: "cpc"},
+	}}
+	return adsData, crmData
+}
+
+func TestTransformData_ConvertsCurrencies(t *testing.T) {
+	logger, hook := test.NewNullLogger()
+	service := NewService(&config.Config{
+		BaseCurrency:  "USD",
+		CurrencyRates: map[string]float64{"EUR": 1.1, "gbp": 1.25},
+	}, storage.NewInMemoryStorage(), logger)
+
+	adsData, crmData := mixedCurrencyData()
+	result, err := service.transformData(adsData, crmData, time.Time{}, time.Time{})
+	require.NoError(t, err)
+	require.Len(t, result, 1)
+
+	// 1000 USD (implicit) + 1100 + 1250 + 1000 JPY passed through + 1000 USD
+	assert.InDelta(t, 5350.0, result[0].Revenue, 1e-9)
+	assert.InDelta(t, 53.5, result[0].ROAS, 1e-9)
+	assert.Equal(t, 5, result[0].ClosedWon)
+
+	require.Len(t, hook.AllEntries(), 1)
+	entry := hook.LastEntry()
+	assert.Equal(t, logrus.WarnLevel, entry.Level)
+	assert.Equal(t, "JPY", entry.Data["currency"])
+	assert.Equal(t, "O-4", entry.Data["opportunity_id"])
+}
+
+func TestTransformData_SkipsUnknownCurrencies(t *testing.T) {
+	logger, hook := test.NewNullLogger()
+	service := NewService(&config.Config{
+		CurrencyRates:   map[string]float64{"EUR": 1.1, "GBP": 1.25},
+		UnknownCurrency: "skip",
+	}, storage.NewInMemoryStorage(), logger)
+
+	adsData, crmData := mixedCurrencyData()
+	result, err := service.transformData(adsData, crmData, time.Time{}, time.Time{})
+	require.NoError(t, err)
+	require.Len(t, result, 1)
+
+	// The JPY opportunity is neither counted nor credited
+	assert.InDelta(t, 4350.0, result[0].Revenue, 1e-9)
+	assert.Equal(t, 4, result[0].Opportunities)
+	assert.Equal(t, 4, result[0].ClosedWon)
+
+	require.Len(t, hook.AllEntries(), 1)
+	assert.Contains(t, hook.LastEntry().Message, "skipping")
+}
+
+func TestParseUnknownCurrencyPolicy(t *testing.T) {
+	policy, err := ParseUnknownCurrencyPolicy("")
+	require.NoError(t, err)
+	assert.Equal(t, UnknownCurrencyPassThrough, policy)
+
+	policy, err = ParseUnknownCurrencyPolicy(" Skip ")
+	require.NoError(t, err)
+	assert.Equal(t, UnknownCurrencySkip, policy)
+
+	_, err = ParseUnknownCurrencyPolicy("convert")
+	assert.Error(t, err)
+}
@@ -0,0 +1,114 @@
+package etl
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"admira-etl/internal/models"
+)
+
+// ErrCursorNotAllowed is returned when a cursor is combined with an offset
+// or a sort other than the default date ascending.
+var ErrCursorNotAllowed = errors.New("cursor pagination requires the default sort and no offset")
+
+// Cursor marks the last row of a page by its (date, channel, campaign_id)
+// key and, as keys aren't unique, by Seq, how many rows sharing that key
+// came before it. Paging resumes at the first row sorting after that row,
+// so rows stored between page fetches don't shift later pages the way
+// offsets do: new rows sort after the stored rows sharing their key.
+type Cursor struct {
+	Date       string `json:"d"`
+	Channel    string `json:"c"`
+	CampaignID string `json:"k"`
+	Seq        int    `json:"n,omitempty"`
+}
+
+// Encode returns the opaque form of c handed to clients as next_cursor.
+func (c Cursor) Encode() string {
+	raw, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(raw)
+}
+
+// DecodeCursor parses a cursor previously returned by Encode.
+func DecodeCursor(value string) (Cursor, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil {
+		return Cursor{}, fmt.Errorf("invalid cursor: %w", err)
+	}
+
+	var c Cursor
+	if err := json.Unmarshal(raw, &c); err != nil {
+		return Cursor{}, fmt.Errorf("invalid cursor: %w", err)
+	}
+	if c.Date == "" {
+		return Cursor{}, errors.New("invalid cursor: missing date")
+	}
+	return c, nil
+}
+
+func cursorFor(item models.TransformedData) Cursor {
+	return Cursor{Date: item.Date, Channel: item.Channel, CampaignID: item.CampaignID}
+}
+
+// compareKey orders two rows by (date, channel, campaign_id), ignoring Seq.
+func compareKey(a, b Cursor) int {
+	switch {
+	case a.Date != b.Date:
+		return compareStrings(a.Date, b.Date)
+	case a.Channel != b.Channel:
+		return compareStrings(a.Channel, b.Channel)
+	default:
+		return compareStrings(a.CampaignID, b.CampaignID)
+	}
+}
+
+func compareStrings(a, b string) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	default:
+		return 0
+	}
+}
+
+// pageAfter returns up to limit rows of data, which must already be in key
+// order, that sort after the cursor (or from the start when after is nil).
+// The second result is the encoded cursor for the following page, or empty
+// once the last row has been returned.
+func pageAfter(data []models.TransformedData, after *Cursor, limit int) ([]models.TransformedData, string) {
+	start := 0
+	if after != nil {
+		// seq is how many rows sharing the cursor's key were skipped so far
+		seq := 0
+		for start < len(data) {
+			cmp := compareKey(cursorFor(data[start]), *after)
+			if cmp > 0 || (cmp == 0 && seq > after.Seq) {
+				break
+			}
+			if cmp == 0 {
+				seq++
+			}
+			start++
+		}
+	}
+
+	end := len(data)
+	if limit > 0 && start+limit < end {
+		end = start + limit
+	}
+
+	page := data[start:end]
+	if end == len(data) || len(page) == 0 {
+		return page, ""
+	}
+
+	next := cursorFor(data[end-1])
+	for i := end - 2; i >= 0 && compareKey(cursorFor(data[i]), next) == 0; i-- {
+		next.Seq++
+	}
+	return page, next.Encode()
+}
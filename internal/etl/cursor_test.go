@@ -0,0 +1,124 @@
+package etl
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"admira-etl/internal/config"
+	"admira-etl/internal/models"
+	"admira-etl/internal/storage"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCursorRoundTrip(t *testing.T) {
+	cursor := Cursor{Date: "2025-01-02", Channel: "google_ads", CampaignID: "C-1001", Seq: 2}
+
+	decoded, err := DecodeCursor(cursor.Encode())
+	require.NoError(t, err)
+	assert.Equal(t, cursor, decoded)
+
+	for _, value := range []string{"not base64!", "bm90IGpzb24", "e30"} {
+		_, err := DecodeCursor(value)
+		assert.Error(t, err, value)
+	}
+}
+
+func TestGetChannelMetrics_CursorStableWhileAppending(t *testing.T) {
+	logger := logrus.New()
+	logger.SetLevel(logrus.ErrorLevel)
+
+	store := storage.NewInMemoryStorage()
+	service := NewService(&config.Config{}, store, logger)
+
+	var original []models.TransformedData
+	for day := 1; day <= 9; day++ {
+		original = append(original, models.TransformedData{
+			Date: fmt.Sprintf("2025-01-%02d", day), Channel: "google_ads", CampaignID: "C-1001",
+		})
+	}
+	require.NoError(t, store.StoreTransformedData(original))
+
+	from, _ := time.Parse("2006-01-02", "2025-01-01")
+	to, _ := time.Parse("2006-01-02", "2025-01-31")
+
+	seen := map[string]int{}
+	var after *Cursor
+	for page := 0; ; page++ {
+		rows, next, err := service.GetChannelMetrics(ChannelMetricsQuery{
+			From: from, To: to, Channel: "google_ads", Limit: 2, After: after,
+		})
+		require.NoError(t, err)
+		for _, row := range rows {
+			seen[row.Date+"/"+row.CampaignID]++
+		}
+
+		// Rows land both before and after the current position between
+		// fetches; offsets would shift, cursors must not
+		require.NoError(t, store.StoreTransformedData([]models.TransformedData{
+			{Date: "2025-01-01", Channel: "google_ads", CampaignID: fmt.Sprintf("C-90%02d", page)},
+			{Date: "2025-01-31", Channel: "google_ads", CampaignID: fmt.Sprintf("C-90%02d", page)},
+		}))
+
+		if next == "" {
+			break
+		}
+		cursor, err := DecodeCursor(next)
+		require.NoError(t, err)
+		after = &cursor
+	}
+
+	for _, row := range original {
+		assert.Equal(t, 1, seen[row.Date+"/"+row.CampaignID], "row %s seen once", row.Date)
+	}
+	for key, count := range seen {
+		assert.Equal(t, 1, count, "row %s duplicated", key)
+	}
+}
+
+func TestPageAfter_SameKeyAcrossPageBoundary(t *testing.T) {
+	// Keys aren't unique: three rows share the first key, so pages of two
+	// end between them
+	data := []models.TransformedData{
+		{Date: "2025-01-01", Channel: "google_ads", CampaignID: "C-1001", Clicks: 1},
+		{Date: "2025-01-01", Channel: "google_ads", CampaignID: "C-1001", Clicks: 2},
+		{Date: "2025-01-01", Channel: "google_ads", CampaignID: "C-1001", Clicks: 3},
+		{Date: "2025-01-01", Channel: "google_ads", CampaignID: "C-1002", Clicks: 4},
+		{Date: "2025-01-02", Channel: "google_ads", CampaignID: "C-1001", Clicks: 5},
+	}
+
+	for _, limit := range []int{1, 2, 3} {
+		var clicks []int
+		var after *Cursor
+		for {
+			page, next := pageAfter(data, after, limit)
+			for _, row := range page {
+				clicks = append(clicks, row.Clicks)
+			}
+			if next == "" {
+				break
+			}
+			cursor, err := DecodeCursor(next)
+			require.NoError(t, err)
+			after = &cursor
+		}
+		assert.Equal(t, []int{1, 2, 3, 4, 5}, clicks, "limit %d", limit)
+	}
+}
+
+func TestGetChannelMetrics_CursorRequiresKeyOrder(t *testing.T) {
+	logger := logrus.New()
+	logger.SetLevel(logrus.ErrorLevel)
+
+	service := NewService(&config.Config{}, storage.NewInMemoryStorage(), logger)
+	after := &Cursor{Date: "2025-01-01"}
+
+	_, _, err := service.GetChannelMetrics(ChannelMetricsQuery{SortBy: SortByCost, After: after})
+	assert.ErrorIs(t, err, ErrCursorNotAllowed)
+
+	_, _, err = service.GetChannelMetrics(ChannelMetricsQuery{Offset: 5, After: after})
+	assert.ErrorIs(t, err, ErrCursorNotAllowed)
+}
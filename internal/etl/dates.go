@@ -0,0 +1,37 @@
+package etl
+
+import (
+	"fmt"
+	"time"
+)
+
+// dateLayout is the canonical YYYY-MM-DD form used throughout the service.
+const dateLayout = "2006-01-02"
+
+// acceptedDateLayouts are tried in order by parseFlexibleDate.
+var acceptedDateLayouts = []string{
+	dateLayout,
+	"2006/01/02",
+	time.RFC3339,
+}
+
+// parseFlexibleDate parses value with the first accepted layout that fits
+// and returns midnight UTC of that calendar date. RFC3339 timestamps keep the
+// date in their own offset; the time of day is dropped.
+func parseFlexibleDate(value string) (time.Time, error) {
+	for _, layout := range acceptedDateLayouts {
+		if t, err := time.Parse(layout, value); err == nil {
+			return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC), nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("unrecognised date %q, expected YYYY-MM-DD, YYYY/MM/DD or RFC3339", value)
+}
+
+// NormalizeDate converts any accepted date format to YYYY-MM-DD.
+func NormalizeDate(value string) (string, error) {
+	t, err := parseFlexibleDate(value)
+	if err != nil {
+		return "", err
+	}
+	return t.Format(dateLayout), nil
+}
@@ -0,0 +1,42 @@
+package etl
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseFlexibleDate(t *testing.T) {
+	expected := time.Date(2025, 1, 2, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name    string
+		value   string
+		wantErr bool
+	}{
+		{name: "canonical", value: "2025-01-02"},
+		{name: "slashes", value: "2025/01/02"},
+		{name: "RFC3339 UTC", value: "2025-01-02T15:04:05Z"},
+		{name: "RFC3339 keeps its own date", value: "2025-01-02T23:30:00-05:00"},
+		{name: "invalid", value: "02-01-2025", wantErr: true},
+		{name: "empty", value: "", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := parseFlexibleDate(tt.value)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.True(t, expected.Equal(result), "got %s", result)
+
+			normalized, err := NormalizeDate(tt.value)
+			require.NoError(t, err)
+			assert.Equal(t, "2025-01-02", normalized)
+		})
+	}
+}
@@ -0,0 +1,148 @@
+package etl
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"admira-etl/internal/logsample"
+	"admira-etl/internal/models"
+
+	"github.com/sirupsen/logrus"
+)
+
+// DeadLetter is a consolidated export record that failed to reach a sink
+// after all retries, kept so it can be inspected and replayed.
+type DeadLetter struct {
+	Sink          string                 `json:"sink"`
+	Record        models.TransformedData `json:"record"`
+	LastError     string                 `json:"last_error"`
+	Attempts      int                    `json:"attempts"`
+	FirstFailedAt time.Time              `json:"first_failed_at"`
+	LastFailedAt  time.Time              `json:"last_failed_at"`
+}
+
+// ReplayResult summarises a dead-letter replay.
+type ReplayResult struct {
+	Retried   int `json:"retried"`
+	Succeeded int `json:"succeeded"`
+	Remaining int `json:"remaining"`
+}
+
+// deadLetterQueue holds failed export records keyed by (sink, date, channel,
+// campaign), so a record failing again updates its entry instead of being
+// queued twice.
+type deadLetterQueue struct {
+	mu    sync.Mutex
+	items map[string]*DeadLetter
+	order []string
+}
+
+func newDeadLetterQueue() *deadLetterQueue {
+	return &deadLetterQueue{
+		items: make(map[string]*DeadLetter),
+	}
+}
+
+func deadLetterKey(sink string, record models.TransformedData) string {
+	return sink + "|" + record.Date + "|" + record.Channel + "|" + record.CampaignID
+}
+
+// add records a failed attempt to deliver record to sink.
+func (q *deadLetterQueue) add(sink string, record models.TransformedData, err error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	now := time.Now()
+	key := deadLetterKey(sink, record)
+	item, exists := q.items[key]
+	if !exists {
+		item = &DeadLetter{Sink: sink, FirstFailedAt: now}
+		q.items[key] = item
+		q.order = append(q.order, key)
+	}
+
+	item.Record = record
+	item.LastError = err.Error()
+	item.Attempts++
+	item.LastFailedAt = now
+}
+
+// remove drops record's entry for sink from the queue, if present.
+func (q *deadLetterQueue) remove(sink string, record models.TransformedData) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	key := deadLetterKey(sink, record)
+	if _, exists := q.items[key]; !exists {
+		return
+	}
+
+	delete(q.items, key)
+	for i, k := range q.order {
+		if k == key {
+			q.order = append(q.order[:i], q.order[i+1:]...)
+			break
+		}
+	}
+}
+
+// list returns copies of the queued items in the order they first failed.
+func (q *deadLetterQueue) list() []DeadLetter {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	items := make([]DeadLetter, 0, len(q.order))
+	for _, key := range q.order {
+		items = append(items, *q.items[key])
+	}
+	return items
+}
+
+// DeadLetters returns the export records waiting to be replayed.
+func (s *Service) DeadLetters() []DeadLetter {
+	return s.deadLetters.list()
+}
+
+// RetryDeadLetters re-sends every dead-lettered record to the sink it failed
+// on, removing those that now succeed and bumping the attempt count of those that fail again.
+func (s *Service) RetryDeadLetters(ctx context.Context) (*ReplayResult, error) {
+	if len(s.sinks) == 0 || s.config.SinkSecret == "" {
+		return nil, errSinkNotConfigured
+	}
+
+	sampler := logsample.New(s.logSampleRate)
+	ctx = logsample.WithSampler(ctx, sampler)
+	defer sampler.Flush(s.logger)
+
+	items := s.deadLetters.list()
+	result := &ReplayResult{Retried: len(items)}
+
+	for _, item := range items {
+		err := errSinkNotConfigured
+		if sink := s.sink(item.Sink); sink != nil {
+			err = sink.Send(ctx, SignedRecord{Record: item.Record, Signature: s.createHMACSignature(item.Record)})
+		}
+		if err != nil {
+			s.metrics.ExportRecords.WithLabelValues("failure").Inc()
+			s.logger.WithError(err).WithFields(logrus.Fields{
+				"sink":   item.Sink,
+				"record": item.Record,
+			}).Warn("Dead-letter replay failed")
+			s.deadLetters.add(item.Sink, item.Record, err)
+			continue
+		}
+		s.metrics.ExportRecords.WithLabelValues("success").Inc()
+		s.deadLetters.remove(item.Sink, item.Record)
+		result.Succeeded++
+	}
+
+	result.Remaining = len(s.deadLetters.list())
+	s.logger.WithFields(logrus.Fields{
+		"retried":   result.Retried,
+		"succeeded": result.Succeeded,
+		"remaining": result.Remaining,
+	}).Info("Dead-letter replay completed")
+
+	return result, nil
+}
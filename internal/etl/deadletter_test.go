@@ -0,0 +1,109 @@
+package etl
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"admira-etl/internal/config"
+	"admira-etl/internal/models"
+	"admira-etl/internal/storage"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDeadLetters_FailThenReplay(t *testing.T) {
+	logger := logrus.New()
+	logger.SetLevel(logrus.FatalLevel)
+
+	var sinkUp atomic.Bool
+	sink := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var record models.TransformedData
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&record))
+		if record.CampaignID == "C-1002" && !sinkUp.Load() {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer sink.Close()
+
+	store := storage.NewInMemoryStorage()
+	service := NewService(&config.Config{
+		SinkURL:    sink.URL,
+		SinkSecret: "secret",
+		MaxRetries: 0,
+		RetryDelay: time.Millisecond,
+	}, store, logger)
+
+	require.NoError(t, store.StoreTransformedData([]models.TransformedData{
+		{Date: "2025-01-01", Channel: "google_ads", CampaignID: "C-1001"},
+		{Date: "2025-01-01", Channel: "google_ads", CampaignID: "C-1002"},
+	}))
+
+	_, err := service.ExportData(context.Background(), "2025-01-01", false, ExportFilter{})
+	require.Error(t, err)
+
+	deadLetters := service.DeadLetters()
+	require.Len(t, deadLetters, 1)
+	assert.Equal(t, "C-1002", deadLetters[0].Record.CampaignID)
+	assert.Equal(t, 1, deadLetters[0].Attempts)
+	assert.Contains(t, deadLetters[0].LastError, "HTTP 503")
+
+	// A replay while the sink is still down keeps the record and counts the attempt
+	result, err := service.RetryDeadLetters(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, &ReplayResult{Retried: 1, Succeeded: 0, Remaining: 1}, result)
+
+	deadLetters = service.DeadLetters()
+	require.Len(t, deadLetters, 1)
+	assert.Equal(t, 2, deadLetters[0].Attempts)
+	assert.False(t, deadLetters[0].LastFailedAt.Before(deadLetters[0].FirstFailedAt))
+
+	// Once the sink recovers the replay drains the queue
+	sinkUp.Store(true)
+	result, err = service.RetryDeadLetters(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, &ReplayResult{Retried: 1, Succeeded: 1, Remaining: 0}, result)
+	assert.Empty(t, service.DeadLetters())
+}
+
+func TestDeadLetterQueue_DeduplicatesByRecordKey(t *testing.T) {
+	queue := newDeadLetterQueue()
+	record := models.TransformedData{Date: "2025-01-01", Channel: "google_ads", CampaignID: "C-1001"}
+	other := models.TransformedData{Date: "2025-01-02", Channel: "google_ads", CampaignID: "C-1001"}
+
+	queue.add("http://sink-a", record, assert.AnError)
+	queue.add("http://sink-a", other, assert.AnError)
+	queue.add("http://sink-a", record, assert.AnError)
+	queue.add("http://sink-b", record, assert.AnError)
+
+	items := queue.list()
+	require.Len(t, items, 3)
+	assert.Equal(t, "2025-01-01", items[0].Record.Date)
+	assert.Equal(t, 2, items[0].Attempts)
+	assert.Equal(t, 1, items[1].Attempts)
+	assert.Equal(t, "http://sink-b", items[2].Sink)
+
+	queue.remove("http://sink-a", record)
+	queue.remove("http://sink-a", record)
+	items = queue.list()
+	require.Len(t, items, 2)
+	assert.Equal(t, "2025-01-02", items[0].Record.Date)
+	assert.Equal(t, "http://sink-b", items[1].Sink)
+}
+
+func TestRetryDeadLetters_SinkNotConfigured(t *testing.T) {
+	logger := logrus.New()
+	logger.SetLevel(logrus.FatalLevel)
+
+	service := NewService(&config.Config{}, storage.NewInMemoryStorage(), logger)
+	_, err := service.RetryDeadLetters(context.Background())
+	assert.ErrorIs(t, err, errSinkNotConfigured)
+}
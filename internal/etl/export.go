@@ -0,0 +1,375 @@
+package etl
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"admira-etl/internal/jobs"
+	"admira-etl/internal/logsample"
+	"admira-etl/internal/models"
+
+	"github.com/sirupsen/logrus"
+)
+
+// SignatureHeader carries the hex-encoded HMAC-SHA256 of each exported
+// record, computed with the sink secret.
+const SignatureHeader = "X-Signature"
+
+// IdempotencyKeyHeader carries a key derived from each exported record's
+// date, channel and campaign, so sinks can drop repeated deliveries.
+const IdempotencyKeyHeader = "Idempotency-Key"
+
+var errSinkNotConfigured = errors.New("sink or secret not configured")
+
+// SignedRecord is a consolidated record together with the signature sent
+// alongside it in the SignatureHeader.
+type SignedRecord struct {
+	Record    models.TransformedData `json:"record"`
+	Signature string                 `json:"signature"`
+}
+
+// ExportSummary describes what an export sent, or with DryRun would have
+// sent. Records counts consolidated records; RecordsExported counts
+// successful (record, sink) deliveries, so with several sinks it can exceed
+// Records.
+type ExportSummary struct {
+	Date            string                 `json:"date"`
+	Channel         string                 `json:"channel,omitempty"`
+	CampaignID      string                 `json:"campaign_id,omitempty"`
+	DryRun          bool                   `json:"dry_run"`
+	Sinks           int                    `json:"sinks"`
+	Records         int                    `json:"records"`
+	RecordsExported int                    `json:"records_exported"`
+	TotalRevenue    float64                `json:"total_revenue"`
+	Channels        []ChannelExportSummary `json:"channels"`
+	Failed          []FailedRecord         `json:"records_failed"`
+
+	// Signed holds the consolidated records and their signatures.
+	Signed []SignedRecord `json:"-"`
+}
+
+// ExportFilter narrows an export to one channel, one campaign or both, for
+// example to re-sync a single integration. Empty fields match everything.
+type ExportFilter struct {
+	Channel    string
+	CampaignID string
+}
+
+// storageFilters returns the filter in the form storage queries take.
+func (f ExportFilter) storageFilters() map[string]string {
+	filters := map[string]string{}
+	if f.Channel != "" {
+		filters["channel"] = f.Channel
+	}
+	if f.CampaignID != "" {
+		filters["campaign_id"] = f.CampaignID
+	}
+	return filters
+}
+
+// ChannelExportSummary breaks an export down by channel.
+type ChannelExportSummary struct {
+	Channel string  `json:"channel"`
+	Records int     `json:"records"`
+	Revenue float64 `json:"revenue"`
+}
+
+// newExportSummary totals the consolidated records, which are already
+// ordered by channel.
+func newExportSummary(date string, filter ExportFilter, dryRun bool, sinks int, signed []SignedRecord) *ExportSummary {
+	summary := &ExportSummary{
+		Date:       date,
+		Channel:    filter.Channel,
+		CampaignID: filter.CampaignID,
+		DryRun:     dryRun,
+		Sinks:      sinks,
+		Records:    len(signed),
+		Channels:   []ChannelExportSummary{},
+		Failed:     []FailedRecord{},
+		Signed:     signed,
+	}
+
+	for _, item := range signed {
+		summary.TotalRevenue += item.Record.Revenue
+
+		last := len(summary.Channels) - 1
+		if last < 0 || summary.Channels[last].Channel != item.Record.Channel {
+			summary.Channels = append(summary.Channels, ChannelExportSummary{Channel: item.Record.Channel})
+			last++
+		}
+		summary.Channels[last].Records++
+		summary.Channels[last].Revenue += item.Record.Revenue
+	}
+	return summary
+}
+
+// ExportData sends the consolidated records for date, narrowed by filter,
+// to every configured sink. Each (record, sink) delivery is attempted
+// independently, so one failing sink doesn't hold back the others; with
+// SinkBulk each sink gets all the records in a single POST instead. With
+// dryRun the records are consolidated and signed but nothing is sent. The
+// returned summary is set whenever the records could be loaded, including
+// alongside an *ExportError when some deliveries failed.
+func (s *Service) ExportData(ctx context.Context, date string, dryRun bool, filter ExportFilter) (*ExportSummary, error) {
+	sinks := s.sinks
+	if len(sinks) == 0 || s.config.SinkSecret == "" {
+		return nil, errSinkNotConfigured
+	}
+
+	// Parse date
+	exportDate, err := parseFlexibleDate(date)
+	if err != nil {
+		return nil, fmt.Errorf("invalid date format: %w", err)
+	}
+
+	// Get data for the specific date, channel and campaign
+	data, err := s.storage.GetTransformedData(exportDate, exportDate, filter.storageFilters(), 0, 0)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get data for export: %w", err)
+	}
+
+	// Group data by channel and campaign for consolidation
+	consolidated := s.ConsolidateDataByChannelAndCampaign(data)
+
+	signed := make([]SignedRecord, 0, len(consolidated))
+	for _, record := range consolidated {
+		signed = append(signed, SignedRecord{Record: record, Signature: s.createHMACSignature(record)})
+	}
+	summary := newExportSummary(exportDate.Format(dateLayout), filter, dryRun, len(sinks), signed)
+
+	if dryRun {
+		s.logger.WithFields(logrus.Fields{
+			"sinks":   len(sinks),
+			"records": len(signed),
+		}).Info("Data export dry run completed")
+		return summary, nil
+	}
+
+	// Export the consolidated records to each sink, carrying on past
+	// failures so one bad delivery doesn't stop the rest. Failures are
+	// dead-lettered for later replay.
+	sampler := logsample.New(s.logSampleRate)
+	ctx = logsample.WithSampler(ctx, sampler)
+	defer sampler.Flush(s.logger)
+	if s.config.SinkBulk && len(consolidated) > 0 {
+		// One delivery per sink carries every record; a failed batch fails
+		// each of its records for that sink. Sinks that can't take a batch
+		// get the records one at a time.
+		batchSignature := s.BatchSignature(consolidated)
+		for _, sink := range sinks {
+			batchSink, ok := sink.(BatchSink)
+			if !ok {
+				s.exportEach(ctx, summary, sink, signed)
+				continue
+			}
+			err := batchSink.SendBatch(ctx, signed, batchSignature)
+			if err != nil {
+				s.logger.WithError(err).WithFields(logrus.Fields{
+					"sink":    sink.Name(),
+					"records": len(consolidated),
+				}).Error("Failed to export batch")
+			}
+			for _, record := range consolidated {
+				s.recordDelivery(summary, sink.Name(), record, err)
+			}
+		}
+	} else {
+		for _, item := range signed {
+			for _, sink := range sinks {
+				s.exportEach(ctx, summary, sink, []SignedRecord{item})
+			}
+		}
+	}
+
+	summary.RecordsExported = len(consolidated)*len(sinks) - len(summary.Failed)
+	s.logger.WithFields(logrus.Fields{
+		"sinks":            len(sinks),
+		"records_exported": summary.RecordsExported,
+		"records_failed":   len(summary.Failed),
+		"total_revenue":    summary.TotalRevenue,
+	}).Info("Data export completed")
+
+	if len(summary.Failed) > 0 {
+		return summary, &ExportError{Exported: summary.RecordsExported, Failed: summary.Failed}
+	}
+	return summary, nil
+}
+
+// ExportJobResult is the result recorded on an async export's job: how many
+// (record, sink) deliveries succeeded and failed, and the export summary.
+// Summary is nil when the export failed before any record was loaded.
+type ExportJobResult struct {
+	Exported int            `json:"exported"`
+	Failed   int            `json:"failed"`
+	Summary  *ExportSummary `json:"summary,omitempty"`
+}
+
+// ExportDataAsync starts ExportData in the background and returns the job
+// tracking it. Like RunIngestionAsync it is detached from ctx's
+// cancellation but keeps its values; only Shutdown cancels it. The job
+// fails if any delivery fails, and its result carries the counts either
+// way.
+func (s *Service) ExportDataAsync(ctx context.Context, date string, dryRun bool, filter ExportFilter) jobs.Job {
+	job := s.jobs.Create(JobTypeExport)
+
+	go func() {
+		s.jobs.Start(job.ID)
+
+		result, err := s.runExportJob(context.WithoutCancel(ctx), date, dryRun, filter)
+		if err != nil {
+			s.logger.WithError(err).WithField("job_id", job.ID).Error("Async export failed")
+		}
+		s.jobs.FinishWithResult(job.ID, result, err)
+	}()
+
+	return job
+}
+
+// runExportJob runs an export as tracked work, so Shutdown waits for it,
+// and condenses its outcome into an ExportJobResult.
+func (s *Service) runExportJob(ctx context.Context, date string, dryRun bool, filter ExportFilter) (*ExportJobResult, error) {
+	ctx, done, err := s.beginWork(ctx, fmt.Sprintf("export date=%q channel=%q campaign_id=%q", date, filter.Channel, filter.CampaignID))
+	if err != nil {
+		return nil, err
+	}
+	defer done()
+
+	summary, err := s.ExportData(ctx, date, dryRun, filter)
+	if summary == nil {
+		return nil, err
+	}
+	return &ExportJobResult{
+		Exported: summary.RecordsExported,
+		Failed:   len(summary.Failed),
+		Summary:  summary,
+	}, err
+}
+
+// FailedRecord identifies a consolidated record that could not be delivered
+// to a sink.
+type FailedRecord struct {
+	Sink       string
+	Channel    string
+	CampaignID string
+	Err        error
+}
+
+// MarshalJSON renders the delivery error as its message.
+func (f FailedRecord) MarshalJSON() ([]byte, error) {
+	message := ""
+	if f.Err != nil {
+		message = f.Err.Error()
+	}
+	return json.Marshal(struct {
+		Sink       string `json:"sink"`
+		Channel    string `json:"channel"`
+		CampaignID string `json:"campaign_id"`
+		Error      string `json:"error"`
+	}{f.Sink, f.Channel, f.CampaignID, message})
+}
+
+// ExportError reports a partially (or entirely) failed export: which
+// deliveries failed after exhausting retries and how many succeeded. With
+// several sinks, each (record, sink) pair counts as one delivery.
+type ExportError struct {
+	Exported int
+	Failed   []FailedRecord
+}
+
+func (e *ExportError) Error() string {
+	parts := make([]string, 0, len(e.Failed))
+	for _, f := range e.Failed {
+		parts = append(parts, fmt.Sprintf("%s/%s -> %s: %v", f.Channel, f.CampaignID, f.Sink, f.Err))
+	}
+	return fmt.Sprintf("failed to export %d of %d records: %s",
+		len(e.Failed), e.Exported+len(e.Failed), strings.Join(parts, "; "))
+}
+
+// recordDelivery accounts for one (record, sink) delivery: a failure is
+// counted, dead-lettered and added to the summary, a success clears any
+// earlier dead letter for the pair.
+func (s *Service) recordDelivery(summary *ExportSummary, sink string, record models.TransformedData, err error) {
+	if err != nil {
+		s.metrics.ExportRecords.WithLabelValues("failure").Inc()
+		s.deadLetters.add(sink, record, err)
+		summary.Failed = append(summary.Failed, FailedRecord{
+			Sink:       sink,
+			Channel:    record.Channel,
+			CampaignID: record.CampaignID,
+			Err:        err,
+		})
+		return
+	}
+	s.metrics.ExportRecords.WithLabelValues("success").Inc()
+	s.deadLetters.remove(sink, record)
+}
+
+// exportEach sends records to sink one at a time, accounting for each
+// delivery in summary.
+func (s *Service) exportEach(ctx context.Context, summary *ExportSummary, sink Sink, records []SignedRecord) {
+	for _, item := range records {
+		err := sink.Send(ctx, item)
+		if err != nil {
+			s.logger.WithError(err).WithFields(logrus.Fields{
+				"sink":   sink.Name(),
+				"record": item.Record,
+			}).Error("Failed to export record")
+		}
+		s.recordDelivery(summary, sink.Name(), item.Record, err)
+	}
+}
+
+// sink returns the configured sink called name, or nil.
+func (s *Service) sink(name string) Sink {
+	for _, sink := range s.sinks {
+		if sink.Name() == name {
+			return sink
+		}
+	}
+	return nil
+}
+
+// batchIdempotencyKey is the hex SHA-256 of the records' idempotency keys,
+// newline-separated, so re-exporting the same date and campaigns reuses it.
+func batchIdempotencyKey(records []models.TransformedData) string {
+	keys := make([]string, 0, len(records))
+	for _, record := range records {
+		keys = append(keys, idempotencyKey(record))
+	}
+	sum := sha256.Sum256([]byte(strings.Join(keys, "\n")))
+	return hex.EncodeToString(sum[:])
+}
+
+// idempotencyKey identifies a consolidated record by its date, channel and
+// campaign. It doesn't depend on the totals, so a record re-exported after
+// late data arrived keeps its key and the sink can treat it as an update.
+func idempotencyKey(record models.TransformedData) string {
+	sum := sha256.Sum256([]byte(record.Date + "|" + record.Channel + "|" + record.CampaignID))
+	return hex.EncodeToString(sum[:])
+}
+
+// createHMACSignature signs the record's fields, pipe-separated in a fixed
+// order, so sinks can verify the payload came from us.
+func (s *Service) createHMACSignature(data models.TransformedData) string {
+	return s.signPayload(signaturePayload(data))
+}
+
+func signaturePayload(data models.TransformedData) string {
+	return fmt.Sprintf("%s|%s|%s|%d|%d|%.2f|%d|%d|%d|%.2f|%.3f|%.3f|%.3f|%.3f|%.3f",
+		data.Date, data.Channel, data.CampaignID, data.Clicks, data.Impressions,
+		data.Cost, data.Leads, data.Opportunities, data.ClosedWon, data.Revenue,
+		data.CPC, data.CPA, data.CVRLeadToOpp, data.CVROppToWon, data.ROAS)
+}
+
+// signPayload returns the hex HMAC-SHA256 of payload under the sink secret.
+func (s *Service) signPayload(payload string) string {
+	mac := hmac.New(sha256.New, []byte(s.config.SinkSecret))
+	mac.Write([]byte(payload))
+	return hex.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,397 @@
+package etl
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"admira-etl/internal/config"
+	"admira-etl/internal/jobs"
+	"admira-etl/internal/models"
+	"admira-etl/internal/storage"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// recordingSink is an httptest sink that remembers the campaign IDs it
+// accepted and can be told to reject everything.
+type recordingSink struct {
+	*httptest.Server
+
+	mu       sync.Mutex
+	received []string
+	failing  atomic.Bool
+}
+
+func newRecordingSink(t *testing.T, secret string, failing bool) *recordingSink {
+	sink := &recordingSink{}
+	sink.failing.Store(failing)
+	sink.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var record models.TransformedData
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&record))
+
+		service := &Service{config: &config.Config{SinkSecret: secret}}
+		expected := service.createHMACSignature(record)
+		assert.True(t, hmac.Equal([]byte(expected), []byte(r.Header.Get(SignatureHeader))))
+
+		if sink.failing.Load() {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+
+		sink.mu.Lock()
+		sink.received = append(sink.received, record.CampaignID)
+		sink.mu.Unlock()
+		w.WriteHeader(http.StatusOK)
+	}))
+	t.Cleanup(sink.Close)
+	return sink
+}
+
+func newExportService(t *testing.T, cfg *config.Config) (*Service, storage.Storage) {
+	logger := logrus.New()
+	logger.SetLevel(logrus.FatalLevel)
+
+	cfg.SinkSecret = "secret"
+	cfg.RetryDelay = time.Millisecond
+
+	store := storage.NewInMemoryStorage()
+	require.NoError(t, store.StoreTransformedData([]models.TransformedData{
+		{Date: "2025-01-01", Channel: "google_ads", CampaignID: "C-1001", Clicks: 100},
+		{Date: "2025-01-01", Channel: "google_ads", CampaignID: "C-1002", Clicks: 200},
+	}))
+
+	return NewService(cfg, store, logger), store
+}
+
+func TestExportData_FansOutToEverySink(t *testing.T) {
+	first := newRecordingSink(t, "secret", false)
+	second := newRecordingSink(t, "secret", false)
+
+	service, _ := newExportService(t, &config.Config{
+		SinkURLs: []string{first.URL, second.URL},
+	})
+
+	_, err := service.ExportData(context.Background(), "2025-01-01", false, ExportFilter{})
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{"C-1001", "C-1002"}, first.received)
+	assert.Equal(t, []string{"C-1001", "C-1002"}, second.received)
+}
+
+func TestExportData_DryRun(t *testing.T) {
+	sink := newRecordingSink(t, "secret", false)
+
+	service, _ := newExportService(t, &config.Config{SinkURLs: []string{sink.URL}})
+
+	summary, err := service.ExportData(context.Background(), "2025-01-01", true, ExportFilter{})
+	require.NoError(t, err)
+	assert.Empty(t, sink.received)
+	assert.True(t, summary.DryRun)
+	assert.Zero(t, summary.RecordsExported)
+
+	// The signatures are the ones a real export would send
+	require.Len(t, summary.Signed, 2)
+	for _, item := range summary.Signed {
+		assert.Equal(t, service.createHMACSignature(item.Record), item.Signature)
+	}
+	assert.Equal(t, "C-1001", summary.Signed[0].Record.CampaignID)
+	assert.Equal(t, "C-1002", summary.Signed[1].Record.CampaignID)
+}
+
+func TestExportData_Summary(t *testing.T) {
+	first := newRecordingSink(t, "secret", false)
+	second := newRecordingSink(t, "secret", false)
+
+	service, store := newExportService(t, &config.Config{SinkURLs: []string{first.URL, second.URL}})
+	require.NoError(t, store.StoreTransformedData([]models.TransformedData{
+		{Date: "2025-01-01", Channel: "google_ads", CampaignID: "C-1001", Clicks: 50, Revenue: 300.0},
+		{Date: "2025-01-01", Channel: "facebook_ads", CampaignID: "C-2001", Clicks: 80, Revenue: 1000.0},
+		{Date: "2025-01-02", Channel: "facebook_ads", CampaignID: "C-2001", Clicks: 80, Revenue: 5000.0},
+	}))
+
+	summary, err := service.ExportData(context.Background(), "2025-01-01", false, ExportFilter{})
+	require.NoError(t, err)
+
+	// C-1001's two rows consolidate into one; the next day is left out
+	assert.Equal(t, "2025-01-01", summary.Date)
+	assert.False(t, summary.DryRun)
+	assert.Equal(t, 2, summary.Sinks)
+	assert.Equal(t, 3, summary.Records)
+	assert.Equal(t, 6, summary.RecordsExported)
+	assert.Equal(t, 1300.0, summary.TotalRevenue)
+	assert.Equal(t, []ChannelExportSummary{
+		{Channel: "facebook_ads", Records: 1, Revenue: 1000.0},
+		{Channel: "google_ads", Records: 2, Revenue: 300.0},
+	}, summary.Channels)
+	assert.Empty(t, summary.Failed)
+}
+
+func TestExportData_Filter(t *testing.T) {
+	sink := newRecordingSink(t, "secret", false)
+
+	service, store := newExportService(t, &config.Config{SinkURLs: []string{sink.URL}})
+	require.NoError(t, store.StoreTransformedData([]models.TransformedData{
+		{Date: "2025-01-01", Channel: "facebook_ads", CampaignID: "C-2001", Clicks: 80},
+		{Date: "2025-01-01", Channel: "facebook_ads", CampaignID: "C-2002", Clicks: 40},
+	}))
+
+	tests := []struct {
+		name     string
+		filter   ExportFilter
+		expected []string
+	}{
+		{"channel", ExportFilter{Channel: "facebook_ads"}, []string{"C-2001", "C-2002"}},
+		{"campaign", ExportFilter{CampaignID: "C-1002"}, []string{"C-1002"}},
+		{"channel and campaign", ExportFilter{Channel: "facebook_ads", CampaignID: "C-2002"}, []string{"C-2002"}},
+		{"no match", ExportFilter{Channel: "google_ads", CampaignID: "C-2001"}, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sink.mu.Lock()
+			sink.received = nil
+			sink.mu.Unlock()
+
+			summary, err := service.ExportData(context.Background(), "2025-01-01", false, tt.filter)
+			require.NoError(t, err)
+
+			assert.Equal(t, tt.expected, sink.received)
+			assert.Equal(t, len(tt.expected), summary.Records)
+			assert.Equal(t, tt.filter.Channel, summary.Channel)
+			assert.Equal(t, tt.filter.CampaignID, summary.CampaignID)
+		})
+	}
+}
+
+func TestExportData_IdempotencyKeyStableAcrossRuns(t *testing.T) {
+	var mu sync.Mutex
+	keys := map[string][]string{}
+	sink := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var record models.TransformedData
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&record))
+
+		mu.Lock()
+		keys[record.CampaignID] = append(keys[record.CampaignID], r.Header.Get(IdempotencyKeyHeader))
+		mu.Unlock()
+		w.WriteHeader(http.StatusOK)
+	}))
+	t.Cleanup(sink.Close)
+
+	service, _ := newExportService(t, &config.Config{SinkURLs: []string{sink.URL}})
+
+	for i := 0; i < 2; i++ {
+		_, err := service.ExportData(context.Background(), "2025-01-01", false, ExportFilter{})
+		require.NoError(t, err)
+	}
+
+	require.Len(t, keys["C-1001"], 2)
+	require.Len(t, keys["C-1002"], 2)
+	assert.NotEmpty(t, keys["C-1001"][0])
+	assert.Equal(t, keys["C-1001"][0], keys["C-1001"][1])
+	assert.Equal(t, keys["C-1002"][0], keys["C-1002"][1])
+	assert.NotEqual(t, keys["C-1001"][0], keys["C-1002"][0])
+}
+
+func TestExportData_ReportsFailuresPerSink(t *testing.T) {
+	healthy := newRecordingSink(t, "secret", false)
+	broken := newRecordingSink(t, "secret", true)
+
+	service, _ := newExportService(t, &config.Config{
+		SinkURLs: []string{healthy.URL, broken.URL},
+	})
+
+	summary, err := service.ExportData(context.Background(), "2025-01-01", false, ExportFilter{})
+
+	var exportErr *ExportError
+	require.ErrorAs(t, err, &exportErr)
+	assert.Equal(t, 2, exportErr.Exported)
+	require.Len(t, exportErr.Failed, 2)
+	for _, f := range exportErr.Failed {
+		assert.Equal(t, broken.URL, f.Sink)
+	}
+
+	// The summary still describes the partial export
+	require.NotNil(t, summary)
+	assert.Equal(t, 2, summary.RecordsExported)
+	assert.Equal(t, exportErr.Failed, summary.Failed)
+
+	// The healthy sink still got everything
+	assert.Equal(t, []string{"C-1001", "C-1002"}, healthy.received)
+
+	// Only the broken sink's deliveries are queued for replay
+	deadLetters := service.DeadLetters()
+	require.Len(t, deadLetters, 2)
+	assert.Equal(t, broken.URL, deadLetters[0].Sink)
+
+	// Replay targets the sink that failed, not every sink
+	broken.failing.Store(false)
+	result, err := service.RetryDeadLetters(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, &ReplayResult{Retried: 2, Succeeded: 2, Remaining: 0}, result)
+	assert.Equal(t, []string{"C-1001", "C-1002"}, healthy.received)
+	assert.Equal(t, []string{"C-1001", "C-1002"}, broken.received)
+}
+
+func TestExportData_Bulk(t *testing.T) {
+	var mu sync.Mutex
+	var batches [][]models.TransformedData
+	var signatures, keys []string
+	failing := atomic.Bool{}
+	sink := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var records []models.TransformedData
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&records))
+
+		mu.Lock()
+		batches = append(batches, records)
+		signatures = append(signatures, r.Header.Get(SignatureHeader))
+		keys = append(keys, r.Header.Get(IdempotencyKeyHeader))
+		mu.Unlock()
+
+		if failing.Load() {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	t.Cleanup(sink.Close)
+
+	service, _ := newExportService(t, &config.Config{SinkURLs: []string{sink.URL}, SinkBulk: true})
+
+	summary, err := service.ExportData(context.Background(), "2025-01-01", false, ExportFilter{})
+	require.NoError(t, err)
+	assert.Equal(t, 2, summary.RecordsExported)
+
+	// One POST carrying both records, signed as a batch
+	require.Len(t, batches, 1)
+	require.Len(t, batches[0], 2)
+	assert.Equal(t, "C-1001", batches[0][0].CampaignID)
+	assert.Equal(t, "C-1002", batches[0][1].CampaignID)
+
+	verifier := &Service{config: &config.Config{SinkSecret: "secret"}}
+	assert.True(t, hmac.Equal([]byte(verifier.BatchSignature(batches[0])), []byte(signatures[0])))
+	assert.NotEqual(t, verifier.createHMACSignature(batches[0][0]), signatures[0])
+
+	// The batch is accepted by the webhook that verifies batch signatures
+	receiver := NewService(&config.Config{SinkSecret: "secret"}, storage.NewInMemoryStorage(), service.logger)
+	stored, err := receiver.IngestSigned(context.Background(), batches[0], signatures[0])
+	require.NoError(t, err)
+	assert.Equal(t, 2, stored)
+
+	t.Run("failed batch fails every record", func(t *testing.T) {
+		failing.Store(true)
+		defer failing.Store(false)
+
+		summary, err := service.ExportData(context.Background(), "2025-01-01", false, ExportFilter{})
+		var exportErr *ExportError
+		require.ErrorAs(t, err, &exportErr)
+		assert.Equal(t, 0, summary.RecordsExported)
+		assert.Len(t, summary.Failed, 2)
+		assert.Len(t, service.DeadLetters(), 2)
+		assert.Equal(t, keys[0], keys[len(keys)-1], "idempotency key is stable across runs")
+	})
+}
+
+func TestExportData_SinkTimeout(t *testing.T) {
+	slow := func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-time.After(300 * time.Millisecond):
+		case <-r.Context().Done():
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"external": {"ads": {"performance": []}}}`))
+	}
+	sink := httptest.NewServer(http.HandlerFunc(slow))
+	t.Cleanup(sink.Close)
+	upstream := httptest.NewServer(http.HandlerFunc(slow))
+	t.Cleanup(upstream.Close)
+
+	service, _ := newExportService(t, &config.Config{
+		SinkURLs:    []string{sink.URL},
+		AdsAPIURL:   upstream.URL,
+		HTTPTimeout: 2 * time.Second,
+		SinkTimeout: 50 * time.Millisecond,
+	})
+
+	start := time.Now()
+	_, err := service.ExportData(context.Background(), "2025-01-01", false, ExportFilter{})
+	var exportErr *ExportError
+	require.ErrorAs(t, err, &exportErr)
+	assert.Len(t, exportErr.Failed, 2)
+	assert.Less(t, time.Since(start), 300*time.Millisecond, "sink requests should give up after SINK_TIMEOUT")
+
+	// The same slowness is fine for fetches, which keep HTTPTimeout
+	ads, err := service.fetchAdsData(context.Background())
+	require.NoError(t, err)
+	assert.Empty(t, ads.Performance)
+}
+
+func TestCreateHMACSignature(t *testing.T) {
+	service := &Service{config: &config.Config{SinkSecret: "secret"}}
+	record := models.TransformedData{Date: "2025-01-01", Channel: "google_ads", CampaignID: "C-1001", Clicks: 100, Cost: 12.5}
+
+	mac := hmac.New(sha256.New, []byte("secret"))
+	mac.Write([]byte("2025-01-01|google_ads|C-1001|100|0|12.50|0|0|0|0.00|0.000|0.000|0.000|0.000|0.000"))
+	assert.Equal(t, hex.EncodeToString(mac.Sum(nil)), service.createHMACSignature(record))
+
+	other := &Service{config: &config.Config{SinkSecret: "other"}}
+	assert.NotEqual(t, service.createHMACSignature(record), other.createHMACSignature(record))
+}
+
+func TestExportDataAsync(t *testing.T) {
+	healthy := newRecordingSink(t, "secret", false)
+	broken := newRecordingSink(t, "secret", true)
+
+	service, _ := newExportService(t, &config.Config{SinkURLs: []string{healthy.URL, broken.URL}})
+
+	finished := func(id string) jobs.Job {
+		var job jobs.Job
+		require.Eventually(t, func() bool {
+			job, _ = service.GetJob(id)
+			return job.FinishedAt != nil
+		}, 2*time.Second, 10*time.Millisecond)
+		return job
+	}
+
+	// One sink rejects everything: the job fails but still reports counts
+	job := service.ExportDataAsync(context.Background(), "2025-01-01", false, ExportFilter{})
+	assert.Equal(t, JobTypeExport, job.Type)
+
+	job = finished(job.ID)
+	assert.Equal(t, jobs.StatusFailed, job.Status)
+	assert.Contains(t, job.Error, "failed to export 2 of 4 records")
+	result, ok := job.Result.(*ExportJobResult)
+	require.True(t, ok)
+	assert.Equal(t, 2, result.Exported)
+	assert.Equal(t, 2, result.Failed)
+	assert.Equal(t, 2, result.Summary.Records)
+	assert.Equal(t, []string{"C-1001", "C-1002"}, healthy.received)
+
+	broken.failing.Store(false)
+	job = finished(service.ExportDataAsync(context.Background(), "2025-01-01", false, ExportFilter{}).ID)
+	assert.Equal(t, jobs.StatusSucceeded, job.Status)
+	result = job.Result.(*ExportJobResult)
+	assert.Equal(t, 4, result.Exported)
+	assert.Zero(t, result.Failed)
+
+	// Without sinks nothing is loaded, so there is no result
+	unconfigured, _ := newExportService(t, &config.Config{})
+	job = unconfigured.ExportDataAsync(context.Background(), "2025-01-01", false, ExportFilter{})
+	require.Eventually(t, func() bool {
+		job, _ = unconfigured.GetJob(job.ID)
+		return job.FinishedAt != nil
+	}, 2*time.Second, 10*time.Millisecond)
+	assert.Equal(t, jobs.StatusFailed, job.Status)
+	assert.Nil(t, job.Result)
+}
@@ -0,0 +1,113 @@
+package etl
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// FileSink appends each record as a line of JSON to a file, for local
+// testing and pipelines that pick exports up from disk. Once the file would
+// grow past maxBytes it is renamed aside with a timestamp suffix and a new
+// one started, so a reader never sees a file cut short.
+type FileSink struct {
+	mu       sync.Mutex
+	path     string
+	maxBytes int64
+	now      func() time.Time
+}
+
+// NewFileSink creates a sink appending to path, rotating it at maxBytes; 0
+// never rotates.
+func NewFileSink(path string, maxBytes int64) *FileSink {
+	return &FileSink{path: path, maxBytes: maxBytes, now: time.Now}
+}
+
+func (f *FileSink) Name() string {
+	return "file:" + f.path
+}
+
+func (f *FileSink) Send(_ context.Context, record SignedRecord) error {
+	return f.append([]SignedRecord{record})
+}
+
+// SendBatch appends every record in one write; the batch signature isn't
+// kept.
+func (f *FileSink) SendBatch(_ context.Context, records []SignedRecord, _ string) error {
+	return f.append(records)
+}
+
+// append writes records as NDJSON lines with a single write, so a failed
+// export never leaves half a batch behind.
+func (f *FileSink) append(records []SignedRecord) error {
+	var lines bytes.Buffer
+	encoder := json.NewEncoder(&lines)
+	for _, record := range records {
+		if err := encoder.Encode(record.Record); err != nil {
+			return fmt.Errorf("failed to encode record: %w", err)
+		}
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if err := os.MkdirAll(filepath.Dir(f.path), 0o755); err != nil {
+		return fmt.Errorf("failed to create sink directory: %w", err)
+	}
+	if err := f.rotateIfFull(int64(lines.Len())); err != nil {
+		return err
+	}
+
+	file, err := os.OpenFile(f.path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0o644)
+	if err != nil {
+		return fmt.Errorf("failed to open sink file: %w", err)
+	}
+	if _, err := file.Write(lines.Bytes()); err != nil {
+		file.Close()
+		return fmt.Errorf("failed to write sink file: %w", err)
+	}
+	if err := file.Close(); err != nil {
+		return fmt.Errorf("failed to close sink file: %w", err)
+	}
+	return nil
+}
+
+// rotateIfFull renames the current file to <path>.<UTC timestamp> when
+// adding pending bytes would take it past maxBytes. An empty file is never
+// rotated, so a single oversized write still lands somewhere.
+func (f *FileSink) rotateIfFull(pending int64) error {
+	if f.maxBytes <= 0 {
+		return nil
+	}
+
+	info, err := os.Stat(f.path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to stat sink file: %w", err)
+	}
+	if info.Size() == 0 || info.Size()+pending <= f.maxBytes {
+		return nil
+	}
+
+	// Rename replaces its target, so never reuse the name of an earlier
+	// rotation made within the same clock tick
+	stamp := f.now().UTC().Format("20060102T150405.000000000Z")
+	rotated := f.path + "." + stamp
+	for i := 1; ; i++ {
+		if _, err := os.Stat(rotated); os.IsNotExist(err) {
+			break
+		}
+		rotated = fmt.Sprintf("%s.%s-%d", f.path, stamp, i)
+	}
+	if err := os.Rename(f.path, rotated); err != nil {
+		return fmt.Errorf("failed to rotate sink file: %w", err)
+	}
+	return nil
+}
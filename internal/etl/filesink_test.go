@@ -0,0 +1,100 @@
+package etl
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"admira-etl/internal/config"
+	"admira-etl/internal/constants"
+	"admira-etl/internal/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// readNDJSON decodes every line of path as a TransformedData.
+func readNDJSON(t *testing.T, path string) []models.TransformedData {
+	t.Helper()
+	file, err := os.Open(path)
+	require.NoError(t, err)
+	defer file.Close()
+
+	var records []models.TransformedData
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		var record models.TransformedData
+		require.NoError(t, json.Unmarshal(scanner.Bytes(), &record), scanner.Text())
+		records = append(records, record)
+	}
+	require.NoError(t, scanner.Err())
+	return records
+}
+
+func TestExportData_FileSink(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "exports", "metrics.ndjson")
+	service, store := newExportService(t, &config.Config{
+		SinkType:     constants.SinkTypeFile,
+		SinkFilePath: path,
+	})
+	require.Len(t, service.sinks, 1)
+	assert.Equal(t, "file:"+path, service.sinks[0].Name())
+
+	summary, err := service.ExportData(context.Background(), "2025-01-01", false, ExportFilter{})
+	require.NoError(t, err)
+	assert.Equal(t, 2, summary.RecordsExported)
+
+	records := readNDJSON(t, path)
+	require.Len(t, records, 2)
+	assert.Equal(t, summary.Signed[0].Record, records[0])
+	assert.Equal(t, summary.Signed[1].Record, records[1])
+	assert.Equal(t, "C-1001", records[0].CampaignID)
+	assert.Equal(t, 200, records[1].Clicks)
+
+	// Later exports append
+	require.NoError(t, store.StoreTransformedData([]models.TransformedData{
+		{Date: "2025-01-02", Channel: "facebook_ads", CampaignID: "C-2001", Clicks: 5},
+	}))
+	_, err = service.ExportData(context.Background(), "2025-01-02", false, ExportFilter{})
+	require.NoError(t, err)
+
+	records = readNDJSON(t, path)
+	require.Len(t, records, 3)
+	assert.Equal(t, "C-2001", records[2].CampaignID)
+}
+
+func TestFileSink_Rotation(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "metrics.ndjson")
+
+	record := SignedRecord{Record: models.TransformedData{Date: "2025-01-01", Channel: "google_ads", CampaignID: "C-1001"}}
+	line, err := json.Marshal(record.Record)
+	require.NoError(t, err)
+
+	// Room for two lines; the third starts a new file
+	sink := NewFileSink(path, int64(2*(len(line)+1)))
+	sink.now = func() time.Time { return time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC) }
+
+	for i := 0; i < 3; i++ {
+		require.NoError(t, sink.Send(context.Background(), record))
+	}
+
+	rotated := path + ".20250101T120000.000000000Z"
+	assert.Len(t, readNDJSON(t, rotated), 2)
+	assert.Len(t, readNDJSON(t, path), 1)
+
+	// A batch bigger than the cap still lands whole, in a fresh file. The
+	// clock hasn't moved, so the rotation gets a distinct name
+	require.NoError(t, sink.SendBatch(context.Background(), []SignedRecord{record, record, record}, ""))
+	assert.Len(t, readNDJSON(t, rotated+"-1"), 1)
+	assert.Len(t, readNDJSON(t, path), 3)
+	assert.Len(t, readNDJSON(t, rotated), 2)
+
+	entries, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	assert.Len(t, entries, 3)
+}
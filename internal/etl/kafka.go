@@ -0,0 +1,102 @@
+package etl
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"admira-etl/internal/models"
+
+	"github.com/segmentio/kafka-go"
+)
+
+// messageWriter is the part of kafka.Writer KafkaSink uses, so tests can
+// swap in a fake producer.
+type messageWriter interface {
+	WriteMessages(ctx context.Context, messages ...kafka.Message) error
+	Close() error
+}
+
+// KafkaSink produces each record as a JSON message to a topic. Messages are
+// keyed by channel and campaign, so a campaign's records land on the same
+// partition in order, and carry the signature and idempotency key as
+// headers named like their HTTP counterparts.
+type KafkaSink struct {
+	topic  string
+	writer messageWriter
+}
+
+// NewKafkaSink creates a sink producing to topic on brokers. Each write
+// waits for every in-sync replica and gives up after timeout; no
+// connection is made until the first write.
+func NewKafkaSink(brokers []string, topic string, timeout time.Duration) *KafkaSink {
+	return &KafkaSink{
+		topic: topic,
+		writer: &kafka.Writer{
+			Addr:         kafka.TCP(brokers...),
+			Topic:        topic,
+			Balancer:     &kafka.Hash{},
+			RequiredAcks: kafka.RequireAll,
+			WriteTimeout: timeout,
+		},
+	}
+}
+
+func (k *KafkaSink) Name() string {
+	return "kafka:" + k.topic
+}
+
+func (k *KafkaSink) Send(ctx context.Context, record SignedRecord) error {
+	message, err := kafkaMessage(record)
+	if err != nil {
+		return err
+	}
+	return k.write(ctx, message)
+}
+
+// SendBatch produces every record in a single write. Each message keeps its
+// own signature, so the batch signature isn't needed.
+func (k *KafkaSink) SendBatch(ctx context.Context, records []SignedRecord, _ string) error {
+	messages := make([]kafka.Message, 0, len(records))
+	for _, record := range records {
+		message, err := kafkaMessage(record)
+		if err != nil {
+			return err
+		}
+		messages = append(messages, message)
+	}
+	return k.write(ctx, messages...)
+}
+
+func (k *KafkaSink) write(ctx context.Context, messages ...kafka.Message) error {
+	if err := k.writer.WriteMessages(ctx, messages...); err != nil {
+		return fmt.Errorf("failed to produce to kafka topic %s: %w", k.topic, err)
+	}
+	return nil
+}
+
+// Close flushes and closes the producer.
+func (k *KafkaSink) Close() error {
+	return k.writer.Close()
+}
+
+func kafkaMessage(record SignedRecord) (kafka.Message, error) {
+	value, err := json.Marshal(record.Record)
+	if err != nil {
+		return kafka.Message{}, fmt.Errorf("failed to encode record: %w", err)
+	}
+	return kafka.Message{
+		Key:   []byte(kafkaKey(record.Record)),
+		Value: value,
+		Headers: []kafka.Header{
+			{Key: SignatureHeader, Value: []byte(record.Signature)},
+			{Key: IdempotencyKeyHeader, Value: []byte(idempotencyKey(record.Record))},
+		},
+	}, nil
+}
+
+// kafkaKey is the message key: the record's channel and campaign.
+func kafkaKey(record models.TransformedData) string {
+	return record.Channel + "|" + record.CampaignID
+}
@@ -0,0 +1,136 @@
+package etl
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"admira-etl/internal/config"
+	"admira-etl/internal/constants"
+	"admira-etl/internal/models"
+
+	"github.com/segmentio/kafka-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeProducer records the messages of each write and can be told to fail.
+type fakeProducer struct {
+	mu      sync.Mutex
+	writes  [][]kafka.Message
+	failing bool
+	closed  bool
+}
+
+func (p *fakeProducer) WriteMessages(_ context.Context, messages ...kafka.Message) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.failing {
+		return errors.New("broker unavailable")
+	}
+	p.writes = append(p.writes, messages)
+	return nil
+}
+
+func (p *fakeProducer) Close() error {
+	p.closed = true
+	return nil
+}
+
+func (p *fakeProducer) messages() []kafka.Message {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	var all []kafka.Message
+	for _, write := range p.writes {
+		all = append(all, write...)
+	}
+	return all
+}
+
+func newKafkaExportService(t *testing.T, bulk bool) (*Service, *fakeProducer) {
+	service, _ := newExportService(t, &config.Config{
+		SinkType:     constants.SinkTypeKafka,
+		KafkaBrokers: []string{"kafka-1:9092"},
+		KafkaTopic:   "admira-metrics",
+		SinkBulk:     bulk,
+	})
+	require.Len(t, service.sinks, 1)
+	assert.Equal(t, "kafka:admira-metrics", service.sinks[0].Name())
+
+	producer := &fakeProducer{}
+	service.sinks[0].(*KafkaSink).writer = producer
+	return service, producer
+}
+
+func header(message kafka.Message, key string) string {
+	for _, h := range message.Headers {
+		if h.Key == key {
+			return string(h.Value)
+		}
+	}
+	return ""
+}
+
+func TestExportData_Kafka(t *testing.T) {
+	service, producer := newKafkaExportService(t, false)
+
+	summary, err := service.ExportData(context.Background(), "2025-01-01", false, ExportFilter{})
+	require.NoError(t, err)
+	assert.Equal(t, 2, summary.RecordsExported)
+
+	// One message per record, keyed by channel and campaign
+	messages := producer.messages()
+	require.Len(t, messages, 2)
+	assert.Len(t, producer.writes, 2)
+	for i, campaign := range []string{"C-1001", "C-1002"} {
+		assert.Equal(t, "google_ads|"+campaign, string(messages[i].Key))
+
+		var record models.TransformedData
+		require.NoError(t, json.Unmarshal(messages[i].Value, &record))
+		assert.Equal(t, campaign, record.CampaignID)
+		assert.Equal(t, "2025-01-01", record.Date)
+		assert.Equal(t, service.createHMACSignature(record), header(messages[i], SignatureHeader))
+		assert.Equal(t, idempotencyKey(record), header(messages[i], IdempotencyKeyHeader))
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	require.NoError(t, service.Shutdown(ctx))
+	assert.True(t, producer.closed)
+}
+
+func TestExportData_KafkaBulk(t *testing.T) {
+	service, producer := newKafkaExportService(t, true)
+
+	_, err := service.ExportData(context.Background(), "2025-01-01", false, ExportFilter{})
+	require.NoError(t, err)
+
+	// Every record goes out in a single write
+	require.Len(t, producer.writes, 1)
+	assert.Len(t, producer.writes[0], 2)
+}
+
+func TestExportData_KafkaFailureIsDeadLettered(t *testing.T) {
+	service, producer := newKafkaExportService(t, false)
+	producer.failing = true
+
+	summary, err := service.ExportData(context.Background(), "2025-01-01", false, ExportFilter{})
+	var exportErr *ExportError
+	require.ErrorAs(t, err, &exportErr)
+	assert.Len(t, summary.Failed, 2)
+	assert.Equal(t, "kafka:admira-metrics", summary.Failed[0].Sink)
+
+	letters := service.DeadLetters()
+	require.Len(t, letters, 2)
+	assert.Equal(t, "kafka:admira-metrics", letters[0].Sink)
+
+	// Replay goes back through the same sink
+	producer.failing = false
+	result, err := service.RetryDeadLetters(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, 2, result.Succeeded)
+	assert.Len(t, producer.messages(), 2)
+}
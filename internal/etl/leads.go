@@ -0,0 +1,61 @@
+package etl
+
+import (
+	"fmt"
+	"strings"
+
+	"admira-etl/internal/constants"
+	"admira-etl/internal/models"
+)
+
+// LeadSource controls where an ad row's lead count comes from.
+type LeadSource string
+
+const (
+	// LeadSourceEstimate assumes a fixed share of clicks become leads.
+	LeadSourceEstimate LeadSource = constants.LeadSourceEstimate
+	// LeadSourceCRM counts matched opportunities in the lead stage, falling
+	// back to the estimate for rows that match none.
+	LeadSourceCRM LeadSource = constants.LeadSourceCRM
+)
+
+// leadEstimateRate is the share of clicks assumed to become leads when the
+// CRM has no lead records to count, for channels without a configured rate.
+const leadEstimateRate = 0.1
+
+// ParseLeadSource converts a configuration value into a LeadSource.
+func ParseLeadSource(value string) (LeadSource, error) {
+	switch source := LeadSource(strings.ToLower(strings.TrimSpace(value))); source {
+	case LeadSourceEstimate, LeadSourceCRM:
+		return source, nil
+	case "":
+		return LeadSourceEstimate, nil
+	default:
+		return "", fmt.Errorf("unknown lead source %q", value)
+	}
+}
+
+// isLead reports whether an opportunity is still in the lead stage.
+func isLead(opp models.Opportunity) bool {
+	return normalizeStage(opp.Stage) == constants.StageLead
+}
+
+// newLeadRates builds the channel-to-rate table used by estimateLeads from
+// the configured rates, normalizing channel names.
+func newLeadRates(configured map[string]float64) map[string]float64 {
+	rates := make(map[string]float64, len(configured))
+	for channel, rate := range configured {
+		rates[strings.ToLower(strings.TrimSpace(channel))] = rate
+	}
+	return rates
+}
+
+// estimateLeads is the click-based lead estimate, at the channel's configured
+// rate or leadEstimateRate for channels without one.
+func (s *Service) estimateLeads(ad models.AdsPerformance) int {
+	rate, ok := s.leadRates[strings.ToLower(strings.TrimSpace(ad.Channel))]
+	if !ok {
+		rate = leadEstimateRate
+	}
+	return int(float64(ad.Clicks) * rate)
+}
@@ -0,0 +1,113 @@
+package etl
+
+import (
+	"testing"
+	"time"
+
+	"admira-etl/internal/config"
+	"admira-etl/internal/models"
+	"admira-etl/internal/storage"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseLeadSource(t *testing.T) {
+	source, err := ParseLeadSource(" CRM ")
+	require.NoError(t, err)
+	assert.Equal(t, LeadSourceCRM, source)
+
+	source, err = ParseLeadSource("")
+	require.NoError(t, err)
+	assert.Equal(t, LeadSourceEstimate, source)
+
+	_, err = ParseLeadSource("forms")
+	assert.Error(t, err)
+}
+
+func TestTransformData_LeadSource(t *testing.T) {
+	logger := logrus.New()
+	logger.SetLevel(logrus.ErrorLevel)
+
+	// Only the first campaign has lead-stage records in the CRM
+	adsData := &models.AdsData{Performance: []models.AdsPerformance{
+		{
+			Date: "2025-01-01", CampaignID: "C-1001", Channel: "google_ads", Clicks: 1000, Cost: 300.0,
+			UTMCampaign: "back_to_school", UTMSource: "google", UTMMedium: "cpc",
+		},
+		{
+			Date: "2025-01-01", CampaignID: "C-2001", Channel: "facebook_ads", Clicks: 500, Cost: 100.0,
+			UTMCampaign: "winter_sale", UTMSource: "facebook", UTMMedium: "cpc",
+		},
+	}}
+	crmData := &models.CRMData{Opportunities: []models.Opportunity{
+		{OpportunityID: "L-1", Stage: "lead", UTMCampaign: "back_to_school", UTMSource: "google", UTMMedium: "cpc"},
+		{OpportunityID: "L-2", Stage: "Lead", UTMCampaign: "back_to_school", UTMSource: "google", UTMMedium: "cpc"},
+		{OpportunityID: "L-3", Stage: "lead", UTMCampaign: "back_to_school", UTMSource: "google", UTMMedium: "cpc"},
+		{OpportunityID: "O-1", Stage: "closed_won", Amount: 900.0, UTMCampaign: "back_to_school", UTMSource: "google", UTMMedium: "cpc"},
+		{OpportunityID: "O-2", Stage: "proposal", Amount: 400.0, UTMCampaign: "winter_sale", UTMSource: "facebook", UTMMedium: "cpc"},
+	}}
+
+	// Expectations follow the output order, facebook_ads first
+	tests := []struct {
+		source        string
+		leads         []int
+		opportunities []int
+	}{
+		// The estimate ignores lead records, which count as opportunities
+		{source: "estimate", leads: []int{50, 100}, opportunities: []int{1, 4}},
+		// CRM leads replace the estimate where there are any
+		{source: "crm", leads: []int{50, 3}, opportunities: []int{1, 1}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.source, func(t *testing.T) {
+			service := NewService(&config.Config{LeadSource: tt.source}, storage.NewInMemoryStorage(), logger)
+
+			result, err := service.transformData(adsData, crmData, time.Time{}, time.Time{})
+			require.NoError(t, err)
+			require.Len(t, result, 2)
+
+			for i, row := range result {
+				assert.Equal(t, tt.leads[i], row.Leads, "leads for %s", row.CampaignID)
+				assert.Equal(t, tt.opportunities[i], row.Opportunities, "opportunities for %s", row.CampaignID)
+			}
+
+			// Ratios follow whichever lead count was used
+			assert.InDelta(t, 300.0/float64(tt.leads[1]), result[1].CPA, 0.001)
+			assert.InDelta(t, float64(tt.opportunities[1])/float64(tt.leads[1]), result[1].CVRLeadToOpp, 0.001)
+			assert.Equal(t, 900.0, result[1].Revenue)
+		})
+	}
+}
+
+func TestTransformData_LeadRates(t *testing.T) {
+	logger := logrus.New()
+	logger.SetLevel(logrus.ErrorLevel)
+
+	adsData := &models.AdsData{Performance: []models.AdsPerformance{
+		{Date: "2025-01-01", CampaignID: "C-1001", Channel: "google_ads", Clicks: 1000, Cost: 600.0},
+		{Date: "2025-01-01", CampaignID: "C-2001", Channel: "facebook_ads", Clicks: 1000, Cost: 400.0},
+		{Date: "2025-01-01", CampaignID: "C-3001", Channel: "tiktok_ads", Clicks: 1000, Cost: 100.0},
+	}}
+
+	// Channel names are matched case-insensitively; tiktok_ads has no rate
+	service := NewService(&config.Config{
+		LeadRates: map[string]float64{"Google_Ads": 0.12, "facebook_ads": 0.08},
+	}, storage.NewInMemoryStorage(), logger)
+
+	result, err := service.transformData(adsData, &models.CRMData{}, time.Time{}, time.Time{})
+	require.NoError(t, err)
+	require.Len(t, result, 3)
+
+	leads := make(map[string]int, len(result))
+	for _, row := range result {
+		leads[row.Channel] = row.Leads
+	}
+	assert.Equal(t, map[string]int{"google_ads": 120, "facebook_ads": 80, "tiktok_ads": 100}, leads)
+
+	// CPA follows the channel's estimate
+	require.Equal(t, "google_ads", result[1].Channel)
+	assert.InDelta(t, 5.0, result[1].CPA, 0.001)
+}
@@ -0,0 +1,97 @@
+package etl
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+)
+
+var errShuttingDown = errors.New("service is shutting down")
+
+// beginWork registers a unit of ETL work so Shutdown can wait for it. The
+// returned context is cancelled when either ctx is or the service starts
+// shutting down; done must be called once the work has finished.
+func (s *Service) beginWork(ctx context.Context, description string) (context.Context, func(), error) {
+	s.workMu.Lock()
+	defer s.workMu.Unlock()
+
+	if s.shuttingDown {
+		return nil, nil, errShuttingDown
+	}
+
+	s.workSeq++
+	id := s.workSeq
+	s.inflight[id] = description
+	s.work.Add(1)
+
+	ctx, cancel := context.WithCancel(ctx)
+	stop := context.AfterFunc(s.lifetime, cancel)
+
+	done := func() {
+		stop()
+		cancel()
+
+		s.workMu.Lock()
+		delete(s.inflight, id)
+		s.workMu.Unlock()
+
+		s.work.Done()
+	}
+	return ctx, done, nil
+}
+
+// Shutdown stops the service from accepting new ETL work, cancels whatever
+// is in flight and waits for it to unwind, up to ctx's deadline. Work that
+// is cancelled returns before storing anything, so no partial run is kept.
+func (s *Service) Shutdown(ctx context.Context) error {
+	s.workMu.Lock()
+	s.shuttingDown = true
+	inflight := make([]string, 0, len(s.inflight))
+	for _, description := range s.inflight {
+		inflight = append(inflight, description)
+	}
+	s.workMu.Unlock()
+
+	if len(inflight) > 0 {
+		sort.Strings(inflight)
+		s.logger.WithField("in_progress", inflight).Warn("Cancelling in-flight ETL work")
+	}
+	s.cancelWork()
+
+	// Services of other namespaces are shut down too; none can be created
+	// once the lifetime is cancelled
+	for _, child := range s.namespaceServices() {
+		if err := child.Shutdown(ctx); err != nil {
+			return err
+		}
+	}
+
+	drained := make(chan struct{})
+	go func() {
+		s.work.Wait()
+		close(drained)
+	}()
+
+	select {
+	case <-drained:
+		s.logger.Info("ETL work drained")
+		s.closeSinks()
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("timed out waiting for ETL work to stop: %w", ctx.Err())
+	}
+}
+
+// closeSinks releases sinks holding connections, such as Kafka producers,
+// once no more exports can run.
+func (s *Service) closeSinks() {
+	for _, sink := range s.sinks {
+		if closer, ok := sink.(io.Closer); ok {
+			if err := closer.Close(); err != nil {
+				s.logger.WithError(err).WithField("sink", sink.Name()).Warn("Failed to close sink")
+			}
+		}
+	}
+}
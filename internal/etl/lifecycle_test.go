@@ -0,0 +1,82 @@
+package etl
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"admira-etl/internal/config"
+	"admira-etl/internal/storage"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestShutdown_CancelsSlowIngestion(t *testing.T) {
+	logger := logrus.New()
+	logger.SetLevel(logrus.FatalLevel)
+
+	// The ads API hangs until the request is abandoned
+	requested := make(chan struct{})
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		close(requested)
+		<-r.Context().Done()
+	}))
+	defer upstream.Close()
+
+	store := storage.NewInMemoryStorage()
+	service := NewService(&config.Config{
+		AdsAPIURL:   upstream.URL,
+		CRMAPIURL:   upstream.URL,
+		HTTPTimeout: time.Minute,
+	}, store, logger)
+
+	result := make(chan error, 1)
+	go func() {
+		result <- service.RunIngestion(context.Background(), IngestOptions{})
+	}()
+	<-requested
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	require.NoError(t, service.Shutdown(ctx))
+
+	// Shutdown only returns once the ingestion has unwound
+	select {
+	case err := <-result:
+		assert.ErrorIs(t, err, context.Canceled)
+	default:
+		t.Fatal("ingestion still running after Shutdown returned")
+	}
+
+	// Nothing from the cancelled run was stored
+	data, err := store.GetTransformedData(time.Time{}, time.Now(), map[string]string{}, 0, 0)
+	require.NoError(t, err)
+	assert.Empty(t, data)
+
+	lastIngestion, err := store.GetLastIngestionTime()
+	require.NoError(t, err)
+	assert.True(t, lastIngestion.IsZero())
+
+	// New work is refused once shutting down
+	assert.ErrorIs(t, service.RunIngestion(context.Background(), IngestOptions{}), errShuttingDown)
+}
+
+func TestShutdown_TimesOut(t *testing.T) {
+	logger := logrus.New()
+	logger.SetLevel(logrus.FatalLevel)
+
+	service := NewService(&config.Config{}, storage.NewInMemoryStorage(), logger)
+
+	// Work that ignores cancellation holds up the drain
+	_, done, err := service.beginWork(context.Background(), "stuck")
+	require.NoError(t, err)
+	defer done()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	assert.ErrorIs(t, service.Shutdown(ctx), context.DeadlineExceeded)
+}
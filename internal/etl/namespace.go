@@ -0,0 +1,98 @@
+package etl
+
+import (
+	"errors"
+	"fmt"
+
+	"admira-etl/internal/constants"
+	"admira-etl/internal/storage"
+	"admira-etl/internal/telemetry"
+)
+
+// ErrNamespacesDisabled is returned when another namespace is requested
+// from a service that was not given any with SetNamespaces.
+var ErrNamespacesDisabled = errors.New("storage namespaces are not enabled")
+
+// ErrNamespaceNotAllowed is returned for a namespace missing from the
+// configured Namespaces.
+var ErrNamespaceNotAllowed = errors.New("namespace not allowed")
+
+// SetNamespaces lets Namespace open services on other storage namespaces.
+func (s *Service) SetNamespaces(namespaces *storage.Namespaces) {
+	s.namespaceMu.Lock()
+	defer s.namespaceMu.Unlock()
+	s.namespaces = namespaces
+}
+
+// Namespace returns the service working on the named storage namespace.
+// An empty name or s's own namespace returns s. The default namespace and
+// those listed in the configured Namespaces get a service of their own,
+// created on first use, that shares s's configuration but has separate
+// storage, jobs and dead letters, and whose metrics are kept out of
+// /metrics so a test run can't skew production figures. They have no
+// export sinks, so their data never reaches the production destinations.
+func (s *Service) Namespace(name string) (*Service, error) {
+	if name == "" || name == s.namespace {
+		return s, nil
+	}
+	if err := storage.ValidateNamespace(name); err != nil {
+		return nil, err
+	}
+
+	s.namespaceMu.Lock()
+	defer s.namespaceMu.Unlock()
+
+	if child, ok := s.children[name]; ok {
+		return child, nil
+	}
+	if s.namespaces == nil {
+		return nil, ErrNamespacesDisabled
+	}
+	if !s.namespaceAllowed(name) {
+		return nil, fmt.Errorf("%w: %q", ErrNamespaceNotAllowed, name)
+	}
+	if s.lifetime.Err() != nil {
+		return nil, errShuttingDown
+	}
+
+	store, err := s.namespaces.Get(name)
+	if err != nil {
+		return nil, err
+	}
+
+	// Without sink URLs the child builds no sinks
+	childConfig := *s.config
+	childConfig.SinkType = constants.SinkTypeHTTP
+	childConfig.SinkURL, childConfig.SinkURLs = "", nil
+
+	child := NewService(&childConfig, store, s.logger)
+	child.namespace = name
+	child.metrics = telemetry.NewETLMetrics()
+	s.children[name] = child
+	return child, nil
+}
+
+// namespaceAllowed reports whether requests may select name.
+func (s *Service) namespaceAllowed(name string) bool {
+	if name == storage.DefaultNamespace {
+		return true
+	}
+	for _, allowed := range s.config.Namespaces {
+		if name == allowed {
+			return true
+		}
+	}
+	return false
+}
+
+// namespaceServices returns the services Namespace has created so far.
+func (s *Service) namespaceServices() []*Service {
+	s.namespaceMu.Lock()
+	defer s.namespaceMu.Unlock()
+
+	children := make([]*Service, 0, len(s.children))
+	for _, child := range s.children {
+		children = append(children, child)
+	}
+	return children
+}
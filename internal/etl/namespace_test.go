@@ -0,0 +1,85 @@
+package etl
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"admira-etl/internal/config"
+	"admira-etl/internal/models"
+	"admira-etl/internal/storage"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNamespace(t *testing.T) {
+	logger := logrus.New()
+	logger.SetLevel(logrus.FatalLevel)
+
+	store := storage.NewInMemoryStorage()
+	service := NewService(&config.Config{
+		Namespace:  "prod",
+		Namespaces: []string{"staging", "preview"},
+		SinkURL:    "http://sink.invalid",
+		SinkSecret: "secret",
+	}, store, logger)
+
+	// Without a registry only the service's own namespace is available
+	_, err := service.Namespace("staging")
+	assert.ErrorIs(t, err, ErrNamespacesDisabled)
+
+	service.SetNamespaces(storage.NewNamespaces(store, func(string) (storage.Storage, error) {
+		return storage.NewInMemoryStorage(), nil
+	}))
+
+	for _, name := range []string{"", "prod"} {
+		own, err := service.Namespace(name)
+		require.NoError(t, err)
+		assert.Same(t, service, own)
+	}
+
+	staging, err := service.Namespace("staging")
+	require.NoError(t, err)
+	assert.NotSame(t, service, staging)
+	again, err := service.Namespace("staging")
+	require.NoError(t, err)
+	assert.Same(t, staging, again)
+
+	_, err = service.Namespace("Not Valid")
+	assert.ErrorIs(t, err, storage.ErrInvalidNamespace)
+
+	// Only the default namespace and the configured ones can be opened
+	_, err = service.Namespace("unlisted")
+	assert.ErrorIs(t, err, ErrNamespaceNotAllowed)
+	byDefault, err := service.Namespace(storage.DefaultNamespace)
+	require.NoError(t, err)
+	assert.NotSame(t, service, byDefault)
+
+	// Exports from another namespace never reach the production sinks
+	assert.Len(t, service.sinks, 1)
+	assert.Empty(t, staging.sinks)
+	_, err = staging.ExportData(context.Background(), "2025-01-01", false, ExportFilter{})
+	assert.ErrorIs(t, err, errSinkNotConfigured)
+
+	// Rows stored in one namespace are invisible to the other
+	require.NoError(t, staging.storage.StoreTransformedData([]models.TransformedData{
+		{Date: "2025-01-01", Channel: "google_ads", CampaignID: "C-1001", Clicks: 10},
+	}))
+	from, _ := time.Parse("2006-01-02", "2025-01-01")
+	summary, err := service.GetMetricsSummary(from, from, "")
+	require.NoError(t, err)
+	assert.Equal(t, 0, summary.Records)
+	summary, err = staging.GetMetricsSummary(from, from, "")
+	require.NoError(t, err)
+	assert.Equal(t, 1, summary.Records)
+
+	// Shutdown covers the namespace services and stops new ones
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	require.NoError(t, service.Shutdown(ctx))
+	assert.ErrorIs(t, staging.RunIngestion(context.Background(), IngestOptions{}), errShuttingDown)
+	_, err = service.Namespace("preview")
+	assert.ErrorIs(t, err, errShuttingDown)
+}
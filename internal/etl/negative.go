@@ -0,0 +1,91 @@
+package etl
+
+import (
+	"fmt"
+	"strings"
+
+	"admira-etl/internal/constants"
+	"admira-etl/internal/models"
+
+	"github.com/sirupsen/logrus"
+)
+
+// NegativeValuePolicy controls what happens to negative clicks,
+// impressions, cost and opportunity amounts, which would otherwise produce
+// meaningless CPC, CPA and ROAS values.
+type NegativeValuePolicy string
+
+const (
+	// NegativeValuesReject treats a negative value as a validation problem:
+	// the record is skipped or fails the run according to the validation
+	// mode, and a pushed payload is refused.
+	NegativeValuesReject NegativeValuePolicy = constants.NegativeValuesReject
+	// NegativeValuesClamp replaces negative values with zero and keeps the
+	// record, e.g. for refund adjustments reported as negative cost.
+	NegativeValuesClamp NegativeValuePolicy = constants.NegativeValuesClamp
+)
+
+// ParseNegativeValuePolicy converts a configuration value into a
+// NegativeValuePolicy.
+func ParseNegativeValuePolicy(value string) (NegativeValuePolicy, error) {
+	switch policy := NegativeValuePolicy(strings.ToLower(strings.TrimSpace(value))); policy {
+	case NegativeValuesReject, NegativeValuesClamp:
+		return policy, nil
+	case "":
+		return NegativeValuesReject, nil
+	default:
+		return "", fmt.Errorf("unknown negative value policy %q", value)
+	}
+}
+
+// clampNegatives zeroes the negative values in adsData and crmData when the
+// policy is clamp, logging each anomaly. Under the reject policy it leaves
+// the data for validation to catch.
+func (s *Service) clampNegatives(adsData *models.AdsData, crmData *models.CRMData) {
+	if s.negativeValues != NegativeValuesClamp {
+		return
+	}
+
+	warn := func(fields logrus.Fields) {
+		s.logger.WithFields(fields).Warn("Clamping negative value to zero")
+	}
+
+	if adsData != nil {
+		for i := range adsData.Performance {
+			ad := &adsData.Performance[i]
+			fields := logrus.Fields{"date": ad.Date, "campaign_id": ad.CampaignID, "channel": ad.Channel}
+			if ad.Clicks < 0 {
+				warn(withField(fields, "clicks", ad.Clicks))
+				ad.Clicks = 0
+			}
+			if ad.Impressions < 0 {
+				warn(withField(fields, "impressions", ad.Impressions))
+				ad.Impressions = 0
+			}
+			if ad.Cost < 0 {
+				warn(withField(fields, "cost", ad.Cost))
+				ad.Cost = 0
+			}
+		}
+	}
+
+	if crmData != nil {
+		for i := range crmData.Opportunities {
+			opp := &crmData.Opportunities[i]
+			if opp.Amount < 0 {
+				warn(logrus.Fields{"opportunity_id": opp.OpportunityID, "amount": opp.Amount})
+				opp.Amount = 0
+			}
+		}
+	}
+}
+
+// withField returns a copy of fields with key set to value.
+func withField(fields logrus.Fields, key string, value interface{}) logrus.Fields {
+	copied := make(logrus.Fields, len(fields)+1)
+	for k, v := range fields {
+		copied[k] = v
+	}
+	copied[key] = value
+	return copied
+}
@@ -0,0 +1,145 @@
+package etl
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"admira-etl/internal/config"
+	"admira-etl/internal/models"
+	"admira-etl/internal/storage"
+
+	"github.com/sirupsen/logrus"
+	"github.com/sirupsen/logrus/hooks/test"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseNegativeValuePolicy(t *testing.T) {
+	policy, err := ParseNegativeValuePolicy("")
+	require.NoError(t, err)
+	assert.Equal(t, NegativeValuesReject, policy)
+
+	policy, err = ParseNegativeValuePolicy(" Clamp ")
+	require.NoError(t, err)
+	assert.Equal(t, NegativeValuesClamp, policy)
+
+	_, err = ParseNegativeValuePolicy("ignore")
+	assert.ErrorContains(t, err, `unknown negative value policy "ignore"`)
+}
+
+// refundUpstream serves an ads row whose cost is a refund adjustment and a
+// negative-amount opportunity next to a regular one.
+func refundUpstream(t *testing.T) *httptest.Server {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/crm" {
+			w.Write([]byte(`{"external":{"crm":{"opportunities":[
+				{"opportunity_id":"O-1","stage":"closed_won","amount":300,"utm_campaign":"spring"},
+				{"opportunity_id":"O-2","stage":"closed_won","amount":-50,"utm_campaign":"spring"}
+			]}}}`))
+			return
+		}
+		w.Write([]byte(`{"external":{"ads":{"performance":[
+			{"date":"2025-01-01","channel":"google_ads","campaign_id":"C-1","clicks":100,"impressions":1000,"cost":-20,"utm_campaign":"spring"},
+			{"date":"2025-01-01","channel":"google_ads","campaign_id":"C-2","clicks":50,"impressions":500,"cost":25}
+		]}}}`))
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func newNegativeValueService(t *testing.T, policy string) (*Service, storage.Storage, *test.Hook) {
+	logger, hook := test.NewNullLogger()
+	upstream := refundUpstream(t)
+	store := storage.NewInMemoryStorage()
+	return NewService(&config.Config{
+		AdsAPIURL:      upstream.URL + "/ads",
+		CRMAPIURL:      upstream.URL + "/crm",
+		RetryDelay:     time.Millisecond,
+		NegativeValues: policy,
+	}, store, logger), store, hook
+}
+
+func TestRunIngestion_ClampsNegativeValues(t *testing.T) {
+	service, store, hook := newNegativeValueService(t, "clamp")
+
+	require.NoError(t, service.RunIngestion(context.Background(), IngestOptions{Full: true}))
+
+	data, err := store.GetTransformedData(time.Time{}, time.Now(), map[string]string{}, 0, 0)
+	require.NoError(t, err)
+	require.Len(t, data, 2)
+
+	// The refund row is kept with zero cost, so no negative CPC or ROAS,
+	// and the negative opportunity adds nothing to revenue
+	refund := data[0]
+	assert.Equal(t, "C-1", refund.CampaignID)
+	assert.Zero(t, refund.Cost)
+	assert.Zero(t, refund.CPC)
+	assert.Zero(t, refund.CPA)
+	assert.Zero(t, refund.ROAS)
+	assert.Equal(t, 300.0, refund.Revenue)
+	assert.Equal(t, 2, refund.ClosedWon)
+
+	assert.Empty(t, service.LastValidationReport().Rejected)
+
+	var anomalies []*logrus.Entry
+	for _, entry := range hook.AllEntries() {
+		if entry.Message == "Clamping negative value to zero" {
+			anomalies = append(anomalies, entry)
+		}
+	}
+	require.Len(t, anomalies, 2)
+	assert.Equal(t, logrus.WarnLevel, anomalies[0].Level)
+	assert.Equal(t, "C-1", anomalies[0].Data["campaign_id"])
+	assert.Equal(t, -20.0, anomalies[0].Data["cost"])
+	assert.Equal(t, "O-2", anomalies[1].Data["opportunity_id"])
+	assert.Equal(t, -50.0, anomalies[1].Data["amount"])
+}
+
+func TestRunIngestion_RejectsNegativeValues(t *testing.T) {
+	service, store, _ := newNegativeValueService(t, "")
+
+	require.NoError(t, service.RunIngestion(context.Background(), IngestOptions{Full: true}))
+
+	// The refund row and the negative opportunity are skipped as invalid
+	data, err := store.GetTransformedData(time.Time{}, time.Now(), map[string]string{}, 0, 0)
+	require.NoError(t, err)
+	require.Len(t, data, 1)
+	assert.Equal(t, "C-2", data[0].CampaignID)
+
+	report := service.LastValidationReport()
+	require.Len(t, report.Rejected, 2)
+	assert.Equal(t, []string{"cost must not be negative, got -20"}, report.Rejected[0].Problems)
+	assert.Equal(t, []string{"amount must not be negative, got -50"}, report.Rejected[1].Problems)
+}
+
+func TestIngestPayload_NegativeValues(t *testing.T) {
+	payload := func() *models.ExternalResponse {
+		return &models.ExternalResponse{External: models.ExternalData{
+			Ads: &models.AdsData{Performance: []models.AdsPerformance{
+				{Date: "2025-01-01", Channel: "google_ads", CampaignID: "C-1", Clicks: -3, Cost: -20},
+			}},
+			CRM: &models.CRMData{Opportunities: []models.Opportunity{
+				{OpportunityID: "O-1", Stage: "closed_won", Amount: -50},
+			}},
+		}}
+	}
+
+	service, _, _ := newNegativeValueService(t, "")
+	_, err := service.IngestPayload(context.Background(), payload(), "")
+	assert.ErrorIs(t, err, ErrInvalidPayload)
+
+	service, store, _ := newNegativeValueService(t, "clamp")
+	records, err := service.IngestPayload(context.Background(), payload(), "")
+	require.NoError(t, err)
+	assert.Equal(t, 1, records)
+
+	data, err := store.GetTransformedData(time.Time{}, time.Now(), map[string]string{}, 0, 0)
+	require.NoError(t, err)
+	require.Len(t, data, 1)
+	assert.Zero(t, data[0].Clicks)
+	assert.Zero(t, data[0].Cost)
+	assert.Zero(t, data[0].Revenue)
+}
@@ -0,0 +1,72 @@
+package etl
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+
+	"admira-etl/internal/constants"
+	"admira-etl/internal/models"
+)
+
+// fetchPages requests source and every page it links to, sending headers
+// with each request and handing each response to collect in order. It stops
+// once a page names no successor and fails rather than returning partial
+// data if the upstream is still paging after constants.MaxExternalPages
+// pages.
+func (s *Service) fetchPages(ctx context.Context, source string, headers map[string]string, collect func(page *models.ExternalResponse)) error {
+	pageURL := source
+	for page := 1; ; page++ {
+		var response models.ExternalResponse
+		if err := s.client.GetWithHeaders(ctx, pageURL, headers, &response); err != nil {
+			if page > 1 {
+				return fmt.Errorf("failed to fetch page %d: %w", page, err)
+			}
+			return err
+		}
+		collect(&response)
+
+		next, err := nextPageURL(source, pageURL, &response)
+		if err != nil {
+			return fmt.Errorf("invalid next page on page %d: %w", page, err)
+		}
+		if next == "" {
+			return nil
+		}
+		if page >= constants.MaxExternalPages {
+			return fmt.Errorf("upstream still paginating after %d pages", constants.MaxExternalPages)
+		}
+		pageURL = next
+	}
+}
+
+// nextPageURL returns the URL of the page after response, or "" on the last
+// page. A next link is resolved against the page it came from; a cursor is
+// set as the cursor query parameter of the source URL.
+func nextPageURL(source, current string, response *models.ExternalResponse) (string, error) {
+	switch {
+	case response.Next != "":
+		base, err := url.Parse(current)
+		if err != nil {
+			return "", err
+		}
+		next, err := url.Parse(response.Next)
+		if err != nil {
+			return "", err
+		}
+		return base.ResolveReference(next).String(), nil
+
+	case response.NextCursor != "":
+		base, err := url.Parse(source)
+		if err != nil {
+			return "", err
+		}
+		query := base.Query()
+		query.Set("cursor", response.NextCursor)
+		base.RawQuery = query.Encode()
+		return base.String(), nil
+
+	default:
+		return "", nil
+	}
+}
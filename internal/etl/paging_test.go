@@ -0,0 +1,156 @@
+package etl
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"admira-etl/internal/config"
+	"admira-etl/internal/constants"
+	"admira-etl/internal/models"
+	"admira-etl/internal/storage"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newPagingService(adsURL, crmURL string) *Service {
+	logger := logrus.New()
+	logger.SetLevel(logrus.FatalLevel)
+	return NewService(&config.Config{
+		AdsAPIURL:  adsURL,
+		CRMAPIURL:  crmURL,
+		RetryDelay: time.Millisecond,
+	}, storage.NewInMemoryStorage(), logger)
+}
+
+func TestFetchAdsData_FollowsNextLinks(t *testing.T) {
+	// Three pages linked by relative next URLs; the middle one has no ads
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var response models.ExternalResponse
+		switch r.URL.Query().Get("page") {
+		case "":
+			response.External.Ads = &models.AdsData{Performance: []models.AdsPerformance{{CampaignID: "C-1"}, {CampaignID: "C-2"}}}
+			response.Next = "ads?page=2"
+		case "2":
+			response.Next = "/v1/ads?page=3"
+		case "3":
+			response.External.Ads = &models.AdsData{Performance: []models.AdsPerformance{{CampaignID: "C-3"}}}
+		}
+		json.NewEncoder(w).Encode(response)
+	}))
+	defer upstream.Close()
+
+	service := newPagingService(upstream.URL+"/v1/ads", "")
+	ads, err := service.fetchAdsData(context.Background())
+	require.NoError(t, err)
+
+	var ids []string
+	for _, ad := range ads.Performance {
+		ids = append(ids, ad.CampaignID)
+	}
+	assert.Equal(t, []string{"C-1", "C-2", "C-3"}, ids)
+}
+
+func TestFetchCRMData_FollowsCursor(t *testing.T) {
+	var requested []string
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requested = append(requested, r.URL.RawQuery)
+
+		var response models.ExternalResponse
+		response.External.CRM = &models.CRMData{}
+		switch r.URL.Query().Get("cursor") {
+		case "":
+			response.External.CRM.Opportunities = []models.Opportunity{{OpportunityID: "O-1"}}
+			response.NextCursor = "abc"
+		case "abc":
+			response.External.CRM.Opportunities = []models.Opportunity{{OpportunityID: "O-2"}, {OpportunityID: "O-3"}}
+			response.NextCursor = "def"
+		case "def":
+			response.External.CRM.Opportunities = []models.Opportunity{{OpportunityID: "O-4"}}
+		}
+		json.NewEncoder(w).Encode(response)
+	}))
+	defer upstream.Close()
+
+	service := newPagingService("", upstream.URL+"/crm?region=eu")
+	crm, err := service.fetchCRMData(context.Background())
+	require.NoError(t, err)
+
+	var ids []string
+	for _, opp := range crm.Opportunities {
+		ids = append(ids, opp.OpportunityID)
+	}
+	assert.Equal(t, []string{"O-1", "O-2", "O-3", "O-4"}, ids)
+
+	// The cursor is added to the configured URL's own query
+	assert.Equal(t, []string{"region=eu", "cursor=abc&region=eu", "cursor=def&region=eu"}, requested)
+}
+
+func TestFetchPages_Errors(t *testing.T) {
+	// An upstream that never stops paging hits the cap
+	var pages int
+	endless := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		pages++
+		fmt.Fprintf(w, `{"external":{},"next_cursor":"%d"}`, pages)
+	}))
+	defer endless.Close()
+
+	service := newPagingService(endless.URL, "")
+	_, err := service.fetchAdsData(context.Background())
+	assert.ErrorContains(t, err, "still paginating")
+	assert.Equal(t, constants.MaxExternalPages, pages)
+
+	// A failing later page fails the fetch rather than returning part of it
+	failing := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("cursor") != "" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		w.Write([]byte(`{"external":{"crm":{"opportunities":[{"opportunity_id":"O-1"}]}},"next_cursor":"x"}`))
+	}))
+	defer failing.Close()
+
+	service = newPagingService("", failing.URL)
+	_, err = service.fetchCRMData(context.Background())
+	assert.ErrorContains(t, err, "failed to fetch page 2")
+}
+
+func TestFetchPages_SendsUpstreamHeaders(t *testing.T) {
+	// Every page of each upstream gets that upstream's headers, and only those
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var response models.ExternalResponse
+		switch r.URL.Path {
+		case "/ads":
+			assert.Equal(t, "ads-key", r.Header.Get("X-API-Key"))
+			assert.Empty(t, r.Header.Get("Authorization"))
+			if r.URL.Query().Get("page") == "" {
+				response.Next = "ads?page=2"
+			}
+			response.External.Ads = &models.AdsData{Performance: []models.AdsPerformance{{CampaignID: "C-1"}}}
+		case "/crm":
+			assert.Equal(t, "Bearer crm-token", r.Header.Get("Authorization"))
+			assert.Empty(t, r.Header.Get("X-API-Key"))
+			response.External.CRM = &models.CRMData{Opportunities: []models.Opportunity{{OpportunityID: "O-1"}}}
+		}
+		json.NewEncoder(w).Encode(response)
+	}))
+	defer upstream.Close()
+
+	service := newPagingService(upstream.URL+"/ads", upstream.URL+"/crm")
+	service.config.AdsAPIHeaders = map[string]string{"X-API-Key": "ads-key"}
+	service.config.CRMAPIHeaders = map[string]string{"Authorization": "Bearer crm-token"}
+
+	ads, err := service.fetchAdsData(context.Background())
+	require.NoError(t, err)
+	assert.Len(t, ads.Performance, 2)
+
+	crm, err := service.fetchCRMData(context.Background())
+	require.NoError(t, err)
+	assert.Len(t, crm.Opportunities, 1)
+}
@@ -0,0 +1,41 @@
+package etl
+
+import "sync"
+
+// minRowsPerWorker keeps small inputs on the calling goroutine, where the
+// cost of spawning workers would outweigh the work itself.
+const minRowsPerWorker = 256
+
+// forEachIndex calls fn for every index in [0, n), splitting the range into
+// contiguous chunks run by at most workers goroutines. fn must only write to
+// state owned by its index, so results land in input order without any
+// further sorting.
+func forEachIndex(n, workers int, fn func(i int)) {
+	if maxWorkers := n / minRowsPerWorker; workers > maxWorkers {
+		workers = maxWorkers
+	}
+	if workers <= 1 {
+		for i := 0; i < n; i++ {
+			fn(i)
+		}
+		return
+	}
+
+	chunk := (n + workers - 1) / workers
+	var wg sync.WaitGroup
+	for start := 0; start < n; start += chunk {
+		end := start + chunk
+		if end > n {
+			end = n
+		}
+
+		wg.Add(1)
+		go func(start, end int) {
+			defer wg.Done()
+			for i := start; i < end; i++ {
+				fn(i)
+			}
+		}(start, end)
+	}
+	wg.Wait()
+}
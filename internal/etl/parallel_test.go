@@ -0,0 +1,132 @@
+package etl
+
+import (
+	"fmt"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"admira-etl/internal/config"
+	"admira-etl/internal/models"
+	"admira-etl/internal/storage"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestForEachIndex(t *testing.T) {
+	for _, tt := range []struct{ n, workers int }{
+		{0, 4},
+		{10, 4},
+		{1000, 1},
+		{1000, 3},
+		{5000, 64},
+	} {
+		t.Run(fmt.Sprintf("n=%d/workers=%d", tt.n, tt.workers), func(t *testing.T) {
+			visits := make([]int32, tt.n)
+			forEachIndex(tt.n, tt.workers, func(i int) {
+				atomic.AddInt32(&visits[i], 1)
+			})
+			for i, count := range visits {
+				require.Equal(t, int32(1), count, "index %d", i)
+			}
+		})
+	}
+}
+
+// largeFixture builds ads spread over channels, campaigns and UTM
+// combinations, with opportunities matching them exactly, by campaign or by
+// source only, and several shared between rows.
+func largeFixture(ads, opportunities int) (*models.AdsData, *models.CRMData) {
+	channels := []string{"google_ads", "facebook_ads", "linkedin_ads"}
+	sources := []string{"google", "facebook", "linkedin", "newsletter"}
+	stages := []string{"lead", "qualified", "proposal", "closed_won"}
+	start := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	adsData := &models.AdsData{Performance: make([]models.AdsPerformance, 0, ads)}
+	for i := 0; i < ads; i++ {
+		adsData.Performance = append(adsData.Performance, models.AdsPerformance{
+			Date:        start.AddDate(0, 0, i%90).Format("2006-01-02"),
+			CampaignID:  fmt.Sprintf("C-%d", i%150),
+			Channel:     channels[i%len(channels)],
+			Clicks:      100 + i%700,
+			Impressions: 5000 + i%9000,
+			Cost:        float64(50+i%400) + 0.25,
+			UTMCampaign: fmt.Sprintf("campaign_%d", i%60),
+			UTMSource:   sources[i%len(sources)],
+			UTMMedium:   "cpc",
+		})
+	}
+
+	crmData := &models.CRMData{Opportunities: make([]models.Opportunity, 0, opportunities)}
+	for i := 0; i < opportunities; i++ {
+		opportunity := models.Opportunity{
+			OpportunityID: fmt.Sprintf("O-%d", i),
+			Stage:         stages[i%len(stages)],
+			Amount:        float64(1000 + i%5000),
+			CreatedAt:     start.Add(time.Duration(i) * time.Hour),
+			UTMCampaign:   fmt.Sprintf("campaign_%d", i%70),
+			UTMSource:     sources[i%len(sources)],
+			UTMMedium:     "cpc",
+		}
+		switch i % 5 {
+		case 3:
+			opportunity.UTMSource, opportunity.UTMMedium = "", ""
+		case 4:
+			opportunity.UTMCampaign, opportunity.UTMMedium = "", ""
+		}
+		crmData.Opportunities = append(crmData.Opportunities, opportunity)
+	}
+
+	return adsData, crmData
+}
+
+func newTransformService(concurrency int, attribution string) *Service {
+	logger := logrus.New()
+	logger.SetLevel(logrus.ErrorLevel)
+
+	return NewService(&config.Config{
+		TransformConcurrency: concurrency,
+		AttributionModel:     attribution,
+	}, storage.NewInMemoryStorage(), logger)
+}
+
+func TestTransformData_ParallelMatchesSequential(t *testing.T) {
+	adsData, crmData := largeFixture(8000, 1500)
+	since := time.Date(2025, 1, 10, 0, 0, 0, 0, time.UTC)
+
+	for _, attribution := range []string{"full", "linear"} {
+		t.Run(attribution, func(t *testing.T) {
+			sequential, err := newTransformService(1, attribution).transformData(adsData, crmData, since, time.Time{})
+			require.NoError(t, err)
+			require.NotEmpty(t, sequential)
+
+			for _, workers := range []int{2, 8, 32} {
+				parallel, err := newTransformService(workers, attribution).transformData(adsData, crmData, since, time.Time{})
+				require.NoError(t, err)
+				assert.Equal(t, sequential, parallel, "workers=%d", workers)
+			}
+		})
+	}
+}
+
+func BenchmarkTransformData(b *testing.B) {
+	adsData, crmData := largeFixture(20000, 4000)
+
+	for _, workers := range []int{1, 4, 0} {
+		name := fmt.Sprintf("workers=%d", workers)
+		if workers == 0 {
+			name = "workers=gomaxprocs"
+		}
+		service := newTransformService(workers, "full")
+
+		b.Run(name, func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				if _, err := service.transformData(adsData, crmData, time.Time{}, time.Time{}); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,104 @@
+package etl
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"admira-etl/internal/models"
+
+	"github.com/sirupsen/logrus"
+)
+
+// ErrInvalidPayload is wrapped by IngestPayload errors caused by the pushed
+// data itself rather than by the service.
+var ErrInvalidPayload = errors.New("invalid payload")
+
+// IngestPayload runs pushed Ads/CRM data through the same transform as a
+// pulled ingestion and stores the result, returning how many rows were
+// produced. since, when set, skips ads rows and opportunities before that
+// date. Pushes don't move the last ingestion time, which tracks what has
+// been pulled from the configured sources.
+func (s *Service) IngestPayload(ctx context.Context, payload *models.ExternalResponse, since string) (int, error) {
+	if payload != nil {
+		s.clampNegatives(payload.External.Ads, payload.External.CRM)
+	}
+	if err := validatePayload(payload); err != nil {
+		return 0, err
+	}
+
+	var sinceTime time.Time
+	if since != "" {
+		var err error
+		sinceTime, err = parseFlexibleDate(since)
+		if err != nil {
+			return 0, fmt.Errorf("%w: invalid since date: %v", ErrInvalidPayload, err)
+		}
+	}
+
+	ctx, done, err := s.beginWork(ctx, fmt.Sprintf("payload ingestion since=%q", since))
+	if err != nil {
+		return 0, err
+	}
+	defer done()
+
+	crmData := payload.External.CRM
+	if crmData == nil {
+		crmData = &models.CRMData{Opportunities: []models.Opportunity{}}
+	}
+
+	transformedData, err := s.transformData(payload.External.Ads, crmData, sinceTime, time.Time{})
+	if err != nil {
+		return 0, fmt.Errorf("failed to transform data: %w", err)
+	}
+
+	if err := ctx.Err(); err != nil {
+		return 0, fmt.Errorf("ingestion cancelled: %w", err)
+	}
+
+	if err := s.storage.StoreTransformedData(transformedData); err != nil {
+		// A file store that fails to persist keeps the rows in memory
+		s.refreshStoredRecords()
+		s.refreshAggregates(transformedData)
+		return 0, fmt.Errorf("failed to store transformed data: %w", err)
+	}
+
+	s.metrics.RecordsProcessed.Add(float64(len(transformedData)))
+	s.refreshStoredRecords()
+	s.refreshAggregates(transformedData)
+	s.logger.WithFields(logrus.Fields{
+		"since":             since,
+		"records_processed": len(transformedData),
+	}).Info("Payload ingestion completed")
+	return len(transformedData), nil
+}
+
+// validatePayload checks that pushed data has ads rows the transform can
+// key on. CRM data is optional.
+func validatePayload(payload *models.ExternalResponse) error {
+	if payload == nil || payload.External.Ads == nil {
+		return fmt.Errorf("%w: external.ads is required", ErrInvalidPayload)
+	}
+
+	if malformed := payload.External.Ads.Malformed; len(malformed) > 0 {
+		return fmt.Errorf("%w: ads row %s: %v", ErrInvalidPayload, malformed[0].Raw, malformed[0].Err)
+	}
+	for i, ad := range payload.External.Ads.Performance {
+		if problems := adsProblems(ad); len(problems) > 0 {
+			return fmt.Errorf("%w: ads row %d: %s", ErrInvalidPayload, i, problems[0])
+		}
+	}
+
+	if crm := payload.External.CRM; crm != nil {
+		if len(crm.Malformed) > 0 {
+			return fmt.Errorf("%w: opportunity %s: %v", ErrInvalidPayload, crm.Malformed[0].Raw, crm.Malformed[0].Err)
+		}
+		for i, opp := range crm.Opportunities {
+			if problems := opportunityProblems(opp); len(problems) > 0 {
+				return fmt.Errorf("%w: opportunity %d: %s", ErrInvalidPayload, i, problems[0])
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,85 @@
+package etl
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"admira-etl/internal/config"
+	"admira-etl/internal/models"
+	"admira-etl/internal/storage"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIngestPayload(t *testing.T) {
+	logger := logrus.New()
+	logger.SetLevel(logrus.ErrorLevel)
+
+	store := storage.NewInMemoryStorage()
+	service := NewService(&config.Config{}, store, logger)
+
+	payload := &models.ExternalResponse{External: models.ExternalData{
+		Ads: &models.AdsData{Performance: []models.AdsPerformance{
+			{Date: "2025-01-01", CampaignID: "C-1001", Channel: "google_ads", Clicks: 100, Cost: 50.0},
+			{Date: "2025-01-02", CampaignID: "C-1001", Channel: "google_ads", Clicks: 200, Cost: 80.0},
+		}},
+	}}
+
+	records, err := service.IngestPayload(context.Background(), payload, "")
+	require.NoError(t, err)
+	assert.Equal(t, 2, records)
+
+	count, err := store.CountTransformedData(nil)
+	require.NoError(t, err)
+	assert.Equal(t, 2, count)
+
+	// Pushes leave the pull watermark alone
+	lastIngestion, err := store.GetLastIngestionTime()
+	require.NoError(t, err)
+	assert.True(t, lastIngestion.IsZero())
+
+	records, err = service.IngestPayload(context.Background(), payload, "2025-01-02")
+	require.NoError(t, err)
+	assert.Equal(t, 1, records)
+
+	from, _ := time.Parse("2006-01-02", "2025-01-02")
+	stored, err := store.GetTransformedData(from, from, map[string]string{}, 0, 0)
+	require.NoError(t, err)
+	assert.Len(t, stored, 2)
+}
+
+func TestIngestPayload_Invalid(t *testing.T) {
+	logger := logrus.New()
+	logger.SetLevel(logrus.ErrorLevel)
+
+	store := storage.NewInMemoryStorage()
+	service := NewService(&config.Config{}, store, logger)
+
+	tests := []struct {
+		name    string
+		payload *models.ExternalResponse
+		since   string
+	}{
+		{"no ads", &models.ExternalResponse{}, ""},
+		{"missing campaign", &models.ExternalResponse{External: models.ExternalData{
+			Ads: &models.AdsData{Performance: []models.AdsPerformance{{Date: "2025-01-01", Channel: "google_ads"}}},
+		}}, ""},
+		{"bad since", &models.ExternalResponse{External: models.ExternalData{
+			Ads: &models.AdsData{},
+		}}, "yesterday"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := service.IngestPayload(context.Background(), tt.payload, tt.since)
+			assert.ErrorIs(t, err, ErrInvalidPayload)
+		})
+	}
+
+	count, err := store.CountTransformedData(nil)
+	require.NoError(t, err)
+	assert.Zero(t, count)
+}
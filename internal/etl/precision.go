@@ -0,0 +1,22 @@
+package etl
+
+import "admira-etl/internal/models"
+
+// roundMetrics rounds the derived metrics to the configured MetricPrecision
+// so sums over the stored values don't carry float noise. 0 keeps full
+// precision.
+func (s *Service) roundMetrics(metrics Metrics) Metrics {
+	decimals := s.config.MetricPrecision
+	if decimals <= 0 {
+		return metrics
+	}
+
+	metrics.CPC = models.RoundTo(metrics.CPC, decimals)
+	metrics.CPA = models.RoundTo(metrics.CPA, decimals)
+	metrics.CVRLeadToOpp = models.RoundTo(metrics.CVRLeadToOpp, decimals)
+	metrics.CVROppToWon = models.RoundTo(metrics.CVROppToWon, decimals)
+	metrics.ROAS = models.RoundTo(metrics.ROAS, decimals)
+	metrics.CTR = models.RoundTo(metrics.CTR, decimals)
+	metrics.CPM = models.RoundTo(metrics.CPM, decimals)
+	return metrics
+}
@@ -0,0 +1,76 @@
+package etl
+
+import (
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"admira-etl/internal/config"
+	"admira-etl/internal/models"
+	"admira-etl/internal/storage"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTransformData_MetricPrecision(t *testing.T) {
+	logger := logrus.New()
+	logger.SetLevel(logrus.ErrorLevel)
+
+	// CPC 7/30, CPA 7/3 and ROAS 2/7 all repeat forever
+	adsData := &models.AdsData{Performance: []models.AdsPerformance{
+		{
+			Date: "2025-01-01", CampaignID: "C-1001", Channel: "google_ads", Clicks: 30, Cost: 7.0,
+			UTMCampaign: "back_to_school", UTMSource: "google", UTMMedium: "cpc",
+		},
+	}}
+	crmData := &models.CRMData{Opportunities: []models.Opportunity{
+		{OpportunityID: "O-1", Stage: "closed_won", Amount: 2.0, UTMCampaign: "back_to_school", UTMSource: "google", UTMMedium: "cpc"},
+	}}
+
+	tests := []struct {
+		name      string
+		precision int
+		cpc       float64
+		cpa       float64
+		roas      float64
+	}{
+		{name: "serialized precision", precision: 4, cpc: 0.2333, cpa: 2.3333, roas: 0.2857},
+		{name: "signature precision", precision: 3, cpc: 0.233, cpa: 2.333, roas: 0.286},
+		{name: "coarser", precision: 2, cpc: 0.23, cpa: 2.33, roas: 0.29},
+		{name: "full precision", precision: 0, cpc: 7.0 / 30, cpa: 7.0 / 3, roas: 2.0 / 7},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			service := NewService(&config.Config{MetricPrecision: tt.precision}, storage.NewInMemoryStorage(), logger)
+
+			result, err := service.transformData(adsData, crmData, time.Time{}, time.Time{})
+			require.NoError(t, err)
+			require.Len(t, result, 1)
+
+			row := result[0]
+			assert.Equal(t, tt.cpc, row.CPC)
+			assert.Equal(t, tt.cpa, row.CPA)
+			assert.Equal(t, tt.roas, row.ROAS)
+		})
+	}
+
+	t.Run("stored values match the signature", func(t *testing.T) {
+		service := NewService(&config.Config{MetricPrecision: 3}, storage.NewInMemoryStorage(), logger)
+
+		result, err := service.transformData(adsData, crmData, time.Time{}, time.Time{})
+		require.NoError(t, err)
+		require.Len(t, result, 1)
+
+		// CPC, CPA and ROAS are fields 10, 11 and 14 of the signed payload
+		fields := strings.Split(signaturePayload(result[0]), "|")
+		for index, stored := range map[int]float64{10: result[0].CPC, 11: result[0].CPA, 14: result[0].ROAS} {
+			signed, err := strconv.ParseFloat(fields[index], 64)
+			require.NoError(t, err)
+			assert.Equal(t, stored, signed, "payload field %d", index)
+		}
+	})
+}
@@ -0,0 +1,55 @@
+package etl
+
+import (
+	"context"
+
+	"admira-etl/internal/constants"
+)
+
+// readinessState debounces dependency probes for /readyz. The service
+// starts unready; it turns ready after ReadinessSuccessThreshold healthy
+// probes in a row and unready again after ReadinessFailureThreshold failed
+// ones, so a single blip neither takes it out of rotation nor puts it back.
+type readinessState struct {
+	ready     bool
+	failures  int
+	successes int
+}
+
+// CheckReadiness probes the dependencies with Ready and reports whether the
+// service should be considered ready, along with each dependency's status
+// from this probe.
+func (s *Service) CheckReadiness(ctx context.Context) (bool, map[string]string) {
+	dependencies := s.Ready(ctx)
+
+	healthy := true
+	for _, health := range dependencies {
+		if health != constants.HealthStatusHealthy {
+			healthy = false
+			break
+		}
+	}
+
+	s.readinessMu.Lock()
+	defer s.readinessMu.Unlock()
+	return s.readiness.observe(healthy, s.config.ReadinessFailureThreshold, s.config.ReadinessSuccessThreshold), dependencies
+}
+
+// observe records one probe and returns the resulting readiness. Thresholds
+// below 1 count as 1.
+func (r *readinessState) observe(healthy bool, failureThreshold, successThreshold int) bool {
+	if healthy {
+		r.failures = 0
+		r.successes++
+		if r.successes >= max(successThreshold, 1) {
+			r.ready = true
+		}
+	} else {
+		r.successes = 0
+		r.failures++
+		if r.failures >= max(failureThreshold, 1) {
+			r.ready = false
+		}
+	}
+	return r.ready
+}
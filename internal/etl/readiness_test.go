@@ -0,0 +1,81 @@
+package etl
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+
+	"admira-etl/internal/config"
+	"admira-etl/internal/constants"
+	"admira-etl/internal/storage"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCheckReadiness(t *testing.T) {
+	logger := logrus.New()
+	logger.SetLevel(logrus.FatalLevel)
+
+	var failing atomic.Bool
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if failing.Load() {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer upstream.Close()
+
+	service := NewService(&config.Config{
+		AdsAPIURL:                 upstream.URL,
+		CRMAPIURL:                 upstream.URL,
+		ReadinessFailureThreshold: 3,
+		ReadinessSuccessThreshold: 2,
+	}, storage.NewInMemoryStorage(), logger)
+
+	probe := func(fail bool) bool {
+		failing.Store(fail)
+		ready, _ := service.CheckReadiness(context.Background())
+		return ready
+	}
+
+	// Unready until two healthy probes in a row
+	assert.False(t, probe(false))
+	assert.True(t, probe(false))
+
+	// A transient failure, even two, keeps it ready
+	assert.True(t, probe(true))
+	assert.True(t, probe(true))
+	assert.True(t, probe(false))
+	assert.True(t, probe(true))
+
+	// A sustained failure turns it unready on the third probe in a row
+	assert.True(t, probe(true))
+	assert.False(t, probe(true))
+	assert.False(t, probe(true))
+
+	// One healthy probe between failures doesn't bring it back
+	assert.False(t, probe(false))
+	assert.False(t, probe(true))
+	assert.False(t, probe(false))
+	assert.True(t, probe(false))
+
+	// Dependencies still report what this probe saw
+	failing.Store(true)
+	ready, dependencies := service.CheckReadiness(context.Background())
+	assert.True(t, ready)
+	assert.Equal(t, constants.HealthStatusUnhealthy, dependencies["ads_api"])
+}
+
+func TestReadinessState_DefaultThresholds(t *testing.T) {
+	var state readinessState
+
+	// Thresholds below 1 act on every probe
+	assert.False(t, state.observe(false, 0, 0))
+	assert.True(t, state.observe(true, 0, 0))
+	assert.False(t, state.observe(false, 0, 0))
+	assert.True(t, state.observe(true, 1, 1))
+}
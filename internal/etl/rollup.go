@@ -0,0 +1,86 @@
+package etl
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"admira-etl/internal/models"
+)
+
+// Granularity is the bucket size used to roll up daily metrics rows.
+type Granularity string
+
+const (
+	GranularityDay   Granularity = "day"
+	GranularityWeek  Granularity = "week"
+	GranularityMonth Granularity = "month"
+)
+
+// bucketKey maps a YYYY-MM-DD date onto its bucket: the date itself for daily
+// rows, the ISO week (2025-W03) for weekly rows and the calendar month
+// (2025-01) for monthly rows.
+func bucketKey(date string, granularity Granularity) (string, error) {
+	t, err := time.Parse("2006-01-02", date)
+	if err != nil {
+		return "", fmt.Errorf("invalid date %q: %w", date, err)
+	}
+
+	switch granularity {
+	case "", GranularityDay:
+		return t.Format("2006-01-02"), nil
+	case GranularityWeek:
+		year, week := t.ISOWeek()
+		return fmt.Sprintf("%d-W%02d", year, week), nil
+	case GranularityMonth:
+		return t.Format("2006-01"), nil
+	default:
+		return "", fmt.Errorf("unknown granularity %q", granularity)
+	}
+}
+
+// rollUp groups rows into (bucket, channel, campaign) buckets, summing totals
+// and recomputing derived metrics with the same logic as export
+// consolidation. The bucket key replaces each row's date.
+func rollUp(data []models.TransformedData, granularity Granularity) ([]models.TransformedData, error) {
+	bucketed := make([]models.TransformedData, 0, len(data))
+	for _, item := range data {
+		key, err := bucketKey(item.Date, granularity)
+		if err != nil {
+			return nil, err
+		}
+		item.Date = key
+		bucketed = append(bucketed, item)
+	}
+
+	result := mergeRows(bucketed, func(item models.TransformedData) string {
+		return item.Date + "|" + item.Channel + "|" + item.CampaignID
+	})
+
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Date != result[j].Date {
+			return result[i].Date < result[j].Date
+		}
+		if result[i].Channel != result[j].Channel {
+			return result[i].Channel < result[j].Channel
+		}
+		return result[i].CampaignID < result[j].CampaignID
+	})
+
+	return result, nil
+}
+
+// paginate returns the limit/offset window of data, mirroring the storage
+// layer's semantics: a non-positive limit returns everything from offset on.
+func paginate(data []models.TransformedData, limit, offset int) []models.TransformedData {
+	if offset >= len(data) {
+		return []models.TransformedData{}
+	}
+
+	end := len(data)
+	if limit > 0 && offset+limit < end {
+		end = offset + limit
+	}
+
+	return data[offset:end]
+}
@@ -0,0 +1,232 @@
+package etl
+
+import (
+	"testing"
+	"time"
+
+	"admira-etl/internal/config"
+	"admira-etl/internal/models"
+	"admira-etl/internal/storage"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBucketKey(t *testing.T) {
+	tests := []struct {
+		date        string
+		granularity Granularity
+		expected    string
+	}{
+		{"2025-01-15", GranularityDay, "2025-01-15"},
+		{"2025-01-15", GranularityWeek, "2025-W03"},
+		{"2025-01-15", GranularityMonth, "2025-01"},
+		// ISO weeks can belong to the neighbouring year
+		{"2024-12-30", GranularityWeek, "2025-W01"},
+		{"2021-01-03", GranularityWeek, "2020-W53"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.date+"/"+string(tt.granularity), func(t *testing.T) {
+			key, err := bucketKey(tt.date, tt.granularity)
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, key)
+		})
+	}
+
+	_, err := bucketKey("2025-01-15", "quarter")
+	assert.Error(t, err)
+}
+
+func TestGetChannelMetrics_Granularity(t *testing.T) {
+	logger := logrus.New()
+	logger.SetLevel(logrus.ErrorLevel)
+
+	store := storage.NewInMemoryStorage()
+	service := NewService(&config.Config{}, store, logger)
+
+	// 2025-01-06..12 is W02, 2025-01-13..19 is W03 and 2025-02-03 is W06
+	data := []models.TransformedData{
+		{Date: "2025-01-06", Channel: "google_ads", CampaignID: "C-1001", Clicks: 100, Cost: 100.0, Leads: 10, Opportunities: 2, ClosedWon: 1, Revenue: 500.0},
+		{Date: "2025-01-08", Channel: "google_ads", CampaignID: "C-1001", Clicks: 300, Cost: 100.0, Leads: 30, Opportunities: 2, ClosedWon: 0, Revenue: 0.0},
+		{Date: "2025-01-08", Channel: "google_ads", CampaignID: "C-1002", Clicks: 50, Cost: 25.0, Leads: 5},
+		{Date: "2025-01-14", Channel: "google_ads", CampaignID: "C-1001", Clicks: 200, Cost: 50.0, Leads: 20, Opportunities: 4, ClosedWon: 2, Revenue: 1000.0},
+		{Date: "2025-02-03", Channel: "google_ads", CampaignID: "C-1001", Clicks: 10, Cost: 10.0, Leads: 1},
+		{Date: "2025-01-07", Channel: "facebook_ads", CampaignID: "C-2001", Clicks: 999, Cost: 999.0},
+	}
+	require.NoError(t, store.StoreTransformedData(data))
+
+	from, _ := time.Parse("2006-01-02", "2025-01-01")
+	to, _ := time.Parse("2006-01-02", "2025-02-28")
+
+	t.Run("weekly", func(t *testing.T) {
+		result, _, err := service.GetChannelMetrics(ChannelMetricsQuery{
+			From: from, To: to, Channel: "google_ads", Granularity: GranularityWeek,
+		})
+		require.NoError(t, err)
+		require.Len(t, result, 4)
+
+		assert.Equal(t, "2025-W02", result[0].Date)
+		assert.Equal(t, "C-1001", result[0].CampaignID)
+		assert.Equal(t, 400, result[0].Clicks)
+		assert.Equal(t, 200.0, result[0].Cost)
+		assert.Equal(t, 40, result[0].Leads)
+		assert.Equal(t, 4, result[0].Opportunities)
+		assert.Equal(t, 1, result[0].ClosedWon)
+		assert.Equal(t, 500.0, result[0].Revenue)
+		assert.InDelta(t, 0.5, result[0].CPC, 0.001)
+		assert.InDelta(t, 5.0, result[0].CPA, 0.001)
+		assert.InDelta(t, 0.1, result[0].CVRLeadToOpp, 0.001)
+		assert.InDelta(t, 0.25, result[0].CVROppToWon, 0.001)
+		assert.InDelta(t, 2.5, result[0].ROAS, 0.001)
+
+		assert.Equal(t, "2025-W02", result[1].Date)
+		assert.Equal(t, "C-1002", result[1].CampaignID)
+		assert.Equal(t, "2025-W03", result[2].Date)
+		assert.Equal(t, 200, result[2].Clicks)
+		assert.Equal(t, "2025-W06", result[3].Date)
+	})
+
+	t.Run("monthly", func(t *testing.T) {
+		result, _, err := service.GetChannelMetrics(ChannelMetricsQuery{
+			From: from, To: to, Channel: "google_ads", Granularity: GranularityMonth,
+		})
+		require.NoError(t, err)
+		require.Len(t, result, 3)
+
+		assert.Equal(t, "2025-01", result[0].Date)
+		assert.Equal(t, "C-1001", result[0].CampaignID)
+		assert.Equal(t, 600, result[0].Clicks)
+		assert.Equal(t, 1500.0, result[0].Revenue)
+		assert.InDelta(t, 6.0, result[0].ROAS, 0.001)
+		assert.Equal(t, "2025-01", result[1].Date)
+		assert.Equal(t, "C-1002", result[1].CampaignID)
+		assert.Equal(t, "2025-02", result[2].Date)
+	})
+
+	t.Run("pagination applies to buckets", func(t *testing.T) {
+		result, _, err := service.GetChannelMetrics(ChannelMetricsQuery{
+			From: from, To: to, Channel: "google_ads", Granularity: GranularityWeek, Limit: 2, Offset: 2,
+		})
+		require.NoError(t, err)
+		require.Len(t, result, 2)
+		assert.Equal(t, "2025-W03", result[0].Date)
+		assert.Equal(t, "2025-W06", result[1].Date)
+	})
+
+	t.Run("daily rows are returned unchanged", func(t *testing.T) {
+		result, _, err := service.GetChannelMetrics(ChannelMetricsQuery{
+			From: from, To: to, Channel: "google_ads", Granularity: GranularityDay,
+		})
+		require.NoError(t, err)
+		assert.Len(t, result, 5)
+	})
+}
+
+func TestGetChannelMetrics_Consolidate(t *testing.T) {
+	logger := logrus.New()
+	logger.SetLevel(logrus.ErrorLevel)
+
+	store := storage.NewInMemoryStorage()
+	service := NewService(&config.Config{}, store, logger)
+
+	require.NoError(t, store.StoreTransformedData([]models.TransformedData{
+		{Date: "2025-01-01", Channel: "google_ads", CampaignID: "C-1001", Clicks: 100, Cost: 100.0, Leads: 10, Opportunities: 2, ClosedWon: 1, Revenue: 500.0},
+		{Date: "2025-01-02", Channel: "google_ads", CampaignID: "C-1002", Clicks: 50, Cost: 25.0, Leads: 5},
+		{Date: "2025-01-03", Channel: "google_ads", CampaignID: "C-1001", Clicks: 300, Cost: 100.0, Leads: 30, Opportunities: 2, ClosedWon: 0, Revenue: 0.0},
+		{Date: "2025-01-03", Channel: "facebook_ads", CampaignID: "C-2001", Clicks: 999, Cost: 999.0},
+	}))
+
+	from, _ := time.Parse("2006-01-02", "2025-01-01")
+	to, _ := time.Parse("2006-01-02", "2025-01-31")
+
+	raw, _, err := service.GetChannelMetrics(ChannelMetricsQuery{From: from, To: to, Channel: "google_ads"})
+	require.NoError(t, err)
+	require.Len(t, raw, 3)
+
+	consolidated, next, err := service.GetChannelMetrics(ChannelMetricsQuery{From: from, To: to, Channel: "google_ads", Consolidate: true})
+	require.NoError(t, err)
+	assert.Empty(t, next)
+	require.Len(t, consolidated, 2)
+
+	// Totals match the raw rows; ratios come from the totals, not the days
+	assert.Equal(t, "", consolidated[0].Date)
+	assert.Equal(t, "C-1001", consolidated[0].CampaignID)
+	assert.Equal(t, raw[0].Clicks+raw[2].Clicks, consolidated[0].Clicks)
+	assert.Equal(t, raw[0].Cost+raw[2].Cost, consolidated[0].Cost)
+	assert.Equal(t, raw[0].Revenue+raw[2].Revenue, consolidated[0].Revenue)
+	assert.InDelta(t, 0.5, consolidated[0].CPC, 0.001)
+	assert.InDelta(t, 5.0, consolidated[0].CPA, 0.001)
+	assert.InDelta(t, 0.1, consolidated[0].CVRLeadToOpp, 0.001)
+	assert.InDelta(t, 0.25, consolidated[0].CVROppToWon, 0.001)
+	assert.InDelta(t, 2.5, consolidated[0].ROAS, 0.001)
+	assert.Equal(t, "C-1002", consolidated[1].CampaignID)
+	assert.Equal(t, raw[1].Clicks, consolidated[1].Clicks)
+
+	t.Run("sort and offset apply to consolidated rows", func(t *testing.T) {
+		result, _, err := service.GetChannelMetrics(ChannelMetricsQuery{
+			From: from, To: to, Channel: "google_ads", Consolidate: true,
+			SortBy: SortByClicks, Order: SortDesc, Limit: 1, Offset: 1,
+		})
+		require.NoError(t, err)
+		require.Len(t, result, 1)
+		assert.Equal(t, "C-1002", result[0].CampaignID)
+	})
+
+	t.Run("cursor is rejected", func(t *testing.T) {
+		_, _, err := service.GetChannelMetrics(ChannelMetricsQuery{
+			From: from, To: to, Channel: "google_ads", Consolidate: true, After: &Cursor{Date: "2025-01-01"},
+		})
+		assert.ErrorIs(t, err, ErrCursorNotAllowed)
+	})
+}
+
+func TestGetChannelMetrics_Thresholds(t *testing.T) {
+	logger := logrus.New()
+	logger.SetLevel(logrus.ErrorLevel)
+
+	store := storage.NewInMemoryStorage()
+	service := NewService(&config.Config{}, store, logger)
+
+	require.NoError(t, store.StoreTransformedData([]models.TransformedData{
+		{Date: "2025-01-01", Channel: "google_ads", CampaignID: "C-1001", Cost: 6.0, Revenue: 100.0},
+		{Date: "2025-01-02", Channel: "google_ads", CampaignID: "C-1001", Cost: 4.0, Revenue: 0.0},
+		{Date: "2025-01-01", Channel: "google_ads", CampaignID: "C-1002", Cost: 9.99, Revenue: 500.0},
+		{Date: "2025-01-02", Channel: "google_ads", CampaignID: "C-1003", Cost: 50.0, Revenue: 0.0},
+	}))
+
+	from, _ := time.Parse("2006-01-02", "2025-01-01")
+	to, _ := time.Parse("2006-01-02", "2025-01-31")
+
+	campaigns := func(data []models.TransformedData) []string {
+		ids := make([]string, 0, len(data))
+		for _, item := range data {
+			ids = append(ids, item.CampaignID+"@"+item.Date)
+		}
+		return ids
+	}
+
+	tests := []struct {
+		name     string
+		query    ChannelMetricsQuery
+		expected []string
+	}{
+		{"no thresholds", ChannelMetricsQuery{}, []string{"C-1001@2025-01-01", "C-1002@2025-01-01", "C-1001@2025-01-02", "C-1003@2025-01-02"}},
+		{"min cost on daily rows", ChannelMetricsQuery{MinCost: 10.0}, []string{"C-1003@2025-01-02"}},
+		// C-1001's days total exactly 10, which is kept
+		{"min cost after consolidation", ChannelMetricsQuery{MinCost: 10.0, Consolidate: true}, []string{"C-1001@", "C-1003@"}},
+		{"min revenue", ChannelMetricsQuery{MinRevenue: 100.0}, []string{"C-1001@2025-01-01", "C-1002@2025-01-01"}},
+		{"both thresholds", ChannelMetricsQuery{MinCost: 9.99, MinRevenue: 0.01, Consolidate: true}, []string{"C-1001@", "C-1002@"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			query := tt.query
+			query.From, query.To, query.Channel = from, to, "google_ads"
+			result, _, err := service.GetChannelMetrics(query)
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, campaigns(result))
+		})
+	}
+}
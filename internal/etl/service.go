@@ -3,99 +3,733 @@ package etl
 import (
 	"context"
 	"fmt"
+	"net/url"
+	"runtime"
 	"sort"
 	"strings"
+	"sync"
 	"time"
+	"unicode"
 
 	"admira-etl/internal/config"
+	"admira-etl/internal/constants"
 	"admira-etl/internal/http"
+	"admira-etl/internal/jobs"
+	"admira-etl/internal/logsample"
 	"admira-etl/internal/models"
 	"admira-etl/internal/storage"
+	"admira-etl/internal/telemetry"
 
 	"github.com/sirupsen/logrus"
 )
 
 type Service struct {
-	config  *config.Config
-	storage storage.Storage
-	client  *http.Client
-	logger  *logrus.Logger
+	config         *config.Config
+	storage        storage.Storage
+	client         *http.Client
+	sinks          []Sink
+	logger         *logrus.Logger
+	matchStrategy  MatchStrategy
+	fuzzyUTM       bool
+	attribution    AttributionModel
+	leadSource     LeadSource
+	leadRates      map[string]float64
+	validationMode ValidationMode
+	negativeValues NegativeValuePolicy
+	currency       *currencyConverter
+	stageWeights   map[string]float64
+	anomalyBounds  map[string]float64
+	concurrency    int
+	logSampleRate  int
+	metrics        *telemetry.ETLMetrics
+	jobs           *jobs.Registry
+	deadLetters    *deadLetterQueue
+	aggregates     *dailyAggregates
+
+	validationMu   sync.Mutex
+	lastValidation *ValidationReport
+
+	readinessMu sync.Mutex
+	readiness   readinessState
+
+	// namespace names the storage namespace this service works on.
+	// namespaces opens the others, whose services are kept in children.
+	namespace   string
+	namespaceMu sync.Mutex
+	namespaces  *storage.Namespaces
+	children    map[string]*Service
+
+	// now is the clock used to stamp ingestion runs; tests replace it.
+	now func() time.Time
+
+	// lifetime is cancelled by Shutdown to stop in-flight work, which is
+	// tracked in inflight (for logging) and work (for draining).
+	lifetime     context.Context
+	cancelWork   context.CancelFunc
+	workMu       sync.Mutex
+	work         sync.WaitGroup
+	workSeq      uint64
+	inflight     map[uint64]string
+	shuttingDown bool
+}
+
+// IngestOptions controls which ads rows an ingestion run processes.
+type IngestOptions struct {
+	// Since limits the run to rows dated on or after this YYYY-MM-DD date.
+	// When both Since and Until are empty, the run is incremental from the
+	// last successful ingestion.
+	Since string
+
+	// Until limits the run to rows dated on or before this YYYY-MM-DD date,
+	// so a bounded historical window can be backfilled.
+	Until string
+
+	// Full ignores the last ingestion time and reprocesses the whole
+	// history (still bounded by Since when set).
+	Full bool
+}
+
+// Job types recorded in the job registry.
+const (
+	JobTypeIngestion = "ingestion"
+	JobTypeExport    = "export"
+)
+
+// MatchStrategy controls how far findMatchingOpportunities falls back when an
+// ad's UTMs have no exact match in the CRM data.
+type MatchStrategy string
+
+const (
+	// StrategyExact only attributes opportunities with identical UTMs.
+	StrategyExact MatchStrategy = "exact"
+	// StrategyCampaignFallback falls back to a campaign-only match.
+	StrategyCampaignFallback MatchStrategy = "campaign_fallback"
+	// StrategyFull falls back to campaign-only and then source-only matches.
+	StrategyFull MatchStrategy = "full"
+)
+
+// ParseMatchStrategy converts a configuration value into a MatchStrategy.
+func ParseMatchStrategy(value string) (MatchStrategy, error) {
+	switch strategy := MatchStrategy(strings.ToLower(strings.TrimSpace(value))); strategy {
+	case StrategyExact, StrategyCampaignFallback, StrategyFull:
+		return strategy, nil
+	case "":
+		return StrategyFull, nil
+	default:
+		return "", fmt.Errorf("unknown match strategy %q", value)
+	}
 }
 
 func NewService(cfg *config.Config, store storage.Storage, logger *logrus.Logger) *Service {
-	httpClient := http.NewClient(http.ClientConfig{
-		Timeout:    cfg.HTTPTimeout,
-		MaxRetries: cfg.MaxRetries,
-		RetryDelay: cfg.RetryDelay,
-	}, logger)
+	retryableNetworkErrors, err := http.ParseNetworkErrorKinds(cfg.RetryableNetworkErrors)
+	if err != nil {
+		logger.WithError(err).Warn("Falling back to the default retryable network errors")
+	}
+
+	backoff, err := http.ParseBackoffStrategy(cfg.RetryBackoff)
+	if err != nil {
+		logger.WithError(err).Warn("Falling back to exponential retry backoff")
+		backoff = http.DefaultBackoffStrategy
+	}
+
+	// An unparseable proxy is rejected by config validation at startup
+	var proxyURL *url.URL
+	if cfg.ProxyURL != "" {
+		if proxyURL, err = url.Parse(cfg.ProxyURL); err != nil {
+			logger.WithError(err).Warn("Ignoring invalid proxy URL")
+			proxyURL = nil
+		}
+	}
+
+	clientConfig := http.ClientConfig{
+		Timeout:                cfg.HTTPTimeout,
+		MaxRetries:             cfg.MaxRetries,
+		RetryDelay:             cfg.RetryDelay,
+		BackoffStrategy:        backoff,
+		MaxRetryDelay:          cfg.MaxRetryDelay,
+		MaxTotalRetryDuration:  cfg.MaxRetryDuration,
+		RetryableNetworkErrors: retryableNetworkErrors,
+		ProxyURL:               proxyURL,
+	}
+	httpClient := http.NewClient(clientConfig, logger)
+
+	// Exports get their own client so a slow sink can be given more (or
+	// less) time than the upstream fetches
+	if cfg.SinkTimeout > 0 {
+		clientConfig.Timeout = cfg.SinkTimeout
+	}
+	sinkClient := http.NewClient(clientConfig, logger)
+
+	sinks, err := newSinks(cfg, sinkClient)
+	if err != nil {
+		logger.WithError(err).Warn("Export disabled")
+	}
+
+	matchStrategy, err := ParseMatchStrategy(cfg.MatchStrategy)
+	if err != nil {
+		logger.WithError(err).Warn("Falling back to full UTM matching")
+		matchStrategy = StrategyFull
+	}
+
+	attribution, err := ParseAttributionModel(cfg.AttributionModel)
+	if err != nil {
+		logger.WithError(err).Warn("Falling back to full attribution")
+		attribution = AttributionFull
+	}
+
+	leadSource, err := ParseLeadSource(cfg.LeadSource)
+	if err != nil {
+		logger.WithError(err).Warn("Falling back to estimated leads")
+		leadSource = LeadSourceEstimate
+	}
+
+	validationMode, err := ParseValidationMode(cfg.ValidationMode)
+	if err != nil {
+		logger.WithError(err).Warn("Falling back to skipping invalid records")
+		validationMode = ValidationSkipInvalid
+	}
+
+	negativeValues, err := ParseNegativeValuePolicy(cfg.NegativeValues)
+	if err != nil {
+		logger.WithError(err).Warn("Falling back to rejecting negative values")
+		negativeValues = NegativeValuesReject
+	}
+
+	unknownCurrency, err := ParseUnknownCurrencyPolicy(cfg.UnknownCurrency)
+	if err != nil {
+		logger.WithError(err).Warn("Falling back to passing unknown currencies through")
+		unknownCurrency = UnknownCurrencyPassThrough
+	}
+
+	concurrency := cfg.TransformConcurrency
+	if concurrency <= 0 {
+		concurrency = runtime.GOMAXPROCS(0)
+	}
+
+	namespace := cfg.Namespace
+	if namespace == "" {
+		namespace = storage.DefaultNamespace
+	}
+
+	lifetime, cancelWork := context.WithCancel(context.Background())
 
 	return &Service{
-		config:  cfg,
-		storage: store,
-		client:  httpClient,
-		logger:  logger,
+		config:         cfg,
+		storage:        store,
+		client:         httpClient,
+		sinks:          sinks,
+		logger:         logger,
+		matchStrategy:  matchStrategy,
+		fuzzyUTM:       cfg.FuzzyUTMMatch,
+		attribution:    attribution,
+		leadSource:     leadSource,
+		leadRates:      newLeadRates(cfg.LeadRates),
+		validationMode: validationMode,
+		negativeValues: negativeValues,
+		currency:       newCurrencyConverter(cfg.BaseCurrency, cfg.CurrencyRates, unknownCurrency),
+		stageWeights:   newStageWeights(cfg.StageWeights),
+		anomalyBounds:  newAnomalyBounds(cfg.AnomalyBounds, logger),
+		concurrency:    concurrency,
+		logSampleRate:  cfg.LogSampleRate,
+		metrics:        telemetry.ETL,
+		jobs:           jobs.NewRegistry(),
+		deadLetters:    newDeadLetterQueue(),
+		aggregates:     newDailyAggregates(),
+		namespace:      namespace,
+		children:       make(map[string]*Service),
+		now:            time.Now,
+		lifetime:       lifetime,
+		cancelWork:     cancelWork,
+		inflight:       make(map[uint64]string),
 	}
 }
 
-func (s *Service) RunIngestion(ctx context.Context, since string) error {
-	s.logger.WithField("since", since).Info("Starting data ingestion")
-
-	// Parse since date
-	var sinceTime time.Time
-	var err error
-	if since != "" {
-		sinceTime, err = time.Parse("2006-01-02", since)
+func (s *Service) RunIngestion(ctx context.Context, opts IngestOptions) (err error) {
+	ctx, done, err := s.beginWork(ctx, fmt.Sprintf("ingestion since=%q until=%q full=%t", opts.Since, opts.Until, opts.Full))
+	if err != nil {
+		return err
+	}
+	defer done()
+
+	// Cap the retry warnings a flapping source can produce in one run
+	sampler := logsample.New(s.logSampleRate)
+	ctx = logsample.WithSampler(ctx, sampler)
+	defer sampler.Flush(s.logger)
+
+	s.logger.WithFields(logrus.Fields{
+		"since": opts.Since,
+		"until": opts.Until,
+		"full":  opts.Full,
+	}).Info("Starting data ingestion")
+
+	start := s.now()
+	defer func() {
+		status := "success"
+		if err != nil {
+			status = "failure"
+		}
+		s.metrics.IngestionDuration.WithLabelValues(status).Observe(time.Since(start).Seconds())
+	}()
+
+	// Parse the since/until window, falling back to the last ingestion time
+	// so a run without one only processes what is new
+	var sinceTime, untilTime time.Time
+	if opts.Since != "" {
+		sinceTime, err = parseFlexibleDate(opts.Since)
 		if err != nil {
 			return fmt.Errorf("invalid since date format: %w", err)
 		}
 	}
-
-	// Fetch data from external APIs
-	adsData, err := s.fetchAdsData(ctx)
-	if err != nil {
-		return fmt.Errorf("failed to fetch ads data: %w", err)
+	if opts.Until != "" {
+		untilTime, err = parseFlexibleDate(opts.Until)
+		if err != nil {
+			return fmt.Errorf("invalid until date format: %w", err)
+		}
+		if !sinceTime.IsZero() && sinceTime.After(untilTime) {
+			return fmt.Errorf("since date %s is after until date %s", opts.Since, opts.Until)
+		}
+	}
+	if opts.Since == "" && opts.Until == "" && !opts.Full {
+		sinceTime, err = s.storage.GetLastIngestionTime()
+		if err != nil {
+			return fmt.Errorf("failed to get last ingestion time: %w", err)
+		}
+		if !sinceTime.IsZero() {
+			s.logger.WithField("last_ingestion", sinceTime).Info("Running incremental ingestion")
+			// Rows dated on the last run's day may have changed after it,
+			// so that whole day is reprocessed and replaced
+			sinceTime = sinceTime.UTC()
+			sinceTime = time.Date(sinceTime.Year(), sinceTime.Month(), sinceTime.Day(), 0, 0, 0, 0, time.UTC)
+		}
 	}
 
-	crmData, err := s.fetchCRMData(ctx)
+	adsData, crmData, partial, err := s.fetchSourceData(ctx)
 	if err != nil {
-		return fmt.Errorf("failed to fetch crm data: %w", err)
+		return err
 	}
 
 	// Transform and merge data
-	transformedData, err := s.transformData(adsData, crmData, sinceTime)
+	transformedData, err := s.transformData(adsData, crmData, sinceTime, untilTime)
 	if err != nil {
 		return fmt.Errorf("failed to transform data: %w", err)
 	}
 
-	// Store transformed data
-	if err := s.storage.StoreTransformedData(transformedData); err != nil {
+	// Bail out before touching storage if the run was cancelled (e.g. by
+	// Shutdown) so a run is either stored whole or not at all
+	if err := ctx.Err(); err != nil {
+		return fmt.Errorf("ingestion cancelled: %w", err)
+	}
+
+	// Without the CRM the run only fills dates nothing is stored for, and
+	// leaves the last ingestion time alone so the next run fetches the CRM
+	// data it missed
+	if partial {
+		stored, err := s.storeUnstoredDates(transformedData)
+		if err != nil {
+			return err
+		}
+		s.logger.WithField("records_processed", stored).Warn("Partial ingestion completed without CRM data")
+		return nil
+	}
+
+	// The reprocessed window replaces what is stored for it, so re-running
+	// over stored days never duplicates their rows. An open end reaches the
+	// last stored date.
+	replaceTo := untilTime
+	if replaceTo.IsZero() {
+		_, last, err := s.storage.DateRange()
+		if err != nil {
+			return fmt.Errorf("failed to get stored date range: %w", err)
+		}
+		replaceTo, _ = time.Parse(dateLayout, last)
+	}
+	deleted, err := s.storage.ReplaceTransformedData(sinceTime, replaceTo, transformedData)
+	first, last := sinceTime.Format(dateLayout), replaceTo.Format(dateLayout)
+	s.aggregates.drop(func(date string) bool {
+		return date < first || date > last
+	})
+	if err != nil {
+		s.refreshStoredRecords()
 		return fmt.Errorf("failed to store transformed data: %w", err)
 	}
 
-	// Update last ingestion time
-	if err := s.storage.SetLastIngestionTime(time.Now()); err != nil {
+	// Update last ingestion time only once the data is stored. The run's
+	// start time is used so rows that appeared while it was in flight are
+	// picked up by the next incremental run.
+	if err := s.storage.SetLastIngestionTime(start); err != nil {
 		return fmt.Errorf("failed to update last ingestion time: %w", err)
 	}
 
-	s.logger.WithField("records_processed", len(transformedData)).Info("Data ingestion completed")
+	s.metrics.RecordsProcessed.Add(float64(len(transformedData)))
+	s.refreshStoredRecords()
+	s.refreshAggregates(transformedData)
+	s.logger.WithFields(logrus.Fields{
+		"records_deleted":   deleted,
+		"records_processed": len(transformedData),
+	}).Info("Data ingestion completed")
 	return nil
 }
 
+// fetchSourceData fetches the Ads and CRM data, zeroes out negative values
+// if configured to, then drops (or, in reject-all mode, fails on) records
+// the transform can't trust. The validation report is kept for
+// LastValidationReport. partial reports that PartialIngest stood in an
+// empty CRM for one that couldn't be fetched.
+func (s *Service) fetchSourceData(ctx context.Context) (adsData *models.AdsData, crmData *models.CRMData, partial bool, err error) {
+	adsData, err = s.fetchAdsData(ctx)
+	if err != nil {
+		return nil, nil, false, fmt.Errorf("failed to fetch ads data: %w", err)
+	}
+
+	crmData, err = s.fetchCRMData(ctx)
+	if err != nil {
+		if !s.config.PartialIngest || ctx.Err() != nil {
+			return nil, nil, false, fmt.Errorf("failed to fetch crm data: %w", err)
+		}
+		// Spend metrics don't need the CRM; funnel metrics come out zeroed
+		s.logger.WithError(err).Warn("Failed to fetch CRM data, ingesting ads without opportunities")
+		crmData = &models.CRMData{Opportunities: []models.Opportunity{}}
+		partial = true
+	}
+
+	s.clampNegatives(adsData, crmData)
+	report, err := s.validateSourceData(adsData, crmData)
+	s.validationMu.Lock()
+	s.lastValidation = report
+	s.validationMu.Unlock()
+	if err != nil {
+		return nil, nil, false, err
+	}
+
+	return adsData, crmData, partial, nil
+}
+
+// storeUnstoredDates stores the rows of a partial run dated on days with
+// nothing stored yet and returns how many it stored. Days already stored
+// keep their rows, whose funnel metrics the CRM-less rows would zero out.
+func (s *Service) storeUnstoredDates(rows []models.TransformedData) (int, error) {
+	if len(rows) == 0 {
+		return 0, nil
+	}
+
+	first, last := rows[0].Date, rows[0].Date
+	for _, row := range rows {
+		if row.Date < first {
+			first = row.Date
+		}
+		if row.Date > last {
+			last = row.Date
+		}
+	}
+	from, errFrom := time.Parse(dateLayout, first)
+	to, errTo := time.Parse(dateLayout, last)
+	if errFrom != nil || errTo != nil {
+		return 0, fmt.Errorf("invalid row date range %s to %s", first, last)
+	}
+
+	existing, err := s.storage.GetTransformedData(from, to, nil, 0, 0)
+	if err != nil {
+		return 0, fmt.Errorf("failed to read stored data: %w", err)
+	}
+	storedDates := make(map[string]bool, len(existing))
+	for _, row := range existing {
+		storedDates[row.Date] = true
+	}
+
+	var fresh []models.TransformedData
+	for _, row := range rows {
+		if !storedDates[row.Date] {
+			fresh = append(fresh, row)
+		}
+	}
+	if len(fresh) == 0 {
+		return 0, nil
+	}
+
+	if err := s.storage.StoreTransformedData(fresh); err != nil {
+		// A file store that fails to persist keeps the rows in memory
+		s.refreshStoredRecords()
+		s.refreshAggregates(fresh)
+		return 0, fmt.Errorf("failed to store transformed data: %w", err)
+	}
+
+	s.metrics.RecordsProcessed.Add(float64(len(fresh)))
+	s.refreshStoredRecords()
+	s.refreshAggregates(fresh)
+	return len(fresh), nil
+}
+
+// ReingestDate reprocesses a single day: it fetches the upstream data,
+// transforms it as a run with since and until both set to date would, and
+// atomically replaces every row stored for that day with the result, so
+// fixing a bad day neither needs a backfill nor duplicates rows. It returns
+// how many rows were removed and stored. Like a push it leaves the last
+// ingestion time alone. When PartialIngest stands in for a failed CRM
+// fetch, a day that already has rows is left as it is.
+func (s *Service) ReingestDate(ctx context.Context, date string) (deleted, stored int, err error) {
+	ctx, done, err := s.beginWork(ctx, fmt.Sprintf("reingestion date=%q", date))
+	if err != nil {
+		return 0, 0, err
+	}
+	defer done()
+
+	day, err := parseFlexibleDate(date)
+	if err != nil {
+		return 0, 0, fmt.Errorf("invalid date format: %w", err)
+	}
+	date = day.Format(dateLayout)
+
+	sampler := logsample.New(s.logSampleRate)
+	ctx = logsample.WithSampler(ctx, sampler)
+	defer sampler.Flush(s.logger)
+
+	s.logger.WithField("date", date).Info("Starting date reingestion")
+
+	adsData, crmData, partial, err := s.fetchSourceData(ctx)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	transformedData, err := s.transformData(adsData, crmData, day, day)
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to transform data: %w", err)
+	}
+
+	if err := ctx.Err(); err != nil {
+		return 0, 0, fmt.Errorf("reingestion cancelled: %w", err)
+	}
+
+	// Without the CRM a stored day is kept rather than replaced
+	if partial {
+		stored, err = s.storeUnstoredDates(transformedData)
+		if err != nil {
+			return 0, 0, err
+		}
+		s.logger.WithFields(logrus.Fields{
+			"date":              date,
+			"records_processed": stored,
+		}).Warn("Date reingestion completed without CRM data")
+		return 0, stored, nil
+	}
+
+	deleted, err = s.storage.ReplaceTransformedData(day, day, transformedData)
+	// The day may have changed even when the replace reports an error, as a
+	// file store that fails to persist keeps the new rows in memory
+	s.aggregates.drop(func(cached string) bool {
+		return cached != date
+	})
+	if err != nil {
+		s.refreshStoredRecords()
+		return 0, 0, fmt.Errorf("failed to replace stored data: %w", err)
+	}
+
+	s.metrics.RecordsProcessed.Add(float64(len(transformedData)))
+	s.refreshStoredRecords()
+	s.refreshAggregates(transformedData)
+	s.logger.WithFields(logrus.Fields{
+		"date":              date,
+		"records_deleted":   deleted,
+		"records_processed": len(transformedData),
+	}).Info("Date reingestion completed")
+	return deleted, len(transformedData), nil
+}
+
+// RunIngestionAsync starts an ingestion in the background and returns the
+// job tracking it. The ingestion is detached from ctx's cancellation so it
+// keeps running after the response has been sent, but keeps its values such
+// as the request ID; only Shutdown cancels it.
+func (s *Service) RunIngestionAsync(ctx context.Context, opts IngestOptions) jobs.Job {
+	job := s.jobs.Create(JobTypeIngestion)
+
+	go func() {
+		s.jobs.Start(job.ID)
+		err := s.RunIngestion(context.WithoutCancel(ctx), opts)
+		if err != nil {
+			s.logger.WithError(err).WithField("job_id", job.ID).Error("Async ingestion failed")
+		}
+		s.jobs.Finish(job.ID, err)
+	}()
+
+	return job
+}
+
+// GetJob returns the current state of a background job.
+func (s *Service) GetJob(id string) (jobs.Job, bool) {
+	return s.jobs.Get(id)
+}
+
+// refreshStoredRecords updates the stored records gauge after the stored
+// rows changed. A failed count only leaves the gauge stale.
+func (s *Service) refreshStoredRecords() {
+	records, err := s.storage.CountTransformedData(nil)
+	if err != nil {
+		s.logger.WithError(err).Warn("Failed to count stored records")
+		return
+	}
+	s.metrics.StoredRecords.Set(float64(records))
+}
+
+// HealthDetail reports the last successful ingestion, the number of stored
+// rows and the configured storage backend.
+func (s *Service) HealthDetail() (*models.HealthDetail, error) {
+	lastIngestion, err := s.storage.GetLastIngestionTime()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get last ingestion time: %w", err)
+	}
+
+	records, err := s.storage.CountTransformedData(nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count stored records: %w", err)
+	}
+	s.metrics.StoredRecords.Set(float64(records))
+
+	backend := s.config.StorageBackend
+	if backend == "" {
+		backend = constants.StorageBackendMemory
+	}
+
+	detail := &models.HealthDetail{
+		Records:        records,
+		StorageBackend: backend,
+	}
+	if !lastIngestion.IsZero() {
+		detail.LastIngestion = lastIngestion.Format(time.RFC3339)
+	}
+	return detail, nil
+}
+
+// IngestionStatus reports when data was last ingested and what is stored:
+// the row count and the first and last dates covered. Before any ingestion
+// the time and dates are empty and the count is zero.
+func (s *Service) IngestionStatus() (*models.IngestionStatus, error) {
+	lastIngestion, err := s.storage.GetLastIngestionTime()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get last ingestion time: %w", err)
+	}
+
+	records, err := s.storage.CountTransformedData(nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count stored records: %w", err)
+	}
+	s.metrics.StoredRecords.Set(float64(records))
+
+	firstDate, lastDate, err := s.storage.DateRange()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get stored date range: %w", err)
+	}
+
+	status := &models.IngestionStatus{
+		Records:   records,
+		FirstDate: firstDate,
+		LastDate:  lastDate,
+	}
+	if !lastIngestion.IsZero() {
+		status.LastIngestion = lastIngestion.Format(time.RFC3339)
+	}
+	return status, nil
+}
+
+// Freshness reports the last ingestion time and whether it is older than
+// StaleAfter, so metrics responses can flag outdated data.
+func (s *Service) Freshness() (models.Freshness, error) {
+	lastIngestion, err := s.storage.GetLastIngestionTime()
+	if err != nil {
+		return models.Freshness{}, fmt.Errorf("failed to get last ingestion time: %w", err)
+	}
+	if lastIngestion.IsZero() {
+		return models.Freshness{Stale: true}, nil
+	}
+
+	staleAfter := s.config.StaleAfter
+	if staleAfter <= 0 {
+		staleAfter = constants.DefaultStaleAfter * time.Hour
+	}
+	return models.Freshness{
+		DataAsOf: lastIngestion.Format(time.RFC3339),
+		Stale:    s.now().Sub(lastIngestion) > staleAfter,
+	}, nil
+}
+
+// Ready probes the upstream dependencies and reports each one as healthy or
+// unhealthy. The Ads and CRM APIs are always checked; HTTP sinks only when
+// configured, named "sink" or, with several, "sink_1", "sink_2", ...
+// Probes run concurrently under their own short timeout so a hung upstream
+// can't stall the caller.
+func (s *Service) Ready(ctx context.Context) map[string]string {
+	dependencies := map[string]string{
+		"ads_api": s.config.AdsAPIURL,
+		"crm_api": s.config.CRMAPIURL,
+	}
+	var sinks []string
+	for _, sink := range s.sinks {
+		if httpSink, ok := sink.(*HTTPSink); ok {
+			sinks = append(sinks, httpSink.url)
+		}
+	}
+	for i, sink := range sinks {
+		name := "sink"
+		if len(sinks) > 1 {
+			name = fmt.Sprintf("sink_%d", i+1)
+		}
+		dependencies[name] = sink
+	}
+
+	timeout := s.config.ReadinessTimeout
+	if timeout <= 0 {
+		timeout = constants.DefaultReadinessTimeout * time.Second
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	var (
+		mu     sync.Mutex
+		wg     sync.WaitGroup
+		status = make(map[string]string, len(dependencies))
+	)
+
+	for name, url := range dependencies {
+		wg.Add(1)
+		go func(name, url string) {
+			defer wg.Done()
+
+			err := fmt.Errorf("%s URL not configured", name)
+			if url != "" {
+				err = s.client.Ping(ctx, url)
+			}
+
+			health := constants.HealthStatusHealthy
+			if err != nil {
+				s.logger.WithError(err).WithField("dependency", name).Warn("Dependency probe failed")
+				health = constants.HealthStatusUnhealthy
+			}
+
+			mu.Lock()
+			status[name] = health
+			mu.Unlock()
+		}(name, url)
+	}
+
+	wg.Wait()
+	return status
+}
+
 func (s *Service) fetchAdsData(ctx context.Context) (*models.AdsData, error) {
 	if s.config.AdsAPIURL == "" {
 		return nil, fmt.Errorf("ads API URL not configured")
 	}
 
-	var response models.ExternalResponse
-	if err := s.client.Get(ctx, s.config.AdsAPIURL, &response); err != nil {
+	ads := &models.AdsData{Performance: []models.AdsPerformance{}}
+	err := s.fetchPages(ctx, s.config.AdsAPIURL, s.config.AdsAPIHeaders, func(page *models.ExternalResponse) {
+		if page.External.Ads != nil {
+			ads.Performance = append(ads.Performance, page.External.Ads.Performance...)
+			ads.Malformed = append(ads.Malformed, page.External.Ads.Malformed...)
+		}
+	})
+	if err != nil {
 		return nil, err
 	}
 
-	if response.External.Ads == nil {
-		return &models.AdsData{Performance: []models.AdsPerformance{}}, nil
-	}
-
-	return response.External.Ads, nil
+	return ads, nil
 }
 
 func (s *Service) fetchCRMData(ctx context.Context) (*models.CRMData, error) {
@@ -103,47 +737,93 @@ func (s *Service) fetchCRMData(ctx context.Context) (*models.CRMData, error) {
 		return nil, fmt.Errorf("crm API URL not configured")
 	}
 
-	var response models.ExternalResponse
-	if err := s.client.Get(ctx, s.config.CRMAPIURL, &response); err != nil {
+	crm := &models.CRMData{Opportunities: []models.Opportunity{}}
+	err := s.fetchPages(ctx, s.config.CRMAPIURL, s.config.CRMAPIHeaders, func(page *models.ExternalResponse) {
+		if page.External.CRM != nil {
+			crm.Opportunities = append(crm.Opportunities, page.External.CRM.Opportunities...)
+			crm.Malformed = append(crm.Malformed, page.External.CRM.Malformed...)
+		}
+	})
+	if err != nil {
 		return nil, err
 	}
 
-	if response.External.CRM == nil {
-		return &models.CRMData{Opportunities: []models.Opportunity{}}, nil
-	}
-
-	return response.External.CRM, nil
+	return crm, nil
 }
 
-func (s *Service) transformData(adsData *models.AdsData, crmData *models.CRMData, sinceTime time.Time) ([]models.TransformedData, error) {
+// invalidDateWarning is logged for ads rows whose date can't be parsed, at
+// most LogSampleRate times per transform.
+const invalidDateWarning = "Invalid date format in ads data, skipping"
+
+// transformData merges ads rows with their matching opportunities. Rows dated
+// before sinceTime or after untilTime are skipped; a zero bound is open.
+// Ads rows sharing a date, channel and campaign are merged first, so each
+// key yields one row. Matching and metric calculation run on up to
+// s.concurrency workers; output is in (date, channel, campaign_id) order.
+func (s *Service) transformData(adsData *models.AdsData, crmData *models.CRMData, sinceTime, untilTime time.Time) ([]models.TransformedData, error) {
 	// Group CRM opportunities by UTM parameters for efficient lookup
-	crmLookup := s.buildCRMLookup(crmData.Opportunities)
+	opportunities := s.dedupeOpportunities(crmData.Opportunities)
+	opportunities = s.normalizeStages(opportunities)
+	opportunities = s.checkCurrencies(opportunities)
+	opportunities = s.filterOpportunitiesSince(opportunities, sinceTime)
+	crmLookup := s.buildCRMLookup(opportunities)
 
-	var transformedData []models.TransformedData
+	// Keep the ads inside the window
+	var ads []models.AdsPerformance
+	invalidDates := logsample.New(s.logSampleRate)
+	defer invalidDates.Flush(s.logger)
 
 	for _, ad := range adsData.Performance {
-		// Filter by date if sinceTime is specified
-		if !sinceTime.IsZero() {
+		// Filter by date if a since/until bound is specified
+		if !sinceTime.IsZero() || !untilTime.IsZero() {
 			adDate, err := time.Parse("2006-01-02", ad.Date)
 			if err != nil {
-				s.logger.WithField("date", ad.Date).Warn("Invalid date format in ads data, skipping")
+				if invalidDates.Allow(invalidDateWarning) {
+					s.logger.WithField("date", ad.Date).Warn(invalidDateWarning)
+				}
 				continue
 			}
-			if adDate.Before(sinceTime) {
+			if !sinceTime.IsZero() && adDate.Before(sinceTime) {
+				continue
+			}
+			if !untilTime.IsZero() && adDate.After(untilTime) {
 				continue
 			}
 		}
 
-		// Find matching CRM opportunities
-		matchingOpportunities := s.findMatchingOpportunities(ad, crmLookup)
+		ads = append(ads, ad)
+	}
+	ads = s.mergeDuplicateAds(ads)
+
+	if len(ads) == 0 {
+		return nil, nil
+	}
+
+	// Match every ad before computing metrics, so opportunities shared
+	// between rows can be attributed across them. The lookup is only read
+	// from here on, so workers can share it.
+	matches := make([][]models.Opportunity, len(ads))
+	matchTypes := make([]string, len(ads))
+	forEachIndex(len(ads), s.concurrency, func(i int) {
+		matches[i], matchTypes[i] = s.findMatchingOpportunities(ads[i], crmLookup)
+	})
+
+	credits := s.attribute(ads, matches)
+
+	transformedData := make([]models.TransformedData, len(ads))
+	forEachIndex(len(ads), s.concurrency, func(i int) {
+		ad := ads[i]
 
 		// Calculate metrics
-		metrics := s.calculateMetrics(ad, matchingOpportunities)
+		metrics := s.roundMetrics(s.calculateMetrics(ad, credits[i]))
 
-		transformedData = append(transformedData, models.TransformedData{
+		transformedData[i] = models.TransformedData{
 			Date:         ad.Date,
 			Channel:      ad.Channel,
 			CampaignID:   ad.CampaignID,
+			UTMCampaign:  ad.UTMCampaign,
+			UTMSource:    ad.UTMSource,
+			UTMMedium:    ad.UTMMedium,
 			Clicks:       ad.Clicks,
 			Impressions:  ad.Impressions,
 			Cost:         ad.Cost,
@@ -151,14 +831,25 @@ func (s *Service) transformData(adsData *models.AdsData, crmData *models.CRMData
 			Opportunities: metrics.Opportunities,
 			ClosedWon:    metrics.ClosedWon,
 			Revenue:      metrics.Revenue,
+			WeightedRevenue: metrics.WeightedRevenue,
 			CPC:          metrics.CPC,
 			CPA:          metrics.CPA,
 			CVRLeadToOpp: metrics.CVRLeadToOpp,
 			CVROppToWon:  metrics.CVROppToWon,
-			
-			ROAS:        metrics.ROAS,
-		})
-	}
+			ROAS:         metrics.ROAS,
+			CTR:          metrics.CTR,
+			CPM:          metrics.CPM,
+			MatchType:    matchTypes[i],
+		}
+	})
+	s.flagAnomalies(transformedData)
+
+	// Upstream pages arrive in no particular order, so emit rows in key
+	// order, (date, channel, campaign_id), the same order stored rows are
+	// read back in. Rows sharing a key keep their upstream order.
+	sort.SliceStable(transformedData, func(i, j int) bool {
+		return compareKey(cursorFor(transformedData[i]), cursorFor(transformedData[j])) < 0
+	})
 
 	return transformedData, nil
 }
@@ -174,11 +865,96 @@ type Metrics struct {
 	Opportunities int
 	ClosedWon     int
 	Revenue       float64
+	WeightedRevenue float64
 	CPC           float64
 	CPA           float64
 	CVRLeadToOpp  float64
 	CVROppToWon   float64
 	ROAS          float64
+	CTR           float64
+	CPM           float64
+}
+
+// mergeDuplicateAds sums ads rows sharing a date, channel and campaign into
+// the first of them, the way export consolidates stored rows, so their
+// clicks, impressions and cost are totalled before any metric is computed
+// and matched opportunities are credited once. The first row's UTMs are
+// kept.
+func (s *Service) mergeDuplicateAds(ads []models.AdsPerformance) []models.AdsPerformance {
+	merged := make([]models.AdsPerformance, 0, len(ads))
+	index := make(map[string]int, len(ads))
+
+	for _, ad := range ads {
+		key := ad.Date + "|" + ad.Channel + "|" + ad.CampaignID
+		if i, seen := index[key]; seen {
+			merged[i].Clicks += ad.Clicks
+			merged[i].Impressions += ad.Impressions
+			merged[i].Cost += ad.Cost
+			continue
+		}
+		index[key] = len(merged)
+		merged = append(merged, ad)
+	}
+
+	if duplicates := len(ads) - len(merged); duplicates > 0 {
+		s.logger.WithField("duplicates", duplicates).Warn("Merged duplicate ads rows")
+	}
+
+	return merged
+}
+
+// dedupeOpportunities keeps one opportunity per OpportunityID, the most
+// recent by CreatedAt, so overlapping CRM pages or retried fetches don't
+// double-count. The first occurrence's position is kept; opportunities
+// without an ID are passed through.
+func (s *Service) dedupeOpportunities(opportunities []models.Opportunity) []models.Opportunity {
+	deduped := make([]models.Opportunity, 0, len(opportunities))
+	index := make(map[string]int, len(opportunities))
+
+	for _, opp := range opportunities {
+		if opp.OpportunityID == "" {
+			deduped = append(deduped, opp)
+			continue
+		}
+		if i, seen := index[opp.OpportunityID]; seen {
+			if opp.CreatedAt.After(deduped[i].CreatedAt) {
+				deduped[i] = opp
+			}
+			continue
+		}
+		index[opp.OpportunityID] = len(deduped)
+		deduped = append(deduped, opp)
+	}
+
+	if dropped := len(opportunities) - len(deduped); dropped > 0 {
+		s.logger.WithField("duplicates", dropped).Warn("Dropped duplicate opportunities")
+	}
+
+	return deduped
+}
+
+// filterOpportunitiesSince drops opportunities created before sinceTime so they
+// aren't attributed to newer ads sharing the same UTMs. Opportunities with a
+// zero CreatedAt are kept: without a creation date there is no evidence they
+// predate the window. A zero sinceTime disables the filter.
+func (s *Service) filterOpportunitiesSince(opportunities []models.Opportunity, sinceTime time.Time) []models.Opportunity {
+	if sinceTime.IsZero() {
+		return opportunities
+	}
+
+	filtered := make([]models.Opportunity, 0, len(opportunities))
+	for _, opp := range opportunities {
+		if !opp.CreatedAt.IsZero() && opp.CreatedAt.Before(sinceTime) {
+			continue
+		}
+		filtered = append(filtered, opp)
+	}
+
+	if dropped := len(opportunities) - len(filtered); dropped > 0 {
+		s.logger.WithField("dropped", dropped).Debug("Excluded opportunities created before since date")
+	}
+
+	return filtered
 }
 
 func (s *Service) buildCRMLookup(opportunities []models.Opportunity) map[CRMLookupKey][]models.Opportunity {
@@ -196,7 +972,10 @@ func (s *Service) buildCRMLookup(opportunities []models.Opportunity) map[CRMLook
 	return lookup
 }
 
-func (s *Service) findMatchingOpportunities(ad models.AdsPerformance, crmLookup map[CRMLookupKey][]models.Opportunity) []models.Opportunity {
+// findMatchingOpportunities returns the opportunities attributed to an ad and
+// which tier produced the match. The tiers tried depend on the configured
+// MatchStrategy: exact, then campaign-only, then source-only.
+func (s *Service) findMatchingOpportunities(ad models.AdsPerformance, crmLookup map[CRMLookupKey][]models.Opportunity) ([]models.Opportunity, string) {
 	// Try exact match first
 	exactKey := CRMLookupKey{
 		UTMCampaign: s.normalizeUTM(ad.UTMCampaign),
@@ -205,7 +984,11 @@ func (s *Service) findMatchingOpportunities(ad models.AdsPerformance, crmLookup
 	}
 
 	if opportunities, exists := crmLookup[exactKey]; exists {
-		return opportunities
+		return opportunities, constants.MatchTypeExact
+	}
+
+	if s.matchStrategy == StrategyExact {
+		return []models.Opportunity{}, constants.MatchTypeNone
 	}
 
 	// Try fallback matching (campaign only)
@@ -216,7 +999,11 @@ func (s *Service) findMatchingOpportunities(ad models.AdsPerformance, crmLookup
 	}
 
 	if opportunities, exists := crmLookup[fallbackKey]; exists {
-		return opportunities
+		return opportunities, constants.MatchTypeCampaign
+	}
+
+	if s.matchStrategy == StrategyCampaignFallback {
+		return []models.Opportunity{}, constants.MatchTypeNone
 	}
 
 	// Try source-only fallback
@@ -227,30 +1014,76 @@ func (s *Service) findMatchingOpportunities(ad models.AdsPerformance, crmLookup
 	}
 
 	if opportunities, exists := crmLookup[sourceKey]; exists {
-		return opportunities
+		return opportunities, constants.MatchTypeSource
 	}
 
-	return []models.Opportunity{}
+	return []models.Opportunity{}, constants.MatchTypeNone
 }
 
+// normalizeUTM lowercases and trims a UTM value. With fuzzy matching it also
+// collapses runs of "-", "_" and whitespace into a single "_" and strips
+// leading and trailing punctuation.
 func (s *Service) normalizeUTM(utm string) string {
-	return strings.ToLower(strings.TrimSpace(utm))
+	normalized := strings.ToLower(strings.TrimSpace(utm))
+	if !s.fuzzyUTM {
+		return normalized
+	}
+
+	normalized = strings.TrimFunc(normalized, func(r rune) bool {
+		return unicode.IsPunct(r) || unicode.IsSymbol(r) || unicode.IsSpace(r)
+	})
+
+	var b strings.Builder
+	separator := false
+	for _, r := range normalized {
+		if r == '-' || r == '_' || unicode.IsSpace(r) {
+			separator = true
+			continue
+		}
+		if separator {
+			b.WriteByte('_')
+			separator = false
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
 }
 
-func (s *Service) calculateMetrics(ad models.AdsPerformance, opportunities []models.Opportunity) Metrics {
+func (s *Service) calculateMetrics(ad models.AdsPerformance, credits []Credit) Metrics {
 	metrics := Metrics{}
 
-	// Count opportunities by stage
-	for _, opp := range opportunities {
-		metrics.Opportunities++
-		if opp.Stage == "closed_won" {
-			metrics.ClosedWon++
-			metrics.Revenue += opp.Amount
+	// Count opportunities by stage, crediting each row its share of revenue.
+	// When leads come from the CRM, lead-stage records are leads rather
+	// than opportunities.
+	crmLeads := 0
+	for _, credit := range credits {
+		if s.leadSource == LeadSourceCRM && isLead(credit.Opportunity) {
+			if credit.Counted {
+				crmLeads++
+			}
+			continue
+		}
+
+		won := isClosedWon(credit.Opportunity)
+		if credit.Counted {
+			metrics.Opportunities++
+			if won {
+				metrics.ClosedWon++
+			}
+		}
+		amount := s.currency.toBase(credit.Opportunity) * credit.Share
+		if won {
+			metrics.Revenue += amount
 		}
+		metrics.WeightedRevenue += amount * s.stageWeights[normalizeStage(credit.Opportunity.Stage)]
 	}
 
-	// Estimate leads (simplified: assume 10% of clicks become leads)
-	metrics.Leads = int(float64(ad.Clicks) * 0.1)
+	// Use the CRM's leads when there are any, otherwise estimate them
+	if crmLeads > 0 {
+		metrics.Leads = crmLeads
+	} else {
+		metrics.Leads = s.estimateLeads(ad)
+	}
 
 	// Calculate CPC
 	if ad.Clicks > 0 {
@@ -276,98 +1109,407 @@ func (s *Service) calculateMetrics(ad models.AdsPerformance, opportunities []mod
 		metrics.ROAS = metrics.Revenue / ad.Cost
 	}
 
+	// Calculate CTR and CPM
+	if ad.Impressions > 0 {
+		metrics.CTR = float64(ad.Clicks) / float64(ad.Impressions)
+		metrics.CPM = ad.Cost / float64(ad.Impressions) * 1000
+	}
+
 	return metrics
 }
 
-func (s *Service) GetChannelMetrics(from, to time.Time, channel string, limit, offset int) ([]models.TransformedData, error) {
-	filters := map[string]string{"channel": channel}
-	return s.storage.GetTransformedData(from, to, filters, limit, offset)
+// ChannelMetricsQuery describes a channel metrics lookup.
+type ChannelMetricsQuery struct {
+	From        time.Time
+	To          time.Time
+	Channel     string
+	Granularity Granularity
+	SortBy      SortField
+	Order       SortOrder
+	Limit       int
+	Offset      int
+	// After resumes paging after a cursor instead of at Offset. Cursors
+	// follow the default date-ascending order.
+	After *Cursor
+	// Consolidate collapses the range into one row per campaign instead of
+	// rolling it up by Granularity. Consolidated rows have no date and are
+	// paged by offset only.
+	Consolidate bool
+	// MinCost and MinRevenue drop rows whose cost or revenue falls below
+	// them, after consolidation or roll-up. A row equal to a threshold is
+	// kept; zero disables the threshold.
+	MinCost    float64
+	MinRevenue float64
 }
 
-func (s *Service) GetFunnelMetrics(from, to time.Time, utmCampaign string, limit, offset int) ([]models.TransformedData, error) {
-	// For funnel metrics, we need to filter by UTM campaign
-	// Since we don't store UTM campaign in transformed data, we'll return all data
-	// and let the client filter by campaign_id
-	filters := map[string]string{}
-	return s.storage.GetTransformedData(from, to, filters, limit, offset)
+// DefaultDateRange fills in the bounds a metrics query left empty: to
+// defaults to today (UTC) and from to DefaultRangeDays before to. Supplied
+// values are returned unchanged, even if malformed, for the caller to
+// validate; a malformed to leaves an omitted from empty.
+func (s *Service) DefaultDateRange(from, to string) (string, string) {
+	if to == "" {
+		to = s.now().UTC().Format(dateLayout)
+	}
+	if from == "" {
+		days := s.config.DefaultRangeDays
+		if days <= 0 {
+			days = constants.DefaultRangeDays
+		}
+		if toDate, err := time.Parse(dateLayout, to); err == nil {
+			from = toDate.AddDate(0, 0, -days).Format(dateLayout)
+		}
+	}
+	return from, to
 }
 
-func (s *Service) ExportData(ctx context.Context, date string) error {
-	if s.config.SinkURL == "" || s.config.SinkSecret == "" {
-		return fmt.Errorf("sink URL or secret not configured")
+// GetChannelMetrics returns a page of the channel's rows and, when the page
+// is in cursor order and more rows follow, the cursor for the next page.
+func (s *Service) GetChannelMetrics(query ChannelMetricsQuery) ([]models.TransformedData, string, error) {
+	if query.Consolidate {
+		if query.After != nil {
+			return nil, "", ErrCursorNotAllowed
+		}
+		data, err := s.consolidatedRows(query.From, query.To, query.Channel)
+		if err != nil {
+			return nil, "", err
+		}
+		for i := range data {
+			data[i].Date = ""
+		}
+		data = applyThresholds(data, query.MinCost, query.MinRevenue)
+		if err := sortRows(data, query.SortBy, query.Order); err != nil {
+			return nil, "", err
+		}
+		return paginate(data, query.Limit, query.Offset), "", nil
 	}
 
-	// Parse date
-	exportDate, err := time.Parse("2006-01-02", date)
+	// Sorting and bucketing need the full range, so paginate afterwards
+	filters := map[string]string{"channel": query.Channel}
+	data, err := s.storage.GetTransformedData(query.From, query.To, filters, 0, 0)
 	if err != nil {
-		return fmt.Errorf("invalid date format: %w", err)
+		return nil, "", err
 	}
 
-	// Get data for the specific date
-	data, err := s.storage.GetTransformedData(exportDate, exportDate, map[string]string{}, 0, 0)
+	if query.Granularity != "" && query.Granularity != GranularityDay {
+		data, err = rollUp(data, query.Granularity)
+		if err != nil {
+			return nil, "", err
+		}
+	}
+
+	data = applyThresholds(data, query.MinCost, query.MinRevenue)
+
+	return pageRows(data, query.SortBy, query.Order, query.Limit, query.Offset, query.After)
+}
+
+// applyThresholds keeps the rows whose cost is at least minCost and whose
+// revenue is at least minRevenue.
+func applyThresholds(data []models.TransformedData, minCost, minRevenue float64) []models.TransformedData {
+	if minCost <= 0 && minRevenue <= 0 {
+		return data
+	}
+
+	kept := data[:0]
+	for _, item := range data {
+		if item.Cost >= minCost && item.Revenue >= minRevenue {
+			kept = append(kept, item)
+		}
+	}
+	return kept
+}
+
+// GetFunnelMetrics returns the range's rows stored under utmCampaign, or
+// every row when it is empty, paged like channel metrics.
+func (s *Service) GetFunnelMetrics(from, to time.Time, utmCampaign string, limit, offset int, after *Cursor) ([]models.TransformedData, string, error) {
+	filters := map[string]string{}
+	if utmCampaign != "" {
+		filters["utm_campaign"] = utmCampaign
+	}
+	data, err := s.storage.GetTransformedData(from, to, filters, 0, 0)
 	if err != nil {
-		return fmt.Errorf("failed to get data for export: %w", err)
+		return nil, "", err
 	}
 
-	// Group data by channel and campaign for consolidation
-	consolidated := s.consolidateDataByChannelAndCampaign(data)
+	return pageRows(data, "", "", limit, offset, after)
+}
 
-	// Export each consolidated record
-	for _, record := range consolidated {
-		if err := s.exportRecord(ctx, record); err != nil {
-			s.logger.WithError(err).WithField("record", record).Error("Failed to export record")
-			return err
+// GetSourceMetrics totals the range's rows per UTM source and medium,
+// optionally restricted to one source and/or medium, ordered by source then
+// medium. Rows stored without UTMs are grouped under an empty source and
+// medium. Date, channel and campaign don't apply to a total and are left
+// empty.
+func (s *Service) GetSourceMetrics(from, to time.Time, utmSource, utmMedium string, limit, offset int) ([]models.TransformedData, error) {
+	filters := map[string]string{}
+	if utmSource != "" {
+		filters["utm_source"] = utmSource
+	}
+	if utmMedium != "" {
+		filters["utm_medium"] = utmMedium
+	}
+
+	data, err := s.storage.GetTransformedData(from, to, filters, 0, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range data {
+		data[i].Date, data[i].Channel, data[i].CampaignID = "", "", ""
+		data[i].UTMCampaign, data[i].MatchType = "", ""
+	}
+	result := mergeRows(data, func(item models.TransformedData) string {
+		return item.UTMSource + "|" + item.UTMMedium
+	})
+
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].UTMSource != result[j].UTMSource {
+			return result[i].UTMSource < result[j].UTMSource
 		}
+		return result[i].UTMMedium < result[j].UTMMedium
+	})
+
+	return paginate(result, limit, offset), nil
+}
+
+// GetCampaignMetrics returns the rows of one campaign over the range, across
+// every channel it ran on, ordered by date. With consolidate they collapse
+// into a single row of campaign totals, ratios recomputed from the totals;
+// it spans the range and all channels, so its date and channel are empty.
+func (s *Service) GetCampaignMetrics(from, to time.Time, campaignID string, consolidate bool, limit, offset int) ([]models.TransformedData, error) {
+	filters := map[string]string{"campaign_id": campaignID}
+	data, err := s.storage.GetTransformedData(from, to, filters, 0, 0)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get campaign data: %w", err)
 	}
 
-	s.logger.WithField("records_exported", len(consolidated)).Info("Data export completed")
-	return nil
+	if consolidate {
+		data = mergeRows(data, func(item models.TransformedData) string {
+			return item.CampaignID
+		})
+		for i := range data {
+			data[i].Date = ""
+			data[i].Channel = ""
+		}
+		return paginate(data, limit, offset), nil
+	}
+
+	if err := sortRows(data, "", ""); err != nil {
+		return nil, err
+	}
+	return paginate(data, limit, offset), nil
 }
 
-func (s *Service) consolidateDataByChannelAndCampaign(data []models.TransformedData) []models.TransformedData {
-	consolidated := make(map[string]models.TransformedData)
+// pageRows sorts data and cuts out the requested page. Pages in the default
+// date-ascending order without an offset are cut by cursor and carry the
+// cursor for the next page; any other combination falls back to offsets.
+func pageRows(data []models.TransformedData, sortBy SortField, order SortOrder, limit, offset int, after *Cursor) ([]models.TransformedData, string, error) {
+	if err := sortRows(data, sortBy, order); err != nil {
+		return nil, "", err
+	}
 
-	for _, item := range data {
-		key := item.Channel + "|" + item.CampaignID
-		if existing, exists := consolidated[key]; exists {
-			// Aggregate metrics
-			existing.Clicks += item.Clicks
-			existing.Impressions += item.Impressions
-			existing.Cost += item.Cost
-			existing.Leads += item.Leads
-			existing.Opportunities += item.Opportunities
-			existing.ClosedWon += item.ClosedWon
-			existing.Revenue += item.Revenue
-			
-			// Recalculate derived metrics
-			if existing.Clicks > 0 {
-				existing.CPC = existing.Cost / float64(existing.Clicks)
-			}
-			if existing.Leads > 0 {
-				existing.CPA = existing.Cost / float64(existing.Leads)
-			}
-			if existing.Leads > 0 {
-				existing.CVRLeadToOpp = float64(existing.Opportunities) / float64(existing.Leads)
-			}
-			if existing.Opportunities > 0 {
-				existing.CVROppToWon = float64(existing.ClosedWon) / float64(existing.Opportunities)
-			}
-			if existing.Cost > 0 {
-				existing.ROAS = existing.Revenue / existing.Cost
+	keyOrder := (sortBy == "" || sortBy == SortByDate) && (order == "" || order == SortAsc)
+	if after != nil && (!keyOrder || offset > 0) {
+		return nil, "", ErrCursorNotAllowed
+	}
+
+	if keyOrder && offset == 0 {
+		page, next := pageAfter(data, after, limit)
+		return page, next, nil
+	}
+	return paginate(data, limit, offset), "", nil
+}
+
+// DeleteData removes stored rows in the date range, optionally restricted
+// to a channel, and returns how many were removed.
+func (s *Service) DeleteData(from, to time.Time, channel string) (int, error) {
+	filters := map[string]string{}
+	if channel != "" {
+		filters["channel"] = channel
+	}
+
+	deleted, err := s.storage.DeleteTransformedData(from, to, filters)
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete data: %w", err)
+	}
+	s.refreshStoredRecords()
+
+	first, last := from.Format(dateLayout), to.Format(dateLayout)
+	s.aggregates.drop(func(date string) bool {
+		return date < first || date > last
+	})
+
+	s.logger.WithFields(logrus.Fields{
+		"from":    from.Format("2006-01-02"),
+		"to":      to.Format("2006-01-02"),
+		"channel": channel,
+		"deleted": deleted,
+	}).Info("Deleted stored data")
+	return deleted, nil
+}
+
+// GetDimensions returns the distinct channels and campaign IDs stored in the
+// date range.
+func (s *Service) GetDimensions(from, to time.Time) (*models.Dimensions, error) {
+	channels, campaignIDs, err := s.storage.Dimensions(from, to)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get dimensions: %w", err)
+	}
+
+	return &models.Dimensions{
+		From:        from.Format("2006-01-02"),
+		To:          to.Format("2006-01-02"),
+		Channels:    channels,
+		CampaignIDs: campaignIDs,
+	}, nil
+}
+
+// GetMetricsSummary sums all rows in the date range (optionally restricted to
+// a channel) and recomputes the derived ratios on the totals. The daily
+// aggregates are summed instead of the rows when enabled.
+func (s *Service) GetMetricsSummary(from, to time.Time, channel string) (*models.MetricsSummary, error) {
+	var totals models.TransformedData
+	records := 0
+
+	if s.aggregatesEnabled() {
+		aggregates, err := s.aggregatedRows(from, to)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get data for summary: %w", err)
+		}
+		for _, aggregate := range aggregates {
+			if channel != "" && aggregate.row.Channel != channel {
+				continue
 			}
-			
-			consolidated[key] = existing
-		} else {
-			consolidated[key] = item
+			addTotals(&totals, aggregate.row)
+			records += aggregate.records
+		}
+	} else {
+		filters := map[string]string{}
+		if channel != "" {
+			filters["channel"] = channel
+		}
+
+		data, err := s.storage.GetTransformedData(from, to, filters, 0, 0)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get data for summary: %w", err)
 		}
+		for _, item := range data {
+			addTotals(&totals, item)
+		}
+		records = len(data)
 	}
+	recomputeDerivedMetrics(&totals)
+
+	return &models.MetricsSummary{
+		From:          from.Format("2006-01-02"),
+		To:            to.Format("2006-01-02"),
+		Channel:       channel,
+		Records:       records,
+		Clicks:        totals.Clicks,
+		Impressions:   totals.Impressions,
+		Cost:          totals.Cost,
+		Leads:         totals.Leads,
+		Opportunities: totals.Opportunities,
+		ClosedWon:     totals.ClosedWon,
+		Revenue:       totals.Revenue,
+		CPC:           totals.CPC,
+		CPA:           totals.CPA,
+		CVRLeadToOpp:  totals.CVRLeadToOpp,
+		CVROppToWon:   totals.CVROppToWon,
+		ROAS:          totals.ROAS,
+		CTR:           totals.CTR,
+		CPM:           totals.CPM,
+	}, nil
+}
 
-	// Convert map to slice
-	var result []models.TransformedData
-	for _, item := range consolidated {
-		result = append(result, item)
+// CompareMetrics summarises [from, to] and the equal-length range ending the
+// day before from, and reports the percentage change of each metric.
+func (s *Service) CompareMetrics(from, to time.Time, channel string) (*models.MetricsComparison, error) {
+	prevFrom, prevTo := previousRange(from, to)
+
+	current, err := s.GetMetricsSummary(from, to, channel)
+	if err != nil {
+		return nil, err
+	}
+	previous, err := s.GetMetricsSummary(prevFrom, prevTo, channel)
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.MetricsComparison{
+		Current:  *current,
+		Previous: *previous,
+		Deltas: models.MetricsDeltas{
+			Clicks:        percentChange(float64(previous.Clicks), float64(current.Clicks)),
+			Impressions:   percentChange(float64(previous.Impressions), float64(current.Impressions)),
+			Cost:          percentChange(previous.Cost, current.Cost),
+			Leads:         percentChange(float64(previous.Leads), float64(current.Leads)),
+			Opportunities: percentChange(float64(previous.Opportunities), float64(current.Opportunities)),
+			ClosedWon:     percentChange(float64(previous.ClosedWon), float64(current.ClosedWon)),
+			Revenue:       percentChange(previous.Revenue, current.Revenue),
+			CPC:           percentChange(previous.CPC, current.CPC),
+			CPA:           percentChange(previous.CPA, current.CPA),
+			CVRLeadToOpp:  percentChange(previous.CVRLeadToOpp, current.CVRLeadToOpp),
+			CVROppToWon:   percentChange(previous.CVROppToWon, current.CVROppToWon),
+			ROAS:          percentChange(previous.ROAS, current.ROAS),
+			CTR:           percentChange(previous.CTR, current.CTR),
+			CPM:           percentChange(previous.CPM, current.CPM),
+		},
+	}, nil
+}
+
+// previousRange returns the range of the same number of days as [from, to]
+// that ends the day before from. Both ranges are inclusive of their ends.
+func previousRange(from, to time.Time) (prevFrom, prevTo time.Time) {
+	days := int(to.Sub(from).Hours()/24) + 1
+	return from.AddDate(0, 0, -days), from.AddDate(0, 0, -1)
+}
+
+// percentChange returns the change from previous to current as a percentage,
+// or nil when previous is zero and the change is undefined.
+func percentChange(previous, current float64) *float64 {
+	if previous == 0 {
+		return nil
+	}
+	change := (current - previous) / previous * 100
+	return &change
+}
+
+// GetTopCampaigns consolidates the range by channel and campaign and returns
+// the n campaigns ranking highest on metric. Ties keep channel/campaign
+// order. Consolidated rows span the whole range, so their date is cleared.
+func (s *Service) GetTopCampaigns(from, to time.Time, metric SortField, n int) ([]models.TransformedData, error) {
+	key, ok := sortKeys[metric]
+	if !ok {
+		return nil, fmt.Errorf("unknown ranking metric %q", metric)
+	}
+
+	data, err := s.storage.GetTransformedData(from, to, map[string]string{}, 0, 0)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get data for ranking: %w", err)
 	}
 
+	ranked := s.ConsolidateDataByChannelAndCampaign(data)
+	for i := range ranked {
+		ranked[i].Date = ""
+	}
+	sort.SliceStable(ranked, func(i, j int) bool {
+		return key(ranked[i]) > key(ranked[j])
+	})
+
+	if n > 0 && n < len(ranked) {
+		ranked = ranked[:n]
+	}
+	return ranked, nil
+}
+
+// ConsolidateDataByChannelAndCampaign collapses data into one row per channel
+// and campaign, summing the totals and recomputing the ratios, ordered by
+// channel then campaign. Each row keeps the date of the first row merged
+// into it.
+func (s *Service) ConsolidateDataByChannelAndCampaign(data []models.TransformedData) []models.TransformedData {
+	result := mergeRows(data, func(item models.TransformedData) string {
+		return item.Channel + "|" + item.CampaignID
+	})
+
 	// Sort by channel and campaign
 	sort.Slice(result, func(i, j int) bool {
 		if result[i].Channel != result[j].Channel {
@@ -379,27 +1521,61 @@ func (s *Service) consolidateDataByChannelAndCampaign(data []models.TransformedD
 	return result
 }
 
-func (s *Service) exportRecord(ctx context.Context, record models.TransformedData) error {
-	// Create HMAC signature
-	signature := s.createHMACSignature(record)
+// mergeRows collapses rows sharing the same key into one, summing their totals
+// and recomputing derived metrics. The first row seen for a key provides the
+// non-additive fields. Output is in order of first appearance.
+func mergeRows(data []models.TransformedData, key func(models.TransformedData) string) []models.TransformedData {
+	merged := make(map[string]int)
+	var result []models.TransformedData
 
-	// Log the signature for debugging
-	s.logger.WithField("signature", signature).Debug("Created HMAC signature for export")
+	for _, item := range data {
+		k := key(item)
+		if idx, exists := merged[k]; exists {
+			addTotals(&result[idx], item)
+			recomputeDerivedMetrics(&result[idx])
+			continue
+		}
+		merged[k] = len(result)
+		result = append(result, item)
+	}
 
-	// Make POST request to sink
-	return s.client.Post(ctx, s.config.SinkURL, record, nil)
+	return result
 }
 
-func (s *Service) createHMACSignature(data models.TransformedData) string {
-	// Simple HMAC implementation (in production, use crypto/hmac)
-	// For this example, we'll create a simple hash
-	payload := fmt.Sprintf("%s|%s|%s|%d|%d|%.2f|%d|%d|%d|%.2f|%.3f|%.3f|%.3f|%.3f|%.3f|%.3f",
-		data.Date, data.Channel, data.CampaignID, data.Clicks, data.Impressions,
-		data.Cost, data.Leads, data.Opportunities, data.ClosedWon, data.Revenue,
-		data.CPC, data.CPA, data.CVRLeadToOpp, data.CVROppToWon, data.ROAS)
-	
-	// In a real implementation, use crypto/hmac with SHA256
-	// For this example, we'll use a simple approach
-	return fmt.Sprintf("hmac-sha256:%x", []byte(payload+s.config.SinkSecret))
+// addTotals adds the additive counters of item into dst. Derived ratios are
+// left untouched; call recomputeDerivedMetrics once all rows have been added.
+func addTotals(dst *models.TransformedData, item models.TransformedData) {
+	dst.Clicks += item.Clicks
+	dst.Impressions += item.Impressions
+	dst.Cost += item.Cost
+	dst.Leads += item.Leads
+	dst.Opportunities += item.Opportunities
+	dst.ClosedWon += item.ClosedWon
+	dst.Revenue += item.Revenue
+	dst.WeightedRevenue += item.WeightedRevenue
+	dst.Anomalies = mergeAnomalies(dst.Anomalies, item.Anomalies)
 }
 
+// recomputeDerivedMetrics recalculates the ratio metrics of an aggregated row
+// from its totals, so ratios are never averaged across rows.
+func recomputeDerivedMetrics(data *models.TransformedData) {
+	if data.Clicks > 0 {
+		data.CPC = data.Cost / float64(data.Clicks)
+	}
+	if data.Leads > 0 {
+		data.CPA = data.Cost / float64(data.Leads)
+	}
+	if data.Leads > 0 {
+		data.CVRLeadToOpp = float64(data.Opportunities) / float64(data.Leads)
+	}
+	if data.Opportunities > 0 {
+		data.CVROppToWon = float64(data.ClosedWon) / float64(data.Opportunities)
+	}
+	if data.Cost > 0 {
+		data.ROAS = data.Revenue / data.Cost
+	}
+	if data.Impressions > 0 {
+		data.CTR = float64(data.Clicks) / float64(data.Impressions)
+		data.CPM = data.Cost / float64(data.Impressions) * 1000
+	}
+}
@@ -1,14 +1,23 @@
 package etl
 
 import (
+	"context"
+	"encoding/json"
+	"errors"
+	"math/rand"
+	"net/http"
+	"net/http/httptest"
+	"sync"
 	"testing"
 	"time"
 
 	"admira-etl/internal/config"
+	"admira-etl/internal/constants"
 	"admira-etl/internal/models"
 	"admira-etl/internal/storage"
 
 	"github.com/sirupsen/logrus"
+	"github.com/sirupsen/logrus/hooks/test"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -87,6 +96,7 @@ func TestTransformData(t *testing.T) {
 					CVRLeadToOpp: 0.02, // 2 / 100
 					CVROppToWon:  0.5,  // 1 / 2
 					ROAS:         20.0, // 5000 / 250
+					MatchType:    constants.MatchTypeExact,
 				},
 			},
 		},
@@ -128,6 +138,7 @@ func TestTransformData(t *testing.T) {
 					CVRLeadToOpp: 0.0,
 					CVROppToWon:  0.0,
 					ROAS:         0.0,
+					MatchType:    constants.MatchTypeNone,
 				},
 			},
 		},
@@ -169,6 +180,7 @@ func TestTransformData(t *testing.T) {
 					CVRLeadToOpp: 0.0,
 					CVROppToWon:  0.0,
 					ROAS:         0.0,
+					MatchType:    constants.MatchTypeNone,
 				},
 			},
 		},
@@ -176,7 +188,7 @@ func TestTransformData(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			result, err := service.transformData(tt.adsData, tt.crmData, tt.sinceTime)
+			result, err := service.transformData(tt.adsData, tt.crmData, tt.sinceTime, time.Time{})
 			require.NoError(t, err)
 			require.Len(t, result, len(tt.expected))
 
@@ -197,6 +209,7 @@ func TestTransformData(t *testing.T) {
 				assert.InDelta(t, expected.CVRLeadToOpp, actual.CVRLeadToOpp, 0.001)
 				assert.InDelta(t, expected.CVROppToWon, actual.CVROppToWon, 0.001)
 				assert.InDelta(t, expected.ROAS, actual.ROAS, 0.001)
+				assert.Equal(t, expected.MatchType, actual.MatchType)
 			}
 		})
 	}
@@ -317,7 +330,7 @@ func TestFindMatchingOpportunities(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			result := service.findMatchingOpportunities(tt.ad, crmLookup)
+			result, _ := service.findMatchingOpportunities(tt.ad, crmLookup)
 			assert.Len(t, result, tt.expectedLen)
 
 			for i, expectedID := range tt.expectedIDs {
@@ -344,8 +357,9 @@ func TestCalculateMetrics(t *testing.T) {
 		{
 			name: "normal metrics calculation",
 			ad: models.AdsPerformance{
-				Clicks: 1000,
-				Cost:   250.0,
+				Clicks:      1000,
+				Impressions: 50000,
+				Cost:        250.0,
 			},
 			opportunities: []models.Opportunity{
 				{Stage: "closed_won", Amount: 5000.0},
@@ -362,6 +376,8 @@ func TestCalculateMetrics(t *testing.T) {
 				CVRLeadToOpp:  0.03, // 3 / 100
 				CVROppToWon:   0.6667, // 2 / 3
 				ROAS:          32.0, // 8000 / 250
+				CTR:           0.02, // 1000 / 50000
+				CPM:           5.0,  // 250 / 50000 * 1000
 			},
 		},
 		{
@@ -381,13 +397,15 @@ func TestCalculateMetrics(t *testing.T) {
 				CVRLeadToOpp:  0.0,
 				CVROppToWon:   0.0,
 				ROAS:          0.0,
+				CTR:           0.0, // Division by zero protection
+				CPM:           0.0, // Division by zero protection
 			},
 		},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			result := service.calculateMetrics(tt.ad, tt.opportunities)
+			result := service.calculateMetrics(tt.ad, fullCredit(tt.opportunities))
 
 			assert.Equal(t, tt.expected.Leads, result.Leads)
 			assert.Equal(t, tt.expected.Opportunities, result.Opportunities)
@@ -398,6 +416,8 @@ func TestCalculateMetrics(t *testing.T) {
 			assert.InDelta(t, tt.expected.CVRLeadToOpp, result.CVRLeadToOpp, 0.001)
 			assert.InDelta(t, tt.expected.CVROppToWon, result.CVROppToWon, 0.001)
 			assert.InDelta(t, tt.expected.ROAS, result.ROAS, 0.001)
+			assert.InDelta(t, tt.expected.CTR, result.CTR, 0.0001)
+			assert.InDelta(t, tt.expected.CPM, result.CPM, 0.0001)
 		})
 	}
 }
@@ -426,5 +446,1232 @@ func TestNormalizeUTM(t *testing.T) {
 			assert.Equal(t, tt.expected, result)
 		})
 	}
+
+	// Separators are left alone unless fuzzy matching is enabled
+	assert.Equal(t, "back-to-school", service.normalizeUTM("Back-To-School"))
+}
+
+func TestNormalizeUTM_Fuzzy(t *testing.T) {
+	logger := logrus.New()
+	logger.SetLevel(logrus.ErrorLevel)
+
+	service := NewService(&config.Config{FuzzyUTMMatch: true}, storage.NewInMemoryStorage(), logger)
+
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"back_to_school", "back_to_school"},
+		{"Back-To-School", "back_to_school"},
+		{"back to  school", "back_to_school"},
+		{"back -_ to school", "back_to_school"},
+		{" \"back_to_school!\" ", "back_to_school"},
+		{"--summer_sale--", "summer_sale"},
+		{"Q1.promo", "q1.promo"},
+		{"", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			assert.Equal(t, tt.expected, service.normalizeUTM(tt.input))
+		})
+	}
+}
+
+func TestFindMatchingOpportunities_Fuzzy(t *testing.T) {
+	logger := logrus.New()
+	logger.SetLevel(logrus.ErrorLevel)
+
+	opportunities := []models.Opportunity{
+		{OpportunityID: "O-1", UTMCampaign: "back_to_school", UTMSource: "google", UTMMedium: "cpc"},
+		{OpportunityID: "O-2", UTMCampaign: "back_to_work", UTMSource: "google", UTMMedium: "cpc"},
+	}
+	ad := models.AdsPerformance{UTMCampaign: "Back-To-School", UTMSource: "Google", UTMMedium: "CPC"}
+
+	// Exact matching only, so a miss isn't rescued by the source fallback
+	strict := NewService(&config.Config{MatchStrategy: "exact"}, storage.NewInMemoryStorage(), logger)
+	result, matchType := strict.findMatchingOpportunities(ad, strict.buildCRMLookup(opportunities))
+	assert.Empty(t, result)
+	assert.Equal(t, constants.MatchTypeNone, matchType)
+
+	fuzzy := NewService(&config.Config{MatchStrategy: "exact", FuzzyUTMMatch: true}, storage.NewInMemoryStorage(), logger)
+	result, matchType = fuzzy.findMatchingOpportunities(ad, fuzzy.buildCRMLookup(opportunities))
+	require.Len(t, result, 1)
+	assert.Equal(t, "O-1", result[0].OpportunityID)
+	assert.Equal(t, constants.MatchTypeExact, matchType)
+
+	// A genuinely different campaign still doesn't match
+	other := models.AdsPerformance{UTMCampaign: "back-to-college", UTMSource: "google", UTMMedium: "cpc"}
+	result, _ = fuzzy.findMatchingOpportunities(other, fuzzy.buildCRMLookup(opportunities))
+	assert.Empty(t, result)
+}
+
+
+func TestGetMetricsSummary(t *testing.T) {
+	logger := logrus.New()
+	logger.SetLevel(logrus.ErrorLevel)
+
+	cfg := &config.Config{}
+	store := storage.NewInMemoryStorage()
+	service := NewService(cfg, store, logger)
+
+	// Day one has a ROAS of 1.0 and day two a ROAS of 10.0; averaging the
+	// per-day ratios would give 5.5, while the true aggregate is 1900/1000.
+	data := []models.TransformedData{
+		{
+			Date: "2025-01-01", Channel: "google_ads", CampaignID: "C-1001",
+			Clicks: 900, Impressions: 30000, Cost: 900.0, Leads: 90,
+			Opportunities: 9, ClosedWon: 3, Revenue: 900.0,
+			CPC: 1.0, CPA: 10.0, CVRLeadToOpp: 0.1, CVROppToWon: 0.3333, ROAS: 1.0,
+		},
+		{
+			Date: "2025-01-02", Channel: "google_ads", CampaignID: "C-1001",
+			Clicks: 100, Impressions: 5000, Cost: 100.0, Leads: 10,
+			Opportunities: 1, ClosedWon: 1, Revenue: 1000.0,
+			CPC: 1.0, CPA: 10.0, CVRLeadToOpp: 0.1, CVROppToWon: 1.0, ROAS: 10.0,
+		},
+		{
+			Date: "2025-01-02", Channel: "facebook_ads", CampaignID: "C-2001",
+			Clicks: 500, Impressions: 20000, Cost: 250.0, Leads: 50,
+			Opportunities: 5, ClosedWon: 1, Revenue: 2500.0,
+		},
+		{
+			Date: "2025-02-01", Channel: "google_ads", CampaignID: "C-1001",
+			Clicks: 100, Cost: 100.0,
+		},
+	}
+	require.NoError(t, store.StoreTransformedData(data))
+
+	from, _ := time.Parse("2006-01-02", "2025-01-01")
+	to, _ := time.Parse("2006-01-02", "2025-01-31")
+
+	summary, err := service.GetMetricsSummary(from, to, "google_ads")
+	require.NoError(t, err)
+
+	assert.Equal(t, "2025-01-01", summary.From)
+	assert.Equal(t, "2025-01-31", summary.To)
+	assert.Equal(t, "google_ads", summary.Channel)
+	assert.Equal(t, 2, summary.Records)
+	assert.Equal(t, 1000, summary.Clicks)
+	assert.Equal(t, 35000, summary.Impressions)
+	assert.Equal(t, 1000.0, summary.Cost)
+	assert.Equal(t, 100, summary.Leads)
+	assert.Equal(t, 10, summary.Opportunities)
+	assert.Equal(t, 4, summary.ClosedWon)
+	assert.Equal(t, 1900.0, summary.Revenue)
+	assert.InDelta(t, 1.0, summary.CPC, 0.001)
+	assert.InDelta(t, 10.0, summary.CPA, 0.001)
+	assert.InDelta(t, 0.1, summary.CVRLeadToOpp, 0.001)
+	assert.InDelta(t, 0.4, summary.CVROppToWon, 0.001)
+	assert.InDelta(t, 1.9, summary.ROAS, 0.001)
+	assert.InDelta(t, 1000.0/35000.0, summary.CTR, 0.0001)
+	assert.InDelta(t, 1000.0/35000.0*1000, summary.CPM, 0.0001)
+
+	// Without a channel every row in the range is included
+	summary, err = service.GetMetricsSummary(from, to, "")
+	require.NoError(t, err)
+	assert.Equal(t, 3, summary.Records)
+	assert.Equal(t, 4400.0, summary.Revenue)
+	assert.InDelta(t, 4400.0/1250.0, summary.ROAS, 0.001)
+}
+
+func TestPreviousRange(t *testing.T) {
+	tests := []struct {
+		from, to, prevFrom, prevTo string
+	}{
+		{"2025-01-13", "2025-01-19", "2025-01-06", "2025-01-12"},
+		{"2025-03-01", "2025-03-01", "2025-02-28", "2025-02-28"},
+		// 31 days back from March crosses February
+		{"2025-03-01", "2025-03-31", "2025-01-29", "2025-02-28"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.from+".."+tt.to, func(t *testing.T) {
+			from, _ := time.Parse("2006-01-02", tt.from)
+			to, _ := time.Parse("2006-01-02", tt.to)
+
+			prevFrom, prevTo := previousRange(from, to)
+			assert.Equal(t, tt.prevFrom, prevFrom.Format("2006-01-02"))
+			assert.Equal(t, tt.prevTo, prevTo.Format("2006-01-02"))
+		})
+	}
+}
+
+func TestCompareMetrics(t *testing.T) {
+	logger := logrus.New()
+	logger.SetLevel(logrus.ErrorLevel)
+
+	store := storage.NewInMemoryStorage()
+	service := NewService(&config.Config{}, store, logger)
+
+	require.NoError(t, store.StoreTransformedData([]models.TransformedData{
+		// Previous week
+		{Date: "2025-01-06", Channel: "google_ads", CampaignID: "C-1001", Clicks: 100, Cost: 200.0, Leads: 10, Revenue: 400.0},
+		{Date: "2025-01-12", Channel: "google_ads", CampaignID: "C-1001", Clicks: 100, Cost: 200.0, Leads: 10, Revenue: 400.0},
+		// Current week
+		{Date: "2025-01-13", Channel: "google_ads", CampaignID: "C-1001", Clicks: 300, Cost: 300.0, Leads: 20, ClosedWon: 2, Revenue: 1200.0},
+		// Outside both windows
+		{Date: "2025-01-05", Channel: "google_ads", CampaignID: "C-1001", Clicks: 9999, Cost: 9999.0},
+		{Date: "2025-01-20", Channel: "google_ads", CampaignID: "C-1001", Clicks: 9999, Cost: 9999.0},
+	}))
+
+	from, _ := time.Parse("2006-01-02", "2025-01-13")
+	to, _ := time.Parse("2006-01-02", "2025-01-19")
+
+	comparison, err := service.CompareMetrics(from, to, "google_ads")
+	require.NoError(t, err)
+
+	assert.Equal(t, "2025-01-13", comparison.Current.From)
+	assert.Equal(t, "2025-01-19", comparison.Current.To)
+	assert.Equal(t, "2025-01-06", comparison.Previous.From)
+	assert.Equal(t, "2025-01-12", comparison.Previous.To)
+	assert.Equal(t, 2, comparison.Previous.Records)
+	assert.Equal(t, 200, comparison.Previous.Clicks)
+	assert.Equal(t, 300, comparison.Current.Clicks)
+
+	require.NotNil(t, comparison.Deltas.Clicks)
+	assert.InDelta(t, 50.0, *comparison.Deltas.Clicks, 0.001)
+	require.NotNil(t, comparison.Deltas.Cost)
+	assert.InDelta(t, -25.0, *comparison.Deltas.Cost, 0.001)
+	require.NotNil(t, comparison.Deltas.Revenue)
+	assert.InDelta(t, 50.0, *comparison.Deltas.Revenue, 0.001)
+	// CPC goes from 400/200 = 2.0 to 300/300 = 1.0
+	require.NotNil(t, comparison.Deltas.CPC)
+	assert.InDelta(t, -50.0, *comparison.Deltas.CPC, 0.001)
+	// ROAS goes from 800/400 = 2.0 to 1200/300 = 4.0
+	require.NotNil(t, comparison.Deltas.ROAS)
+	assert.InDelta(t, 100.0, *comparison.Deltas.ROAS, 0.001)
+
+	// Nothing closed last week, so there is no percentage change
+	assert.Nil(t, comparison.Deltas.ClosedWon)
+}
+
+func TestTransformData_KeepsUTMs(t *testing.T) {
+	logger := logrus.New()
+	logger.SetLevel(logrus.ErrorLevel)
+	service := NewService(&config.Config{}, storage.NewInMemoryStorage(), logger)
+
+	result, err := service.transformData(&models.AdsData{Performance: []models.AdsPerformance{{
+		Date: "2025-01-01", CampaignID: "C-1001", Channel: "google_ads", Clicks: 100,
+		UTMCampaign: "back_to_school", UTMSource: "google", UTMMedium: "cpc",
+	}}}, &models.CRMData{}, time.Time{}, time.Time{})
+	require.NoError(t, err)
+	require.Len(t, result, 1)
+	assert.Equal(t, "back_to_school", result[0].UTMCampaign)
+	assert.Equal(t, "google", result[0].UTMSource)
+	assert.Equal(t, "cpc", result[0].UTMMedium)
+}
+
+func TestGetFunnelMetrics(t *testing.T) {
+	logger := logrus.New()
+	logger.SetLevel(logrus.ErrorLevel)
+
+	store := storage.NewInMemoryStorage()
+	service := NewService(&config.Config{}, store, logger)
+
+	require.NoError(t, store.StoreTransformedData([]models.TransformedData{
+		{Date: "2025-01-01", Channel: "google_ads", CampaignID: "C-1001", UTMCampaign: "back_to_school", Clicks: 100},
+		{Date: "2025-01-01", Channel: "facebook_ads", CampaignID: "C-2001", UTMCampaign: "black_friday", Clicks: 80},
+		{Date: "2025-01-02", Channel: "facebook_ads", CampaignID: "C-2002", UTMCampaign: "back_to_school", Clicks: 10},
+	}))
+
+	from, _ := time.Parse("2006-01-02", "2025-01-01")
+	to, _ := time.Parse("2006-01-02", "2025-01-31")
+
+	data, _, err := service.GetFunnelMetrics(from, to, "back_to_school", 0, 0, nil)
+	require.NoError(t, err)
+	require.Len(t, data, 2)
+	assert.Equal(t, "C-1001", data[0].CampaignID)
+	assert.Equal(t, "C-2002", data[1].CampaignID)
+
+	// Without a campaign every row is returned
+	data, _, err = service.GetFunnelMetrics(from, to, "", 0, 0, nil)
+	require.NoError(t, err)
+	assert.Len(t, data, 3)
+}
+
+func TestGetSourceMetrics(t *testing.T) {
+	logger := logrus.New()
+	logger.SetLevel(logrus.ErrorLevel)
+
+	store := storage.NewInMemoryStorage()
+	service := NewService(&config.Config{}, store, logger)
+
+	require.NoError(t, store.StoreTransformedData([]models.TransformedData{
+		{Date: "2025-01-01", Channel: "google_ads", CampaignID: "C-1001", UTMSource: "google", UTMMedium: "cpc", Clicks: 100, Cost: 50.0, Revenue: 500.0},
+		{Date: "2025-01-02", Channel: "google_ads", CampaignID: "C-1002", UTMSource: "google", UTMMedium: "cpc", Clicks: 300, Cost: 150.0, Revenue: 100.0},
+		{Date: "2025-01-01", Channel: "google_ads", CampaignID: "C-1003", UTMSource: "google", UTMMedium: "display", Clicks: 40, Cost: 20.0},
+		{Date: "2025-01-01", Channel: "facebook_ads", CampaignID: "C-2001", UTMSource: "facebook", UTMMedium: "paid_social", Clicks: 80, Cost: 40.0, Revenue: 400.0},
+		{Date: "2025-01-03", Channel: "facebook_ads", CampaignID: "C-2002", UTMSource: "facebook", UTMMedium: "cpc", Clicks: 10, Cost: 10.0},
+		{Date: "2025-01-03", Channel: "google_ads", CampaignID: "C-1004", Clicks: 5, Cost: 5.0},
+		{Date: "2025-02-01", Channel: "google_ads", CampaignID: "C-1001", UTMSource: "google", UTMMedium: "cpc", Clicks: 999, Cost: 999.0},
+	}))
+
+	from, _ := time.Parse("2006-01-02", "2025-01-01")
+	to, _ := time.Parse("2006-01-02", "2025-01-31")
+
+	groups := func(data []models.TransformedData) []string {
+		keys := make([]string, 0, len(data))
+		for _, item := range data {
+			keys = append(keys, item.UTMSource+"/"+item.UTMMedium)
+		}
+		return keys
+	}
+
+	// Every source/medium pair, rows without UTMs grouped first
+	data, err := service.GetSourceMetrics(from, to, "", "", 0, 0)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"/", "facebook/cpc", "facebook/paid_social", "google/cpc", "google/display"}, groups(data))
+
+	googleCPC := data[3]
+	assert.Equal(t, 400, googleCPC.Clicks)
+	assert.Equal(t, 200.0, googleCPC.Cost)
+	assert.Equal(t, 600.0, googleCPC.Revenue)
+	assert.InDelta(t, 0.5, googleCPC.CPC, 1e-9)
+	assert.InDelta(t, 3.0, googleCPC.ROAS, 1e-9)
+	assert.Empty(t, googleCPC.Date)
+	assert.Empty(t, googleCPC.Channel)
+	assert.Empty(t, googleCPC.CampaignID)
+
+	// Filtering by source, medium or both
+	data, err = service.GetSourceMetrics(from, to, "google", "", 0, 0)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"google/cpc", "google/display"}, groups(data))
+
+	data, err = service.GetSourceMetrics(from, to, "", "cpc", 0, 0)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"facebook/cpc", "google/cpc"}, groups(data))
+
+	data, err = service.GetSourceMetrics(from, to, "facebook", "paid_social", 0, 0)
+	require.NoError(t, err)
+	require.Equal(t, []string{"facebook/paid_social"}, groups(data))
+	assert.Equal(t, 400.0, data[0].Revenue)
+
+	// Pagination applies to the groups
+	data, err = service.GetSourceMetrics(from, to, "", "", 2, 1)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"facebook/cpc", "facebook/paid_social"}, groups(data))
+}
+
+func TestGetCampaignMetrics(t *testing.T) {
+	logger := logrus.New()
+	logger.SetLevel(logrus.ErrorLevel)
+
+	store := storage.NewInMemoryStorage()
+	service := NewService(&config.Config{}, store, logger)
+
+	require.NoError(t, store.StoreTransformedData([]models.TransformedData{
+		{Date: "2025-01-02", Channel: "google_ads", CampaignID: "C-1001", Clicks: 100, Cost: 50.0, Leads: 10, Revenue: 200.0},
+		{Date: "2025-01-01", Channel: "facebook_ads", CampaignID: "C-1001", Clicks: 300, Cost: 150.0, Leads: 20, Revenue: 400.0},
+		{Date: "2025-01-03", Channel: "facebook_ads", CampaignID: "C-1001", Clicks: 100, Cost: 100.0, Leads: 10},
+		{Date: "2025-01-01", Channel: "google_ads", CampaignID: "C-1002", Clicks: 999, Cost: 999.0},
+		{Date: "2025-02-01", Channel: "google_ads", CampaignID: "C-1001", Clicks: 999, Cost: 999.0},
+	}))
+
+	from, _ := time.Parse("2006-01-02", "2025-01-01")
+	to, _ := time.Parse("2006-01-02", "2025-01-31")
+
+	// Rows from both channels, ordered by date
+	data, err := service.GetCampaignMetrics(from, to, "C-1001", false, 0, 0)
+	require.NoError(t, err)
+	require.Len(t, data, 3)
+	assert.Equal(t, "2025-01-01", data[0].Date)
+	assert.Equal(t, "facebook_ads", data[0].Channel)
+	assert.Equal(t, "2025-01-02", data[1].Date)
+	assert.Equal(t, "google_ads", data[1].Channel)
+	assert.Equal(t, "2025-01-03", data[2].Date)
+
+	// Consolidated into campaign totals with ratios from the totals
+	data, err = service.GetCampaignMetrics(from, to, "C-1001", true, 0, 0)
+	require.NoError(t, err)
+	require.Len(t, data, 1)
+	total := data[0]
+	assert.Equal(t, "C-1001", total.CampaignID)
+	assert.Empty(t, total.Date)
+	assert.Empty(t, total.Channel)
+	assert.Equal(t, 500, total.Clicks)
+	assert.Equal(t, 300.0, total.Cost)
+	assert.Equal(t, 40, total.Leads)
+	assert.Equal(t, 600.0, total.Revenue)
+	assert.InDelta(t, 0.6, total.CPC, 1e-9)
+	assert.InDelta(t, 7.5, total.CPA, 1e-9)
+	assert.InDelta(t, 2.0, total.ROAS, 1e-9)
+
+	// Pagination and unknown campaigns
+	data, err = service.GetCampaignMetrics(from, to, "C-1001", false, 1, 1)
+	require.NoError(t, err)
+	require.Len(t, data, 1)
+	assert.Equal(t, "2025-01-02", data[0].Date)
+
+	data, err = service.GetCampaignMetrics(from, to, "C-9999", true, 0, 0)
+	require.NoError(t, err)
+	assert.Empty(t, data)
+}
+
+func TestGetTopCampaigns(t *testing.T) {
+	logger := logrus.New()
+	logger.SetLevel(logrus.ErrorLevel)
+
+	store := storage.NewInMemoryStorage()
+	service := NewService(&config.Config{}, store, logger)
+
+	// C-1001 leads on revenue only once its two days are consolidated;
+	// C-3001 has the best ROAS on little spend
+	require.NoError(t, store.StoreTransformedData([]models.TransformedData{
+		{Date: "2025-01-01", Channel: "google_ads", CampaignID: "C-1001", Cost: 500.0, ClosedWon: 1, Revenue: 1500.0, ROAS: 3.0},
+		{Date: "2025-01-02", Channel: "google_ads", CampaignID: "C-1001", Cost: 500.0, ClosedWon: 1, Revenue: 1500.0, ROAS: 3.0},
+		{Date: "2025-01-01", Channel: "facebook_ads", CampaignID: "C-2001", Cost: 800.0, ClosedWon: 4, Revenue: 2500.0, ROAS: 3.125},
+		{Date: "2025-01-03", Channel: "google_ads", CampaignID: "C-3001", Cost: 100.0, ClosedWon: 1, Revenue: 1000.0, ROAS: 10.0},
+		{Date: "2025-01-03", Channel: "facebook_ads", CampaignID: "C-4001", Cost: 50.0},
+		{Date: "2025-02-01", Channel: "google_ads", CampaignID: "C-5001", Cost: 10.0, Revenue: 99999.0},
+	}))
+
+	from, _ := time.Parse("2006-01-02", "2025-01-01")
+	to, _ := time.Parse("2006-01-02", "2025-01-31")
+
+	campaigns := func(data []models.TransformedData) []string {
+		ids := make([]string, 0, len(data))
+		for _, item := range data {
+			ids = append(ids, item.CampaignID)
+		}
+		return ids
+	}
+
+	tests := []struct {
+		metric   SortField
+		n        int
+		expected []string
+	}{
+		{SortByRevenue, 10, []string{"C-1001", "C-2001", "C-3001", "C-4001"}},
+		{SortByROAS, 2, []string{"C-3001", "C-2001"}},
+		{SortByClosedWon, 1, []string{"C-2001"}},
+		{SortByCost, 3, []string{"C-1001", "C-2001", "C-3001"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.metric), func(t *testing.T) {
+			result, err := service.GetTopCampaigns(from, to, tt.metric, tt.n)
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, campaigns(result))
+		})
+	}
+
+	result, err := service.GetTopCampaigns(from, to, SortByRevenue, 1)
+	require.NoError(t, err)
+	require.Len(t, result, 1)
+	assert.Equal(t, 3000.0, result[0].Revenue)
+	assert.InDelta(t, 3.0, result[0].ROAS, 0.001)
+	assert.Empty(t, result[0].Date)
+
+	_, err = service.GetTopCampaigns(from, to, SortByDate, 10)
+	assert.Error(t, err)
+}
+
+func TestFindMatchingOpportunities_Strategies(t *testing.T) {
+	logger := logrus.New()
+	logger.SetLevel(logrus.ErrorLevel)
+
+	crmLookup := map[CRMLookupKey][]models.Opportunity{
+		{UTMCampaign: "back_to_school", UTMSource: "google", UTMMedium: "cpc"}: {
+			{OpportunityID: "O-9001"},
+		},
+		{UTMCampaign: "summer_sale", UTMSource: "", UTMMedium: ""}: {
+			{OpportunityID: "O-9002"},
+		},
+		{UTMCampaign: "", UTMSource: "newsletter", UTMMedium: ""}: {
+			{OpportunityID: "O-9003"},
+		},
+	}
+
+	exactAd := models.AdsPerformance{UTMCampaign: "back_to_school", UTMSource: "google", UTMMedium: "cpc"}
+	campaignAd := models.AdsPerformance{UTMCampaign: "summer_sale", UTMSource: "google", UTMMedium: "cpc"}
+	sourceAd := models.AdsPerformance{UTMCampaign: "winter_sale", UTMSource: "newsletter", UTMMedium: "email"}
+
+	tests := []struct {
+		name          string
+		strategy      string
+		ad            models.AdsPerformance
+		expectedIDs   []string
+		expectedMatch string
+	}{
+		{"exact strategy exact match", "exact", exactAd, []string{"O-9001"}, constants.MatchTypeExact},
+		{"exact strategy skips campaign fallback", "exact", campaignAd, nil, constants.MatchTypeNone},
+		{"exact strategy skips source fallback", "exact", sourceAd, nil, constants.MatchTypeNone},
+		{"campaign strategy exact match", "campaign_fallback", exactAd, []string{"O-9001"}, constants.MatchTypeExact},
+		{"campaign strategy campaign match", "campaign_fallback", campaignAd, []string{"O-9002"}, constants.MatchTypeCampaign},
+		{"campaign strategy skips source fallback", "campaign_fallback", sourceAd, nil, constants.MatchTypeNone},
+		{"full strategy exact match", "full", exactAd, []string{"O-9001"}, constants.MatchTypeExact},
+		{"full strategy campaign match", "full", campaignAd, []string{"O-9002"}, constants.MatchTypeCampaign},
+		{"full strategy source match", "full", sourceAd, []string{"O-9003"}, constants.MatchTypeSource},
+		{"unknown strategy falls back to full", "bogus", sourceAd, []string{"O-9003"}, constants.MatchTypeSource},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			service := NewService(&config.Config{MatchStrategy: tt.strategy}, storage.NewInMemoryStorage(), logger)
+
+			result, matchType := service.findMatchingOpportunities(tt.ad, crmLookup)
+			assert.Equal(t, tt.expectedMatch, matchType)
+			require.Len(t, result, len(tt.expectedIDs))
+			for i, expectedID := range tt.expectedIDs {
+				assert.Equal(t, expectedID, result[i].OpportunityID)
+			}
+		})
+	}
+}
+
+func TestParseMatchStrategy(t *testing.T) {
+	strategy, err := ParseMatchStrategy(" Campaign_Fallback ")
+	require.NoError(t, err)
+	assert.Equal(t, StrategyCampaignFallback, strategy)
+
+	strategy, err = ParseMatchStrategy("")
+	require.NoError(t, err)
+	assert.Equal(t, StrategyFull, strategy)
+
+	_, err = ParseMatchStrategy("fuzzy")
+	assert.Error(t, err)
+}
+
+func TestTransformData_ExcludesOpportunitiesBeforeSince(t *testing.T) {
+	logger := logrus.New()
+	logger.SetLevel(logrus.ErrorLevel)
+
+	service := NewService(&config.Config{}, storage.NewInMemoryStorage(), logger)
+
+	sinceTime, _ := time.Parse("2006-01-02", "2025-01-01")
+	adsData := &models.AdsData{
+		Performance: []models.AdsPerformance{
+			{
+				Date: "2025-01-02", CampaignID: "C-1001", Channel: "google_ads",
+				Clicks: 1000, Cost: 250.0,
+				UTMCampaign: "back_to_school", UTMSource: "google", UTMMedium: "cpc",
+			},
+		},
+	}
+	crmData := &models.CRMData{
+		Opportunities: []models.Opportunity{
+			{
+				OpportunityID: "O-OLD", Stage: "closed_won", Amount: 9000.0,
+				CreatedAt:   sinceTime.AddDate(0, 0, -10),
+				UTMCampaign: "back_to_school", UTMSource: "google", UTMMedium: "cpc",
+			},
+			{
+				OpportunityID: "O-NEW", Stage: "closed_won", Amount: 1000.0,
+				CreatedAt:   sinceTime.Add(2 * time.Hour),
+				UTMCampaign: "back_to_school", UTMSource: "google", UTMMedium: "cpc",
+			},
+			{
+				OpportunityID: "O-UNDATED", Stage: "proposal", Amount: 500.0,
+				UTMCampaign: "back_to_school", UTMSource: "google", UTMMedium: "cpc",
+			},
+		},
+	}
+
+	result, err := service.transformData(adsData, crmData, sinceTime, time.Time{})
+	require.NoError(t, err)
+	require.Len(t, result, 1)
+
+	// O-OLD is excluded; O-UNDATED has no CreatedAt and is kept
+	assert.Equal(t, 2, result[0].Opportunities)
+	assert.Equal(t, 1, result[0].ClosedWon)
+	assert.Equal(t, 1000.0, result[0].Revenue)
+	assert.InDelta(t, 4.0, result[0].ROAS, 0.001)
+
+	// Without a since date every opportunity is attributed
+	result, err = service.transformData(adsData, crmData, time.Time{}, time.Time{})
+	require.NoError(t, err)
+	require.Len(t, result, 1)
+	assert.Equal(t, 3, result[0].Opportunities)
+	assert.Equal(t, 10000.0, result[0].Revenue)
+}
+
+func TestTransformData_DeduplicatesOpportunities(t *testing.T) {
+	logger := logrus.New()
+	logger.SetLevel(logrus.ErrorLevel)
+
+	service := NewService(&config.Config{}, storage.NewInMemoryStorage(), logger)
+
+	created := time.Date(2025, 1, 1, 10, 0, 0, 0, time.UTC)
+	adsData := &models.AdsData{Performance: []models.AdsPerformance{
+		{
+			Date: "2025-01-01", CampaignID: "C-1001", Channel: "google_ads", Clicks: 1000, Cost: 250.0,
+			UTMCampaign: "back_to_school", UTMSource: "google", UTMMedium: "cpc",
+		},
+	}}
+	crmData := &models.CRMData{Opportunities: []models.Opportunity{
+		{
+			OpportunityID: "O-9001", Stage: "proposal", Amount: 5000.0, CreatedAt: created,
+			UTMCampaign: "back_to_school", UTMSource: "google", UTMMedium: "cpc",
+		},
+		// Same opportunity returned again, later and now won
+		{
+			OpportunityID: "O-9001", Stage: "closed_won", Amount: 5000.0, CreatedAt: created.Add(time.Hour),
+			UTMCampaign: "back_to_school", UTMSource: "google", UTMMedium: "cpc",
+		},
+		// Exact duplicate of another opportunity
+		{
+			OpportunityID: "O-9002", Stage: "closed_won", Amount: 1000.0, CreatedAt: created,
+			UTMCampaign: "back_to_school", UTMSource: "google", UTMMedium: "cpc",
+		},
+		{
+			OpportunityID: "O-9002", Stage: "closed_won", Amount: 1000.0, CreatedAt: created,
+			UTMCampaign: "back_to_school", UTMSource: "google", UTMMedium: "cpc",
+		},
+	}}
+
+	result, err := service.transformData(adsData, crmData, time.Time{}, time.Time{})
+	require.NoError(t, err)
+	require.Len(t, result, 1)
+
+	assert.Equal(t, 2, result[0].Opportunities)
+	assert.Equal(t, 2, result[0].ClosedWon)
+	assert.Equal(t, 6000.0, result[0].Revenue)
+}
+
+func TestTransformData_DeterministicOrder(t *testing.T) {
+	logger := logrus.New()
+	logger.SetLevel(logrus.ErrorLevel)
+
+	performance := []models.AdsPerformance{
+		{Date: "2025-01-02", CampaignID: "C-1001", Channel: "google_ads", Clicks: 1},
+		{Date: "2025-01-01", CampaignID: "C-2001", Channel: "facebook_ads", Clicks: 2},
+		{Date: "2025-01-01", CampaignID: "C-1002", Channel: "google_ads", Clicks: 3},
+		{Date: "2025-01-01", CampaignID: "C-1001", Channel: "google_ads", Clicks: 4},
+		{Date: "2025-01-02", CampaignID: "C-2001", Channel: "facebook_ads", Clicks: 5},
+		{Date: "2024-12-31", CampaignID: "C-1001", Channel: "google_ads", Clicks: 6},
+	}
+	expected := []string{
+		"2024-12-31|google_ads|C-1001",
+		"2025-01-01|facebook_ads|C-2001",
+		"2025-01-01|google_ads|C-1001",
+		"2025-01-01|google_ads|C-1002",
+		"2025-01-02|facebook_ads|C-2001",
+		"2025-01-02|google_ads|C-1001",
+	}
+
+	keys := func(data []models.TransformedData) []string {
+		result := make([]string, 0, len(data))
+		for _, item := range data {
+			result = append(result, item.Date+"|"+item.Channel+"|"+item.CampaignID)
+		}
+		return result
+	}
+
+	// Every upstream ordering, including with concurrent workers, yields
+	// the same sorted rows
+	random := rand.New(rand.NewSource(1))
+	for _, concurrency := range []int{1, 4} {
+		service := NewService(&config.Config{TransformConcurrency: concurrency}, storage.NewInMemoryStorage(), logger)
+		for i := 0; i < 20; i++ {
+			shuffled := append([]models.AdsPerformance(nil), performance...)
+			random.Shuffle(len(shuffled), func(a, b int) { shuffled[a], shuffled[b] = shuffled[b], shuffled[a] })
+
+			result, err := service.transformData(&models.AdsData{Performance: shuffled}, &models.CRMData{}, time.Time{}, time.Time{})
+			require.NoError(t, err)
+			require.Equal(t, expected, keys(result))
+			assert.Equal(t, 4, result[2].Clicks)
+		}
+	}
+}
+
+func TestTransformData_MergesDuplicateAds(t *testing.T) {
+	logger := logrus.New()
+	logger.SetLevel(logrus.ErrorLevel)
+
+	service := NewService(&config.Config{}, storage.NewInMemoryStorage(), logger)
+
+	// The first two rows share a key; the third is another day
+	adsData := &models.AdsData{Performance: []models.AdsPerformance{
+		{
+			Date: "2025-01-01", CampaignID: "C-1001", Channel: "google_ads", Clicks: 600, Impressions: 10000, Cost: 150.0,
+			UTMCampaign: "back_to_school", UTMSource: "google", UTMMedium: "cpc",
+		},
+		{
+			Date: "2025-01-01", CampaignID: "C-1001", Channel: "google_ads", Clicks: 400, Impressions: 6000, Cost: 100.0,
+			UTMCampaign: "back_to_school", UTMSource: "google", UTMMedium: "cpc",
+		},
+		{
+			Date: "2025-01-02", CampaignID: "C-1001", Channel: "google_ads", Clicks: 50, Cost: 10.0,
+			UTMCampaign: "back_to_school", UTMSource: "google", UTMMedium: "cpc",
+		},
+	}}
+	crmData := &models.CRMData{Opportunities: []models.Opportunity{
+		{OpportunityID: "O-1", Stage: "closed_won", Amount: 1000.0, UTMCampaign: "back_to_school", UTMSource: "google", UTMMedium: "cpc"},
+	}}
+
+	result, err := service.transformData(adsData, crmData, time.Time{}, time.Time{})
+	require.NoError(t, err)
+	require.Len(t, result, 2)
+
+	merged := result[0]
+	assert.Equal(t, "2025-01-01", merged.Date)
+	assert.Equal(t, 1000, merged.Clicks)
+	assert.Equal(t, 16000, merged.Impressions)
+	assert.Equal(t, 250.0, merged.Cost)
+	assert.Equal(t, 100, merged.Leads)
+	assert.Equal(t, 0.25, merged.CPC)
+	// The opportunity is credited to the merged row once
+	assert.Equal(t, 1, merged.ClosedWon)
+	assert.Equal(t, 1000.0, merged.Revenue)
+	assert.Equal(t, 4.0, merged.ROAS)
+
+	assert.Equal(t, "2025-01-02", result[1].Date)
+	assert.Equal(t, 50, result[1].Clicks)
 }
 
+func TestTransformData_BoundedWindow(t *testing.T) {
+	logger := logrus.New()
+	logger.SetLevel(logrus.ErrorLevel)
+
+	service := NewService(&config.Config{}, storage.NewInMemoryStorage(), logger)
+
+	adsData := &models.AdsData{Performance: []models.AdsPerformance{
+		{Date: "2024-12-31", CampaignID: "C-1001", Channel: "google_ads", Clicks: 100},
+		{Date: "2025-01-01", CampaignID: "C-1001", Channel: "google_ads", Clicks: 200},
+		{Date: "2025-01-02", CampaignID: "C-1001", Channel: "google_ads", Clicks: 300},
+		{Date: "2025-01-03", CampaignID: "C-1001", Channel: "google_ads", Clicks: 400},
+	}}
+
+	sinceTime, _ := time.Parse("2006-01-02", "2025-01-01")
+	untilTime, _ := time.Parse("2006-01-02", "2025-01-02")
+
+	result, err := service.transformData(adsData, &models.CRMData{}, sinceTime, untilTime)
+	require.NoError(t, err)
+
+	// Both bounds are inclusive; rows either side of the window are skipped
+	require.Len(t, result, 2)
+	assert.Equal(t, "2025-01-01", result[0].Date)
+	assert.Equal(t, "2025-01-02", result[1].Date)
+
+	// An until date alone leaves the window open at the start
+	result, err = service.transformData(adsData, &models.CRMData{}, time.Time{}, untilTime)
+	require.NoError(t, err)
+	assert.Len(t, result, 3)
+}
+
+func TestRunIngestion_SinceAfterUntil(t *testing.T) {
+	logger := logrus.New()
+	logger.SetLevel(logrus.FatalLevel)
+
+	service := NewService(&config.Config{}, storage.NewInMemoryStorage(), logger)
+	err := service.RunIngestion(context.Background(), IngestOptions{Since: "2025-01-05", Until: "2025-01-01"})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "since date 2025-01-05 is after until date 2025-01-01")
+}
+
+func TestReady(t *testing.T) {
+	logger := logrus.New()
+	logger.SetLevel(logrus.ErrorLevel)
+
+	up := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer up.Close()
+
+	hung := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-r.Context().Done():
+		case <-time.After(5 * time.Second):
+		}
+	}))
+	defer hung.Close()
+
+	down := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	downURL := down.URL
+	down.Close()
+
+	t.Run("all reachable", func(t *testing.T) {
+		service := NewService(&config.Config{AdsAPIURL: up.URL, CRMAPIURL: up.URL}, storage.NewInMemoryStorage(), logger)
+
+		status := service.Ready(context.Background())
+		assert.Equal(t, map[string]string{
+			"ads_api": constants.HealthStatusHealthy,
+			"crm_api": constants.HealthStatusHealthy,
+		}, status)
+	})
+
+	t.Run("unreachable and unconfigured dependencies", func(t *testing.T) {
+		service := NewService(&config.Config{AdsAPIURL: downURL, SinkURL: up.URL}, storage.NewInMemoryStorage(), logger)
+
+		status := service.Ready(context.Background())
+		assert.Equal(t, map[string]string{
+			"ads_api": constants.HealthStatusUnhealthy,
+			"crm_api": constants.HealthStatusUnhealthy,
+			"sink":    constants.HealthStatusHealthy,
+		}, status)
+	})
+
+	t.Run("slow dependency times out", func(t *testing.T) {
+		service := NewService(&config.Config{
+			AdsAPIURL:        hung.URL,
+			CRMAPIURL:        up.URL,
+			HTTPTimeout:      30 * time.Second,
+			ReadinessTimeout: 100 * time.Millisecond,
+		}, storage.NewInMemoryStorage(), logger)
+
+		start := time.Now()
+		status := service.Ready(context.Background())
+		assert.Less(t, time.Since(start), 2*time.Second)
+		assert.Equal(t, constants.HealthStatusUnhealthy, status["ads_api"])
+		assert.Equal(t, constants.HealthStatusHealthy, status["crm_api"])
+	})
+}
+
+func TestExportData_PartialFailure(t *testing.T) {
+	logger := logrus.New()
+	logger.SetLevel(logrus.FatalLevel)
+
+	var (
+		mu       sync.Mutex
+		received []string
+	)
+	sink := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var record models.TransformedData
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&record))
+
+		mu.Lock()
+		received = append(received, record.CampaignID)
+		mu.Unlock()
+
+		if record.CampaignID == "C-1002" {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer sink.Close()
+
+	store := storage.NewInMemoryStorage()
+	service := NewService(&config.Config{
+		SinkURL:    sink.URL,
+		SinkSecret: "secret",
+		MaxRetries: 1,
+		RetryDelay: time.Millisecond,
+	}, store, logger)
+
+	require.NoError(t, store.StoreTransformedData([]models.TransformedData{
+		{Date: "2025-01-01", Channel: "google_ads", CampaignID: "C-1001", Clicks: 100},
+		{Date: "2025-01-01", Channel: "google_ads", CampaignID: "C-1002", Clicks: 200},
+		{Date: "2025-01-01", Channel: "google_ads", CampaignID: "C-1003", Clicks: 300},
+	}))
+
+	_, err := service.ExportData(context.Background(), "2025-01-01", false, ExportFilter{})
+	require.Error(t, err)
+
+	var exportErr *ExportError
+	require.ErrorAs(t, err, &exportErr)
+	assert.Equal(t, 2, exportErr.Exported)
+	require.Len(t, exportErr.Failed, 1)
+	assert.Equal(t, "google_ads", exportErr.Failed[0].Channel)
+	assert.Equal(t, "C-1002", exportErr.Failed[0].CampaignID)
+	assert.Contains(t, err.Error(), "failed to export 1 of 3 records: google_ads/C-1002")
+
+	// The failing record was retried and the records after it still sent
+	assert.Equal(t, []string{"C-1001", "C-1002", "C-1002", "C-1003"}, received)
+}
+
+func TestRunIngestion_Incremental(t *testing.T) {
+	logger := logrus.New()
+	logger.SetLevel(logrus.FatalLevel)
+
+	var (
+		mu       sync.Mutex
+		ads      = []string{"2025-01-01", "2025-01-02"}
+		crmFails bool
+	)
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		if r.URL.Path == "/crm" {
+			if crmFails {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			w.Write([]byte(`{"external":{"crm":{"opportunities":[]}}}`))
+			return
+		}
+
+		performance := make([]models.AdsPerformance, 0, len(ads))
+		for _, date := range ads {
+			performance = append(performance, models.AdsPerformance{Date: date, CampaignID: "C-1001", Channel: "google_ads", Clicks: 100})
+		}
+		json.NewEncoder(w).Encode(models.ExternalResponse{External: models.ExternalData{Ads: &models.AdsData{Performance: performance}}})
+	}))
+	defer upstream.Close()
+
+	store := storage.NewInMemoryStorage()
+	service := NewService(&config.Config{
+		AdsAPIURL:  upstream.URL + "/ads",
+		CRMAPIURL:  upstream.URL + "/crm",
+		RetryDelay: time.Millisecond,
+	}, store, logger)
+
+	stored := func() []string {
+		data, err := store.GetTransformedData(time.Time{}, time.Now(), map[string]string{}, 0, 0)
+		require.NoError(t, err)
+		dates := make([]string, 0, len(data))
+		for _, item := range data {
+			dates = append(dates, item.Date)
+		}
+		return dates
+	}
+
+	firstRun := time.Date(2025, 1, 2, 12, 0, 0, 0, time.UTC)
+	service.now = func() time.Time { return firstRun }
+	require.NoError(t, service.RunIngestion(context.Background(), IngestOptions{}))
+	assert.Equal(t, []string{"2025-01-01", "2025-01-02"}, stored())
+
+	lastIngestion, err := store.GetLastIngestionTime()
+	require.NoError(t, err)
+	assert.True(t, firstRun.Equal(lastIngestion))
+
+	// A newer row shows up; the next run without since reprocesses the last
+	// run's day, replacing its row, and picks that up
+	mu.Lock()
+	ads = append(ads, "2025-01-03")
+	mu.Unlock()
+
+	secondRun := time.Date(2025, 1, 3, 12, 0, 0, 0, time.UTC)
+	service.now = func() time.Time { return secondRun }
+	require.NoError(t, service.RunIngestion(context.Background(), IngestOptions{}))
+	assert.Equal(t, []string{"2025-01-01", "2025-01-02", "2025-01-03"}, stored())
+
+	// A failed run leaves the last ingestion time alone
+	mu.Lock()
+	crmFails = true
+	mu.Unlock()
+
+	service.now = func() time.Time { return time.Date(2025, 1, 4, 12, 0, 0, 0, time.UTC) }
+	require.Error(t, service.RunIngestion(context.Background(), IngestOptions{}))
+
+	lastIngestion, err = store.GetLastIngestionTime()
+	require.NoError(t, err)
+	assert.True(t, secondRun.Equal(lastIngestion))
+
+	// full=true reprocesses the whole history, replacing what is stored
+	mu.Lock()
+	crmFails = false
+	mu.Unlock()
+
+	require.NoError(t, service.RunIngestion(context.Background(), IngestOptions{Full: true}))
+	assert.Len(t, stored(), 3)
+}
+
+func TestRunIngestion_PartialWhenCRMDown(t *testing.T) {
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/crm" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		json.NewEncoder(w).Encode(models.ExternalResponse{External: models.ExternalData{Ads: &models.AdsData{Performance: []models.AdsPerformance{
+			{Date: "2025-01-01", CampaignID: "C-1001", Channel: "google_ads", Clicks: 100, Impressions: 2000, Cost: 50.0, UTMCampaign: "back_to_school"},
+		}}}})
+	}))
+	defer upstream.Close()
+
+	newService := func(partial bool) (*Service, storage.Storage) {
+		logger := logrus.New()
+		logger.SetLevel(logrus.FatalLevel)
+		store := storage.NewInMemoryStorage()
+		return NewService(&config.Config{
+			AdsAPIURL:     upstream.URL + "/ads",
+			CRMAPIURL:     upstream.URL + "/crm",
+			RetryDelay:    time.Millisecond,
+			PartialIngest: partial,
+		}, store, logger), store
+	}
+
+	t.Run("strict by default", func(t *testing.T) {
+		service, store := newService(false)
+		err := service.RunIngestion(context.Background(), IngestOptions{Full: true})
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "failed to fetch crm data")
+
+		count, err := store.CountTransformedData(nil)
+		require.NoError(t, err)
+		assert.Zero(t, count)
+	})
+
+	t.Run("partial ingest stores spend metrics", func(t *testing.T) {
+		service, store := newService(true)
+		require.NoError(t, service.RunIngestion(context.Background(), IngestOptions{Full: true}))
+
+		data, err := store.GetTransformedData(time.Time{}, time.Now(), map[string]string{}, 0, 0)
+		require.NoError(t, err)
+		require.Len(t, data, 1)
+		assert.Equal(t, 100, data[0].Clicks)
+		assert.Equal(t, 50.0, data[0].Cost)
+		assert.InDelta(t, 0.5, data[0].CPC, 0.001)
+		assert.InDelta(t, 0.05, data[0].CTR, 0.001)
+		// Leads are estimated from clicks, which needs no CRM data
+		assert.Equal(t, 10, data[0].Leads)
+		assert.Zero(t, data[0].Opportunities)
+		assert.Zero(t, data[0].ClosedWon)
+		assert.Zero(t, data[0].Revenue)
+		assert.Zero(t, data[0].ROAS)
+
+		// The next run still has to fetch the CRM data this one missed
+		lastIngestion, err := store.GetLastIngestionTime()
+		require.NoError(t, err)
+		assert.True(t, lastIngestion.IsZero())
+	})
+}
+
+func TestRunIngestion_PartialKeepsStoredFunnel(t *testing.T) {
+	var (
+		mu      sync.Mutex
+		crmDown bool
+	)
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		if r.URL.Path == "/crm" {
+			if crmDown {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			w.Write([]byte(`{"external":{"crm":{"opportunities":[
+				{"opportunity_id":"O-1","utm_campaign":"back_to_school","utm_source":"google","utm_medium":"cpc","stage":"closed_won","amount":500}
+			]}}}`))
+			return
+		}
+		json.NewEncoder(w).Encode(models.ExternalResponse{External: models.ExternalData{Ads: &models.AdsData{Performance: []models.AdsPerformance{
+			{Date: "2025-01-01", CampaignID: "C-1001", Channel: "google_ads", Clicks: 100, Cost: 50.0, UTMCampaign: "back_to_school", UTMSource: "google", UTMMedium: "cpc"},
+			{Date: "2025-01-02", CampaignID: "C-1001", Channel: "google_ads", Clicks: 80, Cost: 40.0, UTMCampaign: "spring_sale", UTMSource: "google", UTMMedium: "cpc"},
+		}}}})
+	}))
+	defer upstream.Close()
+
+	logger := logrus.New()
+	logger.SetLevel(logrus.FatalLevel)
+	store := storage.NewInMemoryStorage()
+	service := NewService(&config.Config{
+		AdsAPIURL:     upstream.URL + "/ads",
+		CRMAPIURL:     upstream.URL + "/crm",
+		RetryDelay:    time.Millisecond,
+		PartialIngest: true,
+	}, store, logger)
+
+	revenue := func(date string) []float64 {
+		day, _ := time.Parse(dateLayout, date)
+		data, err := store.GetTransformedData(day, day, map[string]string{}, 0, 0)
+		require.NoError(t, err)
+		values := make([]float64, 0, len(data))
+		for _, item := range data {
+			values = append(values, item.Revenue)
+		}
+		return values
+	}
+
+	// Only the 1st is ingested while the CRM is up
+	service.now = func() time.Time { return time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC) }
+	require.NoError(t, service.RunIngestion(context.Background(), IngestOptions{Since: "2025-01-01", Until: "2025-01-01"}))
+	require.Equal(t, []float64{500.0}, revenue("2025-01-01"))
+	watermark, err := store.GetLastIngestionTime()
+	require.NoError(t, err)
+
+	mu.Lock()
+	crmDown = true
+	mu.Unlock()
+
+	// A full run without the CRM keeps the 1st and only fills in the 2nd
+	service.now = func() time.Time { return time.Date(2025, 1, 3, 12, 0, 0, 0, time.UTC) }
+	require.NoError(t, service.RunIngestion(context.Background(), IngestOptions{Full: true}))
+	assert.Equal(t, []float64{500.0}, revenue("2025-01-01"))
+	assert.Equal(t, []float64{0.0}, revenue("2025-01-02"))
+
+	lastIngestion, err := store.GetLastIngestionTime()
+	require.NoError(t, err)
+	assert.True(t, watermark.Equal(lastIngestion))
+
+	// Re-ingesting the stored day without the CRM leaves it alone too
+	deleted, stored, err := service.ReingestDate(context.Background(), "2025-01-01")
+	require.NoError(t, err)
+	assert.Zero(t, deleted)
+	assert.Zero(t, stored)
+	assert.Equal(t, []float64{500.0}, revenue("2025-01-01"))
+}
+
+func TestReingestDate(t *testing.T) {
+	logger := logrus.New()
+	logger.SetLevel(logrus.FatalLevel)
+
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/crm" {
+			w.Write([]byte(`{"external":{"crm":{"opportunities":[]}}}`))
+			return
+		}
+		json.NewEncoder(w).Encode(models.ExternalResponse{External: models.ExternalData{Ads: &models.AdsData{Performance: []models.AdsPerformance{
+			{Date: "2025-01-01", CampaignID: "C-1001", Channel: "google_ads", Clicks: 100},
+			{Date: "2025-01-02", CampaignID: "C-1001", Channel: "google_ads", Clicks: 200},
+		}}}})
+	}))
+	defer upstream.Close()
+
+	store := storage.NewInMemoryStorage()
+	service := NewService(&config.Config{
+		AdsAPIURL:  upstream.URL + "/ads",
+		CRMAPIURL:  upstream.URL + "/crm",
+		RetryDelay: time.Millisecond,
+	}, store, logger)
+
+	// A bad day: a stale duplicate and a campaign upstream no longer reports
+	require.NoError(t, store.StoreTransformedData([]models.TransformedData{
+		{Date: "2025-01-01", Channel: "google_ads", CampaignID: "C-1001", Clicks: 90},
+		{Date: "2025-01-01", Channel: "google_ads", CampaignID: "C-1001", Clicks: 90},
+		{Date: "2025-01-01", Channel: "google_ads", CampaignID: "C-9999", Clicks: 5},
+		{Date: "2025-01-02", Channel: "google_ads", CampaignID: "C-1001", Clicks: 7},
+	}))
+
+	rows := func(date string) []models.TransformedData {
+		day, _ := time.Parse(dateLayout, date)
+		data, err := store.GetTransformedData(day, day, map[string]string{}, 0, 0)
+		require.NoError(t, err)
+		return data
+	}
+
+	// Running it twice still leaves a single row: rows are replaced, not
+	// appended to
+	for i := 0; i < 2; i++ {
+		deleted, stored, err := service.ReingestDate(context.Background(), "2025/01/01")
+		require.NoError(t, err)
+		assert.Equal(t, 1, stored)
+		if i == 0 {
+			assert.Equal(t, 3, deleted)
+		} else {
+			assert.Equal(t, 1, deleted)
+		}
+
+		day := rows("2025-01-01")
+		require.Len(t, day, 1)
+		assert.Equal(t, "C-1001", day[0].CampaignID)
+		assert.Equal(t, 100, day[0].Clicks)
+	}
+
+	// Other days, and the incremental watermark, are left alone
+	other := rows("2025-01-02")
+	require.Len(t, other, 1)
+	assert.Equal(t, 7, other[0].Clicks)
+
+	lastIngestion, err := store.GetLastIngestionTime()
+	require.NoError(t, err)
+	assert.True(t, lastIngestion.IsZero())
+
+	_, _, err = service.ReingestDate(context.Background(), "January 1st")
+	assert.Error(t, err)
+}
+
+// failingReplaceStorage is an in-memory store whose replacements fail
+// without changing anything.
+type failingReplaceStorage struct {
+	*storage.InMemoryStorage
+}
+
+func (failingReplaceStorage) ReplaceTransformedData(time.Time, time.Time, []models.TransformedData) (int, error) {
+	return 0, errors.New("disk full")
+}
+
+func TestReingestDate_FailedReplaceKeepsDay(t *testing.T) {
+	logger := logrus.New()
+	logger.SetLevel(logrus.FatalLevel)
+
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/crm" {
+			w.Write([]byte(`{"external":{"crm":{"opportunities":[]}}}`))
+			return
+		}
+		json.NewEncoder(w).Encode(models.ExternalResponse{External: models.ExternalData{Ads: &models.AdsData{Performance: []models.AdsPerformance{
+			{Date: "2025-01-01", CampaignID: "C-1001", Channel: "google_ads", Clicks: 100},
+		}}}})
+	}))
+	defer upstream.Close()
+
+	store := failingReplaceStorage{storage.NewInMemoryStorage()}
+	service := NewService(&config.Config{
+		AdsAPIURL:  upstream.URL + "/ads",
+		CRMAPIURL:  upstream.URL + "/crm",
+		RetryDelay: time.Millisecond,
+	}, store, logger)
+
+	require.NoError(t, store.StoreTransformedData([]models.TransformedData{
+		{Date: "2025-01-01", Channel: "google_ads", CampaignID: "C-1001", Clicks: 90},
+	}))
+
+	_, _, err := service.ReingestDate(context.Background(), "2025-01-01")
+	require.Error(t, err)
+
+	// The day keeps its previous rows rather than being left empty
+	day, _ := time.Parse(dateLayout, "2025-01-01")
+	summary, err := service.GetMetricsSummary(day, day, "")
+	require.NoError(t, err)
+	assert.Equal(t, 1, summary.Records)
+	assert.Equal(t, 90, summary.Clicks)
+}
+
+func TestFreshness(t *testing.T) {
+	logger := logrus.New()
+	logger.SetLevel(logrus.FatalLevel)
+
+	store := storage.NewInMemoryStorage()
+	service := NewService(&config.Config{StaleAfter: 6 * time.Hour}, store, logger)
+
+	// Nothing ingested yet counts as stale
+	freshness, err := service.Freshness()
+	require.NoError(t, err)
+	assert.Equal(t, models.Freshness{Stale: true}, freshness)
+
+	ingested := time.Date(2025, 1, 2, 12, 0, 0, 0, time.UTC)
+	require.NoError(t, store.SetLastIngestionTime(ingested))
+
+	service.now = func() time.Time { return ingested.Add(6 * time.Hour) }
+	freshness, err = service.Freshness()
+	require.NoError(t, err)
+	assert.Equal(t, models.Freshness{DataAsOf: "2025-01-02T12:00:00Z", Stale: false}, freshness)
+
+	service.now = func() time.Time { return ingested.Add(6*time.Hour + time.Second) }
+	freshness, err = service.Freshness()
+	require.NoError(t, err)
+	assert.True(t, freshness.Stale)
+}
+
+func TestTransformData_SamplesInvalidDateWarnings(t *testing.T) {
+	logger, hook := test.NewNullLogger()
+	service := NewService(&config.Config{LogSampleRate: 3}, storage.NewInMemoryStorage(), logger)
+
+	adsData := &models.AdsData{}
+	for i := 0; i < 20; i++ {
+		adsData.Performance = append(adsData.Performance, models.AdsPerformance{
+			Date: "01/02/2025", CampaignID: "C-1001", Channel: "google_ads",
+		})
+	}
+	since := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	_, err := service.transformData(adsData, &models.CRMData{}, since, time.Time{})
+	require.NoError(t, err)
+
+	// The first three are logged, the other seventeen only counted
+	var logged int
+	var summary *logrus.Entry
+	for _, entry := range hook.AllEntries() {
+		switch entry.Message {
+		case invalidDateWarning:
+			logged++
+		case "Suppressed repeated warnings":
+			summary = entry
+		}
+	}
+	assert.Equal(t, 3, logged)
+	require.NotNil(t, summary)
+	assert.Equal(t, invalidDateWarning, summary.Data["message"])
+	assert.Equal(t, 17, summary.Data["suppressed"])
+
+	// A rate of zero logs every one
+	hook.Reset()
+	service = NewService(&config.Config{}, storage.NewInMemoryStorage(), logger)
+	_, err = service.transformData(adsData, &models.CRMData{}, since, time.Time{})
+	require.NoError(t, err)
+	assert.Len(t, hook.AllEntries(), 20)
+}
@@ -0,0 +1,97 @@
+package etl
+
+import (
+	"context"
+	"fmt"
+
+	"admira-etl/internal/config"
+	"admira-etl/internal/constants"
+	"admira-etl/internal/http"
+	"admira-etl/internal/models"
+)
+
+// Sink is an export destination for consolidated records.
+type Sink interface {
+	// Name identifies the sink in export summaries, logs and dead letters.
+	Name() string
+	// Send delivers one record along with its signature.
+	Send(ctx context.Context, record SignedRecord) error
+}
+
+// BatchSink is a Sink that can deliver every record of an export at once,
+// used when SinkBulk is set. signature covers the whole batch; see
+// BatchSignature.
+type BatchSink interface {
+	Sink
+	SendBatch(ctx context.Context, records []SignedRecord, signature string) error
+}
+
+// SetSinks replaces the sinks built from the configuration, so exports can
+// be sent to destinations the configuration can't describe. SinkSecret is
+// still required to sign the records. Only s exports to them: services
+// opened with Namespace never get sinks, injected or configured.
+func (s *Service) SetSinks(sinks ...Sink) {
+	s.sinks = sinks
+}
+
+// HTTPSink POSTs records as JSON to a URL, signed in the SignatureHeader and
+// keyed in the IdempotencyKeyHeader.
+type HTTPSink struct {
+	url    string
+	client *http.Client
+}
+
+func NewHTTPSink(url string, client *http.Client) *HTTPSink {
+	return &HTTPSink{url: url, client: client}
+}
+
+func (h *HTTPSink) Name() string {
+	return h.url
+}
+
+func (h *HTTPSink) Send(ctx context.Context, record SignedRecord) error {
+	headers := map[string]string{
+		SignatureHeader:      record.Signature,
+		IdempotencyKeyHeader: idempotencyKey(record.Record),
+	}
+	return h.client.PostWithHeaders(ctx, h.url, record.Record, headers, nil)
+}
+
+// SendBatch POSTs the records as one JSON array.
+func (h *HTTPSink) SendBatch(ctx context.Context, records []SignedRecord, signature string) error {
+	body := make([]models.TransformedData, 0, len(records))
+	for _, record := range records {
+		body = append(body, record.Record)
+	}
+	headers := map[string]string{
+		SignatureHeader:      signature,
+		IdempotencyKeyHeader: batchIdempotencyKey(body),
+	}
+	return h.client.PostWithHeaders(ctx, h.url, body, headers, nil)
+}
+
+// newSinks builds the sinks SinkType selects: an HTTPSink per configured
+// URL, sharing client, or a single KafkaSink or FileSink.
+func newSinks(cfg *config.Config, client *http.Client) ([]Sink, error) {
+	switch cfg.SinkType {
+	case "", constants.SinkTypeHTTP:
+		urls := cfg.Sinks()
+		sinks := make([]Sink, 0, len(urls))
+		for _, url := range urls {
+			sinks = append(sinks, NewHTTPSink(url, client))
+		}
+		return sinks, nil
+	case constants.SinkTypeKafka:
+		if len(cfg.KafkaBrokers) == 0 || cfg.KafkaTopic == "" {
+			return nil, nil
+		}
+		return []Sink{NewKafkaSink(cfg.KafkaBrokers, cfg.KafkaTopic, cfg.SinkTimeout)}, nil
+	case constants.SinkTypeFile:
+		if cfg.SinkFilePath == "" {
+			return nil, nil
+		}
+		return []Sink{NewFileSink(cfg.SinkFilePath, int64(cfg.SinkFileMaxBytes))}, nil
+	default:
+		return nil, fmt.Errorf("unknown sink type %q", cfg.SinkType)
+	}
+}
@@ -0,0 +1,113 @@
+package etl
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"admira-etl/internal/config"
+	"admira-etl/internal/models"
+	"admira-etl/internal/storage"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// captureSink is a Sink remembering every record it was sent, failing the
+// campaigns listed in reject.
+type captureSink struct {
+	name    string
+	records []SignedRecord
+	reject  map[string]bool
+}
+
+func (c *captureSink) Name() string { return c.name }
+
+func (c *captureSink) Send(_ context.Context, record SignedRecord) error {
+	if c.reject[record.Record.CampaignID] {
+		return errors.New("rejected")
+	}
+	c.records = append(c.records, record)
+	return nil
+}
+
+func (c *captureSink) campaigns() []string {
+	var ids []string
+	for _, record := range c.records {
+		ids = append(ids, record.Record.CampaignID)
+	}
+	return ids
+}
+
+func TestExportData_InjectedSinks(t *testing.T) {
+	service, _ := newExportService(t, &config.Config{})
+
+	// Nothing is configured, so export is off until sinks are injected
+	_, err := service.ExportData(context.Background(), "2025-01-01", false, ExportFilter{})
+	assert.ErrorIs(t, err, errSinkNotConfigured)
+
+	first := &captureSink{name: "first"}
+	second := &captureSink{name: "second", reject: map[string]bool{"C-1002": true}}
+	service.SetSinks(first, second)
+
+	summary, err := service.ExportData(context.Background(), "2025-01-01", false, ExportFilter{})
+	var exportErr *ExportError
+	require.ErrorAs(t, err, &exportErr)
+	assert.Equal(t, 2, summary.Sinks)
+	assert.Equal(t, 3, summary.RecordsExported)
+	require.Len(t, summary.Failed, 1)
+	assert.Equal(t, "second", summary.Failed[0].Sink)
+
+	// Each record arrives with the signature export computed for it
+	assert.Equal(t, []string{"C-1001", "C-1002"}, first.campaigns())
+	assert.Equal(t, 200, first.records[1].Record.Clicks)
+	for _, record := range first.records {
+		assert.Equal(t, service.createHMACSignature(record.Record), record.Signature)
+	}
+	assert.Equal(t, []string{"C-1001"}, second.campaigns())
+
+	// Replay resends only the failed delivery to its sink
+	delete(second.reject, "C-1002")
+	result, err := service.RetryDeadLetters(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, 1, result.Succeeded)
+	assert.Equal(t, []string{"C-1001", "C-1002"}, second.campaigns())
+	assert.Len(t, first.records, 2)
+}
+
+func TestExportData_InjectedSinksStayOutOfNamespaces(t *testing.T) {
+	service, store := newExportService(t, &config.Config{Namespaces: []string{"staging"}})
+	sink := &captureSink{name: "injected"}
+	service.SetSinks(sink)
+	service.SetNamespaces(storage.NewNamespaces(store, func(string) (storage.Storage, error) {
+		return storage.NewInMemoryStorage(), nil
+	}))
+
+	staging, err := service.Namespace("staging")
+	require.NoError(t, err)
+	require.NoError(t, staging.storage.StoreTransformedData([]models.TransformedData{
+		{Date: "2025-01-01", Channel: "google_ads", CampaignID: "C-2001", Clicks: 10},
+	}))
+
+	// The namespace service exports nowhere, even though its parent has sinks
+	assert.Empty(t, staging.sinks)
+	_, err = staging.ExportData(context.Background(), "2025-01-01", false, ExportFilter{})
+	assert.ErrorIs(t, err, errSinkNotConfigured)
+	assert.Empty(t, sink.records)
+
+	_, err = service.ExportData(context.Background(), "2025-01-01", false, ExportFilter{})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"C-1001", "C-1002"}, sink.campaigns())
+}
+
+func TestExportData_BulkFallsBackForPlainSinks(t *testing.T) {
+	service, _ := newExportService(t, &config.Config{SinkBulk: true})
+	sink := &captureSink{name: "plain"}
+	service.SetSinks(sink)
+
+	// A sink without SendBatch still gets every record, one at a time
+	summary, err := service.ExportData(context.Background(), "2025-01-01", false, ExportFilter{})
+	require.NoError(t, err)
+	assert.Equal(t, 2, summary.RecordsExported)
+	assert.Equal(t, []string{"C-1001", "C-1002"}, sink.campaigns())
+}
@@ -0,0 +1,48 @@
+package etl
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+
+	"admira-etl/internal/storage"
+)
+
+// WriteSnapshot writes a backup of the stored dataset, every row and the
+// last ingestion time, to w as JSON.
+func (s *Service) WriteSnapshot(w io.Writer) error {
+	if err := s.storage.WriteSnapshot(w); err != nil {
+		return fmt.Errorf("failed to write snapshot: %w", err)
+	}
+	return nil
+}
+
+// RestoreSnapshot replaces the stored dataset with a snapshot produced by
+// WriteSnapshot and returns how many rows are now stored. A malformed
+// snapshot wraps ErrInvalidPayload and leaves the data untouched.
+func (s *Service) RestoreSnapshot(ctx context.Context, r io.Reader) (int, error) {
+	_, done, err := s.beginWork(ctx, "snapshot restore")
+	if err != nil {
+		return 0, err
+	}
+	defer done()
+
+	// Whatever was cached described the replaced data
+	defer s.aggregates.drop(func(string) bool { return false })
+
+	if err := s.storage.ReadSnapshot(r); err != nil {
+		if errors.Is(err, storage.ErrInvalidSnapshot) {
+			return 0, fmt.Errorf("%w: %v", ErrInvalidPayload, err)
+		}
+		return 0, fmt.Errorf("failed to restore snapshot: %w", err)
+	}
+
+	records, err := s.storage.CountTransformedData(nil)
+	if err != nil {
+		return 0, fmt.Errorf("failed to count restored rows: %w", err)
+	}
+	s.metrics.StoredRecords.Set(float64(records))
+	s.logger.WithField("records", records).Info("Snapshot restored")
+	return records, nil
+}
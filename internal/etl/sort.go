@@ -0,0 +1,87 @@
+package etl
+
+import (
+	"fmt"
+	"sort"
+
+	"admira-etl/internal/models"
+)
+
+// SortField names the column channel metrics rows are ordered by.
+type SortField string
+
+const (
+	SortByDate          SortField = "date"
+	SortByClicks        SortField = "clicks"
+	SortByImpressions   SortField = "impressions"
+	SortByCost          SortField = "cost"
+	SortByLeads         SortField = "leads"
+	SortByOpportunities SortField = "opportunities"
+	SortByClosedWon     SortField = "closed_won"
+	SortByRevenue       SortField = "revenue"
+	SortByCPC           SortField = "cpc"
+	SortByCPA           SortField = "cpa"
+	SortByROAS          SortField = "roas"
+)
+
+// SortOrder is the direction rows are sorted in.
+type SortOrder string
+
+const (
+	SortAsc  SortOrder = "asc"
+	SortDesc SortOrder = "desc"
+)
+
+// sortKeys extracts the value compared for each sortable numeric column.
+var sortKeys = map[SortField]func(models.TransformedData) float64{
+	SortByClicks:        func(d models.TransformedData) float64 { return float64(d.Clicks) },
+	SortByImpressions:   func(d models.TransformedData) float64 { return float64(d.Impressions) },
+	SortByCost:          func(d models.TransformedData) float64 { return d.Cost },
+	SortByLeads:         func(d models.TransformedData) float64 { return float64(d.Leads) },
+	SortByOpportunities: func(d models.TransformedData) float64 { return float64(d.Opportunities) },
+	SortByClosedWon:     func(d models.TransformedData) float64 { return float64(d.ClosedWon) },
+	SortByRevenue:       func(d models.TransformedData) float64 { return d.Revenue },
+	SortByCPC:           func(d models.TransformedData) float64 { return d.CPC },
+	SortByCPA:           func(d models.TransformedData) float64 { return d.CPA },
+	SortByROAS:          func(d models.TransformedData) float64 { return d.ROAS },
+}
+
+// sortRows orders data in place by field and order, defaulting to date
+// ascending. Dates tie-break on channel and campaign so the default order is
+// the cursor key order; the sort is stable, so rows with equal keys keep
+// their stored order and pagination over them stays consistent.
+func sortRows(data []models.TransformedData, field SortField, order SortOrder) error {
+	if field == "" {
+		field = SortByDate
+	}
+	if order == "" {
+		order = SortAsc
+	}
+	if order != SortAsc && order != SortDesc {
+		return fmt.Errorf("unknown sort order %q", order)
+	}
+	desc := order == SortDesc
+
+	if field == SortByDate {
+		sort.SliceStable(data, func(i, j int) bool {
+			cmp := compareKey(cursorFor(data[i]), cursorFor(data[j]))
+			if desc {
+				return cmp > 0
+			}
+			return cmp < 0
+		})
+		return nil
+	}
+
+	key, ok := sortKeys[field]
+	if !ok {
+		return fmt.Errorf("unknown sort field %q", field)
+	}
+	sort.SliceStable(data, func(i, j int) bool {
+		if desc {
+			return key(data[i]) > key(data[j])
+		}
+		return key(data[i]) < key(data[j])
+	})
+	return nil
+}
@@ -0,0 +1,91 @@
+package etl
+
+import (
+	"testing"
+	"time"
+
+	"admira-etl/internal/config"
+	"admira-etl/internal/models"
+	"admira-etl/internal/storage"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSortRows(t *testing.T) {
+	rows := func() []models.TransformedData {
+		return []models.TransformedData{
+			{Date: "2025-01-02", CampaignID: "A", Cost: 50.0, ROAS: 2.0},
+			{Date: "2025-01-01", CampaignID: "B", Cost: 200.0, ROAS: 0.5},
+			{Date: "2025-01-03", CampaignID: "C", Cost: 50.0, ROAS: 4.0},
+			{Date: "2025-01-01", CampaignID: "D", Cost: 100.0, ROAS: 1.0},
+		}
+	}
+	campaigns := func(data []models.TransformedData) []string {
+		ids := make([]string, 0, len(data))
+		for _, item := range data {
+			ids = append(ids, item.CampaignID)
+		}
+		return ids
+	}
+
+	tests := []struct {
+		name     string
+		field    SortField
+		order    SortOrder
+		expected []string
+	}{
+		{"defaults to date ascending", "", "", []string{"B", "D", "A", "C"}},
+		{"date descending", SortByDate, SortDesc, []string{"C", "A", "D", "B"}},
+		{"cost descending keeps ties in stored order", SortByCost, SortDesc, []string{"B", "D", "A", "C"}},
+		{"roas ascending", SortByROAS, SortAsc, []string{"B", "D", "A", "C"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data := rows()
+			require.NoError(t, sortRows(data, tt.field, tt.order))
+			assert.Equal(t, tt.expected, campaigns(data))
+		})
+	}
+
+	assert.Error(t, sortRows(rows(), "campaign_name", SortAsc))
+	assert.Error(t, sortRows(rows(), SortByCost, "sideways"))
+}
+
+func TestGetChannelMetrics_SortBeforePagination(t *testing.T) {
+	logger := logrus.New()
+	logger.SetLevel(logrus.ErrorLevel)
+
+	store := storage.NewInMemoryStorage()
+	service := NewService(&config.Config{}, store, logger)
+
+	require.NoError(t, store.StoreTransformedData([]models.TransformedData{
+		{Date: "2025-01-01", Channel: "google_ads", CampaignID: "C-1001", Cost: 10.0},
+		{Date: "2025-01-02", Channel: "google_ads", CampaignID: "C-1001", Cost: 300.0},
+		{Date: "2025-01-03", Channel: "google_ads", CampaignID: "C-1001", Cost: 20.0},
+		{Date: "2025-01-04", Channel: "google_ads", CampaignID: "C-1001", Cost: 150.0},
+	}))
+
+	from, _ := time.Parse("2006-01-02", "2025-01-01")
+	to, _ := time.Parse("2006-01-02", "2025-01-31")
+
+	result, _, err := service.GetChannelMetrics(ChannelMetricsQuery{
+		From: from, To: to, Channel: "google_ads",
+		SortBy: SortByCost, Order: SortDesc, Limit: 2,
+	})
+	require.NoError(t, err)
+	require.Len(t, result, 2)
+	assert.Equal(t, "2025-01-02", result[0].Date)
+	assert.Equal(t, "2025-01-04", result[1].Date)
+
+	result, _, err = service.GetChannelMetrics(ChannelMetricsQuery{
+		From: from, To: to, Channel: "google_ads",
+		SortBy: SortByCost, Order: SortDesc, Limit: 2, Offset: 2,
+	})
+	require.NoError(t, err)
+	require.Len(t, result, 2)
+	assert.Equal(t, "2025-01-03", result[0].Date)
+	assert.Equal(t, "2025-01-01", result[1].Date)
+}
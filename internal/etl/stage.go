@@ -0,0 +1,75 @@
+package etl
+
+import (
+	"sort"
+	"strings"
+
+	"admira-etl/internal/constants"
+	"admira-etl/internal/models"
+
+	"github.com/sirupsen/logrus"
+)
+
+// knownStages lists the opportunity stages the CRM is expected to send.
+var knownStages = map[string]bool{
+	constants.StageLead:       true,
+	constants.StageQualified:  true,
+	constants.StageProposal:   true,
+	constants.StageClosedWon:  true,
+	constants.StageClosedLost: true,
+}
+
+// normalizeStage lowercases and trims a stage the same way UTMs are
+// normalized, so "Closed_Won " and "closed_won" compare equal.
+func normalizeStage(stage string) string {
+	return strings.ToLower(strings.TrimSpace(stage))
+}
+
+// isClosedWon reports whether an opportunity's stage is closed_won,
+// ignoring case and surrounding whitespace.
+func isClosedWon(opp models.Opportunity) bool {
+	return normalizeStage(opp.Stage) == constants.StageClosedWon
+}
+
+// newStageWeights builds the stage-to-weight table used for weighted
+// pipeline revenue from the configured weights, normalizing stage names.
+// closed_won counts fully unless the configuration says otherwise.
+func newStageWeights(configured map[string]float64) map[string]float64 {
+	weights := map[string]float64{constants.StageClosedWon: 1.0}
+	for stage, weight := range configured {
+		weights[normalizeStage(stage)] = weight
+	}
+	return weights
+}
+
+// normalizeStages returns the opportunities with their stages normalized,
+// logging one warning per unrecognized stage so data-quality issues in the
+// CRM feed surface without flooding the log. Unknown stages are kept and
+// simply never count as won.
+func (s *Service) normalizeStages(opportunities []models.Opportunity) []models.Opportunity {
+	normalized := make([]models.Opportunity, len(opportunities))
+	unknown := make(map[string][]string)
+	for i, opp := range opportunities {
+		opp.Stage = normalizeStage(opp.Stage)
+		if !knownStages[opp.Stage] {
+			unknown[opp.Stage] = append(unknown[opp.Stage], opp.OpportunityID)
+		}
+		normalized[i] = opp
+	}
+
+	stages := make([]string, 0, len(unknown))
+	for stage := range unknown {
+		stages = append(stages, stage)
+	}
+	sort.Strings(stages)
+	for _, stage := range stages {
+		ids := unknown[stage]
+		s.logger.WithFields(logrus.Fields{
+			"stage":          stage,
+			"count":          len(ids),
+			"opportunity_id": ids[0],
+		}).Warn("Unrecognized opportunity stage, not counted as won")
+	}
+
+	return normalized
+}
@@ -0,0 +1,107 @@
+package etl
+
+import (
+	"testing"
+	"time"
+
+	"admira-etl/internal/config"
+	"admira-etl/internal/models"
+	"admira-etl/internal/storage"
+
+	"github.com/sirupsen/logrus"
+	"github.com/sirupsen/logrus/hooks/test"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCalculateMetrics_StageCase(t *testing.T) {
+	logger := logrus.New()
+	logger.SetLevel(logrus.ErrorLevel)
+	service := NewService(&config.Config{}, storage.NewInMemoryStorage(), logger)
+
+	ad := models.AdsPerformance{Clicks: 100, Cost: 100.0}
+	metrics := service.calculateMetrics(ad, fullCredit([]models.Opportunity{
+		{Stage: "closed_won", Amount: 1000.0},
+		{Stage: "Closed_Won", Amount: 2000.0},
+		{Stage: " CLOSED_WON ", Amount: 3000.0},
+		{Stage: "won", Amount: 4000.0},
+	}))
+
+	assert.Equal(t, 4, metrics.Opportunities)
+	assert.Equal(t, 3, metrics.ClosedWon)
+	assert.Equal(t, 6000.0, metrics.Revenue)
+}
+
+func TestCalculateMetrics_WeightedRevenue(t *testing.T) {
+	logger := logrus.New()
+	logger.SetLevel(logrus.ErrorLevel)
+
+	ad := models.AdsPerformance{Clicks: 100, Cost: 100.0}
+	opportunities := []models.Opportunity{
+		{Stage: "closed_won", Amount: 1000.0},
+		{Stage: "proposal", Amount: 2000.0},
+		{Stage: "qualified", Amount: 3000.0},
+		{Stage: "closed_lost", Amount: 4000.0},
+	}
+
+	t.Run("default weights only count closed_won", func(t *testing.T) {
+		service := NewService(&config.Config{}, storage.NewInMemoryStorage(), logger)
+		metrics := service.calculateMetrics(ad, fullCredit(opportunities))
+		assert.Equal(t, 1000.0, metrics.Revenue)
+		assert.Equal(t, 1000.0, metrics.WeightedRevenue)
+	})
+
+	t.Run("configured weights", func(t *testing.T) {
+		service := NewService(&config.Config{
+			StageWeights: map[string]float64{"Proposal": 0.5, "qualified": 0.1},
+		}, storage.NewInMemoryStorage(), logger)
+		metrics := service.calculateMetrics(ad, fullCredit(opportunities))
+		// Closed revenue is unaffected by the weights
+		assert.Equal(t, 1000.0, metrics.Revenue)
+		assert.InDelta(t, 1000.0+1000.0+300.0, metrics.WeightedRevenue, 0.001)
+	})
+
+	t.Run("closed_won can be overridden", func(t *testing.T) {
+		service := NewService(&config.Config{
+			StageWeights: map[string]float64{"closed_won": 0.9},
+		}, storage.NewInMemoryStorage(), logger)
+		metrics := service.calculateMetrics(ad, fullCredit(opportunities))
+		assert.Equal(t, 1000.0, metrics.Revenue)
+		assert.InDelta(t, 900.0, metrics.WeightedRevenue, 0.001)
+	})
+}
+
+func TestTransformData_UnknownStageWarns(t *testing.T) {
+	logger, hook := test.NewNullLogger()
+	service := NewService(&config.Config{}, storage.NewInMemoryStorage(), logger)
+
+	adsData := &models.AdsData{Performance: []models.AdsPerformance{{
+		Date: "2025-01-01", CampaignID: "C-1001", Channel: "google_ads", Clicks: 100, Cost: 100.0,
+		UTMCampaign: "back_to_school", UTMSource: "google", UTMMedium: "cpc",
+	}}}
+	crmData := &models.CRMData{Opportunities: []models.Opportunity{
+		{OpportunityID: "O-1", Stage: "Closed_Won", Amount: 1000.0, UTMCampaign: "back_to_school", UTMSource: "google", UTMMedium: "cpc"},
+		{OpportunityID: "O-2", Stage: "Proposal", Amount: 500.0, UTMCampaign: "back_to_school", UTMSource: "google", UTMMedium: "cpc"},
+		{OpportunityID: "O-3", Stage: "won", Amount: 700.0, UTMCampaign: "back_to_school", UTMSource: "google", UTMMedium: "cpc"},
+		{OpportunityID: "O-4", Stage: "Won", Amount: 300.0, UTMCampaign: "back_to_school", UTMSource: "google", UTMMedium: "cpc"},
+	}}
+
+	result, err := service.transformData(adsData, crmData, time.Time{}, time.Time{})
+	require.NoError(t, err)
+	require.Len(t, result, 1)
+	assert.Equal(t, 4, result[0].Opportunities)
+	assert.Equal(t, 1, result[0].ClosedWon)
+	assert.Equal(t, 1000.0, result[0].Revenue)
+
+	// The two spellings of "won" normalize to one unknown stage, reported once
+	var warnings []*logrus.Entry
+	for _, entry := range hook.AllEntries() {
+		if entry.Level == logrus.WarnLevel {
+			warnings = append(warnings, entry)
+		}
+	}
+	require.Len(t, warnings, 1)
+	assert.Equal(t, "won", warnings[0].Data["stage"])
+	assert.Equal(t, 2, warnings[0].Data["count"])
+	assert.Equal(t, "O-3", warnings[0].Data["opportunity_id"])
+}
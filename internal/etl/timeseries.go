@@ -0,0 +1,86 @@
+package etl
+
+import (
+	"fmt"
+	"time"
+
+	"admira-etl/internal/constants"
+	"admira-etl/internal/models"
+)
+
+// GapFill selects what a time series reports for dates without any rows.
+type GapFill string
+
+const (
+	GapFillZero GapFill = "zero"
+	GapFillNull GapFill = "null"
+)
+
+// ErrTimeSeriesTooLong is returned for ranges spanning more than
+// constants.MaxTimeSeriesDays days, since every date gets a point.
+var ErrTimeSeriesTooLong = fmt.Errorf("time series ranges are limited to %d days", constants.MaxTimeSeriesDays)
+
+// GetTimeSeries returns one point of metric per date from from to to
+// inclusive, optionally limited to a channel. Each date's rows are summed
+// and its ratios recomputed from those totals, as in a summary. Dates
+// without rows get 0 or, with GapFillNull, a null value, so charts can tell
+// "no data" from a real zero.
+func (s *Service) GetTimeSeries(from, to time.Time, channel string, metric SortField, fill GapFill) (models.TimeSeries, error) {
+	key, ok := sortKeys[metric]
+	if !ok {
+		return models.TimeSeries{}, fmt.Errorf("unknown time series metric %q", metric)
+	}
+	if fill == "" {
+		fill = GapFillZero
+	}
+	if fill != GapFillZero && fill != GapFillNull {
+		return models.TimeSeries{}, fmt.Errorf("unknown gap fill %q", fill)
+	}
+
+	days := int(to.Sub(from).Hours()/24) + 1
+	if days > constants.MaxTimeSeriesDays {
+		return models.TimeSeries{}, ErrTimeSeriesTooLong
+	}
+	if days < 0 {
+		days = 0
+	}
+
+	filters := map[string]string{}
+	if channel != "" {
+		filters["channel"] = channel
+	}
+	data, err := s.storage.GetTransformedData(from, to, filters, 0, 0)
+	if err != nil {
+		return models.TimeSeries{}, fmt.Errorf("failed to get time series data: %w", err)
+	}
+
+	daily := make(map[string]models.TransformedData)
+	for _, row := range mergeRows(data, func(item models.TransformedData) string {
+		return item.Date
+	}) {
+		daily[row.Date] = row
+	}
+
+	series := models.TimeSeries{
+		From:    from.Format(constants.DateFormat),
+		To:      to.Format(constants.DateFormat),
+		Channel: channel,
+		Metric:  string(metric),
+		Fill:    string(fill),
+		Dates:   make([]string, 0, days),
+		Values:  make([]*float64, 0, days),
+	}
+	for date := from; !date.After(to); date = date.AddDate(0, 0, 1) {
+		day := date.Format(constants.DateFormat)
+		series.Dates = append(series.Dates, day)
+
+		row, ok := daily[day]
+		if !ok && fill == GapFillNull {
+			series.Values = append(series.Values, nil)
+			continue
+		}
+		value := key(row)
+		series.Values = append(series.Values, &value)
+	}
+	return series, nil
+}
@@ -0,0 +1,92 @@
+package etl
+
+import (
+	"testing"
+	"time"
+
+	"admira-etl/internal/config"
+	"admira-etl/internal/constants"
+	"admira-etl/internal/models"
+	"admira-etl/internal/storage"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetTimeSeries(t *testing.T) {
+	logger := logrus.New()
+	logger.SetLevel(logrus.ErrorLevel)
+
+	store := storage.NewInMemoryStorage()
+	service := NewService(&config.Config{}, store, logger)
+
+	// Google rows on the 1st and 4th only; the 1st has two campaigns whose ROAS
+	// must come from the day's totals, not an average
+	require.NoError(t, store.StoreTransformedData([]models.TransformedData{
+		{Date: "2025-01-01", Channel: "google_ads", CampaignID: "C-1001", Clicks: 100, Cost: 100.0, Revenue: 100.0},
+		{Date: "2025-01-01", Channel: "google_ads", CampaignID: "C-1002", Clicks: 50, Cost: 300.0, Revenue: 1100.0},
+		{Date: "2025-01-04", Channel: "google_ads", CampaignID: "C-1001", Clicks: 20, Cost: 50.0, Revenue: 25.0, ROAS: 0.5},
+		{Date: "2025-01-03", Channel: "facebook_ads", CampaignID: "C-2001", Clicks: 80, Cost: 10.0, Revenue: 40.0},
+	}))
+
+	from, _ := time.Parse("2006-01-02", "2025-01-01")
+	to, _ := time.Parse("2006-01-02", "2025-01-05")
+	dates := []string{"2025-01-01", "2025-01-02", "2025-01-03", "2025-01-04", "2025-01-05"}
+
+	values := func(series models.TimeSeries) []interface{} {
+		result := make([]interface{}, 0, len(series.Values))
+		for _, value := range series.Values {
+			if value == nil {
+				result = append(result, nil)
+				continue
+			}
+			result = append(result, *value)
+		}
+		return result
+	}
+
+	t.Run("gaps filled with zeros", func(t *testing.T) {
+		series, err := service.GetTimeSeries(from, to, "google_ads", SortByROAS, GapFillZero)
+		require.NoError(t, err)
+		assert.Equal(t, dates, series.Dates)
+		assert.Equal(t, []interface{}{3.0, 0.0, 0.0, 0.5, 0.0}, values(series))
+		assert.Equal(t, "roas", series.Metric)
+		assert.Equal(t, "zero", series.Fill)
+		assert.Equal(t, "google_ads", series.Channel)
+	})
+
+	t.Run("gaps filled with nulls", func(t *testing.T) {
+		series, err := service.GetTimeSeries(from, to, "google_ads", SortByClicks, GapFillNull)
+		require.NoError(t, err)
+		assert.Equal(t, dates, series.Dates)
+		assert.Equal(t, []interface{}{150.0, nil, nil, 20.0, nil}, values(series))
+	})
+
+	t.Run("every channel, zero fill by default", func(t *testing.T) {
+		series, err := service.GetTimeSeries(from, to, "", SortByClicks, "")
+		require.NoError(t, err)
+		assert.Equal(t, []interface{}{150.0, 0.0, 80.0, 20.0, 0.0}, values(series))
+		assert.Equal(t, "zero", series.Fill)
+	})
+
+	t.Run("a real zero is not a gap", func(t *testing.T) {
+		require.NoError(t, store.StoreTransformedData([]models.TransformedData{
+			{Date: "2025-01-02", Channel: "google_ads", CampaignID: "C-1003"},
+		}))
+		series, err := service.GetTimeSeries(from, to, "google_ads", SortByClicks, GapFillNull)
+		require.NoError(t, err)
+		assert.Equal(t, []interface{}{150.0, 0.0, nil, 20.0, nil}, values(series))
+	})
+
+	t.Run("invalid requests", func(t *testing.T) {
+		_, err := service.GetTimeSeries(from, to, "", SortByDate, GapFillZero)
+		assert.Error(t, err)
+
+		_, err = service.GetTimeSeries(from, to, "", SortByClicks, "interpolate")
+		assert.Error(t, err)
+
+		_, err = service.GetTimeSeries(from, from.AddDate(0, 0, constants.MaxTimeSeriesDays), "", SortByClicks, GapFillZero)
+		assert.ErrorIs(t, err, ErrTimeSeriesTooLong)
+	})
+}
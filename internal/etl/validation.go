@@ -0,0 +1,162 @@
+package etl
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"admira-etl/internal/constants"
+	"admira-etl/internal/models"
+
+	"github.com/sirupsen/logrus"
+)
+
+// ValidationMode controls what happens to fetched records that fail
+// validation.
+type ValidationMode string
+
+const (
+	// ValidationSkipInvalid drops invalid records and ingests the rest.
+	ValidationSkipInvalid ValidationMode = constants.ValidationModeSkipInvalid
+	// ValidationRejectAll fails the whole ingestion if any record is invalid.
+	ValidationRejectAll ValidationMode = constants.ValidationModeRejectAll
+)
+
+// ParseValidationMode converts a configuration value into a ValidationMode.
+func ParseValidationMode(value string) (ValidationMode, error) {
+	switch mode := ValidationMode(strings.ToLower(strings.TrimSpace(value))); mode {
+	case ValidationSkipInvalid, ValidationRejectAll:
+		return mode, nil
+	case "":
+		return ValidationSkipInvalid, nil
+	default:
+		return "", fmt.Errorf("unknown validation mode %q", value)
+	}
+}
+
+// RejectedRecord is a fetched record that failed validation, as received,
+// with every problem found in it.
+type RejectedRecord struct {
+	Source   string          `json:"source"`
+	Record   json.RawMessage `json:"record"`
+	Problems []string        `json:"problems"`
+}
+
+// ValidationReport summarises the validation of one ingestion's fetched
+// data.
+type ValidationReport struct {
+	Mode     ValidationMode   `json:"mode"`
+	Checked  int              `json:"checked"`
+	Rejected []RejectedRecord `json:"rejected"`
+}
+
+// ValidationError is returned by RunIngestion in reject-all mode when any
+// fetched record is invalid; nothing is stored.
+type ValidationError struct {
+	Report *ValidationReport
+}
+
+func (e *ValidationError) Error() string {
+	return fmt.Sprintf("%d of %d fetched records failed validation", len(e.Report.Rejected), e.Report.Checked)
+}
+
+// validateSourceData checks every fetched ads row and opportunity, removing
+// the invalid ones from adsData and crmData. In reject-all mode any invalid
+// record fails the run with a *ValidationError instead.
+func (s *Service) validateSourceData(adsData *models.AdsData, crmData *models.CRMData) (*ValidationReport, error) {
+	report := &ValidationReport{Mode: s.validationMode, Rejected: []RejectedRecord{}}
+
+	report.Checked += len(adsData.Performance) + len(adsData.Malformed)
+	for _, malformed := range adsData.Malformed {
+		report.Rejected = append(report.Rejected, malformedRecord("ads", malformed))
+	}
+	valid := adsData.Performance[:0:0]
+	for _, ad := range adsData.Performance {
+		if problems := adsProblems(ad); len(problems) > 0 {
+			report.Rejected = append(report.Rejected, rejectedRecord("ads", ad, problems))
+			continue
+		}
+		valid = append(valid, ad)
+	}
+
+	report.Checked += len(crmData.Opportunities) + len(crmData.Malformed)
+	for _, malformed := range crmData.Malformed {
+		report.Rejected = append(report.Rejected, malformedRecord("crm", malformed))
+	}
+	validOpps := crmData.Opportunities[:0:0]
+	for _, opp := range crmData.Opportunities {
+		if problems := opportunityProblems(opp); len(problems) > 0 {
+			report.Rejected = append(report.Rejected, rejectedRecord("crm", opp, problems))
+			continue
+		}
+		validOpps = append(validOpps, opp)
+	}
+
+	if len(report.Rejected) == 0 {
+		return report, nil
+	}
+	if s.validationMode == ValidationRejectAll {
+		return report, &ValidationError{Report: report}
+	}
+
+	adsData.Performance, adsData.Malformed = valid, nil
+	crmData.Opportunities, crmData.Malformed = validOpps, nil
+	s.logger.WithFields(logrus.Fields{
+		"checked":  report.Checked,
+		"rejected": len(report.Rejected),
+	}).Warn("Skipping fetched records that failed validation")
+	return report, nil
+}
+
+// adsProblems lists what is wrong with an ads row; nil means it is valid.
+func adsProblems(ad models.AdsPerformance) []string {
+	var problems []string
+	if _, err := time.Parse(dateLayout, ad.Date); err != nil {
+		problems = append(problems, fmt.Sprintf("date %q is not YYYY-MM-DD", ad.Date))
+	}
+	if ad.Channel == "" {
+		problems = append(problems, "channel is required")
+	}
+	if ad.CampaignID == "" {
+		problems = append(problems, "campaign_id is required")
+	}
+	if ad.Clicks < 0 {
+		problems = append(problems, fmt.Sprintf("clicks must not be negative, got %d", ad.Clicks))
+	}
+	if ad.Impressions < 0 {
+		problems = append(problems, fmt.Sprintf("impressions must not be negative, got %d", ad.Impressions))
+	}
+	if ad.Cost < 0 {
+		problems = append(problems, fmt.Sprintf("cost must not be negative, got %g", ad.Cost))
+	}
+	return problems
+}
+
+// opportunityProblems lists what is wrong with an opportunity; nil means it
+// is valid. Missing IDs, stages and creation dates are tolerated further
+// down the pipeline.
+func opportunityProblems(opp models.Opportunity) []string {
+	var problems []string
+	if opp.Amount < 0 {
+		problems = append(problems, fmt.Sprintf("amount must not be negative, got %g", opp.Amount))
+	}
+	return problems
+}
+
+func rejectedRecord(source string, record interface{}, problems []string) RejectedRecord {
+	raw, _ := json.Marshal(record)
+	return RejectedRecord{Source: source, Record: raw, Problems: problems}
+}
+
+func malformedRecord(source string, malformed models.MalformedRecord) RejectedRecord {
+	return RejectedRecord{Source: source, Record: malformed.Raw, Problems: []string{malformed.Err.Error()}}
+}
+
+// LastValidationReport returns the validation report of the most recent
+// pulled ingestion, or nil before the first one has fetched its data.
+func (s *Service) LastValidationReport() *ValidationReport {
+	s.validationMu.Lock()
+	defer s.validationMu.Unlock()
+	return s.lastValidation
+}
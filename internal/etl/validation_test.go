@@ -0,0 +1,125 @@
+package etl
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"admira-etl/internal/config"
+	"admira-etl/internal/storage"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// mixedUpstream serves two valid ads rows alongside four broken ones, and
+// one valid and one negative-amount opportunity.
+func mixedUpstream(t *testing.T) *httptest.Server {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/crm" {
+			w.Write([]byte(`{"external":{"crm":{"opportunities":[
+				{"opportunity_id":"O-1","stage":"closed_won","amount":500,"utm_campaign":"spring"},
+				{"opportunity_id":"O-2","stage":"closed_won","amount":-50,"utm_campaign":"spring"}
+			]}}}`))
+			return
+		}
+		w.Write([]byte(`{"external":{"ads":{"performance":[
+			{"date":"2025-01-01","channel":"google_ads","campaign_id":"C-1","clicks":100,"cost":10,"utm_campaign":"spring"},
+			{"channel":"google_ads","campaign_id":"C-2","clicks":100},
+			{"date":"2025-01-01","channel":"google_ads","campaign_id":"C-3","clicks":100,"cost":-5},
+			{"date":"2025-01-01","channel":"google_ads","campaign_id":"C-4","clicks":"100"},
+			{"date":"2025-01-01","campaign_id":"C-5","impressions":-1},
+			{"date":"2025-01-02","channel":"facebook_ads","campaign_id":"C-6","clicks":50}
+		]}}}`))
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func newValidationService(t *testing.T, mode string) (*Service, storage.Storage) {
+	logger := logrus.New()
+	logger.SetLevel(logrus.FatalLevel)
+
+	upstream := mixedUpstream(t)
+	store := storage.NewInMemoryStorage()
+	return NewService(&config.Config{
+		AdsAPIURL:      upstream.URL + "/ads",
+		CRMAPIURL:      upstream.URL + "/crm",
+		RetryDelay:     time.Millisecond,
+		ValidationMode: mode,
+	}, store, logger), store
+}
+
+func TestRunIngestion_SkipsInvalidRecords(t *testing.T) {
+	service, store := newValidationService(t, "")
+
+	require.NoError(t, service.RunIngestion(context.Background(), IngestOptions{Full: true}))
+
+	data, err := store.GetTransformedData(time.Time{}, time.Now(), map[string]string{}, 0, 0)
+	require.NoError(t, err)
+	require.Len(t, data, 2)
+	assert.Equal(t, "C-1", data[0].CampaignID)
+	assert.Equal(t, "C-6", data[1].CampaignID)
+
+	// Only the valid opportunity was matched
+	assert.Equal(t, 500.0, data[0].Revenue)
+
+	report := service.LastValidationReport()
+	require.NotNil(t, report)
+	assert.Equal(t, ValidationSkipInvalid, report.Mode)
+	assert.Equal(t, 8, report.Checked)
+	require.Len(t, report.Rejected, 5)
+
+	problems := make(map[string][]string)
+	for _, rejected := range report.Rejected {
+		var id struct {
+			CampaignID    string `json:"campaign_id"`
+			OpportunityID string `json:"opportunity_id"`
+		}
+		require.NoError(t, json.Unmarshal(rejected.Record, &id))
+		problems[rejected.Source+" "+id.CampaignID+id.OpportunityID] = rejected.Problems
+	}
+	assert.Equal(t, []string{`date "" is not YYYY-MM-DD`}, problems["ads C-2"])
+	assert.Equal(t, []string{"cost must not be negative, got -5"}, problems["ads C-3"])
+	require.Len(t, problems["ads C-4"], 1)
+	assert.Contains(t, problems["ads C-4"][0], "cannot unmarshal string")
+	assert.Equal(t, []string{"channel is required", "impressions must not be negative, got -1"}, problems["ads C-5"])
+	assert.Equal(t, []string{"amount must not be negative, got -50"}, problems["crm O-2"])
+}
+
+func TestRunIngestion_RejectAll(t *testing.T) {
+	service, store := newValidationService(t, "reject_all")
+
+	err := service.RunIngestion(context.Background(), IngestOptions{Full: true})
+
+	var validationErr *ValidationError
+	require.ErrorAs(t, err, &validationErr)
+	assert.Equal(t, ValidationRejectAll, validationErr.Report.Mode)
+	assert.Len(t, validationErr.Report.Rejected, 5)
+	assert.EqualError(t, err, "5 of 8 fetched records failed validation")
+
+	// Nothing was stored and the run doesn't count as an ingestion
+	data, err := store.GetTransformedData(time.Time{}, time.Now(), map[string]string{}, 0, 0)
+	require.NoError(t, err)
+	assert.Empty(t, data)
+	lastIngestion, err := store.GetLastIngestionTime()
+	require.NoError(t, err)
+	assert.True(t, lastIngestion.IsZero())
+}
+
+func TestParseValidationMode(t *testing.T) {
+	mode, err := ParseValidationMode("")
+	require.NoError(t, err)
+	assert.Equal(t, ValidationSkipInvalid, mode)
+
+	mode, err = ParseValidationMode(" Reject_All ")
+	require.NoError(t, err)
+	assert.Equal(t, ValidationRejectAll, mode)
+
+	_, err = ParseValidationMode("lenient")
+	assert.Error(t, err)
+}
@@ -0,0 +1,76 @@
+package etl
+
+import (
+	"context"
+	"crypto/hmac"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"admira-etl/internal/models"
+)
+
+var (
+	// ErrWebhookNotConfigured is returned by IngestSigned when there is no
+	// SINK_SECRET to verify signatures against.
+	ErrWebhookNotConfigured = errors.New("webhook secret not configured")
+	// ErrInvalidSignature is returned by IngestSigned when the signature
+	// doesn't match the pushed rows.
+	ErrInvalidSignature = errors.New("invalid signature")
+)
+
+// BatchSignature signs rows the way IngestSigned verifies them: the HMAC of
+// each row's export payload, newline-separated. A single row gets the same
+// signature ExportData sends with it.
+func (s *Service) BatchSignature(records []models.TransformedData) string {
+	payloads := make([]string, 0, len(records))
+	for _, record := range records {
+		payloads = append(payloads, signaturePayload(record))
+	}
+	return s.signPayload(strings.Join(payloads, "\n"))
+}
+
+// IngestSigned stores already transformed rows pushed by an upstream once
+// signature, computed as BatchSignature does under the sink secret, checks
+// out. Rows are stored as received and, like other pushes, don't move the
+// last ingestion time. It returns how many rows were stored.
+func (s *Service) IngestSigned(ctx context.Context, records []models.TransformedData, signature string) (int, error) {
+	if s.config.SinkSecret == "" {
+		return 0, ErrWebhookNotConfigured
+	}
+	if signature == "" || !hmac.Equal([]byte(signature), []byte(s.BatchSignature(records))) {
+		return 0, ErrInvalidSignature
+	}
+
+	if len(records) == 0 {
+		return 0, fmt.Errorf("%w: no rows", ErrInvalidPayload)
+	}
+	for i, record := range records {
+		if _, err := time.Parse(dateLayout, record.Date); err != nil {
+			return 0, fmt.Errorf("%w: row %d: date %q is not YYYY-MM-DD", ErrInvalidPayload, i, record.Date)
+		}
+		if record.Channel == "" || record.CampaignID == "" {
+			return 0, fmt.Errorf("%w: row %d: channel and campaign_id are required", ErrInvalidPayload, i)
+		}
+	}
+
+	_, done, err := s.beginWork(ctx, "webhook ingestion")
+	if err != nil {
+		return 0, err
+	}
+	defer done()
+
+	if err := s.storage.StoreTransformedData(records); err != nil {
+		// A file store that fails to persist keeps the rows in memory
+		s.refreshStoredRecords()
+		s.refreshAggregates(records)
+		return 0, fmt.Errorf("failed to store transformed data: %w", err)
+	}
+
+	s.metrics.RecordsProcessed.Add(float64(len(records)))
+	s.refreshStoredRecords()
+	s.refreshAggregates(records)
+	s.logger.WithField("records_processed", len(records)).Info("Webhook ingestion completed")
+	return len(records), nil
+}
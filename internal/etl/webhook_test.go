@@ -0,0 +1,89 @@
+package etl
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"admira-etl/internal/config"
+	"admira-etl/internal/models"
+	"admira-etl/internal/storage"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newWebhookService(secret string) (*Service, storage.Storage) {
+	logger := logrus.New()
+	logger.SetLevel(logrus.FatalLevel)
+	store := storage.NewInMemoryStorage()
+	return NewService(&config.Config{SinkSecret: secret}, store, logger), store
+}
+
+func TestIngestSigned(t *testing.T) {
+	records := []models.TransformedData{
+		{Date: "2025-01-01", Channel: "google_ads", CampaignID: "C-1001", Clicks: 100, Cost: 25.0, Revenue: 500.0},
+		{Date: "2025-01-02", Channel: "facebook_ads", CampaignID: "C-2001", Clicks: 50, Cost: 10.0},
+	}
+
+	t.Run("valid signature stores the rows", func(t *testing.T) {
+		service, store := newWebhookService("secret")
+
+		stored, err := service.IngestSigned(context.Background(), records, service.BatchSignature(records))
+		require.NoError(t, err)
+		assert.Equal(t, 2, stored)
+
+		data, err := store.GetTransformedData(time.Time{}, time.Now(), map[string]string{}, 0, 0)
+		require.NoError(t, err)
+		assert.Equal(t, records, data)
+	})
+
+	t.Run("single row uses the export signature", func(t *testing.T) {
+		service, _ := newWebhookService("secret")
+		single := records[:1]
+		assert.Equal(t, service.createHMACSignature(single[0]), service.BatchSignature(single))
+
+		_, err := service.IngestSigned(context.Background(), single, service.createHMACSignature(single[0]))
+		require.NoError(t, err)
+	})
+
+	t.Run("tampered rows are rejected", func(t *testing.T) {
+		service, store := newWebhookService("secret")
+		signature := service.BatchSignature(records)
+
+		tampered := append([]models.TransformedData(nil), records...)
+		tampered[0].Revenue = 5000.0
+
+		_, err := service.IngestSigned(context.Background(), tampered, signature)
+		assert.ErrorIs(t, err, ErrInvalidSignature)
+
+		count, err := store.CountTransformedData(nil)
+		require.NoError(t, err)
+		assert.Zero(t, count)
+	})
+
+	t.Run("wrong secret or missing signature is rejected", func(t *testing.T) {
+		service, _ := newWebhookService("secret")
+		other, _ := newWebhookService("other")
+
+		_, err := service.IngestSigned(context.Background(), records, other.BatchSignature(records))
+		assert.ErrorIs(t, err, ErrInvalidSignature)
+		_, err = service.IngestSigned(context.Background(), records, "")
+		assert.ErrorIs(t, err, ErrInvalidSignature)
+	})
+
+	t.Run("invalid rows are rejected", func(t *testing.T) {
+		service, _ := newWebhookService("secret")
+		invalid := []models.TransformedData{{Date: "01/02/2025", Channel: "google_ads", CampaignID: "C-1001"}}
+
+		_, err := service.IngestSigned(context.Background(), invalid, service.BatchSignature(invalid))
+		assert.ErrorIs(t, err, ErrInvalidPayload)
+	})
+
+	t.Run("requires a secret", func(t *testing.T) {
+		service, _ := newWebhookService("")
+		_, err := service.IngestSigned(context.Background(), records, "anything")
+		assert.ErrorIs(t, err, ErrWebhookNotConfigured)
+	})
+}
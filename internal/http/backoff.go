@@ -0,0 +1,70 @@
+package http
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// BackoffStrategy selects how the delay between retries grows.
+type BackoffStrategy string
+
+const (
+	// BackoffLinear waits RetryDelay times the retry number: 1x, 2x, 3x...
+	BackoffLinear BackoffStrategy = "linear"
+	// BackoffExponential doubles the wait on every retry: 1x, 2x, 4x...
+	BackoffExponential BackoffStrategy = "exponential"
+)
+
+// DefaultBackoffStrategy is used when ClientConfig.BackoffStrategy is unset.
+const DefaultBackoffStrategy = BackoffExponential
+
+// ParseBackoffStrategy converts a configured strategy name; empty selects
+// DefaultBackoffStrategy.
+func ParseBackoffStrategy(value string) (BackoffStrategy, error) {
+	switch strategy := BackoffStrategy(strings.ToLower(strings.TrimSpace(value))); strategy {
+	case "":
+		return DefaultBackoffStrategy, nil
+	case BackoffLinear, BackoffExponential:
+		return strategy, nil
+	default:
+		return "", fmt.Errorf("unknown backoff strategy %q (expected linear or exponential)", value)
+	}
+}
+
+// backoff returns how long to wait before retry number attempt (1 for the
+// first retry), capped at maxDelay when that is positive.
+func backoff(strategy BackoffStrategy, base, maxDelay time.Duration, attempt int) time.Duration {
+	if attempt < 1 || base <= 0 {
+		return 0
+	}
+
+	var delay time.Duration
+	if strategy == BackoffLinear {
+		delay = base * time.Duration(attempt)
+		// Guard against overflow with absurd retry counts
+		if delay/time.Duration(attempt) != base {
+			delay = maxDuration
+		}
+	} else {
+		delay = base
+		for i := 1; i < attempt; i++ {
+			if delay > maxDuration/2 {
+				delay = maxDuration
+				break
+			}
+			delay *= 2
+			if maxDelay > 0 && delay >= maxDelay {
+				break
+			}
+		}
+	}
+
+	if maxDelay > 0 && delay > maxDelay {
+		return maxDelay
+	}
+	return delay
+}
+
+// maxDuration is the longest representable time.Duration.
+const maxDuration = time.Duration(1<<63 - 1)
@@ -0,0 +1,76 @@
+package http
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseBackoffStrategy(t *testing.T) {
+	strategy, err := ParseBackoffStrategy(" Linear ")
+	require.NoError(t, err)
+	assert.Equal(t, BackoffLinear, strategy)
+
+	strategy, err = ParseBackoffStrategy("")
+	require.NoError(t, err)
+	assert.Equal(t, BackoffExponential, strategy)
+
+	_, err = ParseBackoffStrategy("fibonacci")
+	assert.Error(t, err)
+}
+
+func TestBackoff(t *testing.T) {
+	const base = 100 * time.Millisecond
+
+	progression := func(strategy BackoffStrategy, maxDelay time.Duration, retries int) []time.Duration {
+		delays := make([]time.Duration, retries)
+		for i := range delays {
+			delays[i] = backoff(strategy, base, maxDelay, i+1)
+		}
+		return delays
+	}
+
+	tests := []struct {
+		name     string
+		strategy BackoffStrategy
+		maxDelay time.Duration
+		expected []time.Duration
+	}{
+		{
+			name:     "linear",
+			strategy: BackoffLinear,
+			expected: []time.Duration{100 * time.Millisecond, 200 * time.Millisecond, 300 * time.Millisecond, 400 * time.Millisecond, 500 * time.Millisecond},
+		},
+		{
+			name:     "exponential",
+			strategy: BackoffExponential,
+			expected: []time.Duration{100 * time.Millisecond, 200 * time.Millisecond, 400 * time.Millisecond, 800 * time.Millisecond, 1600 * time.Millisecond},
+		},
+		{
+			name:     "linear capped",
+			strategy: BackoffLinear,
+			maxDelay: 250 * time.Millisecond,
+			expected: []time.Duration{100 * time.Millisecond, 200 * time.Millisecond, 250 * time.Millisecond, 250 * time.Millisecond, 250 * time.Millisecond},
+		},
+		{
+			name:     "exponential capped",
+			strategy: BackoffExponential,
+			maxDelay: 500 * time.Millisecond,
+			expected: []time.Duration{100 * time.Millisecond, 200 * time.Millisecond, 400 * time.Millisecond, 500 * time.Millisecond, 500 * time.Millisecond},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, progression(tt.strategy, tt.maxDelay, len(tt.expected)))
+		})
+	}
+
+	t.Run("huge retry counts saturate instead of overflowing", func(t *testing.T) {
+		assert.Equal(t, maxDuration, backoff(BackoffExponential, base, 0, 200))
+		assert.Equal(t, time.Minute, backoff(BackoffExponential, base, time.Minute, 200))
+		assert.Equal(t, time.Minute, backoff(BackoffLinear, time.Hour, time.Minute, 1<<40))
+	})
+}
@@ -2,13 +2,22 @@ package http
 
 import (
 	"bytes"
+	"compress/gzip"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
 	"time"
 
+	"admira-etl/internal/logredact"
+	"admira-etl/internal/logsample"
+	"admira-etl/internal/telemetry"
+
 	"github.com/sirupsen/logrus"
 )
 
@@ -17,66 +26,350 @@ type Client struct {
 	logger     *logrus.Logger
 	maxRetries int
 	retryDelay time.Duration
+	backoff    BackoffStrategy
+	maxDelay   time.Duration
+	retryLimit time.Duration
+	retryable  map[int]bool
+	retryNet   map[NetworkErrorKind]bool
+	gzipAbove  int
+	maxBody    int64
+	metrics    *telemetry.HTTPClientMetrics
+}
+
+// DefaultRetryableStatusCodes are the responses worth retrying when
+// ClientConfig.RetryableStatusCodes is unset: rate limiting and transient
+// server or gateway failures.
+var DefaultRetryableStatusCodes = []int{
+	http.StatusTooManyRequests,
+	http.StatusInternalServerError,
+	http.StatusBadGateway,
+	http.StatusServiceUnavailable,
+	http.StatusGatewayTimeout,
 }
 
+// Connection pool defaults used when the matching ClientConfig field is zero.
+const (
+	DefaultMaxIdleConns        = 100
+	DefaultMaxIdleConnsPerHost = 10
+	DefaultIdleConnTimeout     = 90 * time.Second
+	DefaultTLSHandshakeTimeout = 10 * time.Second
+)
+
+// DefaultMaxResponseBytes caps response bodies when
+// ClientConfig.MaxResponseBytes is zero.
+const DefaultMaxResponseBytes = 32 << 20
+
+// retryWarning is logged for every failed attempt, subject to the
+// logsample.Sampler carried by the request context.
+const retryWarning = "Request failed, retrying"
+
 type ClientConfig struct {
 	Timeout    time.Duration
 	MaxRetries int
 	RetryDelay time.Duration
+
+	// BackoffStrategy sets how the delay grows from RetryDelay between
+	// retries; defaults to DefaultBackoffStrategy. MaxRetryDelay caps any
+	// single delay; zero leaves it uncapped.
+	BackoffStrategy BackoffStrategy
+	MaxRetryDelay   time.Duration
+
+	// MaxTotalRetryDuration bounds the time one call spends on attempts and
+	// the backoff between them. A retry whose backoff would end past it is
+	// not made and the last error is returned. Zero leaves retries bounded
+	// by MaxRetries and the context only.
+	MaxTotalRetryDuration time.Duration
+
+	// Connection pooling and handshake tuning for the underlying transport;
+	// zero values fall back to the Default* constants.
+	MaxIdleConns        int
+	MaxIdleConnsPerHost int
+	IdleConnTimeout     time.Duration
+	TLSHandshakeTimeout time.Duration
+
+	// ProxyURL routes every request through this proxy. When nil the
+	// HTTP_PROXY, HTTPS_PROXY and NO_PROXY environment variables apply.
+	ProxyURL *url.URL
+
+	// GzipRequestThreshold compresses POST bodies of at least this many
+	// bytes and marks them with Content-Encoding: gzip. Zero disables
+	// request compression; responses are always accepted gzipped.
+	GzipRequestThreshold int
+
+	// MaxResponseBytes caps how much of a (decompressed) response body is
+	// read; larger bodies fail with *ResponseTooLargeError. Defaults to
+	// DefaultMaxResponseBytes.
+	MaxResponseBytes int64
+
+	// RetryableStatusCodes lists the HTTP statuses that are retried; any
+	// other error status fails immediately. Defaults to
+	// DefaultRetryableStatusCodes.
+	RetryableStatusCodes []int
+
+	// RetryableNetworkErrors lists the transport failures that are retried;
+	// any other kind fails immediately. Defaults to
+	// DefaultRetryableNetworkErrors.
+	RetryableNetworkErrors []NetworkErrorKind
+
+	// Metrics receives request and retry counts; defaults to the
+	// package-level telemetry.HTTPClient collectors.
+	Metrics *telemetry.HTTPClientMetrics
 }
 
 func NewClient(config ClientConfig, logger *logrus.Logger) *Client {
+	metrics := config.Metrics
+	if metrics == nil {
+		metrics = telemetry.HTTPClient
+	}
+
+	retryableCodes := config.RetryableStatusCodes
+	if retryableCodes == nil {
+		retryableCodes = DefaultRetryableStatusCodes
+	}
+	retryable := make(map[int]bool, len(retryableCodes))
+	for _, code := range retryableCodes {
+		retryable[code] = true
+	}
+
+	retryableKinds := config.RetryableNetworkErrors
+	if retryableKinds == nil {
+		retryableKinds = DefaultRetryableNetworkErrors
+	}
+	retryNet := make(map[NetworkErrorKind]bool, len(retryableKinds))
+	for _, kind := range retryableKinds {
+		retryNet[kind] = true
+	}
+
+	strategy := config.BackoffStrategy
+	if strategy == "" {
+		strategy = DefaultBackoffStrategy
+	}
+
+	maxBody := config.MaxResponseBytes
+	if maxBody <= 0 {
+		maxBody = DefaultMaxResponseBytes
+	}
+
 	return &Client{
 		httpClient: &http.Client{
-			Timeout: config.Timeout,
+			Timeout:   config.Timeout,
+			Transport: newTransport(config),
 		},
 		logger:     logger,
 		maxRetries: config.MaxRetries,
 		retryDelay: config.RetryDelay,
+		backoff:    strategy,
+		maxDelay:   config.MaxRetryDelay,
+		retryLimit: config.MaxTotalRetryDuration,
+		retryable:  retryable,
+		retryNet:   retryNet,
+		gzipAbove:  config.GzipRequestThreshold,
+		maxBody:    maxBody,
+		metrics:    metrics,
 	}
 }
 
+// newTransport starts from the stdlib default transport, keeping its dialer
+// settings, and applies the proxy and pool tuning from config.
+func newTransport(config ClientConfig) *http.Transport {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+
+	transport.Proxy = http.ProxyFromEnvironment
+	if config.ProxyURL != nil {
+		transport.Proxy = http.ProxyURL(config.ProxyURL)
+	}
+
+	transport.MaxIdleConns = DefaultMaxIdleConns
+	if config.MaxIdleConns > 0 {
+		transport.MaxIdleConns = config.MaxIdleConns
+	}
+	transport.MaxIdleConnsPerHost = DefaultMaxIdleConnsPerHost
+	if config.MaxIdleConnsPerHost > 0 {
+		transport.MaxIdleConnsPerHost = config.MaxIdleConnsPerHost
+	}
+	transport.IdleConnTimeout = DefaultIdleConnTimeout
+	if config.IdleConnTimeout > 0 {
+		transport.IdleConnTimeout = config.IdleConnTimeout
+	}
+	transport.TLSHandshakeTimeout = DefaultTLSHandshakeTimeout
+	if config.TLSHandshakeTimeout > 0 {
+		transport.TLSHandshakeTimeout = config.TLSHandshakeTimeout
+	}
+
+	return transport
+}
+
 func (c *Client) Get(ctx context.Context, url string, result interface{}) error {
-	return c.doWithRetry(ctx, "GET", url, nil, result)
+	return c.GetWithHeaders(ctx, url, nil, result)
+}
+
+// GetWithHeaders is Get with extra request headers, such as an upstream's
+// auth token, sent unchanged on every retry attempt.
+func (c *Client) GetWithHeaders(ctx context.Context, url string, headers map[string]string, result interface{}) error {
+	return c.doWithRetry(ctx, "GET", url, nil, headers, result)
+}
+
+// GetWithTimeout is Get bounded by timeout across all attempts, including
+// the backoff between them. A non-positive timeout leaves ctx unchanged.
+func (c *Client) GetWithTimeout(ctx context.Context, url string, timeout time.Duration, result interface{}) error {
+	ctx, cancel := withTimeout(ctx, timeout)
+	defer cancel()
+	return c.Get(ctx, url, result)
 }
 
 func (c *Client) Post(ctx context.Context, url string, body interface{}, result interface{}) error {
+	return c.PostWithHeaders(ctx, url, body, nil, result)
+}
+
+// PostWithHeaders is Post with extra request headers, sent unchanged on
+// every retry attempt.
+func (c *Client) PostWithHeaders(ctx context.Context, url string, body interface{}, headers map[string]string, result interface{}) error {
 	jsonBody, err := json.Marshal(body)
 	if err != nil {
 		return fmt.Errorf("failed to marshal request body: %w", err)
 	}
 
-	return c.doWithRetry(ctx, "POST", url, jsonBody, result)
+	if c.gzipAbove > 0 && len(jsonBody) >= c.gzipAbove {
+		jsonBody, err = gzipBytes(jsonBody)
+		if err != nil {
+			return fmt.Errorf("failed to compress request body: %w", err)
+		}
+
+		withEncoding := make(map[string]string, len(headers)+1)
+		for key, value := range headers {
+			withEncoding[key] = value
+		}
+		withEncoding["Content-Encoding"] = "gzip"
+		headers = withEncoding
+	}
+
+	return c.doWithRetry(ctx, "POST", url, jsonBody, headers, result)
+}
+
+// PostWithTimeout is PostWithHeaders bounded by timeout across all
+// attempts. A non-positive timeout leaves ctx unchanged.
+func (c *Client) PostWithTimeout(ctx context.Context, url string, body interface{}, headers map[string]string, timeout time.Duration, result interface{}) error {
+	ctx, cancel := withTimeout(ctx, timeout)
+	defer cancel()
+	return c.PostWithHeaders(ctx, url, body, headers, result)
+}
+
+func withTimeout(ctx context.Context, timeout time.Duration) (context.Context, context.CancelFunc) {
+	if timeout <= 0 {
+		return ctx, func() {}
+	}
+	return context.WithTimeout(ctx, timeout)
+}
+
+func gzipBytes(data []byte) ([]byte, error) {
+	var buf bytes.Buffer
+	writer := gzip.NewWriter(&buf)
+	if _, err := writer.Write(data); err != nil {
+		return nil, err
+	}
+	if err := writer.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// Ping issues a single HEAD request, without retries, to check that url is
+// reachable. Any response below 500 counts as reachable since upstreams may
+// reject HEAD or require auth while still being up.
+func (c *Client) Ping(ctx context.Context, url string) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodHead, url, nil)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	if id := RequestIDFromContext(ctx); id != "" {
+		req.Header.Set(RequestIDHeader, id)
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 500 {
+		return &HTTPError{
+			StatusCode: resp.StatusCode,
+			Message:    http.StatusText(resp.StatusCode),
+		}
+	}
+
+	return nil
 }
 
-func (c *Client) doWithRetry(ctx context.Context, method, url string, body []byte, result interface{}) error {
+// doWithRetry retries failed requests with a delay growing by the backoff
+// strategy. The context bounds the whole sequence: once it is done, or its
+// deadline would pass before the next attempt could start, the loop stops
+// with an error wrapping the context's. Running out of the retry budget
+// stops it with an error wrapping the last failure.
+func (c *Client) doWithRetry(ctx context.Context, method, url string, body []byte, headers map[string]string, result interface{}) error {
 	var lastErr error
+	start := time.Now()
 
 	for attempt := 0; attempt <= c.maxRetries; attempt++ {
 		if attempt > 0 {
+			delay := backoff(c.backoff, c.retryDelay, c.maxDelay, attempt)
+			if c.retryLimit > 0 && time.Since(start)+delay > c.retryLimit {
+				return fmt.Errorf("request abandoned after %d attempts, the next retry would exceed the %s retry budget: %w",
+					attempt, c.retryLimit, lastErr)
+			}
+			if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) < delay {
+				return fmt.Errorf("request abandoned after %d attempts, deadline is before the next retry: %w (last error: %v)",
+					attempt, context.DeadlineExceeded, lastErr)
+			}
+
+			c.metrics.Retries.WithLabelValues(method).Inc()
+			timer := time.NewTimer(delay)
 			select {
 			case <-ctx.Done():
-				return ctx.Err()
-			case <-time.After(c.retryDelay * time.Duration(attempt)):
-				// Exponential backoff
+				timer.Stop()
+				return fmt.Errorf("request abandoned after %d attempts: %w (last error: %v)", attempt, ctx.Err(), lastErr)
+			case <-timer.C:
 			}
 		}
 
-		err := c.doRequest(ctx, method, url, body, result)
+		err := c.doRequest(ctx, method, url, body, headers, result)
 		if err == nil {
 			return nil
 		}
 
+		// The failure came from the caller's deadline or cancellation, so
+		// another attempt can't succeed
+		if ctx.Err() != nil {
+			return fmt.Errorf("request abandoned after %d attempts: %w", attempt+1, err)
+		}
+
 		lastErr = err
-		c.logger.WithFields(logrus.Fields{
-			"attempt": attempt + 1,
-			"url":     url,
-			"method":  method,
-			"error":   err.Error(),
-		}).Warn("Request failed, retrying")
-
-		// Don't retry on client errors (4xx)
-		if httpErr, ok := err.(*HTTPError); ok && httpErr.StatusCode >= 400 && httpErr.StatusCode < 500 {
+		if logsample.FromContext(ctx).Allow(retryWarning) {
+			fields := logrus.Fields{
+				"attempt": attempt + 1,
+				"url":     url,
+				"method":  method,
+				"error":   err.Error(),
+			}
+			if len(headers) > 0 {
+				fields["headers"] = logredact.Headers(headers)
+			}
+			c.logger.WithFields(fields).Warn(retryWarning)
+		}
+
+		// Only retry the statuses configured as transient
+		if httpErr, ok := err.(*HTTPError); ok && !c.retryable[httpErr.StatusCode] {
+			return err
+		}
+
+		// Nor the transport failures that aren't transient
+		if kind, ok := classifyNetworkError(err); ok && !c.retryNet[kind] {
+			return err
+		}
+
+		// An oversized body will be just as large next time
+		var tooLarge *ResponseTooLargeError
+		if errors.As(err, &tooLarge) {
 			return err
 		}
 	}
@@ -84,7 +377,7 @@ func (c *Client) doWithRetry(ctx context.Context, method, url string, body []byt
 	return fmt.Errorf("request failed after %d attempts: %w", c.maxRetries+1, lastErr)
 }
 
-func (c *Client) doRequest(ctx context.Context, method, url string, body []byte, result interface{}) error {
+func (c *Client) doRequest(ctx context.Context, method, url string, body []byte, headers map[string]string, result interface{}) error {
 	var reqBody io.Reader
 	if body != nil {
 		reqBody = bytes.NewReader(body)
@@ -98,17 +391,43 @@ func (c *Client) doRequest(ctx context.Context, method, url string, body []byte,
 	if body != nil {
 		req.Header.Set("Content-Type", "application/json")
 	}
+	// Asking for gzip explicitly turns off the transport's transparent
+	// decompression, so the body is decompressed below instead
+	req.Header.Set("Accept-Encoding", "gzip")
+	if id := RequestIDFromContext(ctx); id != "" {
+		req.Header.Set(RequestIDHeader, id)
+	}
+	for key, value := range headers {
+		req.Header.Set(key, value)
+	}
 
 	resp, err := c.httpClient.Do(req)
 	if err != nil {
+		c.metrics.Requests.WithLabelValues(method, "error").Inc()
 		return fmt.Errorf("request failed: %w", err)
 	}
 	defer resp.Body.Close()
+	c.metrics.Requests.WithLabelValues(method, strconv.Itoa(resp.StatusCode)).Inc()
+
+	var reader io.Reader = resp.Body
+	if strings.EqualFold(resp.Header.Get("Content-Encoding"), "gzip") && !resp.Uncompressed {
+		gz, err := gzip.NewReader(resp.Body)
+		if err != nil {
+			return fmt.Errorf("failed to decompress response body: %w", err)
+		}
+		defer gz.Close()
+		reader = gz
+	}
 
-	respBody, err := io.ReadAll(resp.Body)
+	// Read one byte past the limit to tell a body of exactly the limit
+	// from one that exceeds it
+	respBody, err := io.ReadAll(io.LimitReader(reader, c.maxBody+1))
 	if err != nil {
 		return fmt.Errorf("failed to read response body: %w", err)
 	}
+	if int64(len(respBody)) > c.maxBody {
+		return &ResponseTooLargeError{Limit: c.maxBody}
+	}
 
 	if resp.StatusCode >= 400 {
 		return &HTTPError{
@@ -135,3 +454,13 @@ func (e *HTTPError) Error() string {
 	return fmt.Sprintf("HTTP %d: %s", e.StatusCode, e.Message)
 }
 
+// ResponseTooLargeError is returned when a response body exceeds the
+// client's MaxResponseBytes.
+type ResponseTooLargeError struct {
+	Limit int64
+}
+
+func (e *ResponseTooLargeError) Error() string {
+	return fmt.Sprintf("response body exceeds %d bytes", e.Limit)
+}
+
@@ -1,13 +1,22 @@
 package http
 
 import (
+	"compress/gzip"
 	"context"
+	"encoding/json"
+	"errors"
+	"io"
 	"net/http"
 	"net/http/httptest"
+	"net/url"
+	"sync/atomic"
 	"testing"
 	"time"
 
+	"admira-etl/internal/logsample"
+
 	"github.com/sirupsen/logrus"
+	"github.com/sirupsen/logrus/hooks/test"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -131,3 +140,432 @@ func TestHTTPError(t *testing.T) {
 	assert.Equal(t, "HTTP 404: Not Found", err.Error())
 }
 
+
+func TestClient_Ping(t *testing.T) {
+	logger := logrus.New()
+	logger.SetLevel(logrus.ErrorLevel)
+
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		assert.Equal(t, http.MethodHead, r.Method)
+		if r.URL.Path == "/down" {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}))
+	defer server.Close()
+
+	client := NewClient(ClientConfig{
+		Timeout:    5 * time.Second,
+		MaxRetries: 3,
+		RetryDelay: 50 * time.Millisecond,
+	}, logger)
+
+	// A 4xx still proves the upstream is reachable
+	require.NoError(t, client.Ping(context.Background(), server.URL+"/up"))
+
+	err := client.Ping(context.Background(), server.URL+"/down")
+	require.Error(t, err)
+	httpErr, ok := err.(*HTTPError)
+	require.True(t, ok)
+	assert.Equal(t, http.StatusServiceUnavailable, httpErr.StatusCode)
+
+	// Pings are never retried
+	assert.Equal(t, 2, requests)
+}
+
+func TestClient_PostWithHeaders(t *testing.T) {
+	logger := logrus.New()
+	logger.SetLevel(logrus.ErrorLevel)
+
+	attempts := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts++
+		assert.Equal(t, "abc123", r.Header.Get("X-Signature"))
+		assert.Equal(t, "application/json", r.Header.Get("Content-Type"))
+		if attempts < 2 {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	client := NewClient(ClientConfig{
+		Timeout:    5 * time.Second,
+		MaxRetries: 2,
+		RetryDelay: 10 * time.Millisecond,
+	}, logger)
+
+	err := client.PostWithHeaders(context.Background(), server.URL, map[string]string{"test": "data"},
+		map[string]string{"X-Signature": "abc123"}, nil)
+
+	require.NoError(t, err)
+	assert.Equal(t, 2, attempts)
+}
+
+func TestClient_GetWithHeaders(t *testing.T) {
+	logger, hook := test.NewNullLogger()
+
+	attempts := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts++
+		assert.Equal(t, "Bearer s3cret", r.Header.Get("Authorization"))
+		assert.Equal(t, "acme", r.Header.Get("X-Tenant"))
+		if attempts < 2 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.Write([]byte(`{"status": "ok"}`))
+	}))
+	defer server.Close()
+
+	client := NewClient(ClientConfig{
+		Timeout:    5 * time.Second,
+		MaxRetries: 2,
+		RetryDelay: time.Millisecond,
+	}, logger)
+
+	var result map[string]string
+	err := client.GetWithHeaders(context.Background(), server.URL,
+		map[string]string{"Authorization": "Bearer s3cret", "X-Tenant": "acme"}, &result)
+	require.NoError(t, err)
+	assert.Equal(t, "ok", result["status"])
+	assert.Equal(t, 2, attempts)
+
+	// The retry warning names the headers but never logs their values
+	require.Len(t, hook.AllEntries(), 1)
+	assert.Equal(t, map[string]string{"Authorization": "[REDACTED]", "X-Tenant": "[REDACTED]"}, hook.LastEntry().Data["headers"])
+}
+
+func TestClient_RetryableStatusCodes(t *testing.T) {
+	logger := logrus.New()
+	logger.SetLevel(logrus.ErrorLevel)
+
+	tests := []struct {
+		name      string
+		status    int
+		retryable []int
+		attempts  int32
+	}{
+		{name: "503 is retried by default", status: http.StatusServiceUnavailable, attempts: 3},
+		{name: "429 is retried by default", status: http.StatusTooManyRequests, attempts: 3},
+		{name: "501 fails immediately", status: http.StatusNotImplemented, attempts: 1},
+		{name: "custom list retries 501", status: http.StatusNotImplemented, retryable: []int{http.StatusNotImplemented}, attempts: 3},
+		{name: "custom list drops 503", status: http.StatusServiceUnavailable, retryable: []int{http.StatusBadGateway}, attempts: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var attempts atomic.Int32
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				attempts.Add(1)
+				w.WriteHeader(tt.status)
+			}))
+			defer server.Close()
+
+			client := NewClient(ClientConfig{
+				Timeout:              5 * time.Second,
+				MaxRetries:           2,
+				RetryDelay:           time.Millisecond,
+				RetryableStatusCodes: tt.retryable,
+			}, logger)
+
+			err := client.Get(context.Background(), server.URL, nil)
+
+			var httpErr *HTTPError
+			require.ErrorAs(t, err, &httpErr)
+			assert.Equal(t, tt.status, httpErr.StatusCode)
+			assert.Equal(t, tt.attempts, attempts.Load())
+		})
+	}
+}
+
+func TestNewClient_Transport(t *testing.T) {
+	logger := logrus.New()
+
+	client := NewClient(ClientConfig{
+		Timeout:             5 * time.Second,
+		MaxIdleConns:        50,
+		MaxIdleConnsPerHost: 5,
+		IdleConnTimeout:     30 * time.Second,
+		TLSHandshakeTimeout: 3 * time.Second,
+	}, logger)
+
+	transport, ok := client.httpClient.Transport.(*http.Transport)
+	require.True(t, ok)
+	assert.Equal(t, 50, transport.MaxIdleConns)
+	assert.Equal(t, 5, transport.MaxIdleConnsPerHost)
+	assert.Equal(t, 30*time.Second, transport.IdleConnTimeout)
+	assert.Equal(t, 3*time.Second, transport.TLSHandshakeTimeout)
+	assert.Equal(t, 5*time.Second, client.httpClient.Timeout)
+
+	// Unset fields fall back to the defaults
+	transport = NewClient(ClientConfig{}, logger).httpClient.Transport.(*http.Transport)
+	assert.Equal(t, DefaultMaxIdleConns, transport.MaxIdleConns)
+	assert.Equal(t, DefaultMaxIdleConnsPerHost, transport.MaxIdleConnsPerHost)
+	assert.Equal(t, DefaultIdleConnTimeout, transport.IdleConnTimeout)
+	assert.Equal(t, DefaultTLSHandshakeTimeout, transport.TLSHandshakeTimeout)
+}
+
+func TestNewClient_Proxy(t *testing.T) {
+	logger := logrus.New()
+	req := httptest.NewRequest(http.MethodGet, "https://ads.example.com/v1/performance", nil)
+
+	proxyURL, err := url.Parse("http://proxy.internal:3128")
+	require.NoError(t, err)
+	transport := NewClient(ClientConfig{ProxyURL: proxyURL}, logger).httpClient.Transport.(*http.Transport)
+	require.NotNil(t, transport.Proxy)
+	got, err := transport.Proxy(req)
+	require.NoError(t, err)
+	assert.Equal(t, proxyURL, got)
+
+	// Without one the environment decides
+	transport = NewClient(ClientConfig{}, logger).httpClient.Transport.(*http.Transport)
+	assert.NotNil(t, transport.Proxy)
+}
+
+func TestClient_GzipResponse(t *testing.T) {
+	logger := logrus.New()
+	logger.SetLevel(logrus.ErrorLevel)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "gzip", r.Header.Get("Accept-Encoding"))
+
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Content-Encoding", "gzip")
+		gz := gzip.NewWriter(w)
+		gz.Write([]byte(`{"status": "ok"}`))
+		gz.Close()
+	}))
+	defer server.Close()
+
+	client := NewClient(ClientConfig{Timeout: 5 * time.Second}, logger)
+
+	var result map[string]string
+	require.NoError(t, client.Get(context.Background(), server.URL, &result))
+	assert.Equal(t, "ok", result["status"])
+}
+
+func TestClient_GzipRequestAboveThreshold(t *testing.T) {
+	logger := logrus.New()
+	logger.SetLevel(logrus.ErrorLevel)
+
+	var encodings []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		encodings = append(encodings, r.Header.Get("Content-Encoding"))
+
+		var body io.Reader = r.Body
+		if r.Header.Get("Content-Encoding") == "gzip" {
+			gz, err := gzip.NewReader(r.Body)
+			require.NoError(t, err)
+			body = gz
+		}
+
+		var received map[string]string
+		require.NoError(t, json.NewDecoder(body).Decode(&received))
+		assert.Equal(t, "data", received["test"])
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	client := NewClient(ClientConfig{
+		Timeout:              5 * time.Second,
+		GzipRequestThreshold: 20,
+	}, logger)
+
+	require.NoError(t, client.Post(context.Background(), server.URL, map[string]string{"test": "data"}, nil))
+	require.NoError(t, client.Post(context.Background(), server.URL, map[string]string{"test": "data", "padding": "xxxxxxxxxxxx"}, nil))
+
+	// Only the body over the threshold was compressed
+	assert.Equal(t, []string{"", "gzip"}, encodings)
+}
+
+func TestClient_MaxResponseBytes(t *testing.T) {
+	logger := logrus.New()
+	logger.SetLevel(logrus.ErrorLevel)
+
+	var attempts atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts.Add(1)
+		// Stream well past the limit in chunks
+		chunk := make([]byte, 512)
+		for i := 0; i < 8; i++ {
+			w.Write(chunk)
+		}
+	}))
+	defer server.Close()
+
+	client := NewClient(ClientConfig{
+		Timeout:          5 * time.Second,
+		MaxRetries:       2,
+		RetryDelay:       time.Millisecond,
+		MaxResponseBytes: 1024,
+	}, logger)
+
+	err := client.Get(context.Background(), server.URL, nil)
+
+	var tooLarge *ResponseTooLargeError
+	require.ErrorAs(t, err, &tooLarge)
+	assert.Equal(t, int64(1024), tooLarge.Limit)
+	assert.Equal(t, int32(1), attempts.Load(), "oversized responses are not retried")
+}
+
+func TestClient_PropagatesRequestID(t *testing.T) {
+	logger := logrus.New()
+	logger.SetLevel(logrus.ErrorLevel)
+
+	received := make(chan string, 4)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received <- r.Method + " " + r.Header.Get(RequestIDHeader)
+		w.Write([]byte(`{}`))
+	}))
+	defer server.Close()
+
+	client := NewClient(ClientConfig{Timeout: 5 * time.Second}, logger)
+	ctx := WithRequestID(context.Background(), "req-42")
+	assert.Equal(t, "req-42", RequestIDFromContext(ctx))
+
+	require.NoError(t, client.Get(ctx, server.URL, nil))
+	assert.Equal(t, "GET req-42", <-received)
+
+	require.NoError(t, client.Post(ctx, server.URL, map[string]string{"a": "b"}, nil))
+	assert.Equal(t, "POST req-42", <-received)
+
+	require.NoError(t, client.Ping(ctx, server.URL))
+	assert.Equal(t, "HEAD req-42", <-received)
+
+	// Without an ID on the context no header is sent
+	require.NoError(t, client.Get(context.Background(), server.URL, nil))
+	assert.Equal(t, "GET ", <-received)
+}
+
+func TestClient_GetWithTimeout_SlowServer(t *testing.T) {
+	logger := logrus.New()
+	logger.SetLevel(logrus.ErrorLevel)
+
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-release:
+		case <-time.After(5 * time.Second):
+		}
+		w.Write([]byte(`{}`))
+	}))
+	defer server.Close()
+	defer close(release)
+
+	// No client-wide timeout, so only the per-call deadline bounds the call
+	client := NewClient(ClientConfig{MaxRetries: 3, RetryDelay: 10 * time.Millisecond}, logger)
+
+	start := time.Now()
+	err := client.GetWithTimeout(context.Background(), server.URL, 100*time.Millisecond, nil)
+
+	require.Error(t, err)
+	assert.True(t, errors.Is(err, context.DeadlineExceeded), "unexpected error: %v", err)
+	assert.Less(t, time.Since(start), time.Second)
+}
+
+func TestClient_DeadlineStopsRetryBackoff(t *testing.T) {
+	logger := logrus.New()
+	logger.SetLevel(logrus.ErrorLevel)
+
+	var attempts int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&attempts, 1)
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	client := NewClient(ClientConfig{Timeout: 5 * time.Second, MaxRetries: 3, RetryDelay: 2 * time.Second}, logger)
+
+	// The first backoff outlasts the deadline, so there is no second attempt
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	err := client.PostWithTimeout(ctx, server.URL, map[string]string{"a": "b"}, nil, 0, nil)
+
+	require.Error(t, err)
+	assert.True(t, errors.Is(err, context.DeadlineExceeded), "unexpected error: %v", err)
+	assert.Less(t, time.Since(start), time.Second)
+	assert.Equal(t, int32(1), atomic.LoadInt32(&attempts))
+
+	// Cancellation during the backoff ends the wait as well
+	ctx, cancel = context.WithCancel(context.Background())
+	time.AfterFunc(100*time.Millisecond, cancel)
+
+	start = time.Now()
+	err = client.Get(ctx, server.URL, nil)
+
+	require.Error(t, err)
+	assert.True(t, errors.Is(err, context.Canceled), "unexpected error: %v", err)
+	assert.Less(t, time.Since(start), time.Second)
+}
+
+func TestClient_RetryBudget(t *testing.T) {
+	logger := logrus.New()
+	logger.SetLevel(logrus.ErrorLevel)
+
+	var attempts int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&attempts, 1)
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	// Backoffs of 40ms, 80ms, 160ms...: the third retry would end past 150ms
+	client := NewClient(ClientConfig{
+		Timeout:               5 * time.Second,
+		MaxRetries:            10,
+		RetryDelay:            40 * time.Millisecond,
+		MaxTotalRetryDuration: 150 * time.Millisecond,
+	}, logger)
+
+	start := time.Now()
+	err := client.Get(context.Background(), server.URL, nil)
+
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "retry budget")
+	var httpErr *HTTPError
+	require.True(t, errors.As(err, &httpErr), "unexpected error: %v", err)
+	assert.Equal(t, http.StatusServiceUnavailable, httpErr.StatusCode)
+	assert.Equal(t, int32(3), atomic.LoadInt32(&attempts))
+	assert.Less(t, time.Since(start), 150*time.Millisecond)
+}
+
+func TestClient_SamplesRetryWarnings(t *testing.T) {
+	logger, hook := test.NewNullLogger()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	client := NewClient(ClientConfig{
+		Timeout:    5 * time.Second,
+		MaxRetries: 2,
+		RetryDelay: time.Millisecond,
+	}, logger)
+
+	// Three failing requests of three attempts each share one sampler
+	sampler := logsample.New(4)
+	ctx := logsample.WithSampler(context.Background(), sampler)
+	for i := 0; i < 3; i++ {
+		require.Error(t, client.Get(ctx, server.URL, nil))
+	}
+	assert.Len(t, hook.AllEntries(), 4)
+
+	sampler.Flush(logger)
+	summary := hook.LastEntry()
+	assert.Equal(t, "Suppressed repeated warnings", summary.Message)
+	assert.Equal(t, retryWarning, summary.Data["message"])
+	assert.Equal(t, 5, summary.Data["suppressed"])
+
+	// Without a sampler every attempt is logged
+	hook.Reset()
+	require.Error(t, client.Get(context.Background(), server.URL, nil))
+	assert.Len(t, hook.AllEntries(), 3)
+}
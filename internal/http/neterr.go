@@ -0,0 +1,113 @@
+package http
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"strings"
+	"syscall"
+)
+
+// NetworkErrorKind classifies a transport failure, one that happened before
+// any HTTP response arrived, for deciding whether to retry it.
+type NetworkErrorKind string
+
+const (
+	NetworkErrorTimeout           NetworkErrorKind = "timeout"
+	NetworkErrorConnectionRefused NetworkErrorKind = "connection_refused"
+	NetworkErrorConnectionReset   NetworkErrorKind = "connection_reset"
+	NetworkErrorDNSTemporary      NetworkErrorKind = "dns_temporary"
+	NetworkErrorDNSNotFound       NetworkErrorKind = "dns_not_found"
+	NetworkErrorOther             NetworkErrorKind = "other"
+)
+
+var networkErrorKinds = []NetworkErrorKind{
+	NetworkErrorTimeout,
+	NetworkErrorConnectionRefused,
+	NetworkErrorConnectionReset,
+	NetworkErrorDNSTemporary,
+	NetworkErrorDNSNotFound,
+	NetworkErrorOther,
+}
+
+// DefaultRetryableNetworkErrors are the transport failures retried when
+// ClientConfig.RetryableNetworkErrors is unset: the transient ones. A host
+// that doesn't resolve, a bad certificate or an unsupported scheme won't
+// fix itself between attempts.
+var DefaultRetryableNetworkErrors = []NetworkErrorKind{
+	NetworkErrorTimeout,
+	NetworkErrorConnectionRefused,
+	NetworkErrorConnectionReset,
+	NetworkErrorDNSTemporary,
+}
+
+// ParseNetworkErrorKinds converts configured kind names, returning nil for
+// an empty list so the client falls back to DefaultRetryableNetworkErrors.
+func ParseNetworkErrorKinds(values []string) ([]NetworkErrorKind, error) {
+	if len(values) == 0 {
+		return nil, nil
+	}
+
+	kinds := make([]NetworkErrorKind, 0, len(values))
+	for _, value := range values {
+		kind, ok := lookupNetworkErrorKind(value)
+		if !ok {
+			names := make([]string, len(networkErrorKinds))
+			for i, known := range networkErrorKinds {
+				names[i] = string(known)
+			}
+			return nil, fmt.Errorf("unknown network error kind %q (expected one of %s)", value, strings.Join(names, ", "))
+		}
+		kinds = append(kinds, kind)
+	}
+	return kinds, nil
+}
+
+func lookupNetworkErrorKind(value string) (NetworkErrorKind, bool) {
+	value = strings.ToLower(strings.TrimSpace(value))
+	for _, kind := range networkErrorKinds {
+		if string(kind) == value {
+			return kind, true
+		}
+	}
+	return "", false
+}
+
+// classifyNetworkError reports the kind of transport failure err is, or
+// false if err isn't one (an HTTP error status, a bad body, ...).
+func classifyNetworkError(err error) (NetworkErrorKind, bool) {
+	var dnsErr *net.DNSError
+	if errors.As(err, &dnsErr) {
+		switch {
+		case dnsErr.IsNotFound:
+			return NetworkErrorDNSNotFound, true
+		case dnsErr.IsTimeout || dnsErr.IsTemporary:
+			return NetworkErrorDNSTemporary, true
+		default:
+			return NetworkErrorOther, true
+		}
+	}
+
+	switch {
+	case errors.Is(err, syscall.ECONNREFUSED):
+		return NetworkErrorConnectionRefused, true
+	case errors.Is(err, syscall.ECONNRESET), errors.Is(err, syscall.EPIPE),
+		errors.Is(err, io.EOF), errors.Is(err, io.ErrUnexpectedEOF):
+		return NetworkErrorConnectionReset, true
+	}
+
+	var netErr net.Error
+	if errors.As(err, &netErr) && netErr.Timeout() {
+		return NetworkErrorTimeout, true
+	}
+
+	var opErr *net.OpError
+	var urlErr *url.Error
+	if errors.As(err, &opErr) || errors.As(err, &urlErr) {
+		return NetworkErrorOther, true
+	}
+
+	return "", false
+}
@@ -0,0 +1,131 @@
+package http
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"sync/atomic"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// failingTransport fails every round trip with err, counting the attempts.
+type failingTransport struct {
+	err      error
+	attempts int32
+}
+
+func (f *failingTransport) RoundTrip(*http.Request) (*http.Response, error) {
+	atomic.AddInt32(&f.attempts, 1)
+	return nil, f.err
+}
+
+func TestClassifyNetworkError(t *testing.T) {
+	wrap := func(err error) error {
+		return fmt.Errorf("request failed: %w", &url.Error{Op: "Get", URL: "http://example.test", Err: err})
+	}
+
+	tests := []struct {
+		name string
+		err  error
+		kind NetworkErrorKind
+		ok   bool
+	}{
+		{"no such host", wrap(&net.DNSError{Err: "no such host", Name: "example.test", IsNotFound: true}), NetworkErrorDNSNotFound, true},
+		{"dns timeout", wrap(&net.DNSError{Err: "i/o timeout", Name: "example.test", IsTimeout: true}), NetworkErrorDNSTemporary, true},
+		{"refused", wrap(&net.OpError{Op: "dial", Err: os.NewSyscallError("connect", syscall.ECONNREFUSED)}), NetworkErrorConnectionRefused, true},
+		{"reset", wrap(&net.OpError{Op: "read", Err: os.NewSyscallError("read", syscall.ECONNRESET)}), NetworkErrorConnectionReset, true},
+		{"eof", wrap(io.EOF), NetworkErrorConnectionReset, true},
+		{"timeout", wrap(&net.OpError{Op: "dial", Err: os.ErrDeadlineExceeded}), NetworkErrorTimeout, true},
+		{"other transport", wrap(errors.New("unsupported protocol scheme")), NetworkErrorOther, true},
+		{"http status", &HTTPError{StatusCode: 503}, "", false},
+		{"bad body", errors.New("failed to unmarshal response"), "", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			kind, ok := classifyNetworkError(tt.err)
+			assert.Equal(t, tt.ok, ok)
+			assert.Equal(t, tt.kind, kind)
+		})
+	}
+}
+
+func TestParseNetworkErrorKinds(t *testing.T) {
+	kinds, err := ParseNetworkErrorKinds(nil)
+	require.NoError(t, err)
+	assert.Nil(t, kinds)
+
+	kinds, err = ParseNetworkErrorKinds([]string{"timeout", " DNS_NOT_FOUND "})
+	require.NoError(t, err)
+	assert.Equal(t, []NetworkErrorKind{NetworkErrorTimeout, NetworkErrorDNSNotFound}, kinds)
+
+	_, err = ParseNetworkErrorKinds([]string{"timeout", "flaky"})
+	assert.ErrorContains(t, err, `unknown network error kind "flaky"`)
+}
+
+func TestClient_RetriesTimeouts(t *testing.T) {
+	logger := logrus.New()
+	logger.SetLevel(logrus.ErrorLevel)
+
+	var attempts int32
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&attempts, 1)
+		select {
+		case <-release:
+		case <-time.After(time.Second):
+		}
+	}))
+	defer server.Close()
+	defer close(release)
+
+	client := NewClient(ClientConfig{Timeout: 50 * time.Millisecond, MaxRetries: 2, RetryDelay: time.Millisecond}, logger)
+
+	err := client.Get(context.Background(), server.URL, nil)
+	require.Error(t, err)
+	kind, ok := classifyNetworkError(err)
+	require.True(t, ok)
+	assert.Equal(t, NetworkErrorTimeout, kind)
+	assert.Equal(t, int32(3), atomic.LoadInt32(&attempts))
+}
+
+func TestClient_UnknownHostIsNotRetried(t *testing.T) {
+	logger := logrus.New()
+	logger.SetLevel(logrus.ErrorLevel)
+
+	transport := &failingTransport{err: &net.OpError{Op: "dial", Net: "tcp",
+		Err: &net.DNSError{Err: "no such host", Name: "ads.invalid", IsNotFound: true}}}
+
+	client := NewClient(ClientConfig{Timeout: time.Second, MaxRetries: 3, RetryDelay: time.Millisecond}, logger)
+	client.httpClient.Transport = transport
+
+	err := client.Get(context.Background(), "http://ads.invalid/v1", nil)
+	var dnsErr *net.DNSError
+	require.ErrorAs(t, err, &dnsErr)
+	assert.Equal(t, int32(1), atomic.LoadInt32(&transport.attempts))
+
+	// Configuring the kind as retryable brings the retries back
+	transport = &failingTransport{err: transport.err}
+	client = NewClient(ClientConfig{
+		Timeout:                time.Second,
+		MaxRetries:             3,
+		RetryDelay:             time.Millisecond,
+		RetryableNetworkErrors: []NetworkErrorKind{NetworkErrorDNSNotFound},
+	}, logger)
+	client.httpClient.Transport = transport
+
+	require.Error(t, client.Get(context.Background(), "http://ads.invalid/v1", nil))
+	assert.Equal(t, int32(4), atomic.LoadInt32(&transport.attempts))
+}
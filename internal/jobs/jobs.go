@@ -0,0 +1,141 @@
+package jobs
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"sync"
+	"time"
+)
+
+type Status string
+
+const (
+	StatusPending   Status = "pending"
+	StatusRunning   Status = "running"
+	StatusSucceeded Status = "succeeded"
+	StatusFailed    Status = "failed"
+)
+
+// FinishedJobTTL is how long a finished job can still be looked up before
+// the registry forgets it.
+const FinishedJobTTL = 24 * time.Hour
+
+// Job records the state of a piece of background work.
+type Job struct {
+	ID         string     `json:"id"`
+	Type       string     `json:"type"`
+	Status     Status     `json:"status"`
+	Error      string     `json:"error,omitempty"`
+	CreatedAt  time.Time  `json:"created_at"`
+	StartedAt  *time.Time `json:"started_at,omitempty"`
+	FinishedAt *time.Time `json:"finished_at,omitempty"`
+
+	// Result is what the work produced, if it reports anything, such as an
+	// export's delivery counts. It is set once the job finishes.
+	Result interface{} `json:"result,omitempty"`
+}
+
+// Registry is an in-memory, concurrency-safe store of jobs. Callers only ever
+// receive copies, so a Job value never changes underneath them. Jobs
+// finished more than FinishedJobTTL ago are dropped as new ones are created,
+// so a long-running process doesn't accumulate them forever.
+type Registry struct {
+	mu   sync.RWMutex
+	jobs map[string]*Job
+	now  func() time.Time
+}
+
+func NewRegistry() *Registry {
+	return &Registry{
+		jobs: make(map[string]*Job),
+		now:  time.Now,
+	}
+}
+
+// Create registers a new pending job of the given type.
+func (r *Registry) Create(jobType string) Job {
+	job := &Job{
+		ID:        newID(),
+		Type:      jobType,
+		Status:    StatusPending,
+		CreatedAt: r.now(),
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.dropExpiredLocked(job.CreatedAt)
+	r.jobs[job.ID] = job
+	return *job
+}
+
+// dropExpiredLocked forgets the jobs finished more than FinishedJobTTL
+// before now. The caller must hold r.mu for writing.
+func (r *Registry) dropExpiredLocked(now time.Time) {
+	cutoff := now.Add(-FinishedJobTTL)
+	for id, job := range r.jobs {
+		if job.FinishedAt != nil && job.FinishedAt.Before(cutoff) {
+			delete(r.jobs, id)
+		}
+	}
+}
+
+func (r *Registry) Get(id string) (Job, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	job, exists := r.jobs[id]
+	if !exists {
+		return Job{}, false
+	}
+	return *job, true
+}
+
+// Start marks a job as running.
+func (r *Registry) Start(id string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if job, exists := r.jobs[id]; exists {
+		now := r.now()
+		job.Status = StatusRunning
+		job.StartedAt = &now
+	}
+}
+
+// Finish marks a job as succeeded, or failed with err's message when err is
+// not nil.
+func (r *Registry) Finish(id string, err error) {
+	r.FinishWithResult(id, nil, err)
+}
+
+// FinishWithResult is Finish that also records the job's result, kept even
+// when the job failed. result must not be modified afterwards since copies
+// of the job share it.
+func (r *Registry) FinishWithResult(id string, result interface{}, err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	job, exists := r.jobs[id]
+	if !exists {
+		return
+	}
+
+	now := r.now()
+	job.FinishedAt = &now
+	job.Result = result
+	if err != nil {
+		job.Status = StatusFailed
+		job.Error = err.Error()
+		return
+	}
+	job.Status = StatusSucceeded
+}
+
+func newID() string {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		// crypto/rand only fails if the OS entropy source is broken
+		panic(err)
+	}
+	return hex.EncodeToString(b)
+}
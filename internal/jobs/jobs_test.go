@@ -0,0 +1,108 @@
+package jobs
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRegistry_Lifecycle(t *testing.T) {
+	registry := NewRegistry()
+
+	job := registry.Create("ingestion")
+	assert.Len(t, job.ID, 32)
+	assert.Equal(t, "ingestion", job.Type)
+	assert.Equal(t, StatusPending, job.Status)
+	assert.Nil(t, job.StartedAt)
+
+	registry.Start(job.ID)
+	running, ok := registry.Get(job.ID)
+	require.True(t, ok)
+	assert.Equal(t, StatusRunning, running.Status)
+	require.NotNil(t, running.StartedAt)
+	assert.Nil(t, running.FinishedAt)
+
+	registry.Finish(job.ID, nil)
+	done, ok := registry.Get(job.ID)
+	require.True(t, ok)
+	assert.Equal(t, StatusSucceeded, done.Status)
+	assert.Empty(t, done.Error)
+	require.NotNil(t, done.FinishedAt)
+
+	// Earlier copies are unaffected by later transitions
+	assert.Equal(t, StatusRunning, running.Status)
+}
+
+func TestRegistry_Failure(t *testing.T) {
+	registry := NewRegistry()
+
+	job := registry.Create("ingestion")
+	registry.Start(job.ID)
+	registry.Finish(job.ID, errors.New("upstream unavailable"))
+
+	failed, ok := registry.Get(job.ID)
+	require.True(t, ok)
+	assert.Equal(t, StatusFailed, failed.Status)
+	assert.Equal(t, "upstream unavailable", failed.Error)
+}
+
+func TestRegistry_UnknownJob(t *testing.T) {
+	registry := NewRegistry()
+
+	_, ok := registry.Get("missing")
+	assert.False(t, ok)
+
+	// Transitions on unknown IDs are no-ops
+	registry.Start("missing")
+	registry.Finish("missing", nil)
+
+	assert.NotEqual(t, registry.Create("a").ID, registry.Create("b").ID)
+}
+
+func TestRegistry_FinishWithResult(t *testing.T) {
+	registry := NewRegistry()
+
+	job := registry.Create("export")
+	registry.Start(job.ID)
+	registry.FinishWithResult(job.ID, map[string]int{"exported": 3, "failed": 1}, errors.New("1 delivery failed"))
+
+	// The result is kept alongside the failure
+	failed, ok := registry.Get(job.ID)
+	require.True(t, ok)
+	assert.Equal(t, StatusFailed, failed.Status)
+	assert.Equal(t, map[string]int{"exported": 3, "failed": 1}, failed.Result)
+
+	// Finish records no result
+	other := registry.Create("ingestion")
+	registry.Finish(other.ID, nil)
+	done, _ := registry.Get(other.ID)
+	assert.Nil(t, done.Result)
+}
+
+func TestRegistry_DropsExpiredJobs(t *testing.T) {
+	now := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	registry := NewRegistry()
+	registry.now = func() time.Time { return now }
+
+	finished := registry.Create("ingestion")
+	registry.Finish(finished.ID, nil)
+	running := registry.Create("export")
+	registry.Start(running.ID)
+
+	// Within the TTL a finished job can still be looked up
+	now = now.Add(FinishedJobTTL)
+	registry.Create("ingestion")
+	_, ok := registry.Get(finished.ID)
+	assert.True(t, ok)
+
+	// Past it the next job created drops it, but never an unfinished one
+	now = now.Add(time.Second)
+	registry.Create("ingestion")
+	_, ok = registry.Get(finished.ID)
+	assert.False(t, ok)
+	_, ok = registry.Get(running.ID)
+	assert.True(t, ok)
+}
@@ -0,0 +1,115 @@
+// Package logredact masks sensitive fields, such as revenue figures and
+// signatures, in log entries before they are written, so they don't leak
+// into shared log aggregators.
+package logredact
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// Mask replaces the value of every redacted field.
+const Mask = "[REDACTED]"
+
+// DefaultFields are the field names redacted when none are configured.
+var DefaultFields = []string{"revenue", "weighted_revenue", "total_revenue", "amount", "signature"}
+
+// Hook is a logrus hook that masks the configured fields, both top-level
+// log fields and fields nested in logged values such as records.
+type Hook struct {
+	fields map[string]bool
+}
+
+// New returns a Hook masking the named fields, matched case-insensitively.
+// With no fields it masks DefaultFields.
+func New(fields []string) *Hook {
+	if len(fields) == 0 {
+		fields = DefaultFields
+	}
+	hook := &Hook{fields: make(map[string]bool, len(fields))}
+	for _, field := range fields {
+		hook.fields[strings.ToLower(strings.TrimSpace(field))] = true
+	}
+	return hook
+}
+
+// Levels applies the hook at every level.
+func (h *Hook) Levels() []logrus.Level {
+	return logrus.AllLevels
+}
+
+// Fire masks the entry's sensitive fields. logrus hands hooks a copy of the
+// fields, so the caller's logrus.Fields are left untouched.
+func (h *Hook) Fire(entry *logrus.Entry) error {
+	for key, value := range entry.Data {
+		if h.fields[strings.ToLower(key)] {
+			entry.Data[key] = Mask
+			continue
+		}
+		entry.Data[key] = h.redactValue(value)
+	}
+	return nil
+}
+
+// redactValue masks sensitive keys nested in a structured value (a record,
+// a map, a slice of them) by converting it to its JSON form. Scalars, errors
+// and times are returned unchanged, as is anything that won't marshal.
+func (h *Hook) redactValue(value interface{}) interface{} {
+	switch value.(type) {
+	case nil, error, time.Time:
+		return value
+	}
+
+	kind := reflect.TypeOf(value).Kind()
+	if kind == reflect.Pointer {
+		kind = reflect.TypeOf(value).Elem().Kind()
+	}
+	switch kind {
+	case reflect.Struct, reflect.Map, reflect.Slice, reflect.Array:
+	default:
+		return value
+	}
+
+	raw, err := json.Marshal(value)
+	if err != nil {
+		return value
+	}
+	var decoded interface{}
+	if err := json.Unmarshal(raw, &decoded); err != nil {
+		return value
+	}
+	return h.mask(decoded)
+}
+
+// mask replaces the sensitive keys of decoded JSON, recursively.
+func (h *Hook) mask(value interface{}) interface{} {
+	switch node := value.(type) {
+	case map[string]interface{}:
+		for key, child := range node {
+			if h.fields[strings.ToLower(key)] {
+				node[key] = Mask
+				continue
+			}
+			node[key] = h.mask(child)
+		}
+	case []interface{}:
+		for i, child := range node {
+			node[i] = h.mask(child)
+		}
+	}
+	return value
+}
+
+// Headers returns request headers fit for logging: the names are kept and
+// every value is replaced by Mask, since they often carry credentials.
+func Headers(headers map[string]string) map[string]string {
+	masked := make(map[string]string, len(headers))
+	for name := range headers {
+		masked[name] = Mask
+	}
+	return masked
+}
@@ -0,0 +1,84 @@
+package logredact
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"admira-etl/internal/models"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newCapturingLogger(hook *Hook) (*logrus.Logger, *bytes.Buffer) {
+	var out bytes.Buffer
+	logger := logrus.New()
+	logger.SetOutput(&out)
+	logger.SetFormatter(&logrus.JSONFormatter{})
+	logger.SetLevel(logrus.DebugLevel)
+	if hook != nil {
+		logger.AddHook(hook)
+	}
+	return logger, &out
+}
+
+func TestHook_MasksSensitiveFields(t *testing.T) {
+	record := models.TransformedData{Date: "2025-01-01", Channel: "google_ads", CampaignID: "C-1001", Clicks: 100, Revenue: 98765.43}
+	fields := logrus.Fields{
+		"record":        record,
+		"records":       []models.TransformedData{record},
+		"signature":     "f00dfeedcafe",
+		"total_revenue": 98765.43,
+		"sink":          "https://sink.example.com",
+	}
+
+	t.Run("redaction off", func(t *testing.T) {
+		logger, out := newCapturingLogger(nil)
+		logger.WithFields(fields).WithError(errors.New("boom")).Error("Failed to export record")
+		assert.Contains(t, out.String(), "98765.43")
+		assert.Contains(t, out.String(), "f00dfeedcafe")
+	})
+
+	t.Run("redaction on", func(t *testing.T) {
+		logger, out := newCapturingLogger(New(nil))
+		logger.WithFields(fields).WithError(errors.New("boom")).Error("Failed to export record")
+		logger.WithField("signature", "f00dfeedcafe").Debug("Signed record")
+
+		assert.NotContains(t, out.String(), "98765.43")
+		assert.NotContains(t, out.String(), "f00dfeedcafe")
+
+		lines := bytes.Split(bytes.TrimSpace(out.Bytes()), []byte("\n"))
+		require.Len(t, lines, 2)
+		var entry map[string]interface{}
+		require.NoError(t, json.Unmarshal(lines[0], &entry))
+
+		assert.Equal(t, Mask, entry["signature"])
+		assert.Equal(t, Mask, entry["total_revenue"])
+		logged := entry["record"].(map[string]interface{})
+		assert.Equal(t, Mask, logged["revenue"])
+		assert.Equal(t, Mask, logged["weighted_revenue"])
+		// Everything else is kept
+		assert.Equal(t, "C-1001", logged["campaign_id"])
+		assert.Equal(t, 100.0, logged["clicks"])
+		assert.Equal(t, Mask, entry["records"].([]interface{})[0].(map[string]interface{})["revenue"])
+		assert.Equal(t, "https://sink.example.com", entry["sink"])
+		assert.Equal(t, "boom", entry["error"])
+	})
+
+	t.Run("caller's fields are untouched", func(t *testing.T) {
+		logger, _ := newCapturingLogger(New(nil))
+		logger.WithFields(fields).Error("Failed to export record")
+		assert.Equal(t, "f00dfeedcafe", fields["signature"])
+		assert.Equal(t, record, fields["record"])
+	})
+
+	t.Run("custom fields", func(t *testing.T) {
+		logger, out := newCapturingLogger(New([]string{"Sink"}))
+		logger.WithFields(fields).Error("Failed to export record")
+		assert.NotContains(t, out.String(), "sink.example.com")
+		assert.Contains(t, out.String(), "98765.43")
+	})
+}
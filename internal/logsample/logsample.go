@@ -0,0 +1,86 @@
+// Package logsample caps how often a repeated warning is logged during one
+// run (an ingestion, an export), so a bad batch or a flapping sink doesn't
+// flood the logs. Occurrences over the cap are counted and reported once
+// when the run ends.
+package logsample
+
+import (
+	"context"
+	"sort"
+	"sync"
+
+	"github.com/sirupsen/logrus"
+)
+
+// Sampler counts occurrences of each warning message. A nil *Sampler, or
+// one with a limit of zero, lets everything through.
+type Sampler struct {
+	limit int
+
+	mu   sync.Mutex
+	seen map[string]int
+}
+
+// New returns a Sampler that lets the first limit occurrences of each
+// message through. A limit of zero or less disables sampling.
+func New(limit int) *Sampler {
+	return &Sampler{limit: limit, seen: make(map[string]int)}
+}
+
+// Allow records an occurrence of message and reports whether it should be
+// logged.
+func (s *Sampler) Allow(message string) bool {
+	if s == nil || s.limit <= 0 {
+		return true
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.seen[message]++
+	return s.seen[message] <= s.limit
+}
+
+// Flush logs one warning for every message that went over the limit,
+// carrying the number of occurrences that were suppressed, and starts
+// counting afresh.
+func (s *Sampler) Flush(logger *logrus.Logger) {
+	if s == nil || s.limit <= 0 {
+		return
+	}
+
+	s.mu.Lock()
+	seen := s.seen
+	s.seen = make(map[string]int)
+	s.mu.Unlock()
+
+	messages := make([]string, 0, len(seen))
+	for message, count := range seen {
+		if count > s.limit {
+			messages = append(messages, message)
+		}
+	}
+	sort.Strings(messages)
+
+	for _, message := range messages {
+		logger.WithFields(logrus.Fields{
+			"message":    message,
+			"logged":     s.limit,
+			"suppressed": seen[message] - s.limit,
+		}).Warn("Suppressed repeated warnings")
+	}
+}
+
+type samplerKey struct{}
+
+// WithSampler returns a copy of ctx carrying s, which code further down the
+// call chain consults before logging a repeated warning.
+func WithSampler(ctx context.Context, s *Sampler) context.Context {
+	return context.WithValue(ctx, samplerKey{}, s)
+}
+
+// FromContext returns the Sampler stored by WithSampler, or nil (which
+// samples nothing) when there is none.
+func FromContext(ctx context.Context) *Sampler {
+	s, _ := ctx.Value(samplerKey{}).(*Sampler)
+	return s
+}
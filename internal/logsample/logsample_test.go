@@ -0,0 +1,60 @@
+package logsample
+
+import (
+	"context"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+	"github.com/sirupsen/logrus/hooks/test"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSampler(t *testing.T) {
+	sampler := New(2)
+
+	allowed := 0
+	for i := 0; i < 5; i++ {
+		if sampler.Allow("noisy") {
+			allowed++
+		}
+	}
+	assert.Equal(t, 2, allowed)
+	assert.True(t, sampler.Allow("quiet"))
+
+	logger, hook := test.NewNullLogger()
+	sampler.Flush(logger)
+
+	// Only the message that went over the limit is summarised
+	require.Len(t, hook.AllEntries(), 1)
+	entry := hook.LastEntry()
+	assert.Equal(t, logrus.WarnLevel, entry.Level)
+	assert.Equal(t, "Suppressed repeated warnings", entry.Message)
+	assert.Equal(t, "noisy", entry.Data["message"])
+	assert.Equal(t, 3, entry.Data["suppressed"])
+
+	// Flushing resets the counts
+	assert.True(t, sampler.Allow("noisy"))
+	hook.Reset()
+	sampler.Flush(logger)
+	assert.Empty(t, hook.AllEntries())
+}
+
+func TestSampler_Disabled(t *testing.T) {
+	logger, hook := test.NewNullLogger()
+
+	for _, sampler := range []*Sampler{nil, New(0)} {
+		for i := 0; i < 100; i++ {
+			assert.True(t, sampler.Allow("noisy"))
+		}
+		sampler.Flush(logger)
+	}
+	assert.Empty(t, hook.AllEntries())
+}
+
+func TestFromContext(t *testing.T) {
+	assert.Nil(t, FromContext(context.Background()))
+
+	sampler := New(1)
+	assert.Same(t, sampler, FromContext(WithSampler(context.Background(), sampler)))
+}
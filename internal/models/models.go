@@ -1,10 +1,19 @@
 package models
 
-import "time"
+import (
+	"encoding/json"
+	"math"
+	"time"
+)
 
 // External API Response Structures
+// Paginated upstreams point at the following page with either Next, a URL
+// that may be relative to the current one, or NextCursor, passed back as the
+// cursor query parameter. Both are empty on the last page.
 type ExternalResponse struct {
-	External ExternalData `json:"external"`
+	External   ExternalData `json:"external"`
+	Next       string       `json:"next,omitempty"`
+	NextCursor string       `json:"next_cursor,omitempty"`
 }
 
 type ExternalData struct {
@@ -15,6 +24,41 @@ type ExternalData struct {
 // Ads Data Models
 type AdsData struct {
 	Performance []AdsPerformance `json:"performance"`
+
+	// Malformed lists the rows that couldn't be decoded, such as clicks sent
+	// as a string; they are left out of Performance.
+	Malformed []MalformedRecord `json:"-"`
+}
+
+// MalformedRecord is a record that couldn't be decoded, kept as received.
+type MalformedRecord struct {
+	Raw json.RawMessage
+	Err error
+}
+
+// UnmarshalJSON decodes each row on its own so one malformed row doesn't
+// fail the whole response.
+func (a *AdsData) UnmarshalJSON(data []byte) error {
+	var raw struct {
+		Performance []json.RawMessage `json:"performance"`
+	}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+
+	a.Performance, a.Malformed = nil, nil
+	if raw.Performance != nil {
+		a.Performance = make([]AdsPerformance, 0, len(raw.Performance))
+	}
+	for _, row := range raw.Performance {
+		var ad AdsPerformance
+		if err := json.Unmarshal(row, &ad); err != nil {
+			a.Malformed = append(a.Malformed, MalformedRecord{Raw: row, Err: err})
+			continue
+		}
+		a.Performance = append(a.Performance, ad)
+	}
+	return nil
 }
 
 type AdsPerformance struct {
@@ -32,6 +76,35 @@ type AdsPerformance struct {
 // CRM Data Models
 type CRMData struct {
 	Opportunities []Opportunity `json:"opportunities"`
+
+	// Malformed lists the opportunities that couldn't be decoded; they are
+	// left out of Opportunities.
+	Malformed []MalformedRecord `json:"-"`
+}
+
+// UnmarshalJSON decodes each opportunity on its own so one malformed record
+// doesn't fail the whole response.
+func (c *CRMData) UnmarshalJSON(data []byte) error {
+	var raw struct {
+		Opportunities []json.RawMessage `json:"opportunities"`
+	}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+
+	c.Opportunities, c.Malformed = nil, nil
+	if raw.Opportunities != nil {
+		c.Opportunities = make([]Opportunity, 0, len(raw.Opportunities))
+	}
+	for _, record := range raw.Opportunities {
+		var opp Opportunity
+		if err := json.Unmarshal(record, &opp); err != nil {
+			c.Malformed = append(c.Malformed, MalformedRecord{Raw: record, Err: err})
+			continue
+		}
+		c.Opportunities = append(c.Opportunities, opp)
+	}
+	return nil
 }
 
 type Opportunity struct {
@@ -39,6 +112,7 @@ type Opportunity struct {
 	ContactEmail  string    `json:"contact_email"`
 	Stage         string    `json:"stage"`
 	Amount        float64   `json:"amount"`
+	Currency      string    `json:"currency,omitempty"`
 	CreatedAt     time.Time `json:"created_at"`
 	UTMCampaign   string    `json:"utm_campaign"`
 	UTMSource     string    `json:"utm_source"`
@@ -50,6 +124,9 @@ type TransformedData struct {
 	Date         string  `json:"date"`
 	Channel      string  `json:"channel"`
 	CampaignID   string  `json:"campaign_id"`
+	UTMCampaign  string  `json:"utm_campaign,omitempty"`
+	UTMSource    string  `json:"utm_source,omitempty"`
+	UTMMedium    string  `json:"utm_medium,omitempty"`
 	Clicks       int     `json:"clicks"`
 	Impressions  int     `json:"impressions"`
 	Cost         float64 `json:"cost"`
@@ -57,42 +134,337 @@ type TransformedData struct {
 	Opportunities int    `json:"opportunities"`
 	ClosedWon    int     `json:"closed_won"`
 	Revenue      float64 `json:"revenue"`
+	// WeightedRevenue is pipeline revenue weighted by stage; with the
+	// default weights it equals Revenue.
+	WeightedRevenue float64 `json:"weighted_revenue"`
 	CPC          float64 `json:"cpc"`
 	CPA          float64 `json:"cpa"`
 	CVRLeadToOpp float64 `json:"cvr_lead_to_opp"`
 	CVROppToWon  float64 `json:"cvr_opp_to_won"`
 	ROAS         float64 `json:"roas"`
+	CTR          float64 `json:"ctr"`
+	CPM          float64 `json:"cpm"`
+	MatchType    string  `json:"match_type,omitempty"`
+	// Anomalies lists the derived metrics that exceeded their configured
+	// sanity bound when the row was transformed.
+	Anomalies []string `json:"anomalies,omitempty"`
+}
+
+// MarshalJSON rounds the derived ratios so output is free of float noise:
+// CPC, CPA, CTR, CPM and the conversion rates to 4 decimals, ROAS to 3. Raw
+// values stay untouched in memory.
+func (t TransformedData) MarshalJSON() ([]byte, error) {
+	// The alias has the same fields but not this method, avoiding recursion
+	type plain TransformedData
+	rounded := plain(t)
+	rounded.CPC = RoundTo(t.CPC, 4)
+	rounded.CPA = RoundTo(t.CPA, 4)
+	rounded.CVRLeadToOpp = RoundTo(t.CVRLeadToOpp, 4)
+	rounded.CVROppToWon = RoundTo(t.CVROppToWon, 4)
+	rounded.ROAS = RoundTo(t.ROAS, 3)
+	rounded.CTR = RoundTo(t.CTR, 4)
+	rounded.CPM = RoundTo(t.CPM, 4)
+	return json.Marshal(rounded)
+}
+
+// Ratios groups the derived metrics of a row, as nested under "ratios" in
+// version 2 of the metrics responses.
+type Ratios struct {
+	CPC          float64 `json:"cpc"`
+	CPA          float64 `json:"cpa"`
+	CVRLeadToOpp float64 `json:"cvr_lead_to_opp"`
+	CVROppToWon  float64 `json:"cvr_opp_to_won"`
+	ROAS         float64 `json:"roas"`
+	CTR          float64 `json:"ctr"`
+	CPM          float64 `json:"cpm"`
+}
+
+// TransformedDataV2 is the version 2 shape of a transformed row: the
+// counters stay at the top level and the derived ratios move into Ratios.
+type TransformedDataV2 struct {
+	Date            string  `json:"date"`
+	Channel         string  `json:"channel"`
+	CampaignID      string  `json:"campaign_id"`
+	UTMCampaign     string  `json:"utm_campaign,omitempty"`
+	UTMSource       string  `json:"utm_source,omitempty"`
+	UTMMedium       string  `json:"utm_medium,omitempty"`
+	Clicks          int     `json:"clicks"`
+	Impressions     int     `json:"impressions"`
+	Cost            float64 `json:"cost"`
+	Leads           int     `json:"leads"`
+	Opportunities   int     `json:"opportunities"`
+	ClosedWon       int     `json:"closed_won"`
+	Revenue         float64 `json:"revenue"`
+	WeightedRevenue float64 `json:"weighted_revenue"`
+	Ratios          Ratios  `json:"ratios"`
+	MatchType       string  `json:"match_type,omitempty"`
+	Anomalies       []string `json:"anomalies,omitempty"`
+}
+
+// V2 converts the row to the version 2 shape, rounding the ratios the same
+// way MarshalJSON does.
+func (t TransformedData) V2() TransformedDataV2 {
+	return TransformedDataV2{
+		Date:            t.Date,
+		Channel:         t.Channel,
+		CampaignID:      t.CampaignID,
+		UTMCampaign:     t.UTMCampaign,
+		UTMSource:       t.UTMSource,
+		UTMMedium:       t.UTMMedium,
+		Clicks:          t.Clicks,
+		Impressions:     t.Impressions,
+		Cost:            t.Cost,
+		Leads:           t.Leads,
+		Opportunities:   t.Opportunities,
+		ClosedWon:       t.ClosedWon,
+		Revenue:         t.Revenue,
+		WeightedRevenue: t.WeightedRevenue,
+		Ratios: Ratios{
+			CPC:          RoundTo(t.CPC, 4),
+			CPA:          RoundTo(t.CPA, 4),
+			CVRLeadToOpp: RoundTo(t.CVRLeadToOpp, 4),
+			CVROppToWon:  RoundTo(t.CVROppToWon, 4),
+			ROAS:         RoundTo(t.ROAS, 3),
+			CTR:          RoundTo(t.CTR, 4),
+			CPM:          RoundTo(t.CPM, 4),
+		},
+		MatchType: t.MatchType,
+		Anomalies: t.Anomalies,
+	}
+}
+
+// RoundTo rounds half away from zero to the given number of decimals.
+func RoundTo(value float64, decimals int) float64 {
+	scale := math.Pow10(decimals)
+	return math.Round(value*scale) / scale
 }
 
 // API Request/Response Models
 type IngestRequest struct {
-	Since string `form:"since" binding:"omitempty,datetime=2006-01-02"`
+	Since string `form:"since"`
+	Until string `form:"until"`
+	Async bool   `form:"async"`
+	Full  bool   `form:"full"`
+}
+
+type ReingestDateRequest struct {
+	Date string `form:"date" binding:"required"`
+}
+
+type IngestDataRequest struct {
+	Since string `form:"since"`
 }
 
 type MetricsChannelRequest struct {
-	From    string `form:"from" binding:"required,datetime=2006-01-02"`
-	To      string `form:"to" binding:"required,datetime=2006-01-02"`
-	Channel string `form:"channel" binding:"required"`
-	Limit   int    `form:"limit" binding:"min=1,max=1000"`
-	Offset  int    `form:"offset" binding:"min=0"`
+	From        string  `form:"from" binding:"omitempty,datetime=2006-01-02"`
+	To          string  `form:"to" binding:"omitempty,datetime=2006-01-02"`
+	Channel     string  `form:"channel" binding:"required"`
+	Granularity string  `form:"granularity" binding:"omitempty,oneof=day week month"`
+	SortBy      string  `form:"sort_by" binding:"omitempty,oneof=date clicks impressions cost leads opportunities closed_won revenue cpc cpa roas"`
+	Order       string  `form:"order" binding:"omitempty,oneof=asc desc"`
+	Limit       int     `form:"limit"`
+	Offset      int     `form:"offset" binding:"min=0"`
+	Cursor      string  `form:"cursor"`
+	Consolidate bool    `form:"consolidate"`
+	MinCost     float64 `form:"min_cost" binding:"min=0"`
+	MinRevenue  float64 `form:"min_revenue" binding:"min=0"`
 }
 
 type MetricsFunnelRequest struct {
-	From        string `form:"from" binding:"required,datetime=2006-01-02"`
-	To          string `form:"to" binding:"required,datetime=2006-01-02"`
+	From        string `form:"from" binding:"omitempty,datetime=2006-01-02"`
+	To          string `form:"to" binding:"omitempty,datetime=2006-01-02"`
 	UTMCampaign string `form:"utm_campaign" binding:"required"`
-	Limit       int    `form:"limit" binding:"min=1,max=1000"`
+	Limit       int    `form:"limit"`
 	Offset      int    `form:"offset" binding:"min=0"`
+	Cursor      string `form:"cursor"`
+}
+
+type MetricsCampaignRequest struct {
+	From        string `form:"from" binding:"omitempty,datetime=2006-01-02"`
+	To          string `form:"to" binding:"omitempty,datetime=2006-01-02"`
+	CampaignID  string `form:"campaign_id" binding:"required"`
+	Consolidate bool   `form:"consolidate"`
+	Limit       int    `form:"limit"`
+	Offset      int    `form:"offset" binding:"min=0"`
+}
+
+type MetricsSourceRequest struct {
+	From      string `form:"from" binding:"omitempty,datetime=2006-01-02"`
+	To        string `form:"to" binding:"omitempty,datetime=2006-01-02"`
+	UTMSource string `form:"utm_source"`
+	UTMMedium string `form:"utm_medium"`
+	Limit     int    `form:"limit"`
+	Offset    int    `form:"offset" binding:"min=0"`
+}
+
+type MetricsSummaryRequest struct {
+	From    string `form:"from" binding:"omitempty,datetime=2006-01-02"`
+	To      string `form:"to" binding:"omitempty,datetime=2006-01-02"`
+	Channel string `form:"channel"`
+}
+
+type TimeSeriesRequest struct {
+	From    string `form:"from" binding:"omitempty,datetime=2006-01-02"`
+	To      string `form:"to" binding:"omitempty,datetime=2006-01-02"`
+	Channel string `form:"channel"`
+	Metric  string `form:"metric" binding:"required,oneof=clicks impressions cost leads opportunities closed_won revenue cpc cpa roas"`
+	Fill    string `form:"fill" binding:"omitempty,oneof=zero null"`
+}
+
+type DimensionsRequest struct {
+	From string `form:"from" binding:"omitempty,datetime=2006-01-02"`
+	To   string `form:"to" binding:"omitempty,datetime=2006-01-02"`
+}
+
+type MetricsCompareRequest struct {
+	From    string `form:"from" binding:"omitempty,datetime=2006-01-02"`
+	To      string `form:"to" binding:"omitempty,datetime=2006-01-02"`
+	Channel string `form:"channel"`
+}
+
+type TopCampaignsRequest struct {
+	From   string `form:"from" binding:"omitempty,datetime=2006-01-02"`
+	To     string `form:"to" binding:"omitempty,datetime=2006-01-02"`
+	Metric string `form:"metric" binding:"omitempty,oneof=revenue roas closed_won cost"`
+	N      int    `form:"n" binding:"min=0"`
+}
+
+type DeleteDataRequest struct {
+	From    string `form:"from" binding:"required,datetime=2006-01-02"`
+	To      string `form:"to" binding:"required,datetime=2006-01-02"`
+	Channel string `form:"channel"`
+}
+
+// Dimensions lists the distinct channels and campaign IDs stored over a date
+// range, for building filters.
+type Dimensions struct {
+	From        string   `json:"from"`
+	To          string   `json:"to"`
+	Channels    []string `json:"channels"`
+	CampaignIDs []string `json:"campaign_ids"`
+}
+
+// TimeSeries is one metric over a date range as parallel arrays, one point
+// per date, for charting. Values holds null for dates without data when
+// Fill is "null".
+type TimeSeries struct {
+	From    string     `json:"from"`
+	To      string     `json:"to"`
+	Channel string     `json:"channel,omitempty"`
+	Metric  string     `json:"metric"`
+	Fill    string     `json:"fill"`
+	Dates   []string   `json:"dates"`
+	Values  []*float64 `json:"values"`
+}
+
+// MetricsSummary holds totals over a date range with ratios recomputed from
+// those totals.
+type MetricsSummary struct {
+	From          string  `json:"from"`
+	To            string  `json:"to"`
+	Channel       string  `json:"channel,omitempty"`
+	Records       int     `json:"records"`
+	Clicks        int     `json:"clicks"`
+	Impressions   int     `json:"impressions"`
+	Cost          float64 `json:"cost"`
+	Leads         int     `json:"leads"`
+	Opportunities int     `json:"opportunities"`
+	ClosedWon     int     `json:"closed_won"`
+	Revenue       float64 `json:"revenue"`
+	CPC           float64 `json:"cpc"`
+	CPA           float64 `json:"cpa"`
+	CVRLeadToOpp  float64 `json:"cvr_lead_to_opp"`
+	CVROppToWon   float64 `json:"cvr_opp_to_won"`
+	ROAS          float64 `json:"roas"`
+	CTR           float64 `json:"ctr"`
+	CPM           float64 `json:"cpm"`
+}
+
+// MetricsComparison pairs the summary of a range with the summary of the
+// equal-length range immediately before it.
+type MetricsComparison struct {
+	Current  MetricsSummary `json:"current"`
+	Previous MetricsSummary `json:"previous"`
+	Deltas   MetricsDeltas  `json:"deltas"`
+}
+
+// MetricsDeltas holds the percentage change of each metric from the previous
+// range to the current one (25 means +25%). A metric whose previous value is
+// zero has no meaningful percentage change and is null.
+type MetricsDeltas struct {
+	Clicks        *float64 `json:"clicks"`
+	Impressions   *float64 `json:"impressions"`
+	Cost          *float64 `json:"cost"`
+	Leads         *float64 `json:"leads"`
+	Opportunities *float64 `json:"opportunities"`
+	ClosedWon     *float64 `json:"closed_won"`
+	Revenue       *float64 `json:"revenue"`
+	CPC           *float64 `json:"cpc"`
+	CPA           *float64 `json:"cpa"`
+	CVRLeadToOpp  *float64 `json:"cvr_lead_to_opp"`
+	CVROppToWon   *float64 `json:"cvr_opp_to_won"`
+	ROAS          *float64 `json:"roas"`
+	CTR           *float64 `json:"ctr"`
+	CPM           *float64 `json:"cpm"`
 }
 
 type ExportRequest struct {
-	Date string `form:"date" binding:"required,datetime=2006-01-02"`
+	Date       string `form:"date" binding:"required"`
+	Channel    string `form:"channel"`
+	CampaignID string `form:"campaign_id"`
+	DryRun     bool   `form:"dry_run"`
+	Async      bool   `form:"async"`
+}
+
+type HealthRequest struct {
+	Verbose bool `form:"verbose"`
 }
 
 type HealthResponse struct {
-	Status    string `json:"status"`
-	Timestamp string `json:"timestamp"`
-	Version   string `json:"version"`
+	Status       string            `json:"status"`
+	Timestamp    string            `json:"timestamp"`
+	Version      string            `json:"version"`
+	Dependencies map[string]string `json:"dependencies,omitempty"`
+	Detail       *HealthDetail     `json:"detail,omitempty"`
+}
+
+// HealthDetail describes the stored dataset for verbose health checks.
+// LastIngestion is empty until an ingestion has completed.
+type HealthDetail struct {
+	LastIngestion  string `json:"last_ingestion,omitempty"`
+	Records        int    `json:"records"`
+	StorageBackend string `json:"storage_backend"`
+}
+
+// RuntimeStats is a lightweight snapshot of the process for spotting
+// goroutine or memory leaks without exposing pprof. LastGC is empty until
+// the first collection.
+type RuntimeStats struct {
+	Goroutines   int    `json:"goroutines"`
+	HeapAlloc    uint64 `json:"heap_alloc_bytes"`
+	HeapSys      uint64 `json:"heap_sys_bytes"`
+	HeapObjects  uint64 `json:"heap_objects"`
+	TotalAlloc   uint64 `json:"total_alloc_bytes"`
+	NumGC        uint32 `json:"num_gc"`
+	PauseTotalNs uint64 `json:"gc_pause_total_ns"`
+	LastGC       string `json:"last_gc,omitempty"`
+}
+
+// IngestionStatus describes the last pulled ingestion and the stored data.
+// LastIngestion, FirstDate and LastDate are empty until data is ingested.
+type IngestionStatus struct {
+	LastIngestion string `json:"last_ingestion,omitempty"`
+	Records       int    `json:"records"`
+	FirstDate     string `json:"first_date,omitempty"`
+	LastDate      string `json:"last_date,omitempty"`
+}
+
+// Freshness tells metrics consumers how current the data is. DataAsOf is
+// the last ingestion time, empty before the first one; Stale is true when
+// that is older than the configured threshold or there has been none.
+type Freshness struct {
+	DataAsOf string `json:"data_as_of,omitempty"`
+	Stale    bool   `json:"stale"`
 }
 
 type ErrorResponse struct {
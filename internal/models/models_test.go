@@ -0,0 +1,78 @@
+package models
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTransformedData_MarshalJSONRoundsRatios(t *testing.T) {
+	data := TransformedData{
+		Date:         "2025-01-01",
+		Channel:      "google_ads",
+		CampaignID:   "C-1001",
+		Cost:         350.75,
+		Revenue:      5000.0,
+		CPC:          0.29229166666666667,
+		CPA:          14.030000000000001,
+		CVRLeadToOpp: 0.06666666666666667,
+		CVROppToWon:  0.6666666666666666,
+		ROAS:         14.255167498218104,
+	}
+
+	encoded, err := json.Marshal(data)
+	require.NoError(t, err)
+
+	body := string(encoded)
+	assert.Contains(t, body, `"cpc":0.2923`)
+	assert.Contains(t, body, `"cpa":14.03`)
+	assert.Contains(t, body, `"cvr_lead_to_opp":0.0667`)
+	assert.Contains(t, body, `"cvr_opp_to_won":0.6667`)
+	assert.Contains(t, body, `"roas":14.255`)
+	assert.Contains(t, body, `"cost":350.75`)
+
+	// Marshalling is stable and leaves the value itself unrounded
+	again, err := json.Marshal(data)
+	require.NoError(t, err)
+	assert.Equal(t, encoded, again)
+	assert.Equal(t, 0.6666666666666666, data.CVROppToWon)
+
+	// Slices and pointers go through the same rounding
+	encoded, err = json.Marshal([]*TransformedData{&data})
+	require.NoError(t, err)
+	assert.Contains(t, string(encoded), `"roas":14.255`)
+}
+
+func TestAdsData_UnmarshalJSONKeepsMalformedRows(t *testing.T) {
+	var ads AdsData
+	require.NoError(t, json.Unmarshal([]byte(`{"performance":[
+		{"campaign_id":"C-1","clicks":10},
+		{"campaign_id":"C-2","clicks":"ten"},
+		{"campaign_id":"C-3","clicks":30}
+	]}`), &ads))
+
+	require.Len(t, ads.Performance, 2)
+	assert.Equal(t, "C-1", ads.Performance[0].CampaignID)
+	assert.Equal(t, "C-3", ads.Performance[1].CampaignID)
+
+	require.Len(t, ads.Malformed, 1)
+	assert.JSONEq(t, `{"campaign_id":"C-2","clicks":"ten"}`, string(ads.Malformed[0].Raw))
+	assert.Error(t, ads.Malformed[0].Err)
+
+	// A list that isn't a list still fails the whole document
+	assert.Error(t, json.Unmarshal([]byte(`{"performance":{}}`), &ads))
+}
+
+func TestCRMData_UnmarshalJSONKeepsMalformedRecords(t *testing.T) {
+	var crm CRMData
+	require.NoError(t, json.Unmarshal([]byte(`{"opportunities":[
+		{"opportunity_id":"O-1","amount":"lots"},
+		{"opportunity_id":"O-2","amount":100}
+	]}`), &crm))
+
+	require.Len(t, crm.Opportunities, 1)
+	assert.Equal(t, "O-2", crm.Opportunities[0].OpportunityID)
+	require.Len(t, crm.Malformed, 1)
+}
@@ -0,0 +1,92 @@
+package scheduler
+
+import (
+	"context"
+	"fmt"
+	"sync/atomic"
+	"time"
+
+	"github.com/robfig/cron/v3"
+	"github.com/sirupsen/logrus"
+)
+
+// Clock abstracts time so tests can drive the scheduler deterministically.
+type Clock interface {
+	Now() time.Time
+	After(d time.Duration) <-chan time.Time
+}
+
+type realClock struct{}
+
+func (realClock) Now() time.Time                         { return time.Now() }
+func (realClock) After(d time.Duration) <-chan time.Time { return time.After(d) }
+
+// Scheduler runs a job on a cron schedule. A tick that arrives while the
+// previous run is still going is skipped rather than queued.
+type Scheduler struct {
+	spec     string
+	schedule cron.Schedule
+	job      func(ctx context.Context) error
+	logger   *logrus.Logger
+	clock    Clock
+	running  atomic.Bool
+}
+
+// New parses spec as a standard five-field cron expression (descriptors
+// such as "@hourly" are accepted too) and returns a Scheduler for job.
+func New(spec string, job func(ctx context.Context) error, logger *logrus.Logger) (*Scheduler, error) {
+	schedule, err := cron.ParseStandard(spec)
+	if err != nil {
+		return nil, fmt.Errorf("invalid cron expression %q: %w", spec, err)
+	}
+
+	return &Scheduler{
+		spec:     spec,
+		schedule: schedule,
+		job:      job,
+		logger:   logger,
+		clock:    realClock{},
+	}, nil
+}
+
+// Run fires the job at each scheduled time until ctx is cancelled. Each run
+// gets ctx, so cancelling it also signals an in-flight run to stop.
+func (s *Scheduler) Run(ctx context.Context) {
+	s.logger.WithField("schedule", s.spec).Info("Scheduler started")
+
+	for {
+		now := s.clock.Now()
+		next := s.schedule.Next(now)
+
+		select {
+		case <-ctx.Done():
+			s.logger.Info("Scheduler stopped")
+			return
+		case <-s.clock.After(next.Sub(now)):
+		}
+
+		if !s.running.CompareAndSwap(false, true) {
+			s.logger.WithField("scheduled_for", next).Warn("Previous scheduled run still in progress, skipping")
+			continue
+		}
+
+		go s.fire(ctx, next)
+	}
+}
+
+func (s *Scheduler) fire(ctx context.Context, scheduledFor time.Time) {
+	defer s.running.Store(false)
+
+	start := s.clock.Now()
+	err := s.job(ctx)
+
+	entry := s.logger.WithFields(logrus.Fields{
+		"scheduled_for": scheduledFor,
+		"duration":      s.clock.Now().Sub(start).String(),
+	})
+	if err != nil {
+		entry.WithError(err).Error("Scheduled run failed")
+		return
+	}
+	entry.Info("Scheduled run completed")
+}
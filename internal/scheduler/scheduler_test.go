@@ -0,0 +1,107 @@
+package scheduler
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeClock hands each wait to the test, which advances time and releases it.
+type fakeClock struct {
+	mu    sync.Mutex
+	now   time.Time
+	waits chan time.Duration
+	fire  chan time.Time
+}
+
+func newFakeClock(now time.Time) *fakeClock {
+	return &fakeClock{
+		now:   now,
+		waits: make(chan time.Duration),
+		fire:  make(chan time.Time),
+	}
+}
+
+func (c *fakeClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+func (c *fakeClock) After(d time.Duration) <-chan time.Time {
+	c.waits <- d
+	return c.fire
+}
+
+// tick waits for the scheduler to ask for a timer, checks its duration, then
+// advances the clock by it and fires.
+func (c *fakeClock) tick(t *testing.T, expected time.Duration) {
+	t.Helper()
+
+	select {
+	case d := <-c.waits:
+		assert.Equal(t, expected, d)
+		c.mu.Lock()
+		c.now = c.now.Add(d)
+		now := c.now
+		c.mu.Unlock()
+		c.fire <- now
+	case <-time.After(time.Second):
+		t.Fatal("scheduler never waited for the next tick")
+	}
+}
+
+func TestScheduler_RunsAtScheduledTimeAndSkipsOverlap(t *testing.T) {
+	logger := logrus.New()
+	logger.SetLevel(logrus.FatalLevel)
+
+	calls := make(chan time.Time, 10)
+	release := make(chan struct{})
+	clock := newFakeClock(time.Date(2025, 1, 1, 10, 2, 0, 0, time.UTC))
+
+	s, err := New("*/5 * * * *", func(ctx context.Context) error {
+		calls <- clock.Now()
+		<-release
+		return nil
+	}, logger)
+	require.NoError(t, err)
+	s.clock = clock
+
+	ctx, cancel := context.WithCancel(context.Background())
+	stopped := make(chan struct{})
+	go func() {
+		s.Run(ctx)
+		close(stopped)
+	}()
+
+	// From 10:02 the next run is at 10:05
+	clock.tick(t, 3*time.Minute)
+	select {
+	case at := <-calls:
+		assert.Equal(t, time.Date(2025, 1, 1, 10, 5, 0, 0, time.UTC), at)
+	case <-time.After(time.Second):
+		t.Fatal("job was not triggered")
+	}
+
+	// The 10:10 tick arrives while the first run is still going
+	clock.tick(t, 5*time.Minute)
+
+	// Once the scheduler waits for 10:15 the 10:10 tick has been handled;
+	// let the first run finish and stop the scheduler
+	<-clock.waits
+	close(release)
+	cancel()
+	<-stopped
+
+	assert.Empty(t, calls, "overlapping tick should have been skipped")
+}
+
+func TestNew_InvalidSpec(t *testing.T) {
+	_, err := New("every five minutes", func(ctx context.Context) error { return nil }, logrus.New())
+	assert.Error(t, err)
+}
@@ -0,0 +1,167 @@
+package storage
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"admira-etl/internal/models"
+)
+
+// FileStorage keeps the dataset in memory for queries and persists the whole
+// of it to a JSON file after every write, so a single-node deployment
+// survives restarts without a database.
+type FileStorage struct {
+	*InMemoryStorage
+
+	// persistMu serialises write+persist so the file always reflects the
+	// latest in-memory state.
+	persistMu sync.Mutex
+	path      string
+}
+
+type fileSnapshot struct {
+	Data           []models.TransformedData `json:"data"`
+	LastIngestion  time.Time                `json:"last_ingestion"`
+	IngestionTimes map[string]time.Time     `json:"ingestion_times"`
+}
+
+// NewFileStorage creates a FileStorage backed by path, loading any data
+// previously written there. A missing file starts an empty dataset.
+func NewFileStorage(path string) (*FileStorage, error) {
+	fs := &FileStorage{
+		InMemoryStorage: NewInMemoryStorage(),
+		path:            path,
+	}
+
+	if err := fs.load(); err != nil {
+		return nil, err
+	}
+
+	return fs, nil
+}
+
+func (f *FileStorage) StoreTransformedData(data []models.TransformedData) error {
+	f.persistMu.Lock()
+	defer f.persistMu.Unlock()
+
+	if err := f.InMemoryStorage.StoreTransformedData(data); err != nil {
+		return err
+	}
+
+	return f.persist()
+}
+
+func (f *FileStorage) DeleteTransformedData(from, to time.Time, filters map[string]string) (int, error) {
+	f.persistMu.Lock()
+	defer f.persistMu.Unlock()
+
+	deleted, err := f.InMemoryStorage.DeleteTransformedData(from, to, filters)
+	if err != nil || deleted == 0 {
+		return deleted, err
+	}
+
+	return deleted, f.persist()
+}
+
+func (f *FileStorage) ReplaceTransformedData(from, to time.Time, data []models.TransformedData) (int, error) {
+	f.persistMu.Lock()
+	defer f.persistMu.Unlock()
+
+	deleted, err := f.InMemoryStorage.ReplaceTransformedData(from, to, data)
+	if err != nil {
+		return deleted, err
+	}
+
+	return deleted, f.persist()
+}
+
+func (f *FileStorage) SetLastIngestionTime(t time.Time) error {
+	f.persistMu.Lock()
+	defer f.persistMu.Unlock()
+
+	if err := f.InMemoryStorage.SetLastIngestionTime(t); err != nil {
+		return err
+	}
+
+	return f.persist()
+}
+
+func (f *FileStorage) load() error {
+	content, err := os.ReadFile(f.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read storage file: %w", err)
+	}
+
+	var snapshot fileSnapshot
+	if err := json.Unmarshal(content, &snapshot); err != nil {
+		return fmt.Errorf("failed to parse storage file: %w", err)
+	}
+
+	s := f.InMemoryStorage
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if snapshot.Data != nil {
+		s.data = snapshot.Data
+		s.index.rebuild(s.data)
+	}
+	if snapshot.IngestionTimes != nil {
+		s.ingestionTimes = snapshot.IngestionTimes
+	}
+	s.lastIngestion = snapshot.LastIngestion
+
+	return nil
+}
+
+// persist writes the current state to a temp file in the same directory and
+// renames it over the target, so readers never observe a partial file.
+func (f *FileStorage) persist() error {
+	s := f.InMemoryStorage
+	s.mu.RLock()
+	content, err := json.Marshal(fileSnapshot{
+		Data:           s.data,
+		LastIngestion:  s.lastIngestion,
+		IngestionTimes: s.ingestionTimes,
+	})
+	s.mu.RUnlock()
+	if err != nil {
+		return fmt.Errorf("failed to encode storage file: %w", err)
+	}
+
+	dir := filepath.Dir(f.path)
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return fmt.Errorf("failed to create storage directory: %w", err)
+	}
+
+	tmp, err := os.CreateTemp(dir, filepath.Base(f.path)+".tmp-*")
+	if err != nil {
+		return fmt.Errorf("failed to create temp storage file: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(content); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write temp storage file: %w", err)
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to sync temp storage file: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to close temp storage file: %w", err)
+	}
+
+	if err := os.Rename(tmp.Name(), f.path); err != nil {
+		return fmt.Errorf("failed to replace storage file: %w", err)
+	}
+
+	return nil
+}
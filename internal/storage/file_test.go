@@ -0,0 +1,140 @@
+package storage
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"admira-etl/internal/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFileStorage_RecoversData(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "nested", "data.json")
+
+	storage, err := NewFileStorage(path)
+	require.NoError(t, err)
+
+	data := []models.TransformedData{
+		{Date: "2025-01-01", Channel: "google_ads", CampaignID: "C-1001", Clicks: 1000, Cost: 250.0, ROAS: 20.0},
+		{Date: "2025-01-02", Channel: "facebook_ads", CampaignID: "C-1002", Clicks: 800, Cost: 200.0},
+	}
+	require.NoError(t, storage.StoreTransformedData(data))
+
+	ingestedAt := time.Date(2025, 1, 3, 10, 30, 0, 0, time.UTC)
+	require.NoError(t, storage.SetLastIngestionTime(ingestedAt))
+
+	// A fresh instance over the same path sees everything written so far
+	reloaded, err := NewFileStorage(path)
+	require.NoError(t, err)
+
+	from, _ := time.Parse("2006-01-02", "2025-01-01")
+	to, _ := time.Parse("2006-01-02", "2025-01-02")
+	retrieved, err := reloaded.GetTransformedData(from, to, map[string]string{}, 0, 0)
+	require.NoError(t, err)
+	assert.Equal(t, data, retrieved)
+
+	lastIngestion, err := reloaded.GetLastIngestionTime()
+	require.NoError(t, err)
+	assert.True(t, ingestedAt.Equal(lastIngestion))
+
+	assert.True(t, reloaded.HasBeenIngested("2025-01-01"))
+	assert.False(t, reloaded.HasBeenIngested("2025-01-03"))
+
+	// No temp files are left behind by the atomic writes
+	entries, err := os.ReadDir(filepath.Dir(path))
+	require.NoError(t, err)
+	assert.Len(t, entries, 1)
+}
+
+func TestFileStorage_MissingFileStartsEmpty(t *testing.T) {
+	storage, err := NewFileStorage(filepath.Join(t.TempDir(), "missing.json"))
+	require.NoError(t, err)
+
+	lastIngestion, err := storage.GetLastIngestionTime()
+	require.NoError(t, err)
+	assert.True(t, lastIngestion.IsZero())
+
+	retrieved, err := storage.GetTransformedData(time.Time{}, time.Now(), map[string]string{}, 0, 0)
+	require.NoError(t, err)
+	assert.Empty(t, retrieved)
+}
+
+func TestFileStorage_CorruptFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "data.json")
+	require.NoError(t, os.WriteFile(path, []byte("{not json"), 0o644))
+
+	_, err := NewFileStorage(path)
+	assert.Error(t, err)
+}
+
+func TestFileStorage_DeletePersists(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "data.json")
+
+	storage, err := NewFileStorage(path)
+	require.NoError(t, err)
+	require.NoError(t, storage.StoreTransformedData([]models.TransformedData{
+		{Date: "2025-01-01", Channel: "google_ads", CampaignID: "C-1001"},
+		{Date: "2025-01-01", Channel: "facebook_ads", CampaignID: "C-2001"},
+	}))
+
+	day, _ := time.Parse("2006-01-02", "2025-01-01")
+	deleted, err := storage.DeleteTransformedData(day, day, map[string]string{"channel": "google_ads"})
+	require.NoError(t, err)
+	assert.Equal(t, 1, deleted)
+
+	reloaded, err := NewFileStorage(path)
+	require.NoError(t, err)
+	retrieved, err := reloaded.GetTransformedData(day, day, map[string]string{}, 0, 0)
+	require.NoError(t, err)
+	require.Len(t, retrieved, 1)
+	assert.Equal(t, "facebook_ads", retrieved[0].Channel)
+}
+
+func TestFileStorage_ReplacePersists(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "data.json")
+
+	storage, err := NewFileStorage(path)
+	require.NoError(t, err)
+	require.NoError(t, storage.StoreTransformedData([]models.TransformedData{
+		{Date: "2025-01-01", Channel: "google_ads", CampaignID: "C-1001", Clicks: 100},
+		{Date: "2025-01-01", Channel: "facebook_ads", CampaignID: "C-2001", Clicks: 200},
+	}))
+
+	day, _ := time.Parse("2006-01-02", "2025-01-01")
+	deleted, err := storage.ReplaceTransformedData(day, day, []models.TransformedData{
+		{Date: "2025-01-01", Channel: "google_ads", CampaignID: "C-1001", Clicks: 150},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, 2, deleted)
+
+	reloaded, err := NewFileStorage(path)
+	require.NoError(t, err)
+	retrieved, err := reloaded.GetTransformedData(day, day, map[string]string{}, 0, 0)
+	require.NoError(t, err)
+	require.Len(t, retrieved, 1)
+	assert.Equal(t, 150, retrieved[0].Clicks)
+}
+
+func TestFileStorage_RetentionPersists(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "data.json")
+
+	storage, err := NewFileStorage(path)
+	require.NoError(t, err)
+	storage.now = func() time.Time { return time.Date(2025, 1, 31, 0, 0, 0, 0, time.UTC) }
+	storage.SetRetention(7)
+
+	require.NoError(t, storage.StoreTransformedData([]models.TransformedData{
+		{Date: "2025-01-01", Channel: "google_ads", CampaignID: "C-1001"},
+		{Date: "2025-01-30", Channel: "google_ads", CampaignID: "C-1001"},
+	}))
+
+	reloaded, err := NewFileStorage(path)
+	require.NoError(t, err)
+	count, err := reloaded.CountTransformedData(nil)
+	require.NoError(t, err)
+	assert.Equal(t, 1, count)
+}
@@ -0,0 +1,79 @@
+package storage
+
+import (
+	"sort"
+	"time"
+
+	"admira-etl/internal/models"
+)
+
+// dateIndex maps each YYYY-MM-DD date in InMemoryStorage.data to the
+// positions of its rows, keeping the dates sorted so range queries can seek
+// to their window instead of scanning and re-parsing every row. Rows with
+// unparseable dates are left out, as no range query returns them.
+type dateIndex struct {
+	dates []indexedDate
+	rows  map[string][]int
+}
+
+type indexedDate struct {
+	key string
+	day time.Time
+}
+
+// add records that data[row] is dated date. Rows must be added in storage
+// order.
+func (ix *dateIndex) add(date string, row int) {
+	if rows, ok := ix.rows[date]; ok {
+		ix.rows[date] = append(rows, row)
+		return
+	}
+
+	day, err := time.Parse("2006-01-02", date)
+	if err != nil {
+		return
+	}
+	if ix.rows == nil {
+		ix.rows = make(map[string][]int)
+	}
+	ix.rows[date] = []int{row}
+
+	// YYYY-MM-DD strings sort chronologically
+	i := sort.Search(len(ix.dates), func(i int) bool { return ix.dates[i].key >= date })
+	ix.dates = append(ix.dates, indexedDate{})
+	copy(ix.dates[i+1:], ix.dates[i:])
+	ix.dates[i] = indexedDate{key: date, day: day}
+}
+
+// rebuild indexes data from scratch, for when rows have been removed and
+// the positions of the rest have shifted.
+func (ix *dateIndex) rebuild(data []models.TransformedData) {
+	ix.dates = nil
+	ix.rows = nil
+	for i, item := range data {
+		ix.add(item.Date, i)
+	}
+}
+
+// between returns the positions of the rows dated within [from, to], in
+// storage order.
+func (ix *dateIndex) between(from, to time.Time) []int {
+	start := sort.Search(len(ix.dates), func(i int) bool { return !ix.dates[i].day.Before(from) })
+
+	var rows []int
+	spans := 0
+	for _, date := range ix.dates[start:] {
+		if date.day.After(to) {
+			break
+		}
+		rows = append(rows, ix.rows[date.key]...)
+		spans++
+	}
+
+	// Each date's positions are ascending already; only a window spanning
+	// several dates needs merging back into storage order
+	if spans > 1 {
+		sort.Ints(rows)
+	}
+	return rows
+}
@@ -0,0 +1,130 @@
+package storage
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"admira-etl/internal/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// naiveScan is the full scan GetTransformedData did before the date index.
+func naiveScan(data []models.TransformedData, from, to time.Time, filters map[string]string) []models.TransformedData {
+	var filtered []models.TransformedData
+	for _, item := range data {
+		itemDate, err := time.Parse("2006-01-02", item.Date)
+		if err != nil {
+			continue
+		}
+		if itemDate.Before(from) || itemDate.After(to) {
+			continue
+		}
+		if !matchesFilters(item, filters) {
+			continue
+		}
+		filtered = append(filtered, item)
+	}
+	return paginate(filtered, 0, 0)
+}
+
+// seedRows stores rows over days days, interleaving dates out of order and
+// mixing in unparseable dates, across several batches.
+func seedRows(t testing.TB, storage *InMemoryStorage, days, perDay int) {
+	start := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	for i := 0; i < perDay; i++ {
+		batch := make([]models.TransformedData, 0, days+1)
+		for d := 0; d < days; d++ {
+			// Visit the days in a scrambled order
+			day := start.AddDate(0, 0, (d*7)%days)
+			channel := "google_ads"
+			if (d+i)%3 == 0 {
+				channel = "facebook_ads"
+			}
+			batch = append(batch, models.TransformedData{
+				Date:       day.Format("2006-01-02"),
+				Channel:    channel,
+				CampaignID: fmt.Sprintf("C-%d-%d", i, d),
+			})
+		}
+		batch = append(batch, models.TransformedData{Date: "not-a-date", Channel: "google_ads", CampaignID: "C-bad"})
+		require.NoError(t, storage.StoreTransformedData(batch))
+	}
+}
+
+func TestInMemoryStorage_IndexMatchesNaiveScan(t *testing.T) {
+	storage := NewInMemoryStorage()
+	seedRows(t, storage, 30, 4)
+
+	date := func(value string) time.Time {
+		parsed, err := time.Parse("2006-01-02", value)
+		require.NoError(t, err)
+		return parsed
+	}
+
+	tests := []struct {
+		name     string
+		from, to time.Time
+		filters  map[string]string
+	}{
+		{"everything", time.Time{}, time.Date(2100, 1, 1, 0, 0, 0, 0, time.UTC), nil},
+		{"single day", date("2025-01-10"), date("2025-01-10"), nil},
+		{"week", date("2025-01-05"), date("2025-01-11"), nil},
+		{"week by channel", date("2025-01-05"), date("2025-01-11"), map[string]string{"channel": "facebook_ads"}},
+		{"to mid-day", date("2025-01-28"), date("2025-01-29").Add(15 * time.Hour), nil},
+		{"from mid-day", date("2025-01-02").Add(time.Hour), date("2025-01-04"), nil},
+		{"before the data", date("2024-01-01"), date("2024-12-31"), nil},
+		{"after the data", date("2025-03-01"), date("2025-03-31"), nil},
+		{"inverted", date("2025-01-10"), date("2025-01-05"), nil},
+	}
+
+	check := func(t *testing.T) {
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				indexed, err := storage.GetTransformedData(tt.from, tt.to, tt.filters, 0, 0)
+				require.NoError(t, err)
+				assert.Equal(t, naiveScan(storage.data, tt.from, tt.to, tt.filters), indexed)
+			})
+		}
+	}
+
+	t.Run("after stores", check)
+
+	// Deleting shifts the positions of the remaining rows
+	_, err := storage.DeleteTransformedData(date("2025-01-08"), date("2025-01-12"), map[string]string{"channel": "google_ads"})
+	require.NoError(t, err)
+	seedRows(t, storage, 10, 1)
+	t.Run("after a delete", check)
+}
+
+func benchmarkGetTransformedData(b *testing.B, scan func(storage *InMemoryStorage, from, to time.Time) []models.TransformedData) {
+	storage := NewInMemoryStorage()
+	seedRows(b, storage, 365, 100)
+
+	from := time.Date(2025, 6, 1, 0, 0, 0, 0, time.UTC)
+	to := from.AddDate(0, 0, 6)
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if rows := scan(storage, from, to); len(rows) != 700 {
+			b.Fatalf("got %d rows", len(rows))
+		}
+	}
+}
+
+func BenchmarkGetTransformedData_Indexed(b *testing.B) {
+	benchmarkGetTransformedData(b, func(storage *InMemoryStorage, from, to time.Time) []models.TransformedData {
+		rows, _ := storage.GetTransformedData(from, to, nil, 0, 0)
+		return rows
+	})
+}
+
+func BenchmarkGetTransformedData_NaiveScan(b *testing.B) {
+	benchmarkGetTransformedData(b, func(storage *InMemoryStorage, from, to time.Time) []models.TransformedData {
+		storage.mu.RLock()
+		defer storage.mu.RUnlock()
+		return naiveScan(storage.data, from, to, nil)
+	})
+}
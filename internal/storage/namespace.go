@@ -0,0 +1,84 @@
+package storage
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+)
+
+// DefaultNamespace names the storage used when no other namespace is
+// selected. It maps to the backend's original location, so deployments that
+// never use namespaces keep their existing data.
+const DefaultNamespace = "default"
+
+// ErrInvalidNamespace is returned for namespace names that are not safe to
+// embed in file names and Redis keys.
+var ErrInvalidNamespace = errors.New("invalid namespace")
+
+var namespacePattern = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]{0,62}$`)
+
+// ValidateNamespace checks name is 1-63 lowercase letters, digits, '-' or
+// '_', starting with a letter or digit.
+func ValidateNamespace(name string) error {
+	if !namespacePattern.MatchString(name) {
+		return fmt.Errorf("%w %q: use 1-63 lowercase letters, digits, '-' or '_'", ErrInvalidNamespace, name)
+	}
+	return nil
+}
+
+// Opener creates the storage of a namespace other than the default one.
+type Opener func(namespace string) (Storage, error)
+
+// Namespaces hands out one Storage per namespace, each holding its own rows
+// and ingestion times. The default namespace is the storage given to
+// NewNamespaces; the others are opened on first use and then reused.
+type Namespaces struct {
+	mu     sync.Mutex
+	open   Opener
+	stores map[string]Storage
+}
+
+func NewNamespaces(defaultStore Storage, open Opener) *Namespaces {
+	return &Namespaces{
+		open:   open,
+		stores: map[string]Storage{DefaultNamespace: defaultStore},
+	}
+}
+
+// Get returns the storage of namespace, opening it if needed. An empty name
+// selects the default namespace.
+func (n *Namespaces) Get(namespace string) (Storage, error) {
+	if namespace == "" {
+		namespace = DefaultNamespace
+	}
+	if err := ValidateNamespace(namespace); err != nil {
+		return nil, err
+	}
+
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	if store, ok := n.stores[namespace]; ok {
+		return store, nil
+	}
+	store, err := n.open(namespace)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open namespace %q: %w", namespace, err)
+	}
+	n.stores[namespace] = store
+	return store, nil
+}
+
+// NamespacePath returns the file a namespace's FileStorage uses: path itself
+// for the default namespace, otherwise path with the namespace inserted
+// before its extension (data.json becomes data.staging.json).
+func NamespacePath(path, namespace string) string {
+	if namespace == "" || namespace == DefaultNamespace {
+		return path
+	}
+	ext := filepath.Ext(path)
+	return strings.TrimSuffix(path, ext) + "." + namespace + ext
+}
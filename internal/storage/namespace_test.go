@@ -0,0 +1,75 @@
+package storage
+
+import (
+	"testing"
+	"time"
+
+	"admira-etl/internal/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNamespaces_Isolated(t *testing.T) {
+	opened := 0
+	namespaces := NewNamespaces(NewInMemoryStorage(), func(string) (Storage, error) {
+		opened++
+		return NewInMemoryStorage(), nil
+	})
+
+	production, err := namespaces.Get("")
+	require.NoError(t, err)
+	staging, err := namespaces.Get("staging")
+	require.NoError(t, err)
+
+	require.NoError(t, production.StoreTransformedData([]models.TransformedData{
+		{Date: "2025-01-01", Channel: "google_ads", CampaignID: "C-1001", Clicks: 100},
+	}))
+	require.NoError(t, staging.StoreTransformedData([]models.TransformedData{
+		{Date: "2025-01-01", Channel: "facebook_ads", CampaignID: "C-2001", Clicks: 7},
+		{Date: "2025-01-02", Channel: "facebook_ads", CampaignID: "C-2001", Clicks: 8},
+	}))
+
+	from, _ := time.Parse("2006-01-02", "2025-01-01")
+	to, _ := time.Parse("2006-01-02", "2025-01-31")
+
+	rows, err := production.GetTransformedData(from, to, nil, 0, 0)
+	require.NoError(t, err)
+	require.Len(t, rows, 1)
+	assert.Equal(t, "C-1001", rows[0].CampaignID)
+
+	rows, err = staging.GetTransformedData(from, to, nil, 0, 0)
+	require.NoError(t, err)
+	require.Len(t, rows, 2)
+	assert.Equal(t, "C-2001", rows[0].CampaignID)
+
+	// The default namespace is the store given to NewNamespaces and others
+	// are opened once
+	again, err := namespaces.Get(DefaultNamespace)
+	require.NoError(t, err)
+	assert.Same(t, production, again)
+	again, err = namespaces.Get("staging")
+	require.NoError(t, err)
+	assert.Same(t, staging, again)
+	assert.Equal(t, 1, opened)
+}
+
+func TestValidateNamespace(t *testing.T) {
+	for _, name := range []string{"staging", "preview-2", "team_a", "0"} {
+		assert.NoError(t, ValidateNamespace(name), name)
+	}
+	for _, name := range []string{"", "Staging", "../prod", "a:b", "-x", string(make([]byte, 64))} {
+		assert.ErrorIs(t, ValidateNamespace(name), ErrInvalidNamespace, name)
+	}
+
+	namespaces := NewNamespaces(NewInMemoryStorage(), nil)
+	_, err := namespaces.Get("a/b")
+	assert.ErrorIs(t, err, ErrInvalidNamespace)
+}
+
+func TestNamespacePath(t *testing.T) {
+	assert.Equal(t, "data/admira-etl.json", NamespacePath("data/admira-etl.json", ""))
+	assert.Equal(t, "data/admira-etl.json", NamespacePath("data/admira-etl.json", DefaultNamespace))
+	assert.Equal(t, "data/admira-etl.staging.json", NamespacePath("data/admira-etl.json", "staging"))
+	assert.Equal(t, "data/store.staging", NamespacePath("data/store", "staging"))
+}
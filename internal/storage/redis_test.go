@@ -0,0 +1,241 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"testing"
+	"time"
+
+	"admira-etl/internal/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newTestRedisStorage connects to REDIS_URL (or a local server) under a key
+// prefix unique to the test, skipping when no server is reachable.
+func newTestRedisStorage(t *testing.T) *RedisStorage {
+	t.Helper()
+
+	url := os.Getenv("REDIS_URL")
+	if url == "" {
+		url = "redis://localhost:6379/15"
+	}
+
+	storage, err := NewRedisStorage(url)
+	if err != nil {
+		t.Skipf("redis not reachable at %s: %v", url, err)
+	}
+	storage.prefix = fmt.Sprintf("admira-etl-test:%s:%d:", t.Name(), time.Now().UnixNano())
+
+	t.Cleanup(func() {
+		ctx := context.Background()
+		keys, err := storage.client.Keys(ctx, storage.prefix+"*").Result()
+		if err == nil && len(keys) > 0 {
+			storage.client.Del(ctx, keys...)
+		}
+		storage.Close()
+	})
+	return storage
+}
+
+func TestRedisStorage_StoreAndGet(t *testing.T) {
+	storage := newTestRedisStorage(t)
+
+	data := []models.TransformedData{
+		{Date: "2025-01-01", Channel: "google_ads", CampaignID: "C-1001", Clicks: 1000, Cost: 250.0, ROAS: 20.0},
+		{Date: "2025-01-02", Channel: "facebook_ads", CampaignID: "C-1002", Clicks: 800, Cost: 200.0},
+		{Date: "2025-01-01", Channel: "facebook_ads", CampaignID: "C-1003", Clicks: 300, Cost: 90.0},
+	}
+	require.NoError(t, storage.StoreTransformedData(data))
+
+	from, _ := time.Parse("2006-01-02", "2025-01-01")
+	to, _ := time.Parse("2006-01-02", "2025-01-02")
+
+	// Rows come back by date, then in insertion order
+	retrieved, err := storage.GetTransformedData(from, to, map[string]string{}, 0, 0)
+	require.NoError(t, err)
+	assert.Equal(t, []models.TransformedData{data[0], data[2], data[1]}, retrieved)
+
+	retrieved, err = storage.GetTransformedData(from, to, map[string]string{"channel": "facebook_ads"}, 1, 1)
+	require.NoError(t, err)
+	require.Len(t, retrieved, 1)
+	assert.Equal(t, "C-1002", retrieved[0].CampaignID)
+
+	retrieved, err = storage.GetTransformedData(to, to, map[string]string{}, 0, 0)
+	require.NoError(t, err)
+	assert.Len(t, retrieved, 1)
+
+	count, err := storage.CountTransformedData(nil)
+	require.NoError(t, err)
+	assert.Equal(t, 3, count)
+
+	count, err = storage.CountTransformedData(map[string]string{"channel": "facebook_ads"})
+	require.NoError(t, err)
+	assert.Equal(t, 2, count)
+
+	count, err = storage.CountTransformedData(map[string]string{"channel": "facebook_ads", "campaign_id": "C-1003"})
+	require.NoError(t, err)
+	assert.Equal(t, 1, count)
+
+	assert.True(t, storage.HasBeenIngested("2025-01-01"))
+	assert.False(t, storage.HasBeenIngested("2025-01-03"))
+}
+
+func TestRedisStorage_IngestionTime(t *testing.T) {
+	storage := newTestRedisStorage(t)
+
+	lastIngestion, err := storage.GetLastIngestionTime()
+	require.NoError(t, err)
+	assert.True(t, lastIngestion.IsZero())
+
+	ingestedAt := time.Date(2025, 1, 3, 10, 30, 0, 0, time.UTC)
+	require.NoError(t, storage.SetLastIngestionTime(ingestedAt))
+
+	lastIngestion, err = storage.GetLastIngestionTime()
+	require.NoError(t, err)
+	assert.True(t, ingestedAt.Equal(lastIngestion))
+}
+
+func TestRedisStorage_DeleteTransformedData(t *testing.T) {
+	storage := newTestRedisStorage(t)
+
+	require.NoError(t, storage.StoreTransformedData([]models.TransformedData{
+		{Date: "2025-01-01", Channel: "google_ads", CampaignID: "C-1001"},
+		{Date: "2025-01-01", Channel: "facebook_ads", CampaignID: "C-2001"},
+		{Date: "2025-01-02", Channel: "google_ads", CampaignID: "C-1001"},
+	}))
+
+	from, _ := time.Parse("2006-01-02", "2025-01-01")
+	to, _ := time.Parse("2006-01-02", "2025-01-02")
+
+	deleted, err := storage.DeleteTransformedData(from, to, map[string]string{"channel": "google_ads"})
+	require.NoError(t, err)
+	assert.Equal(t, 2, deleted)
+
+	retrieved, err := storage.GetTransformedData(from, to, map[string]string{}, 0, 0)
+	require.NoError(t, err)
+	require.Len(t, retrieved, 1)
+	assert.Equal(t, "C-2001", retrieved[0].CampaignID)
+
+	// A date left without rows no longer counts as ingested
+	assert.True(t, storage.HasBeenIngested("2025-01-01"))
+	assert.False(t, storage.HasBeenIngested("2025-01-02"))
+}
+
+func TestRedisStorage_ReplaceTransformedData(t *testing.T) {
+	storage := newTestRedisStorage(t)
+
+	require.NoError(t, storage.StoreTransformedData([]models.TransformedData{
+		{Date: "2025-01-01", Channel: "google_ads", CampaignID: "C-1001", Clicks: 100},
+		{Date: "2025-01-02", Channel: "google_ads", CampaignID: "C-1001", Clicks: 200},
+		{Date: "2025-01-02", Channel: "facebook_ads", CampaignID: "C-2001", Clicks: 300},
+	}))
+
+	from, _ := time.Parse("2006-01-02", "2025-01-01")
+	day, _ := time.Parse("2006-01-02", "2025-01-02")
+
+	deleted, err := storage.ReplaceTransformedData(day, day, []models.TransformedData{
+		{Date: "2025-01-02", Channel: "google_ads", CampaignID: "C-1001", Clicks: 250},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, 2, deleted)
+
+	retrieved, err := storage.GetTransformedData(from, day, map[string]string{}, 0, 0)
+	require.NoError(t, err)
+	require.Len(t, retrieved, 2)
+	assert.Equal(t, 100, retrieved[0].Clicks)
+	assert.Equal(t, 250, retrieved[1].Clicks)
+
+	// Replacing a day with nothing empties it
+	deleted, err = storage.ReplaceTransformedData(day, day, nil)
+	require.NoError(t, err)
+	assert.Equal(t, 1, deleted)
+	assert.False(t, storage.HasBeenIngested("2025-01-02"))
+}
+
+func TestRedisStorage_DateRange(t *testing.T) {
+	storage := newTestRedisStorage(t)
+
+	first, last, err := storage.DateRange()
+	require.NoError(t, err)
+	assert.Empty(t, first)
+	assert.Empty(t, last)
+
+	require.NoError(t, storage.StoreTransformedData([]models.TransformedData{
+		{Date: "2025-01-05", Channel: "google_ads", CampaignID: "C-1001"},
+		{Date: "2025-01-02", Channel: "google_ads", CampaignID: "C-1001"},
+		{Date: "2025-01-09", Channel: "facebook_ads", CampaignID: "C-2001"},
+	}))
+
+	first, last, err = storage.DateRange()
+	require.NoError(t, err)
+	assert.Equal(t, "2025-01-02", first)
+	assert.Equal(t, "2025-01-09", last)
+}
+
+func TestRedisStorage_Dimensions(t *testing.T) {
+	storage := newTestRedisStorage(t)
+	require.NoError(t, storage.StoreTransformedData([]models.TransformedData{
+		{Date: "2025-01-01", Channel: "google_ads", CampaignID: "C-1001"},
+		{Date: "2025-01-02", Channel: "google_ads", CampaignID: "C-1002"},
+		{Date: "2025-01-02", Channel: "facebook_ads", CampaignID: "C-1002"},
+		{Date: "2025-02-01", Channel: "linkedin_ads", CampaignID: "C-3001"},
+	}))
+
+	from, _ := time.Parse("2006-01-02", "2025-01-01")
+	to, _ := time.Parse("2006-01-02", "2025-01-31")
+	channels, campaigns, err := storage.Dimensions(from, to)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"facebook_ads", "google_ads"}, channels)
+	assert.Equal(t, []string{"C-1001", "C-1002"}, campaigns)
+}
+
+func TestRedisStorage_Namespace(t *testing.T) {
+	storage := newTestRedisStorage(t)
+	// The namespace's keys extend storage's prefix, so cleanup covers both
+	staging := storage.Namespace("staging")
+	assert.Same(t, storage, storage.Namespace(DefaultNamespace))
+
+	require.NoError(t, storage.StoreTransformedData([]models.TransformedData{
+		{Date: "2025-01-01", Channel: "google_ads", CampaignID: "C-1001"},
+	}))
+	require.NoError(t, staging.StoreTransformedData([]models.TransformedData{
+		{Date: "2025-01-01", Channel: "facebook_ads", CampaignID: "C-2001"},
+		{Date: "2025-01-02", Channel: "facebook_ads", CampaignID: "C-2001"},
+	}))
+
+	count, err := storage.CountTransformedData(nil)
+	require.NoError(t, err)
+	assert.Equal(t, 1, count)
+	count, err = staging.CountTransformedData(nil)
+	require.NoError(t, err)
+	assert.Equal(t, 2, count)
+}
+
+func TestRedisStorage_Retention(t *testing.T) {
+	storage := newTestRedisStorage(t)
+	storage.now = func() time.Time { return time.Date(2025, 3, 31, 15, 0, 0, 0, time.UTC) }
+	storage.SetRetention(30)
+
+	// The cutoff is 2025-03-01: rows dated that day or later are kept
+	require.NoError(t, storage.StoreTransformedData([]models.TransformedData{
+		{Date: "2025-01-15", Channel: "google_ads", CampaignID: "C-1001"},
+		{Date: "2025-02-28", Channel: "google_ads", CampaignID: "C-1001"},
+		{Date: "2025-03-01", Channel: "google_ads", CampaignID: "C-1001"},
+		{Date: "2025-03-30", Channel: "facebook_ads", CampaignID: "C-2001"},
+	}))
+
+	count, err := storage.CountTransformedData(nil)
+	require.NoError(t, err)
+	assert.Equal(t, 2, count)
+
+	assert.False(t, storage.HasBeenIngested("2025-02-28"))
+	assert.True(t, storage.HasBeenIngested("2025-03-01"))
+}
+
+func TestNewRedisStorage_InvalidURL(t *testing.T) {
+	_, err := NewRedisStorage("not-a-redis-url")
+	assert.ErrorContains(t, err, "invalid redis URL")
+}
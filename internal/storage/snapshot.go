@@ -0,0 +1,83 @@
+package storage
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"admira-etl/internal/models"
+)
+
+// ErrInvalidSnapshot is wrapped by ReadSnapshot errors caused by the
+// snapshot itself rather than the backend.
+var ErrInvalidSnapshot = errors.New("invalid snapshot")
+
+// Snapshot is the backup format shared by every backend: all stored rows,
+// in storage order, and the last ingestion time.
+type Snapshot struct {
+	Data          []models.TransformedData `json:"data"`
+	LastIngestion time.Time                `json:"last_ingestion"`
+}
+
+// decodeSnapshot reads a Snapshot from r, requiring every row to carry a
+// YYYY-MM-DD date so it can be indexed.
+func decodeSnapshot(r io.Reader) (*Snapshot, error) {
+	var snapshot Snapshot
+	if err := json.NewDecoder(r).Decode(&snapshot); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidSnapshot, err)
+	}
+	for i, item := range snapshot.Data {
+		if _, err := time.Parse("2006-01-02", item.Date); err != nil {
+			return nil, fmt.Errorf("%w: row %d: date %q is not YYYY-MM-DD", ErrInvalidSnapshot, i, item.Date)
+		}
+	}
+	if snapshot.Data == nil {
+		snapshot.Data = []models.TransformedData{}
+	}
+	return &snapshot, nil
+}
+
+func (s *InMemoryStorage) WriteSnapshot(w io.Writer) error {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if err := json.NewEncoder(w).Encode(Snapshot{Data: s.data, LastIngestion: s.lastIngestion}); err != nil {
+		return fmt.Errorf("failed to write snapshot: %w", err)
+	}
+	return nil
+}
+
+func (s *InMemoryStorage) ReadSnapshot(r io.Reader) error {
+	snapshot, err := decodeSnapshot(r)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	s.data = snapshot.Data
+	s.index.rebuild(s.data)
+	s.ingestionTimes = make(map[string]time.Time)
+	for _, item := range s.data {
+		s.ingestionTimes[item.Date] = now
+	}
+	s.lastIngestion = snapshot.LastIngestion
+	s.evictExpiredLocked()
+
+	return nil
+}
+
+func (f *FileStorage) ReadSnapshot(r io.Reader) error {
+	f.persistMu.Lock()
+	defer f.persistMu.Unlock()
+
+	if err := f.InMemoryStorage.ReadSnapshot(r); err != nil {
+		return err
+	}
+
+	return f.persist()
+}
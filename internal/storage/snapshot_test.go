@@ -0,0 +1,98 @@
+package storage
+
+import (
+	"bytes"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"admira-etl/internal/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSnapshot_RoundTrip(t *testing.T) {
+	backends := map[string]func(t *testing.T) Storage{
+		"memory": func(t *testing.T) Storage { return NewInMemoryStorage() },
+		"file": func(t *testing.T) Storage {
+			fs, err := NewFileStorage(filepath.Join(t.TempDir(), "data.json"))
+			require.NoError(t, err)
+			return fs
+		},
+		"redis": func(t *testing.T) Storage { return newTestRedisStorage(t) },
+	}
+
+	data := []models.TransformedData{
+		{Date: "2025-01-01", Channel: "google_ads", CampaignID: "C-1001", Clicks: 1000, Cost: 250.0, Revenue: 5000.0, ROAS: 20.0},
+		{Date: "2025-01-01", Channel: "facebook_ads", CampaignID: "C-1003", Clicks: 300, Cost: 90.0},
+		{Date: "2025-01-02", Channel: "facebook_ads", CampaignID: "C-1002", Clicks: 800, Cost: 200.0, MatchType: "exact"},
+	}
+	lastIngestion := time.Date(2025, 1, 3, 4, 5, 6, 0, time.UTC)
+	from, _ := time.Parse("2006-01-02", "2000-01-01")
+	to, _ := time.Parse("2006-01-02", "2100-01-01")
+
+	for name, newStorage := range backends {
+		t.Run(name, func(t *testing.T) {
+			store := newStorage(t)
+			require.NoError(t, store.StoreTransformedData(data))
+			require.NoError(t, store.SetLastIngestionTime(lastIngestion))
+
+			var snapshot bytes.Buffer
+			require.NoError(t, store.WriteSnapshot(&snapshot))
+
+			// Clear everything, then restore
+			_, err := store.DeleteTransformedData(from, to, nil)
+			require.NoError(t, err)
+			require.NoError(t, store.SetLastIngestionTime(time.Time{}))
+			count, err := store.CountTransformedData(nil)
+			require.NoError(t, err)
+			require.Zero(t, count)
+
+			require.NoError(t, store.ReadSnapshot(bytes.NewReader(snapshot.Bytes())))
+
+			restored, err := store.GetTransformedData(from, to, nil, 0, 0)
+			require.NoError(t, err)
+			assert.Equal(t, data, restored)
+			restoredAt, err := store.GetLastIngestionTime()
+			require.NoError(t, err)
+			assert.True(t, lastIngestion.Equal(restoredAt), "last ingestion %s", restoredAt)
+			first, last, err := store.DateRange()
+			require.NoError(t, err)
+			assert.Equal(t, "2025-01-01", first)
+			assert.Equal(t, "2025-01-02", last)
+
+			// A restore replaces what is stored rather than adding to it
+			require.NoError(t, store.ReadSnapshot(bytes.NewReader(snapshot.Bytes())))
+			count, err = store.CountTransformedData(nil)
+			require.NoError(t, err)
+			assert.Equal(t, len(data), count)
+
+			// Malformed snapshots are rejected without touching the data
+			for _, bad := range []string{`{"data": [`, `{"data": [{"date": "01/02/2025"}]}`} {
+				err := store.ReadSnapshot(strings.NewReader(bad))
+				assert.ErrorIs(t, err, ErrInvalidSnapshot)
+			}
+			count, err = store.CountTransformedData(nil)
+			require.NoError(t, err)
+			assert.Equal(t, len(data), count)
+		})
+	}
+}
+
+func TestFileStorage_ReadSnapshotPersists(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "data.json")
+	store, err := NewFileStorage(path)
+	require.NoError(t, err)
+
+	snapshot := `{"data": [{"date": "2025-01-01", "channel": "google_ads", "campaign_id": "C-1001", "clicks": 10}], "last_ingestion": "2025-01-02T00:00:00Z"}`
+	require.NoError(t, store.ReadSnapshot(strings.NewReader(snapshot)))
+
+	reopened, err := NewFileStorage(path)
+	require.NoError(t, err)
+	count, err := reopened.CountTransformedData(nil)
+	require.NoError(t, err)
+	assert.Equal(t, 1, count)
+	assert.True(t, reopened.HasBeenIngested("2025-01-01"))
+}
@@ -1,6 +1,8 @@
 package storage
 
 import (
+	"io"
+	"sort"
 	"sync"
 	"time"
 
@@ -10,29 +12,76 @@ import (
 type Storage interface {
 	StoreTransformedData(data []models.TransformedData) error
 	GetTransformedData(from, to time.Time, filters map[string]string, limit, offset int) ([]models.TransformedData, error)
+	// DeleteTransformedData removes the rows dated within [from, to] that
+	// match filters and returns how many were removed.
+	DeleteTransformedData(from, to time.Time, filters map[string]string) (int, error)
+	// ReplaceTransformedData removes every row dated within [from, to] and
+	// stores data in their place as one step, so readers see either the old
+	// rows or the new ones. It returns how many rows were removed.
+	ReplaceTransformedData(from, to time.Time, data []models.TransformedData) (int, error)
+	// CountTransformedData returns how many stored rows match filters,
+	// without loading them for the caller. Empty filters count every row.
+	CountTransformedData(filters map[string]string) (int, error)
+	// DateRange returns the earliest and latest YYYY-MM-DD dates of the
+	// stored rows, both empty when nothing is stored.
+	DateRange() (first, last string, err error)
+	// Dimensions returns the distinct channels and campaign IDs of the rows
+	// dated within [from, to], each sorted and empty when nothing matches.
+	Dimensions(from, to time.Time) (channels, campaignIDs []string, err error)
 	GetLastIngestionTime() (time.Time, error)
 	SetLastIngestionTime(t time.Time) error
+	// WriteSnapshot writes every stored row and the last ingestion time to
+	// w as a JSON Snapshot.
+	WriteSnapshot(w io.Writer) error
+	// ReadSnapshot replaces everything stored with the Snapshot read from
+	// r. A malformed snapshot wraps ErrInvalidSnapshot and changes nothing.
+	ReadSnapshot(r io.Reader) error
 }
 
 type InMemoryStorage struct {
 	mu              sync.RWMutex
 	data            []models.TransformedData
+	index           dateIndex
 	lastIngestion   time.Time
 	ingestionTimes  map[string]time.Time // Track ingestion times by date for idempotency
+
+	// retentionDays bounds how old stored rows may be; 0 keeps them
+	// forever. now is the clock eviction measures against.
+	retentionDays int
+	now           func() time.Time
 }
 
 func NewInMemoryStorage() *InMemoryStorage {
 	return &InMemoryStorage{
 		data:           make([]models.TransformedData, 0),
 		ingestionTimes: make(map[string]time.Time),
+		now:            time.Now,
 	}
 }
 
+// SetRetention makes every store evict rows dated more than days days
+// before today (UTC). 0 disables eviction.
+func (s *InMemoryStorage) SetRetention(days int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.retentionDays = days
+}
+
 func (s *InMemoryStorage) StoreTransformedData(data []models.TransformedData) error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
+	s.storeLocked(data)
+	return nil
+}
+
+// storeLocked appends data and evicts what retention no longer keeps. The
+// caller must hold s.mu for writing.
+func (s *InMemoryStorage) storeLocked(data []models.TransformedData) {
 	// Append new data
+	for i, item := range data {
+		s.index.add(item.Date, len(s.data)+i)
+	}
 	s.data = append(s.data, data...)
 
 	// Update ingestion times for idempotency
@@ -40,7 +89,23 @@ func (s *InMemoryStorage) StoreTransformedData(data []models.TransformedData) er
 		s.ingestionTimes[item.Date] = time.Now()
 	}
 
-	return nil
+	s.evictExpiredLocked()
+}
+
+// evictExpiredLocked drops rows older than the retention window. Dates are
+// compared as YYYY-MM-DD strings; rows with unparseable dates are kept.
+func (s *InMemoryStorage) evictExpiredLocked() int {
+	if s.retentionDays <= 0 {
+		return 0
+	}
+
+	cutoff := s.now().UTC().AddDate(0, 0, -s.retentionDays).Format("2006-01-02")
+	return s.removeLocked(func(item models.TransformedData) bool {
+		if _, err := time.Parse("2006-01-02", item.Date); err != nil {
+			return false
+		}
+		return item.Date < cutoff
+	})
 }
 
 func (s *InMemoryStorage) GetTransformedData(from, to time.Time, filters map[string]string, limit, offset int) ([]models.TransformedData, error) {
@@ -49,42 +114,156 @@ func (s *InMemoryStorage) GetTransformedData(from, to time.Time, filters map[str
 
 	var filtered []models.TransformedData
 
-	for _, item := range s.data {
-		itemDate, err := time.Parse("2006-01-02", item.Date)
-		if err != nil {
+	// The index narrows the rows to the date range
+	for _, row := range s.index.between(from, to) {
+		item := s.data[row]
+		if !matchesFilters(item, filters) {
 			continue
 		}
+		filtered = append(filtered, item)
+	}
+
+	return paginate(filtered, limit, offset), nil
+}
+
+// paginate returns the limit/offset window of rows; a non-positive limit
+// returns everything from offset on.
+func paginate(rows []models.TransformedData, limit, offset int) []models.TransformedData {
+	start := offset
+	end := offset + limit
+	if limit <= 0 {
+		end = len(rows)
+	}
+	if start >= len(rows) {
+		return []models.TransformedData{}
+	}
+	if end > len(rows) {
+		end = len(rows)
+	}
+
+	return rows[start:end]
+}
+
+func (s *InMemoryStorage) DeleteTransformedData(from, to time.Time, filters map[string]string) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.removeLocked(func(item models.TransformedData) bool {
+		itemDate, err := time.Parse("2006-01-02", item.Date)
+		inRange := err == nil && !itemDate.Before(from) && !itemDate.After(to)
+		return inRange && matchesFilters(item, filters)
+	}), nil
+}
+
+func (s *InMemoryStorage) ReplaceTransformedData(from, to time.Time, data []models.TransformedData) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	deleted := s.removeLocked(func(item models.TransformedData) bool {
+		itemDate, err := time.Parse("2006-01-02", item.Date)
+		return err == nil && !itemDate.Before(from) && !itemDate.After(to)
+	})
+	s.storeLocked(data)
+	return deleted, nil
+}
 
-		// Filter by date range
-		if itemDate.Before(from) || itemDate.After(to) {
+// removeLocked drops the rows matching remove and returns how many went.
+// The caller must hold s.mu for writing.
+func (s *InMemoryStorage) removeLocked(remove func(models.TransformedData) bool) int {
+	kept := s.data[:0]
+	remainingDates := make(map[string]bool)
+	deleted := 0
+
+	for _, item := range s.data {
+		if remove(item) {
+			deleted++
 			continue
 		}
+		kept = append(kept, item)
+		remainingDates[item.Date] = true
+	}
 
-		// Apply additional filters
-		if !s.matchesFilters(item, filters) {
-			continue
+	// Zero the leftover tail so the removed rows can be garbage collected
+	for i := len(kept); i < len(s.data); i++ {
+		s.data[i] = models.TransformedData{}
+	}
+	s.data = kept
+	if deleted > 0 {
+		s.index.rebuild(s.data)
+	}
+
+	// Dates with nothing left no longer count as ingested
+	for date := range s.ingestionTimes {
+		if !remainingDates[date] {
+			delete(s.ingestionTimes, date)
 		}
+	}
 
-		filtered = append(filtered, item)
+	return deleted
+}
+
+func (s *InMemoryStorage) CountTransformedData(filters map[string]string) (int, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if len(filters) == 0 {
+		return len(s.data), nil
 	}
 
-	// Apply pagination
-	start := offset
-	end := offset + limit
-	if limit <= 0 {
-		end = len(filtered)
+	count := 0
+	for _, item := range s.data {
+		if matchesFilters(item, filters) {
+			count++
+		}
 	}
-	if start >= len(filtered) {
-		return []models.TransformedData{}, nil
+	return count, nil
+}
+
+func (s *InMemoryStorage) DateRange() (string, string, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var first, last string
+	for _, item := range s.data {
+		if _, err := time.Parse("2006-01-02", item.Date); err != nil {
+			continue
+		}
+		if first == "" || item.Date < first {
+			first = item.Date
+		}
+		if item.Date > last {
+			last = item.Date
+		}
 	}
-	if end > len(filtered) {
-		end = len(filtered)
+	return first, last, nil
+}
+
+// Dimensions walks the date index for the range and collects the distinct
+// values without copying any rows.
+func (s *InMemoryStorage) Dimensions(from, to time.Time) ([]string, []string, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	channels := make(map[string]struct{})
+	campaigns := make(map[string]struct{})
+	for _, row := range s.index.between(from, to) {
+		channels[s.data[row].Channel] = struct{}{}
+		campaigns[s.data[row].CampaignID] = struct{}{}
 	}
+	return sortedKeys(channels), sortedKeys(campaigns), nil
+}
 
-	return filtered[start:end], nil
+// sortedKeys returns the keys of set in ascending order.
+func sortedKeys(set map[string]struct{}) []string {
+	keys := make([]string, 0, len(set))
+	for key := range set {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
 }
 
-func (s *InMemoryStorage) matchesFilters(item models.TransformedData, filters map[string]string) bool {
+func matchesFilters(item models.TransformedData, filters map[string]string) bool {
 	for key, value := range filters {
 		switch key {
 		case "channel":
@@ -96,8 +275,17 @@ func (s *InMemoryStorage) matchesFilters(item models.TransformedData, filters ma
 				return false
 			}
 		case "utm_campaign":
-			// This would need to be stored in the transformed data
-			// For now, we'll skip this filter
+			if item.UTMCampaign != value {
+				return false
+			}
+		case "utm_source":
+			if item.UTMSource != value {
+				return false
+			}
+		case "utm_medium":
+			if item.UTMMedium != value {
+				return false
+			}
 		}
 	}
 	return true
@@ -36,11 +36,15 @@ func TestInMemoryStorage_StoreTransformedData(t *testing.T) {
 	require.NoError(t, err)
 
 	// Verify data was stored
-	from, _ := time.Parse("2006-01-01", "2025-01-01")
-	to, _ := time.Parse("2006-01-01", "2025-01-02")
+	from, _ := time.Parse("2006-01-02", "2025-01-01")
+	to, _ := time.Parse("2006-01-02", "2025-01-02")
 	retrieved, err := storage.GetTransformedData(from, to, map[string]string{}, 0, 0)
 	require.NoError(t, err)
 	assert.Len(t, retrieved, 2)
+
+	count, err := storage.CountTransformedData(nil)
+	require.NoError(t, err)
+	assert.Equal(t, 2, count)
 }
 
 func TestInMemoryStorage_GetTransformedData(t *testing.T) {
@@ -144,8 +148,8 @@ func TestInMemoryStorage_GetTransformedData(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			from, _ := time.Parse("2006-01-01", tt.from)
-			to, _ := time.Parse("2006-01-01", tt.to)
+			from, _ := time.Parse("2006-01-02", tt.from)
+			to, _ := time.Parse("2006-01-02", tt.to)
 			
 			result, err := storage.GetTransformedData(from, to, tt.filters, tt.limit, tt.offset)
 			require.NoError(t, err)
@@ -194,3 +198,272 @@ func TestInMemoryStorage_HasBeenIngested(t *testing.T) {
 	assert.False(t, storage.HasBeenIngested("2025-01-02"))
 }
 
+
+func TestInMemoryStorage_DeleteTransformedData(t *testing.T) {
+	data := []models.TransformedData{
+		{Date: "2025-01-01", Channel: "google_ads", CampaignID: "C-1001"},
+		{Date: "2025-01-01", Channel: "facebook_ads", CampaignID: "C-2001"},
+		{Date: "2025-01-02", Channel: "google_ads", CampaignID: "C-1001"},
+		{Date: "2025-01-03", Channel: "google_ads", CampaignID: "C-1001"},
+	}
+	all := func(s *InMemoryStorage) []models.TransformedData {
+		retrieved, err := s.GetTransformedData(time.Time{}, time.Now(), map[string]string{}, 0, 0)
+		require.NoError(t, err)
+		return retrieved
+	}
+	day := func(value string) time.Time {
+		parsed, _ := time.Parse("2006-01-02", value)
+		return parsed
+	}
+
+	t.Run("date range", func(t *testing.T) {
+		storage := NewInMemoryStorage()
+		require.NoError(t, storage.StoreTransformedData(data))
+
+		deleted, err := storage.DeleteTransformedData(day("2025-01-01"), day("2025-01-02"), map[string]string{})
+		require.NoError(t, err)
+		assert.Equal(t, 3, deleted)
+
+		remaining := all(storage)
+		require.Len(t, remaining, 1)
+		assert.Equal(t, "2025-01-03", remaining[0].Date)
+		assert.False(t, storage.HasBeenIngested("2025-01-01"))
+		assert.True(t, storage.HasBeenIngested("2025-01-03"))
+	})
+
+	t.Run("channel scoped", func(t *testing.T) {
+		storage := NewInMemoryStorage()
+		require.NoError(t, storage.StoreTransformedData(data))
+
+		deleted, err := storage.DeleteTransformedData(day("2025-01-01"), day("2025-01-03"), map[string]string{"channel": "facebook_ads"})
+		require.NoError(t, err)
+		assert.Equal(t, 1, deleted)
+
+		remaining := all(storage)
+		require.Len(t, remaining, 3)
+		for _, item := range remaining {
+			assert.Equal(t, "google_ads", item.Channel)
+		}
+		// Google rows remain on that date, so it still counts as ingested
+		assert.True(t, storage.HasBeenIngested("2025-01-01"))
+	})
+
+	t.Run("nothing matches", func(t *testing.T) {
+		storage := NewInMemoryStorage()
+		require.NoError(t, storage.StoreTransformedData(data))
+
+		deleted, err := storage.DeleteTransformedData(day("2025-02-01"), day("2025-02-28"), map[string]string{})
+		require.NoError(t, err)
+		assert.Zero(t, deleted)
+		assert.Len(t, all(storage), 4)
+	})
+}
+
+func TestInMemoryStorage_ReplaceTransformedData(t *testing.T) {
+	storage := NewInMemoryStorage()
+	require.NoError(t, storage.StoreTransformedData([]models.TransformedData{
+		{Date: "2025-01-01", Channel: "google_ads", CampaignID: "C-1001", Clicks: 100},
+		{Date: "2025-01-02", Channel: "google_ads", CampaignID: "C-1001", Clicks: 200},
+		{Date: "2025-01-02", Channel: "facebook_ads", CampaignID: "C-2001", Clicks: 300},
+		{Date: "2025-01-03", Channel: "google_ads", CampaignID: "C-1001", Clicks: 400},
+	}))
+
+	day, _ := time.Parse("2006-01-02", "2025-01-02")
+	deleted, err := storage.ReplaceTransformedData(day, day, []models.TransformedData{
+		{Date: "2025-01-02", Channel: "google_ads", CampaignID: "C-1001", Clicks: 250},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, 2, deleted)
+
+	retrieved, err := storage.GetTransformedData(time.Time{}, time.Now(), map[string]string{}, 0, 0)
+	require.NoError(t, err)
+	clicks := make([]int, len(retrieved))
+	for i, item := range retrieved {
+		clicks[i] = item.Clicks
+	}
+	// The replacement is appended like any other store
+	assert.Equal(t, []int{100, 400, 250}, clicks)
+
+	// Replacing a day with nothing empties it
+	deleted, err = storage.ReplaceTransformedData(day, day, nil)
+	require.NoError(t, err)
+	assert.Equal(t, 1, deleted)
+	assert.False(t, storage.HasBeenIngested("2025-01-02"))
+}
+
+func TestInMemoryStorage_CountTransformedData(t *testing.T) {
+	storage := NewInMemoryStorage()
+
+	count, err := storage.CountTransformedData(nil)
+	require.NoError(t, err)
+	assert.Zero(t, count)
+
+	require.NoError(t, storage.StoreTransformedData([]models.TransformedData{
+		{Date: "2025-01-01", Channel: "google_ads", CampaignID: "C-1001", UTMSource: "google"},
+		{Date: "2025-01-02", Channel: "google_ads", CampaignID: "C-1001", UTMSource: "google"},
+		{Date: "2025-01-02", Channel: "google_ads", CampaignID: "C-1002"},
+		{Date: "2025-01-01", Channel: "facebook_ads", CampaignID: "C-2001", UTMSource: "facebook"},
+	}))
+
+	tests := []struct {
+		name     string
+		filters  map[string]string
+		expected int
+	}{
+		{"no filters", nil, 4},
+		{"empty filters", map[string]string{}, 4},
+		{"channel", map[string]string{"channel": "google_ads"}, 3},
+		{"channel and campaign", map[string]string{"channel": "google_ads", "campaign_id": "C-1001"}, 2},
+		{"utm source", map[string]string{"utm_source": "facebook"}, 1},
+		{"no match", map[string]string{"channel": "tiktok_ads"}, 0},
+	}
+
+	from, _ := time.Parse("2006-01-02", "2025-01-01")
+	to, _ := time.Parse("2006-01-02", "2025-01-31")
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			count, err := storage.CountTransformedData(tt.filters)
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, count)
+
+			// The count agrees with the rows a full read returns
+			rows, err := storage.GetTransformedData(from, to, tt.filters, 0, 0)
+			require.NoError(t, err)
+			assert.Len(t, rows, count)
+		})
+	}
+}
+
+func TestInMemoryStorage_Retention(t *testing.T) {
+	storage := NewInMemoryStorage()
+	storage.now = func() time.Time { return time.Date(2025, 3, 31, 15, 0, 0, 0, time.UTC) }
+	storage.SetRetention(30)
+
+	// The cutoff is 2025-03-01: rows dated that day or later are kept
+	require.NoError(t, storage.StoreTransformedData([]models.TransformedData{
+		{Date: "2025-01-15", Channel: "google_ads", CampaignID: "C-1001"},
+		{Date: "2025-02-28", Channel: "google_ads", CampaignID: "C-1001"},
+		{Date: "2025-03-01", Channel: "google_ads", CampaignID: "C-1001"},
+		{Date: "2025-03-30", Channel: "facebook_ads", CampaignID: "C-2001"},
+	}))
+
+	count, err := storage.CountTransformedData(nil)
+	require.NoError(t, err)
+	assert.Equal(t, 2, count)
+
+	from, _ := time.Parse("2006-01-02", "2025-01-01")
+	to, _ := time.Parse("2006-01-02", "2025-12-31")
+	retrieved, err := storage.GetTransformedData(from, to, map[string]string{}, 0, 0)
+	require.NoError(t, err)
+	require.Len(t, retrieved, 2)
+	assert.Equal(t, "2025-03-01", retrieved[0].Date)
+	assert.Equal(t, "2025-03-30", retrieved[1].Date)
+
+	assert.False(t, storage.HasBeenIngested("2025-02-28"))
+	assert.True(t, storage.HasBeenIngested("2025-03-01"))
+
+	// As the clock moves on, the next store evicts rows that have aged out
+	storage.now = func() time.Time { return time.Date(2025, 4, 15, 0, 0, 0, 0, time.UTC) }
+	require.NoError(t, storage.StoreTransformedData([]models.TransformedData{
+		{Date: "2025-04-14", Channel: "google_ads", CampaignID: "C-1001"},
+	}))
+
+	retrieved, err = storage.GetTransformedData(from, to, map[string]string{}, 0, 0)
+	require.NoError(t, err)
+	require.Len(t, retrieved, 2)
+	assert.Equal(t, "2025-03-30", retrieved[0].Date)
+	assert.Equal(t, "2025-04-14", retrieved[1].Date)
+}
+
+func TestInMemoryStorage_RetentionDisabled(t *testing.T) {
+	storage := NewInMemoryStorage()
+	storage.now = func() time.Time { return time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC) }
+
+	require.NoError(t, storage.StoreTransformedData([]models.TransformedData{
+		{Date: "2020-01-01", Channel: "google_ads", CampaignID: "C-1001"},
+		{Date: "2025-01-01", Channel: "google_ads", CampaignID: "C-1001"},
+	}))
+
+	count, err := storage.CountTransformedData(nil)
+	require.NoError(t, err)
+	assert.Equal(t, 2, count)
+}
+
+func TestInMemoryStorage_DateRange(t *testing.T) {
+	storage := NewInMemoryStorage()
+
+	first, last, err := storage.DateRange()
+	require.NoError(t, err)
+	assert.Empty(t, first)
+	assert.Empty(t, last)
+
+	require.NoError(t, storage.StoreTransformedData([]models.TransformedData{
+		{Date: "2025-01-05", Channel: "google_ads", CampaignID: "C-1001"},
+		{Date: "2025-01-02", Channel: "google_ads", CampaignID: "C-1001"},
+		{Date: "not-a-date", Channel: "google_ads", CampaignID: "C-1001"},
+		{Date: "2025-01-09", Channel: "facebook_ads", CampaignID: "C-2001"},
+	}))
+
+	first, last, err = storage.DateRange()
+	require.NoError(t, err)
+	assert.Equal(t, "2025-01-02", first)
+	assert.Equal(t, "2025-01-09", last)
+}
+
+func TestInMemoryStorage_Dimensions(t *testing.T) {
+	storage := NewInMemoryStorage()
+	from, _ := time.Parse("2006-01-02", "2025-01-01")
+	to, _ := time.Parse("2006-01-02", "2025-01-31")
+
+	channels, campaigns, err := storage.Dimensions(from, to)
+	require.NoError(t, err)
+	assert.Empty(t, channels)
+	assert.Empty(t, campaigns)
+
+	// Channels and campaigns repeat across days and each other
+	require.NoError(t, storage.StoreTransformedData([]models.TransformedData{
+		{Date: "2025-01-01", Channel: "google_ads", CampaignID: "C-1001"},
+		{Date: "2025-01-02", Channel: "google_ads", CampaignID: "C-1001"},
+		{Date: "2025-01-02", Channel: "google_ads", CampaignID: "C-1002"},
+		{Date: "2025-01-03", Channel: "facebook_ads", CampaignID: "C-1002"},
+		{Date: "2025-01-03", Channel: "facebook_ads", CampaignID: "C-2001"},
+		{Date: "2025-02-01", Channel: "linkedin_ads", CampaignID: "C-3001"},
+	}))
+
+	channels, campaigns, err = storage.Dimensions(from, to)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"facebook_ads", "google_ads"}, channels)
+	assert.Equal(t, []string{"C-1001", "C-1002", "C-2001"}, campaigns)
+
+	day, _ := time.Parse("2006-01-02", "2025-01-03")
+	channels, campaigns, err = storage.Dimensions(day, day)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"facebook_ads"}, channels)
+	assert.Equal(t, []string{"C-1002", "C-2001"}, campaigns)
+}
+
+func TestInMemoryStorage_UTMFilters(t *testing.T) {
+	storage := NewInMemoryStorage()
+	require.NoError(t, storage.StoreTransformedData([]models.TransformedData{
+		{Date: "2025-01-01", CampaignID: "C-1", UTMCampaign: "spring", UTMSource: "google", UTMMedium: "cpc"},
+		{Date: "2025-01-01", CampaignID: "C-2", UTMCampaign: "spring", UTMSource: "facebook", UTMMedium: "cpc"},
+		{Date: "2025-01-01", CampaignID: "C-3", UTMCampaign: "summer", UTMSource: "google", UTMMedium: "display"},
+		{Date: "2025-01-01", CampaignID: "C-4"},
+	}))
+
+	from, _ := time.Parse("2006-01-02", "2025-01-01")
+	campaigns := func(filters map[string]string) []string {
+		data, err := storage.GetTransformedData(from, from, filters, 0, 0)
+		require.NoError(t, err)
+		var ids []string
+		for _, item := range data {
+			ids = append(ids, item.CampaignID)
+		}
+		return ids
+	}
+
+	assert.Equal(t, []string{"C-1", "C-3"}, campaigns(map[string]string{"utm_source": "google"}))
+	assert.Equal(t, []string{"C-1", "C-2"}, campaigns(map[string]string{"utm_medium": "cpc"}))
+	assert.Equal(t, []string{"C-3"}, campaigns(map[string]string{"utm_campaign": "summer"}))
+	assert.Equal(t, []string{"C-2"}, campaigns(map[string]string{"utm_source": "facebook", "utm_medium": "cpc"}))
+}
@@ -0,0 +1,108 @@
+package telemetry
+
+import (
+	"net/http"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "admira_etl"
+
+// Registry holds every collector exposed at /metrics. A dedicated registry
+// keeps the output limited to what this service registers.
+var Registry = prometheus.NewRegistry()
+
+// ETLMetrics instruments ingestion and export in etl.Service.
+type ETLMetrics struct {
+	IngestionDuration *prometheus.HistogramVec
+	RecordsProcessed  prometheus.Counter
+	ExportRecords     *prometheus.CounterVec
+	StoredRecords     prometheus.Gauge
+}
+
+// HTTPClientMetrics instruments outgoing requests made by http.Client.
+type HTTPClientMetrics struct {
+	Requests *prometheus.CounterVec
+	Retries  *prometheus.CounterVec
+}
+
+// APIMetrics instruments requests served by the REST API.
+type APIMetrics struct {
+	RequestDuration *prometheus.HistogramVec
+}
+
+// NewETLMetrics returns a fresh set of ETL collectors. Only ETL is
+// registered; services given another set keep their activity out of
+// /metrics.
+func NewETLMetrics() *ETLMetrics {
+	return &ETLMetrics{
+		IngestionDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "ingestion_duration_seconds",
+			Help:      "Duration of ingestion runs by outcome.",
+			Buckets:   prometheus.ExponentialBuckets(0.05, 2, 12),
+		}, []string{"status"}),
+		RecordsProcessed: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "ingestion_records_processed_total",
+			Help:      "Transformed records stored by ingestion runs.",
+		}),
+		ExportRecords: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "export_records_total",
+			Help:      "Consolidated records sent to the sink by outcome.",
+		}, []string{"status"}),
+		StoredRecords: prometheus.NewGauge(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "stored_records",
+			Help:      "Transformed records currently held in storage.",
+		}),
+	}
+}
+
+var (
+	ETL = NewETLMetrics()
+
+	HTTPClient = &HTTPClientMetrics{
+		Requests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "http_client_requests_total",
+			Help:      "Outgoing HTTP request attempts by method and status code.",
+		}, []string{"method", "status"}),
+		Retries: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "http_client_retries_total",
+			Help:      "Outgoing HTTP requests retried after a failed attempt.",
+		}, []string{"method"}),
+	}
+
+	API = &APIMetrics{
+		RequestDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "http_request_duration_seconds",
+			Help:      "Latency of API requests by method, route and status code.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"method", "route", "status"}),
+	}
+)
+
+func init() {
+	Registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		ETL.IngestionDuration,
+		ETL.RecordsProcessed,
+		ETL.ExportRecords,
+		ETL.StoredRecords,
+		HTTPClient.Requests,
+		HTTPClient.Retries,
+		API.RequestDuration,
+	)
+}
+
+// Handler serves the registry in the Prometheus exposition format.
+func Handler() http.Handler {
+	return promhttp.HandlerFor(Registry, promhttp.HandlerOpts{})
+}
@@ -0,0 +1,48 @@
+// Package version holds the build's version information. The variables are
+// meant to be set at link time, e.g.
+//
+//	go build -ldflags "-X admira-etl/internal/version.Version=1.2.0 \
+//	  -X admira-etl/internal/version.Commit=$(git rev-parse --short HEAD) \
+//	  -X admira-etl/internal/version.BuildTime=$(date -u +%Y-%m-%dT%H:%M:%SZ)"
+//
+// and read "dev" otherwise.
+package version
+
+import "runtime/debug"
+
+// Unset is the value of every variable not injected at build time.
+const Unset = "dev"
+
+var (
+	Version   = Unset
+	Commit    = Unset
+	BuildTime = Unset
+)
+
+// Info describes the running build.
+type Info struct {
+	Version   string `json:"version"`
+	Commit    string `json:"commit"`
+	BuildTime string `json:"build_time"`
+}
+
+// Get returns the injected build information. A commit or build time that
+// wasn't injected is taken from the VCS stamp the go tool embeds when
+// building from a git checkout, if there is one.
+func Get() Info {
+	info := Info{Version: Version, Commit: Commit, BuildTime: BuildTime}
+
+	build, ok := debug.ReadBuildInfo()
+	if !ok {
+		return info
+	}
+	for _, setting := range build.Settings {
+		switch {
+		case setting.Key == "vcs.revision" && info.Commit == Unset:
+			info.Commit = setting.Value
+		case setting.Key == "vcs.time" && info.BuildTime == Unset:
+			info.BuildTime = setting.Value
+		}
+	}
+	return info
+}
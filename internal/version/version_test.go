@@ -0,0 +1,19 @@
+package version
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGet(t *testing.T) {
+	// Test binaries carry no VCS stamp, so the defaults come through
+	assert.Equal(t, Info{Version: Unset, Commit: Unset, BuildTime: Unset}, Get())
+
+	defer func(version, commit, buildTime string) {
+		Version, Commit, BuildTime = version, commit, buildTime
+	}(Version, Commit, BuildTime)
+	Version, Commit, BuildTime = "1.2.0", "abc1234", "2025-01-01T00:00:00Z"
+
+	assert.Equal(t, Info{Version: "1.2.0", Commit: "abc1234", BuildTime: "2025-01-01T00:00:00Z"}, Get())
+}
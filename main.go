@@ -7,11 +7,13 @@ import (
 	"os"
 	"os/signal"
 	"syscall"
-	"time"
 
 	"admira-etl/internal/api"
 	"admira-etl/internal/config"
+	"admira-etl/internal/constants"
 	"admira-etl/internal/etl"
+	"admira-etl/internal/logredact"
+	"admira-etl/internal/scheduler"
 	"admira-etl/internal/storage"
 
 	"github.com/gin-gonic/gin"
@@ -26,7 +28,10 @@ func main() {
 	}
 
 	// Initialize configuration
-	cfg := config.Load()
+	cfg, err := config.Load()
+	if err != nil {
+		log.Fatalf("Failed to load configuration: %v", err)
+	}
 
 	// Initialize logger
 	logger := logrus.New()
@@ -38,12 +43,85 @@ func main() {
 	} else {
 		logger.SetLevel(logrus.InfoLevel)
 	}
+	if cfg.LogRedact {
+		logger.AddHook(logredact.New(cfg.LogRedactFields))
+	}
 
-	// Initialize storage
-	store := storage.NewInMemoryStorage()
+	// Fail fast on misconfiguration
+	if err := cfg.Validate(); err != nil {
+		logger.WithError(err).Fatal("Invalid configuration")
+	}
+
+	// Initialize storage. Each backend also knows how to open the separate
+	// storage of another namespace
+	var store storage.Storage
+	var openNamespace storage.Opener
+	switch cfg.StorageBackend {
+	case constants.StorageBackendFile:
+		fileStore, err := storage.NewFileStorage(cfg.StorageFilePath)
+		if err != nil {
+			logger.WithError(err).Fatal("Failed to initialize file storage")
+		}
+		fileStore.SetRetention(cfg.DataRetentionDays)
+		store = fileStore
+		openNamespace = func(namespace string) (storage.Storage, error) {
+			fileStore, err := storage.NewFileStorage(storage.NamespacePath(cfg.StorageFilePath, namespace))
+			if err != nil {
+				return nil, err
+			}
+			fileStore.SetRetention(cfg.DataRetentionDays)
+			return fileStore, nil
+		}
+	case constants.StorageBackendRedis:
+		redisStore, err := storage.NewRedisStorage(cfg.RedisURL)
+		if err != nil {
+			logger.WithError(err).Fatal("Failed to initialize redis storage")
+		}
+		defer redisStore.Close()
+		redisStore.SetRetention(cfg.DataRetentionDays)
+		store = redisStore
+		openNamespace = func(namespace string) (storage.Storage, error) {
+			return redisStore.Namespace(namespace), nil
+		}
+	case constants.StorageBackendMemory:
+		memoryStore := storage.NewInMemoryStorage()
+		memoryStore.SetRetention(cfg.DataRetentionDays)
+		store = memoryStore
+		openNamespace = func(namespace string) (storage.Storage, error) {
+			memoryStore := storage.NewInMemoryStorage()
+			memoryStore.SetRetention(cfg.DataRetentionDays)
+			return memoryStore, nil
+		}
+	default:
+		logger.WithField("backend", cfg.StorageBackend).Fatal("Unknown storage backend")
+	}
+	namespaces := storage.NewNamespaces(store, openNamespace)
+	store, err = namespaces.Get(cfg.Namespace)
+	if err != nil {
+		logger.WithError(err).Fatal("Failed to open storage namespace")
+	}
+	logger.WithFields(logrus.Fields{
+		"backend":        cfg.StorageBackend,
+		"retention_days": cfg.DataRetentionDays,
+		"namespace":      cfg.Namespace,
+	}).Info("Storage initialized")
 
 	// Initialize ETL service
 	etlService := etl.NewService(cfg, store, logger)
+	etlService.SetNamespaces(namespaces)
+
+	// Start scheduled ingestion if configured
+	schedulerCtx, stopScheduler := context.WithCancel(context.Background())
+	defer stopScheduler()
+	if cfg.IngestSchedule != "" {
+		ingestScheduler, err := scheduler.New(cfg.IngestSchedule, func(ctx context.Context) error {
+			return etlService.RunIngestion(ctx, etl.IngestOptions{})
+		}, logger)
+		if err != nil {
+			logger.WithError(err).Fatal("Failed to initialize ingestion scheduler")
+		}
+		go ingestScheduler.Run(schedulerCtx)
+	}
 
 	// Initialize API handlers
 	handlers := api.NewHandlers(etlService, logger)
@@ -51,32 +129,26 @@ func main() {
 	// Setup router
 	router := gin.New()
 	router.Use(gin.Logger())
-	router.Use(gin.Recovery())
+	router.Use(api.Recovery(logger))
 
-	// Add request ID middleware
-	router.Use(func(c *gin.Context) {
-		c.Header("X-Request-ID", c.GetHeader("X-Request-ID"))
-		c.Next()
-	})
+	// Propagate the request ID to responses and downstream calls
+	router.Use(api.RequestID())
 
-	// Setup routes
-	api.SetupRoutes(router, handlers)
+	// Cancel and answer requests running past REQUEST_TIMEOUT
+	router.Use(api.RequestTimeout(cfg.RequestTimeout, logger))
 
-	// Start server with graceful shutdown
-	port := os.Getenv("PORT")
-	if port == "" {
-		port = "8080"
-	}
+	// Setup routes
+	api.SetupRoutes(router, handlers, cfg)
 
 	// Create server with graceful shutdown
 	srv := &http.Server{
-		Addr:    ":" + port,
+		Addr:    ":" + cfg.Port,
 		Handler: router,
 	}
 
 	// Start server in a goroutine
 	go func() {
-		logger.WithField("port", port).Info("Starting server")
+		logger.WithField("port", cfg.Port).Info("Starting server")
 		if err := srv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
 			logger.WithError(err).Fatal("Failed to start server")
 		}
@@ -88,10 +160,17 @@ func main() {
 	<-quit
 	logger.Info("Shutting down server...")
 
-	// Give outstanding requests 30 seconds to complete
-	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	// Give outstanding requests up to the configured timeout to complete
+	ctx, cancel := context.WithTimeout(context.Background(), cfg.ShutdownTimeout)
 	defer cancel()
 
+	// Stop scheduling and ETL work first so long-running ingestions unwind
+	// instead of holding up the HTTP drain
+	stopScheduler()
+	if err := etlService.Shutdown(ctx); err != nil {
+		logger.WithError(err).Error("ETL work did not stop in time")
+	}
+
 	if err := srv.Shutdown(ctx); err != nil {
 		logger.WithError(err).Fatal("Server forced to shutdown")
 	}